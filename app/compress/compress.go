@@ -0,0 +1,229 @@
+// Package compress implements the record batch compression codecs Kafka
+// selects with the low three bits of a batch's Attributes.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/snappy/xerial"
+	"github.com/klauspost/compress/zstd"
+)
+
+// MaxDecompressedBytes is the most a batch's records may decompress to. It
+// follows socket.request.max.bytes, the largest request the broker reads.
+var MaxDecompressedBytes int32 = 104857600
+
+// Codec is the compression type stored in record batch attributes
+type Codec int8
+
+const (
+	None   Codec = 0
+	Gzip   Codec = 1
+	Snappy Codec = 2
+	LZ4    Codec = 3
+	Zstd   Codec = 4
+)
+
+func (c Codec) String() string {
+	switch c {
+	case None:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Snappy:
+		return "snappy"
+	case LZ4:
+		return "lz4"
+	case Zstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("unknown(%d)", int8(c))
+}
+
+// ProducerCompressionType is the compression.type value that keeps whatever
+// codec the producer used
+const ProducerCompressionType = "producer"
+
+// ParseCompressionType maps a compression.type config value to a codec.
+// "producer" is not a codec and must be handled by the caller.
+func ParseCompressionType(name string) (Codec, bool) {
+	switch name {
+	case "uncompressed":
+		return None, true
+	case "gzip":
+		return Gzip, true
+	case "snappy":
+		return Snappy, true
+	case "lz4":
+		return LZ4, true
+	case "zstd":
+		return Zstd, true
+	}
+	return None, false
+}
+
+// ValidCompressionType reports whether name is an accepted compression.type value
+func ValidCompressionType(name string) bool {
+	_, ok := ParseCompressionType(name)
+	return ok || name == ProducerCompressionType
+}
+
+// zstd encoders and decoders are safe for concurrent EncodeAll/DecodeAll calls
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithZeroFrames(true))
+
+	// zstdDecoder is rebuilt when MaxDecompressedBytes changes, since the
+	// limit is fixed when a decoder is created
+	zstdMu           sync.Mutex
+	zstdDecoder      *zstd.Decoder
+	zstdDecoderLimit int32
+)
+
+// Compress encodes data with codec
+func Compress(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case None:
+		return data, nil
+	case Gzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Snappy:
+		// xerial framing, as written by the Java producer; plain snappy is accepted on decode
+		return xerial.Encode(nil, data), nil
+	case LZ4:
+		return encodeLZ4(data)
+	case Zstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported compression codec %v", codec)
+}
+
+// Decompress decodes data that was compressed with codec. Output larger than
+// MaxDecompressedBytes is rejected.
+func Decompress(codec Codec, data []byte) ([]byte, error) {
+	limit := MaxDecompressedBytes
+	switch codec {
+	case None:
+		return data, nil
+	case Gzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		out, err := readLimited(reader, limit)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return out, nil
+	case Snappy:
+		out, err := decodeSnappy(data, limit)
+		if err != nil {
+			return nil, fmt.Errorf("snappy: %w", err)
+		}
+		return out, nil
+	case LZ4:
+		out, err := decodeLZ4(data, limit)
+		if err != nil {
+			return nil, fmt.Errorf("lz4: %w", err)
+		}
+		return out, nil
+	case Zstd:
+		decoder, err := zstdDecoderFor(limit)
+		if err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+		out, err := decoder.DecodeAll(data, nil)
+		if err == zstd.ErrDecoderSizeExceeded {
+			return nil, fmt.Errorf("zstd: %w", tooLarge(limit))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported compression codec %v", codec)
+}
+
+func tooLarge(limit int32) error {
+	return fmt.Errorf("decompressed records exceed %d bytes", limit)
+}
+
+// readLimited reads reader to the end, failing once it yields more than limit bytes
+func readLimited(reader io.Reader, limit int32) ([]byte, error) {
+	out, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > int(limit) {
+		return nil, tooLarge(limit)
+	}
+	return out, nil
+}
+
+// decodeSnappy sums the decoded lengths stored in the snappy chunks before
+// decoding, so oversized output is rejected without being allocated
+func decodeSnappy(data []byte, limit int32) ([]byte, error) {
+	size, err := snappyDecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if size > int64(limit) {
+		return nil, tooLarge(limit)
+	}
+	return xerial.DecodeCapped(make([]byte, 0, size), data)
+}
+
+// xerialHeader starts snappy data in xerial framing: a magic, a version and a
+// compatible version, followed by length-prefixed chunks
+var xerialHeader = []byte{130, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+func snappyDecodedLen(data []byte) (int64, error) {
+	if len(data) < 8 || !bytes.Equal(data[:8], xerialHeader) {
+		size, err := s2.DecodedLen(data)
+		return int64(size), err
+	}
+	var total int64
+	for pos := 16; pos+4 <= len(data); {
+		chunk := int(binary.BigEndian.Uint32(data[pos:]))
+		pos += 4
+		if chunk < 0 || chunk > len(data)-pos {
+			return 0, xerial.ErrMalformed
+		}
+		size, err := s2.DecodedLen(data[pos : pos+chunk])
+		if err != nil {
+			return 0, err
+		}
+		total += int64(size)
+		pos += chunk
+	}
+	return total, nil
+}
+
+func zstdDecoderFor(limit int32) (*zstd.Decoder, error) {
+	zstdMu.Lock()
+	defer zstdMu.Unlock()
+	if zstdDecoder == nil || zstdDecoderLimit != limit {
+		decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(limit)))
+		if err != nil {
+			return nil, err
+		}
+		if zstdDecoder != nil {
+			zstdDecoder.Close()
+		}
+		zstdDecoder, zstdDecoderLimit = decoder, limit
+	}
+	return zstdDecoder, nil
+}
@@ -0,0 +1,32 @@
+package compress
+
+import (
+	"bytes"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+// Kafka uses the LZ4 frame format for magic v2 batches. Frames are written
+// with 64 KiB independent blocks and no checksums, like the Java client.
+
+// encodeLZ4 compresses data into a single LZ4 frame
+func encodeLZ4(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := lz4.NewWriter(&buf)
+	if err := writer.Apply(lz4.BlockSizeOption(lz4.Block64Kb), lz4.ChecksumOption(false)); err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeLZ4 decompresses one or more concatenated LZ4 frames, failing once
+// the output exceeds limit
+func decodeLZ4(data []byte, limit int32) ([]byte, error) {
+	return readLimited(lz4.NewReader(bytes.NewReader(data)), limit)
+}
@@ -0,0 +1,120 @@
+// Package config loads the broker configuration from a server.properties
+// file, KAFKA_* environment variables and command-line overrides, and applies
+// it to the settings of the other packages.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvPrefix marks environment variables holding broker configs, named like
+// the apache/kafka Docker image does: KAFKA_LOG_DIRS is log.dirs, "__" stands
+// for "_" and "___" for "-"
+const EnvPrefix = "KAFKA_"
+
+// Load reads the configuration named on the command line and applies it.
+// Later sources win: the properties file (the first argument or -config),
+// then the environment, then every -override name=value.
+func Load(args []string) error {
+	flags := flag.NewFlagSet("kafgo", flag.ContinueOnError)
+	path := flags.String("config", "", "path of a server.properties file")
+	overrides := make(map[string]string)
+	flags.Func("override", "set a config, as name=value (repeatable)", func(override string) error {
+		name, value, found := strings.Cut(override, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("expected name=value, got %q", override)
+		}
+		overrides[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		return nil
+	})
+
+	// Accept "kafgo server.properties --override ..." like kafka-server-start.sh
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		*path = args[0]
+		args = args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	props := make(map[string]string)
+	if *path != "" {
+		fileProps, err := ReadProperties(*path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Loaded %d configs from %s\n", len(fileProps), *path)
+		props = fileProps
+	}
+	for name, value := range envProperties(os.Environ()) {
+		props[name] = value
+	}
+	for name, value := range overrides {
+		props[name] = value
+	}
+	return Apply(props)
+}
+
+// envProperties returns the known configs set in the environment
+func envProperties(environ []string) map[string]string {
+	props := make(map[string]string)
+	for _, entry := range environ {
+		key, value, found := strings.Cut(entry, "=")
+		if !found || !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(key, EnvPrefix))
+		name = strings.ReplaceAll(name, "___", "-")
+		name = strings.ReplaceAll(name, "__", "\x00")
+		name = strings.ReplaceAll(name, "_", ".")
+		name = strings.ReplaceAll(name, "\x00", "_")
+		// KAFKA_OPTS, KAFKA_HEAP_OPTS and the like are not broker configs
+		if findSetting(name) != nil {
+			props[name] = value
+		}
+	}
+	return props
+}
+
+// Apply validates props and writes them to the settings they configure, in
+// the order of settings so that more specific configs win over their
+// fallbacks (log.retention.ms over log.retention.hours)
+func Apply(props map[string]string) error {
+	unknown := make([]string, 0)
+	for name := range props {
+		if findSetting(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		fmt.Printf("Warning: ignoring unsupported config %s\n", name)
+	}
+
+	for _, setting := range settings {
+		value, found := props[setting.name]
+		if !found || setting.apply == nil {
+			continue
+		}
+		if err := setting.apply(value, props); err != nil {
+			return fmt.Errorf("invalid value %q for config %s: %w", value, setting.name, err)
+		}
+	}
+	return finish(props)
+}
+
+func findSetting(name string) *setting {
+	for i := range settings {
+		if settings[i].name == name {
+			return &settings[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"kafgo/app/metadata"
+	"kafgo/app/server"
+	"kafgo/app/storage"
+)
+
+// keepSettings restores the settings a test may apply once it ends
+func keepSettings(t *testing.T) {
+	t.Helper()
+	nodeID := server.BrokerNodeID
+	host, port, listeners := server.BrokerHost, server.BrokerPort, slices.Clone(server.Listeners)
+	numPartitions := server.DefaultNumPartitions
+	logDirs, metadataLogDir := storage.LogDirs, metadata.MetadataLogDir
+	retentionMs := storage.RetentionMs
+	t.Cleanup(func() {
+		server.BrokerNodeID = nodeID
+		server.ProducerIDs.SetBrokerID(nodeID)
+		server.BrokerHost, server.BrokerPort, server.Listeners = host, port, listeners
+		server.DefaultNumPartitions = numPartitions
+		storage.LogDirs, metadata.MetadataLogDir = logDirs, metadataLogDir
+		storage.RetentionMs = retentionMs
+	})
+}
+
+func TestLoad(t *testing.T) {
+	const properties = "node.id=2\nnum.partitions=3\nlog.dirs=/file/logs\nlog.retention.hours=1\n"
+	path := filepath.Join(t.TempDir(), "server.properties")
+	if err := os.WriteFile(path, []byte(properties), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// args follow the program name; "$file" is replaced by the properties file
+		args []string
+		env  map[string]string
+		// want are node.id, num.partitions, log.retention.ms and log.dirs afterwards
+		wantNodeID        int32
+		wantNumPartitions int32
+		wantRetentionMs   int64
+		wantLogDirs       []string
+		wantErr           bool
+	}{
+		{
+			name:              "properties file",
+			args:              []string{"$file"},
+			wantNodeID:        2,
+			wantNumPartitions: 3,
+			wantRetentionMs:   3600000,
+			wantLogDirs:       []string{"/file/logs"},
+		},
+		{
+			name:              "-config flag",
+			args:              []string{"-config", "$file"},
+			wantNodeID:        2,
+			wantNumPartitions: 3,
+			wantRetentionMs:   3600000,
+			wantLogDirs:       []string{"/file/logs"},
+		},
+		{
+			name:              "environment over the file",
+			args:              []string{"$file"},
+			env:               map[string]string{"KAFKA_NODE_ID": "4", "KAFKA_LOG_DIRS": "/env/a,/env/b", "KAFKA_LOG_RETENTION_MS": "500"},
+			wantNodeID:        4,
+			wantNumPartitions: 3,
+			wantRetentionMs:   500,
+			wantLogDirs:       []string{"/env/a", "/env/b"},
+		},
+		{
+			name:              "overrides over the environment",
+			args:              []string{"$file", "--override", "node.id=5", "--override", "num.partitions = 6"},
+			env:               map[string]string{"KAFKA_NODE_ID": "4"},
+			wantNodeID:        5,
+			wantNumPartitions: 6,
+			wantRetentionMs:   3600000,
+			wantLogDirs:       []string{"/file/logs"},
+		},
+		{
+			name:              "log.retention.ms over a later log.retention.hours",
+			args:              []string{"$file", "--override", "log.retention.ms=1000"},
+			env:               map[string]string{"KAFKA_LOG_RETENTION_HOURS": "2"},
+			wantNodeID:        2,
+			wantNumPartitions: 3,
+			wantRetentionMs:   1000,
+			wantLogDirs:       []string{"/file/logs"},
+		},
+		{
+			name:              "environment variables that are not configs",
+			args:              []string{"$file"},
+			env:               map[string]string{"KAFKA_HEAP_OPTS": "-Xmx1G", "KAFKA_OPTS": "-Dfoo"},
+			wantNodeID:        2,
+			wantNumPartitions: 3,
+			wantRetentionMs:   3600000,
+			wantLogDirs:       []string{"/file/logs"},
+		},
+		{name: "missing properties file", args: []string{filepath.Join(t.TempDir(), "missing.properties")}, wantErr: true},
+		{name: "override without a value", args: []string{"--override", "node.id"}, wantErr: true},
+		{name: "invalid override value", args: []string{"--override", "node.id=-1"}, wantErr: true},
+		{name: "invalid environment value", env: map[string]string{"KAFKA_NUM_PARTITIONS": "0"}, wantErr: true},
+		{name: "extra argument", args: []string{"$file", "other.properties"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keepSettings(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			args := slices.Clone(tt.args)
+			for i, arg := range args {
+				if arg == "$file" {
+					args[i] = path
+				}
+			}
+
+			err := Load(args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if server.BrokerNodeID != tt.wantNodeID {
+				t.Errorf("node.id %d, want %d", server.BrokerNodeID, tt.wantNodeID)
+			}
+			if server.DefaultNumPartitions != tt.wantNumPartitions {
+				t.Errorf("num.partitions %d, want %d", server.DefaultNumPartitions, tt.wantNumPartitions)
+			}
+			if storage.RetentionMs != tt.wantRetentionMs {
+				t.Errorf("log.retention.ms %d, want %d", storage.RetentionMs, tt.wantRetentionMs)
+			}
+			if !slices.Equal(storage.LogDirs, tt.wantLogDirs) {
+				t.Errorf("log.dirs %v, want %v", storage.LogDirs, tt.wantLogDirs)
+			}
+			// metadata.log.dir defaults to the first log directory
+			if metadata.MetadataLogDir != tt.wantLogDirs[0] {
+				t.Errorf("metadata.log.dir %s, want %s", metadata.MetadataLogDir, tt.wantLogDirs[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReadProperties reads a Java properties file such as Kafka's
+// server.properties: "key=value" or "key: value" lines, "#" and "!" comments
+// and lines continued with a trailing backslash
+func ReadProperties(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	var logical strings.Builder
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		// An odd number of trailing backslashes continues the line
+		trailing := len(line) - len(strings.TrimRight(line, `\`))
+		if trailing%2 == 1 {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+		entry := logical.String()
+		logical.Reset()
+
+		key, value, found := cutProperty(entry)
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected key=value", path, lineNumber)
+		}
+		props[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// The last line may end with a backslash continuing nothing
+	if logical.Len() > 0 {
+		key, value, found := cutProperty(logical.String())
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected key=value", path, lineNumber)
+		}
+		props[key] = value
+	}
+	return props, nil
+}
+
+// cutProperty splits a properties line at its first unescaped '=' or ':'
+func cutProperty(line string) (string, string, bool) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			key := strings.TrimSpace(unescape(line[:i]))
+			return key, strings.TrimSpace(unescape(line[i+1:])), key != ""
+		}
+	}
+	return "", "", false
+}
+
+// unescape removes the backslashes of escaped characters such as "\:" and "\="
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ReadProperties must turn any file into either properties or an error; a
+// panic fails the fuzz run. Run it with e.g.
+//
+//	go test ./app/config -run '^$' -fuzz FuzzReadProperties -fuzztime 30s
+func FuzzReadProperties(f *testing.F) {
+	f.Add([]byte("node.id=1\nlisteners=PLAINTEXT://:9092\n"))
+	f.Add([]byte("# comment\n! comment\nlog.dirs = /a,\\\n  /b\n"))
+	f.Add([]byte("key\\:with\\=escapes: value\\t\n"))
+	f.Add([]byte("no separator\n"))
+	f.Add([]byte("trailing=\\"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := filepath.Join(t.TempDir(), "server.properties")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		props, err := ReadProperties(path)
+		if err != nil {
+			return
+		}
+		for key := range props {
+			if key == "" {
+				t.Fatalf("empty key in %q", data)
+			}
+		}
+	})
+}
@@ -0,0 +1,314 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"kafgo/app/compress"
+	"kafgo/app/group"
+	"kafgo/app/metadata"
+	"kafgo/app/server"
+	"kafgo/app/storage"
+	"kafgo/app/txn"
+)
+
+// setting is a supported broker config and how to apply a value of it
+type setting struct {
+	name  string
+	apply func(value string, props map[string]string) error
+}
+
+// settings lists every supported config. A config listed after another one
+// overrides it: node.id wins over broker.id and log.retention.ms over
+// log.retention.minutes and log.retention.hours.
+var settings = []setting{
+	{"broker.id", nodeID},
+	{"node.id", nodeID},
+	// The listeners are applied by finish
+	{"listeners", nil},
+	{"advertised.listeners", nil},
+	{"controller.listener.names", nil},
+	{"log.dir", logDirs},
+	{"log.dirs", logDirs},
+	{"metadata.log.dir", stringValue(&metadata.MetadataLogDir)},
+	{"num.partitions", int32Value(&server.DefaultNumPartitions, 1, math.MaxInt32)},
+	{"default.replication.factor", int16Value(&server.DefaultReplicationFactor, 1, math.MaxInt16)},
+	{"socket.request.max.bytes", socketRequestMaxBytes},
+
+	{"log.segment.bytes", int64Value(&storage.SegmentBytes, 14, math.MaxInt32)},
+	{"log.roll.hours", scaledInt64Value(&storage.SegmentMs, time.Hour, 1)},
+	{"log.roll.ms", int64Value(&storage.SegmentMs, 1, math.MaxInt64)},
+	{"log.index.size.max.bytes", intValue(&storage.SegmentIndexBytes, 4, math.MaxInt32)},
+	{"log.index.interval.bytes", intValue(&storage.IndexIntervalBytes, 0, math.MaxInt32)},
+	{"log.message.timestamp.type", oneOf(&storage.LogMessageTimestampType, "CreateTime", "LogAppendTime")},
+	{"compression.type", compressionType},
+	{"log.retention.hours", scaledInt64Value(&storage.RetentionMs, time.Hour, -1)},
+	{"log.retention.minutes", scaledInt64Value(&storage.RetentionMs, time.Minute, -1)},
+	{"log.retention.ms", int64Value(&storage.RetentionMs, -1, math.MaxInt64)},
+	{"log.retention.bytes", int64Value(&storage.RetentionBytes, -1, math.MaxInt64)},
+	{"log.retention.check.interval.ms", int64Value(&storage.RetentionCheckIntervalMs, 1, math.MaxInt64)},
+	{"log.cleanup.policy", cleanupPolicy},
+	{"log.cleaner.delete.retention.ms", int64Value(&storage.DeleteRetentionMs, 0, math.MaxInt64)},
+	{"log.cleaner.backoff.ms", int64Value(&storage.LogCleanerBackoffMs, 1, math.MaxInt64)},
+	{"log.flush.interval.messages", int64Value(&storage.FlushIntervalMessages, 1, math.MaxInt64)},
+	{"log.flush.interval.ms", int64Value(&storage.FlushIntervalMs, 0, math.MaxInt64)},
+	{"log.flush.scheduler.interval.ms", int64Value(&storage.FlushSchedulerIntervalMs, 1, math.MaxInt64)},
+	{"file.delete.delay.ms", int64Value(&storage.FileDeleteDelayMs, 0, math.MaxInt64)},
+
+	{"offset.metadata.max.bytes", intValue(&group.OffsetMetadataMaxBytes, 0, math.MaxInt32)},
+	{"group.min.session.timeout.ms", durationMsValue(&group.MinSessionTimeout, 0)},
+	{"group.max.session.timeout.ms", durationMsValue(&group.MaxSessionTimeout, 0)},
+	{"group.initial.rebalance.delay.ms", durationMsValue(&group.InitialRebalanceDelay, 0)},
+	{"transaction.max.timeout.ms", int32Value(&txn.TransactionMaxTimeoutMs, 1, math.MaxInt32)},
+	{"transaction.abort.timed.out.transaction.cleanup.interval.ms", durationMsValue(&txn.TransactionAbortInterval, 1)},
+}
+
+func nodeID(value string, props map[string]string) error {
+	id, err := parseInt(value, 0, math.MaxInt32, 32)
+	if err != nil {
+		return err
+	}
+	server.BrokerNodeID = int32(id)
+	server.ProducerIDs.SetBrokerID(int32(id))
+	return nil
+}
+
+func logDirs(value string, props map[string]string) error {
+	dirs := splitList(value)
+	if len(dirs) == 0 {
+		return fmt.Errorf("no log directory")
+	}
+	storage.LogDirs = dirs
+	return nil
+}
+
+// socketRequestMaxBytes also caps what a batch may decompress to
+func socketRequestMaxBytes(value string, props map[string]string) error {
+	if err := int32Value(&server.SocketRequestMaxBytes, 1, math.MaxInt32)(value, props); err != nil {
+		return err
+	}
+	compress.MaxDecompressedBytes = server.SocketRequestMaxBytes
+	return nil
+}
+
+func compressionType(value string, props map[string]string) error {
+	if !compress.ValidCompressionType(value) {
+		return fmt.Errorf("must be one of: uncompressed, gzip, snappy, lz4, zstd, producer")
+	}
+	storage.CompressionType = value
+	return nil
+}
+
+func cleanupPolicy(value string, props map[string]string) error {
+	policies := splitList(value)
+	if len(policies) == 0 {
+		return fmt.Errorf("must be one of: compact, delete")
+	}
+	for _, policy := range policies {
+		if policy != "compact" && policy != "delete" {
+			return fmt.Errorf("must be one of: compact, delete")
+		}
+	}
+	storage.LogCleanupPolicy = strings.Join(policies, ",")
+	return nil
+}
+
+// finish applies the configs that depend on others: the listeners, which
+// skip controller listeners and provide the advertised address when
+// advertised.listeners is not set, and metadata.log.dir, which defaults to
+// the first log directory
+func finish(props map[string]string) error {
+	controllerNames := make(map[string]bool)
+	for _, name := range splitList(props["controller.listener.names"]) {
+		controllerNames[name] = true
+	}
+
+	if value, found := props["listeners"]; found {
+		listeners, err := parseListeners(value, controllerNames)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for config listeners: %w", value, err)
+		}
+		server.Listeners = server.Listeners[:0]
+		for _, listener := range listeners {
+			server.Listeners = append(server.Listeners, net.JoinHostPort(listener.host, strconv.Itoa(int(listener.port))))
+		}
+		if _, advertised := props["advertised.listeners"]; !advertised {
+			server.BrokerHost, server.BrokerPort = listeners[0].host, listeners[0].port
+			if server.BrokerHost == "" || net.ParseIP(server.BrokerHost).IsUnspecified() {
+				server.BrokerHost = "localhost"
+			}
+		}
+	}
+
+	if value, found := props["advertised.listeners"]; found {
+		listeners, err := parseListeners(value, controllerNames)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for config advertised.listeners: %w", value, err)
+		}
+		host := listeners[0].host
+		if host == "" || net.ParseIP(host).IsUnspecified() {
+			return fmt.Errorf("invalid value %q for config advertised.listeners: %s is not a valid address to advertise", value, host)
+		}
+		server.BrokerHost, server.BrokerPort = host, listeners[0].port
+	}
+
+	if _, found := props["metadata.log.dir"]; !found {
+		metadata.MetadataLogDir = storage.LogDirs[0]
+	}
+	return nil
+}
+
+// listener is one entry of listeners or advertised.listeners
+type listener struct {
+	name string
+	host string
+	port int32
+}
+
+// parseListeners parses a list of NAME://host:port listeners, leaving out
+// controller listeners
+func parseListeners(value string, controllerNames map[string]bool) ([]listener, error) {
+	listeners := make([]listener, 0)
+	for _, entry := range splitList(value) {
+		name, address, found := strings.Cut(entry, "://")
+		if !found || name == "" {
+			return nil, fmt.Errorf("%s is not of the form NAME://host:port", entry)
+		}
+		host, portValue, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		port, err := parseInt(portValue, 0, math.MaxUint16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in %s: %w", entry, err)
+		}
+		if controllerNames[name] {
+			continue
+		}
+		listeners = append(listeners, listener{name: name, host: host, port: int32(port)})
+	}
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("no broker listener")
+	}
+	return listeners, nil
+}
+
+func stringValue(target *string) func(string, map[string]string) error {
+	return func(value string, props map[string]string) error {
+		if value == "" {
+			return fmt.Errorf("must not be empty")
+		}
+		*target = value
+		return nil
+	}
+}
+
+func oneOf(target *string, allowed ...string) func(string, map[string]string) error {
+	return func(value string, props map[string]string) error {
+		for _, name := range allowed {
+			if value == name {
+				*target = value
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of: %s", strings.Join(allowed, ", "))
+	}
+}
+
+func intValue(target *int, min, max int64) func(string, map[string]string) error {
+	return func(value string, props map[string]string) error {
+		n, err := parseInt(value, min, max, 32)
+		if err != nil {
+			return err
+		}
+		*target = int(n)
+		return nil
+	}
+}
+
+func int16Value(target *int16, min, max int64) func(string, map[string]string) error {
+	return func(value string, props map[string]string) error {
+		n, err := parseInt(value, min, max, 16)
+		if err != nil {
+			return err
+		}
+		*target = int16(n)
+		return nil
+	}
+}
+
+func int32Value(target *int32, min, max int64) func(string, map[string]string) error {
+	return func(value string, props map[string]string) error {
+		n, err := parseInt(value, min, max, 32)
+		if err != nil {
+			return err
+		}
+		*target = int32(n)
+		return nil
+	}
+}
+
+func int64Value(target *int64, min, max int64) func(string, map[string]string) error {
+	return func(value string, props map[string]string) error {
+		n, err := parseInt(value, min, max, 64)
+		if err != nil {
+			return err
+		}
+		*target = n
+		return nil
+	}
+}
+
+// scaledInt64Value sets a millisecond target from a value in hours or
+// minutes; -1 (no limit) is kept as is
+func scaledInt64Value(target *int64, unit time.Duration, min int64) func(string, map[string]string) error {
+	return func(value string, props map[string]string) error {
+		n, err := parseInt(value, min, math.MaxInt32, 32)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			*target = n
+		} else {
+			*target = n * unit.Milliseconds()
+		}
+		return nil
+	}
+}
+
+func durationMsValue(target *time.Duration, min int64) func(string, map[string]string) error {
+	return func(value string, props map[string]string) error {
+		n, err := parseInt(value, min, math.MaxInt64/int64(time.Millisecond), 64)
+		if err != nil {
+			return err
+		}
+		*target = time.Duration(n) * time.Millisecond
+		return nil
+	}
+}
+
+// parseInt parses a bitSize-bit integer in [min, max]
+func parseInt(value string, min, max int64, bitSize int) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("not a number")
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("must be in [%d, %d]", min, max)
+	}
+	return n, nil
+}
+
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
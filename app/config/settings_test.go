@@ -0,0 +1,82 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"kafgo/app/server"
+)
+
+func TestApplyListeners(t *testing.T) {
+	tests := []struct {
+		name          string
+		props         map[string]string
+		wantListeners []string
+		wantHost      string
+		wantPort      int32
+		wantErr       bool
+	}{
+		{
+			name:          "advertised from the first listener",
+			props:         map[string]string{"listeners": "PLAINTEXT://broker1:9093,INTERNAL://:9094"},
+			wantListeners: []string{"broker1:9093", ":9094"},
+			wantHost:      "broker1",
+			wantPort:      9093,
+		},
+		{
+			name:          "unspecified host advertised as localhost",
+			props:         map[string]string{"listeners": "PLAINTEXT://0.0.0.0:19092"},
+			wantListeners: []string{"0.0.0.0:19092"},
+			wantHost:      "localhost",
+			wantPort:      19092,
+		},
+		{
+			name:          "controller listeners skipped",
+			props:         map[string]string{"listeners": "CONTROLLER://:9093, PLAINTEXT://[::1]:9092", "controller.listener.names": "CONTROLLER"},
+			wantListeners: []string{"[::1]:9092"},
+			wantHost:      "::1",
+			wantPort:      9092,
+		},
+		{
+			name:          "advertised.listeners",
+			props:         map[string]string{"listeners": "PLAINTEXT://:9092", "advertised.listeners": "PLAINTEXT://kafka.example.com:29092"},
+			wantListeners: []string{":9092"},
+			wantHost:      "kafka.example.com",
+			wantPort:      29092,
+		},
+		{name: "missing listener name", props: map[string]string{"listeners": "://:9092"}, wantErr: true},
+		{name: "missing port", props: map[string]string{"listeners": "PLAINTEXT://broker1"}, wantErr: true},
+		{name: "port out of range", props: map[string]string{"listeners": "PLAINTEXT://:65536"}, wantErr: true},
+		{
+			name:    "only controller listeners",
+			props:   map[string]string{"listeners": "CONTROLLER://:9093", "controller.listener.names": "CONTROLLER"},
+			wantErr: true,
+		},
+		{
+			name:    "unspecified advertised host",
+			props:   map[string]string{"listeners": "PLAINTEXT://:9092", "advertised.listeners": "PLAINTEXT://0.0.0.0:9092"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keepSettings(t)
+			err := Apply(tt.props)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Apply succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(server.Listeners, tt.wantListeners) {
+				t.Errorf("listeners %v, want %v", server.Listeners, tt.wantListeners)
+			}
+			if server.BrokerHost != tt.wantHost || server.BrokerPort != tt.wantPort {
+				t.Errorf("advertised %s:%d, want %s:%d", server.BrokerHost, server.BrokerPort, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
@@ -0,0 +1,540 @@
+package group
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kafka protocol error codes returned by the group coordinator
+const (
+	ErrNone                     int16 = 0
+	COORDINATOR_NOT_AVAILABLE   int16 = 15
+	ILLEGAL_GENERATION          int16 = 22
+	INCONSISTENT_GROUP_PROTOCOL int16 = 23
+	INVALID_GROUP_ID            int16 = 24
+	UNKNOWN_MEMBER_ID           int16 = 25
+	INVALID_SESSION_TIMEOUT     int16 = 26
+	REBALANCE_IN_PROGRESS       int16 = 27
+	MEMBER_ID_REQUIRED          int16 = 79
+)
+
+// Coordinator settings, named after the broker configs they mirror
+var (
+	MinSessionTimeout     = 6 * time.Second  // group.min.session.timeout.ms
+	MaxSessionTimeout     = 30 * time.Minute // group.max.session.timeout.ms
+	InitialRebalanceDelay = 3 * time.Second  // group.initial.rebalance.delay.ms
+)
+
+// JoinParams is a decoded JoinGroup request
+type JoinParams struct {
+	GroupID            string
+	MemberID           string
+	GroupInstanceID    string
+	ClientID           string
+	SessionTimeoutMs   int32
+	RebalanceTimeoutMs int32
+	ProtocolType       string
+	Protocols          []Protocol
+	// RequireKnownMemberID is set from JoinGroup v4, where new members first get
+	// their ID with MEMBER_ID_REQUIRED and then rejoin with it
+	RequireKnownMemberID bool
+}
+
+// SyncParams is a decoded SyncGroup request
+type SyncParams struct {
+	GroupID      string
+	GenerationID int32
+	MemberID     string
+	ProtocolType string
+	ProtocolName string
+	// Assignments is only sent by the leader, keyed by member ID
+	Assignments map[string][]byte
+}
+
+// LeavingMember identifies one member in a LeaveGroup request
+type LeavingMember struct {
+	MemberID        string
+	GroupInstanceID string
+}
+
+// Coordinator runs the classic rebalance protocol for every group on this broker
+type Coordinator struct {
+	mu sync.Mutex
+	// commitLocks serialize the OffsetCommits of each group, which append to
+	// __consumer_offsets without holding mu, so their offsets reach the cache
+	// in log order
+	commitLocks map[string]*commitLock
+	groups      map[string]*Group
+	// offsets caches the latest commit per group, mirroring __consumer_offsets
+	offsets map[string]map[TopicPartition]OffsetAndMetadata
+	// pendingTxnOffsets holds transactional commits by producer ID and group
+	// until the producer's transaction is committed or aborted
+	pendingTxnOffsets map[int64]map[string]map[TopicPartition]OffsetAndMetadata
+	// verifyTxn checks TxnOffsetCommits against the transaction coordinator
+	verifyTxn TxnVerifier
+}
+
+func NewCoordinator() *Coordinator {
+	return &Coordinator{
+		commitLocks:       make(map[string]*commitLock),
+		groups:            make(map[string]*Group),
+		offsets:           make(map[string]map[TopicPartition]OffsetAndMetadata),
+		pendingTxnOffsets: make(map[int64]map[string]map[TopicPartition]OffsetAndMetadata),
+	}
+}
+
+// Start runs the background loop that expires member sessions and rebalance timeouts
+func (c *Coordinator) Start() {
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			c.expire(now)
+		}
+	}()
+}
+
+// JoinGroup adds or refreshes a member and blocks until the join phase of the
+// resulting rebalance completes
+func (c *Coordinator) JoinGroup(params JoinParams) JoinResult {
+	c.mu.Lock()
+
+	result, wait := c.joinGroup(params)
+	c.mu.Unlock()
+
+	if wait != nil {
+		result = <-wait
+	}
+	fmt.Printf("JoinGroup %s member=%s: error=%d generation=%d leader=%s\n",
+		params.GroupID, result.MemberID, result.ErrorCode, result.GenerationID, result.LeaderID)
+	return result
+}
+
+func (c *Coordinator) joinGroup(params JoinParams) (JoinResult, chan JoinResult) {
+	result := JoinResult{MemberID: params.MemberID, GenerationID: -1}
+
+	if params.GroupID == "" {
+		result.ErrorCode = INVALID_GROUP_ID
+		return result, nil
+	}
+	sessionTimeout := time.Duration(params.SessionTimeoutMs) * time.Millisecond
+	if sessionTimeout < MinSessionTimeout || sessionTimeout > MaxSessionTimeout {
+		result.ErrorCode = INVALID_SESSION_TIMEOUT
+		return result, nil
+	}
+
+	group, exists := c.groups[params.GroupID]
+	if !exists {
+		if params.MemberID != "" {
+			result.ErrorCode = UNKNOWN_MEMBER_ID
+			return result, nil
+		}
+		group = newGroup(params.GroupID)
+		c.groups[params.GroupID] = group
+	}
+
+	member, known := group.Members[params.MemberID]
+	if !known && !group.supportsProtocols(params.ProtocolType, params.Protocols) {
+		result.ErrorCode = INCONSISTENT_GROUP_PROTOCOL
+		return result, nil
+	}
+
+	if params.MemberID == "" {
+		// A static member rejoining under a new member ID replaces its old registration
+		if params.GroupInstanceID != "" {
+			for id, existing := range group.Members {
+				if existing.GroupInstanceID == params.GroupInstanceID {
+					c.removeMember(group, id)
+				}
+			}
+		}
+
+		memberID := newMemberID(params.ClientID)
+		if params.RequireKnownMemberID && params.GroupInstanceID == "" {
+			group.pendingMembers[memberID] = time.Now().Add(sessionTimeout)
+			result.MemberID = memberID
+			result.ErrorCode = MEMBER_ID_REQUIRED
+			return result, nil
+		}
+		params.MemberID = memberID
+		return c.addMemberAndRebalance(group, params)
+	}
+
+	if !known {
+		if _, pending := group.pendingMembers[params.MemberID]; pending {
+			delete(group.pendingMembers, params.MemberID)
+			return c.addMemberAndRebalance(group, params)
+		}
+		result.ErrorCode = UNKNOWN_MEMBER_ID
+		return result, nil
+	}
+
+	// Known member rejoining
+	protocolsChanged := !sameProtocols(member.Protocols, params.Protocols)
+	member.Protocols = params.Protocols
+	member.SessionTimeout = sessionTimeout
+	member.RebalanceTimeout = time.Duration(params.RebalanceTimeoutMs) * time.Millisecond
+	member.lastHeartbeat = time.Now()
+
+	switch group.State {
+	case PreparingRebalance:
+		return c.parkJoin(group, member)
+	case CompletingRebalance, Stable:
+		if protocolsChanged || (group.State == Stable && member.MemberID == group.LeaderID) {
+			c.prepareRebalance(group)
+			return c.parkJoin(group, member)
+		}
+		// Nothing changed: resend the current generation
+		return group.joinResultFor(member), nil
+	default:
+		result.ErrorCode = UNKNOWN_MEMBER_ID
+		return result, nil
+	}
+}
+
+// addMemberAndRebalance registers a new member and parks its join
+func (c *Coordinator) addMemberAndRebalance(group *Group, params JoinParams) (JoinResult, chan JoinResult) {
+	member := &Member{
+		MemberID:         params.MemberID,
+		GroupInstanceID:  params.GroupInstanceID,
+		ClientID:         params.ClientID,
+		SessionTimeout:   time.Duration(params.SessionTimeoutMs) * time.Millisecond,
+		RebalanceTimeout: time.Duration(params.RebalanceTimeoutMs) * time.Millisecond,
+		ProtocolType:     params.ProtocolType,
+		Protocols:        params.Protocols,
+		lastHeartbeat:    time.Now(),
+	}
+	if len(group.Members) == 0 {
+		group.ProtocolType = params.ProtocolType
+	}
+	group.Members[member.MemberID] = member
+
+	if group.State != PreparingRebalance {
+		c.prepareRebalance(group)
+	} else if group.initialRebalance {
+		// Every new member of an empty group extends the initial delay, up to the rebalance timeout
+		group.rebalanceDeadline = time.Now().Add(min(InitialRebalanceDelay, group.maxRebalanceTimeout()))
+	}
+
+	return c.parkJoin(group, member)
+}
+
+// parkJoin registers a member's pending JoinGroup and completes the join phase if it was the last one
+func (c *Coordinator) parkJoin(group *Group, member *Member) (JoinResult, chan JoinResult) {
+	wait := make(chan JoinResult, 1)
+	member.awaitingJoin = wait
+	c.maybeCompleteJoin(group)
+	return JoinResult{}, wait
+}
+
+// prepareRebalance moves the group to PreparingRebalance, failing any parked SyncGroup
+func (c *Coordinator) prepareRebalance(group *Group) {
+	for _, member := range group.Members {
+		if member.awaitingSync != nil {
+			member.awaitingSync <- SyncResult{ErrorCode: REBALANCE_IN_PROGRESS}
+			member.awaitingSync = nil
+		}
+	}
+
+	group.initialRebalance = group.State == Empty
+	if group.initialRebalance {
+		group.rebalanceDeadline = time.Now().Add(min(InitialRebalanceDelay, group.maxRebalanceTimeout()))
+	} else {
+		group.rebalanceDeadline = time.Now().Add(group.maxRebalanceTimeout())
+	}
+	fmt.Printf("Group %s: %s -> PreparingRebalance\n", group.ID, group.State)
+	group.State = PreparingRebalance
+}
+
+// maybeCompleteJoin finishes the join phase once every member has rejoined
+func (c *Coordinator) maybeCompleteJoin(group *Group) {
+	if group.State != PreparingRebalance || !group.allMembersJoined() {
+		return
+	}
+	if group.initialRebalance && time.Now().Before(group.rebalanceDeadline) {
+		return
+	}
+	c.completeJoin(group)
+}
+
+// completeJoin starts a new generation with the members that rejoined and answers their JoinGroups
+func (c *Coordinator) completeJoin(group *Group) {
+	for id, member := range group.Members {
+		if member.awaitingJoin == nil {
+			fmt.Printf("Group %s: member %s did not rejoin in time\n", group.ID, id)
+			delete(group.Members, id)
+		}
+	}
+
+	group.GenerationID++
+	group.initialRebalance = false
+	if len(group.Members) == 0 {
+		group.State = Empty
+		group.ProtocolName = ""
+		group.LeaderID = ""
+		fmt.Printf("Group %s: generation %d is empty\n", group.ID, group.GenerationID)
+		return
+	}
+
+	group.ProtocolName = group.selectProtocol()
+	if _, exists := group.Members[group.LeaderID]; !exists {
+		ids := make([]string, 0, len(group.Members))
+		for id := range group.Members {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		group.LeaderID = ids[0]
+	}
+	group.State = CompletingRebalance
+	fmt.Printf("Group %s: generation %d with %d members, protocol %s, leader %s\n",
+		group.ID, group.GenerationID, len(group.Members), group.ProtocolName, group.LeaderID)
+
+	now := time.Now()
+	for _, member := range group.Members {
+		member.lastHeartbeat = now
+		member.awaitingJoin <- group.joinResultFor(member)
+		member.awaitingJoin = nil
+	}
+}
+
+// joinResultFor builds the JoinGroup response of a member for the current generation
+func (g *Group) joinResultFor(member *Member) JoinResult {
+	result := JoinResult{
+		ErrorCode:    ErrNone,
+		GenerationID: g.GenerationID,
+		ProtocolType: g.ProtocolType,
+		ProtocolName: g.ProtocolName,
+		LeaderID:     g.LeaderID,
+		MemberID:     member.MemberID,
+	}
+	if member.MemberID == g.LeaderID {
+		for _, m := range g.Members {
+			result.Members = append(result.Members, MemberMetadata{
+				MemberID:        m.MemberID,
+				GroupInstanceID: m.GroupInstanceID,
+				Metadata:        m.metadataFor(g.ProtocolName),
+			})
+		}
+		sort.Slice(result.Members, func(i, j int) bool {
+			return result.Members[i].MemberID < result.Members[j].MemberID
+		})
+	}
+	return result
+}
+
+// SyncGroup stores the leader's assignment and blocks until this member's assignment is known
+func (c *Coordinator) SyncGroup(params SyncParams) SyncResult {
+	c.mu.Lock()
+	result, wait := c.syncGroup(params)
+	c.mu.Unlock()
+
+	if wait != nil {
+		result = <-wait
+	}
+	fmt.Printf("SyncGroup %s member=%s: error=%d assignment=%d bytes\n",
+		params.GroupID, params.MemberID, result.ErrorCode, len(result.Assignment))
+	return result
+}
+
+func (c *Coordinator) syncGroup(params SyncParams) (SyncResult, chan SyncResult) {
+	group, exists := c.groups[params.GroupID]
+	if !exists {
+		return SyncResult{ErrorCode: UNKNOWN_MEMBER_ID}, nil
+	}
+	member, known := group.Members[params.MemberID]
+	if !known {
+		return SyncResult{ErrorCode: UNKNOWN_MEMBER_ID}, nil
+	}
+	if params.GenerationID != group.GenerationID {
+		return SyncResult{ErrorCode: ILLEGAL_GENERATION}, nil
+	}
+	if (params.ProtocolType != "" && params.ProtocolType != group.ProtocolType) ||
+		(params.ProtocolName != "" && params.ProtocolName != group.ProtocolName) {
+		return SyncResult{ErrorCode: INCONSISTENT_GROUP_PROTOCOL}, nil
+	}
+	member.lastHeartbeat = time.Now()
+
+	switch group.State {
+	case PreparingRebalance:
+		return SyncResult{ErrorCode: REBALANCE_IN_PROGRESS}, nil
+	case CompletingRebalance:
+		wait := make(chan SyncResult, 1)
+		member.awaitingSync = wait
+
+		if member.MemberID == group.LeaderID {
+			for id, m := range group.Members {
+				m.Assignment = params.Assignments[id]
+			}
+			group.State = Stable
+			fmt.Printf("Group %s: generation %d is Stable\n", group.ID, group.GenerationID)
+
+			for _, m := range group.Members {
+				if m.awaitingSync != nil {
+					m.awaitingSync <- group.syncResultFor(m)
+					m.awaitingSync = nil
+				}
+			}
+		}
+		return SyncResult{}, wait
+	case Stable:
+		return group.syncResultFor(member), nil
+	default:
+		return SyncResult{ErrorCode: UNKNOWN_MEMBER_ID}, nil
+	}
+}
+
+func (g *Group) syncResultFor(member *Member) SyncResult {
+	return SyncResult{
+		ErrorCode:    ErrNone,
+		ProtocolType: g.ProtocolType,
+		ProtocolName: g.ProtocolName,
+		Assignment:   member.Assignment,
+	}
+}
+
+// Heartbeat keeps a member's session alive and tells it when to rejoin
+func (c *Coordinator) Heartbeat(groupID string, generationID int32, memberID string) int16 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, exists := c.groups[groupID]
+	if !exists {
+		return UNKNOWN_MEMBER_ID
+	}
+	member, known := group.Members[memberID]
+	if !known {
+		return UNKNOWN_MEMBER_ID
+	}
+
+	switch group.State {
+	case PreparingRebalance:
+		member.lastHeartbeat = time.Now()
+		return REBALANCE_IN_PROGRESS
+	case CompletingRebalance, Stable:
+		if generationID != group.GenerationID {
+			return ILLEGAL_GENERATION
+		}
+		member.lastHeartbeat = time.Now()
+		return ErrNone
+	default:
+		return UNKNOWN_MEMBER_ID
+	}
+}
+
+// LeaveGroup removes members and returns an error code for each of them
+func (c *Coordinator) LeaveGroup(groupID string, members []LeavingMember) (int16, []int16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errorCodes := make([]int16, len(members))
+	group, exists := c.groups[groupID]
+	if !exists {
+		return UNKNOWN_MEMBER_ID, errorCodes
+	}
+
+	for i, leaving := range members {
+		memberID := leaving.MemberID
+		if memberID == "" && leaving.GroupInstanceID != "" {
+			for id, member := range group.Members {
+				if member.GroupInstanceID == leaving.GroupInstanceID {
+					memberID = id
+				}
+			}
+		}
+		if _, known := group.Members[memberID]; !known {
+			errorCodes[i] = UNKNOWN_MEMBER_ID
+			continue
+		}
+		fmt.Printf("Group %s: member %s left\n", groupID, memberID)
+		c.removeMember(group, memberID)
+	}
+	c.maybeRemoveGroup(group)
+	return ErrNone, errorCodes
+}
+
+// removeMember drops a member and rebalances the rest of the group
+func (c *Coordinator) removeMember(group *Group, memberID string) {
+	member := group.Members[memberID]
+	delete(group.Members, memberID)
+
+	if member.awaitingJoin != nil {
+		member.awaitingJoin <- JoinResult{ErrorCode: UNKNOWN_MEMBER_ID, MemberID: memberID, GenerationID: -1}
+		member.awaitingJoin = nil
+	}
+	if member.awaitingSync != nil {
+		member.awaitingSync <- SyncResult{ErrorCode: UNKNOWN_MEMBER_ID}
+		member.awaitingSync = nil
+	}
+
+	switch group.State {
+	case CompletingRebalance, Stable:
+		c.prepareRebalance(group)
+		c.maybeCompleteJoin(group)
+	case PreparingRebalance:
+		c.maybeCompleteJoin(group)
+	}
+}
+
+// maybeRemoveGroup drops a group that has no members left
+func (c *Coordinator) maybeRemoveGroup(group *Group) {
+	if group.State != Empty || len(group.Members) > 0 || len(group.pendingMembers) > 0 {
+		return
+	}
+	fmt.Printf("Group %s: Empty -> Dead\n", group.ID)
+	group.State = Dead
+	delete(c.groups, group.ID)
+}
+
+// expire removes members whose session timed out and completes overdue rebalances
+func (c *Coordinator) expire(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, group := range c.groups {
+		for id, deadline := range group.pendingMembers {
+			if now.After(deadline) {
+				delete(group.pendingMembers, id)
+			}
+		}
+
+		for id, member := range group.Members {
+			// Members with a parked JoinGroup or SyncGroup are waiting on us, not the other way round
+			if member.awaitingJoin != nil || member.awaitingSync != nil {
+				continue
+			}
+			if now.Sub(member.lastHeartbeat) > member.SessionTimeout {
+				fmt.Printf("Group %s: member %s session expired\n", group.ID, id)
+				c.removeMember(group, id)
+			}
+		}
+
+		if group.State == PreparingRebalance && now.After(group.rebalanceDeadline) {
+			c.completeJoin(group)
+		} else {
+			c.maybeCompleteJoin(group)
+		}
+		c.maybeRemoveGroup(group)
+	}
+}
+
+// newMemberID builds a member ID the way Kafka does: client ID plus a random UUID
+func newMemberID(clientID string) string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return fmt.Sprintf("%s-%x-%x-%x-%x-%x", clientID, id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+func sameProtocols(a, b []Protocol) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || string(a[i].Metadata) != string(b[i].Metadata) {
+			return false
+		}
+	}
+	return true
+}
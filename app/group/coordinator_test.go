@@ -0,0 +1,301 @@
+package group
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+// The tests drive joinGroup and syncGroup directly, so a parked request is an
+// unanswered channel instead of a blocked goroutine, and move time forward
+// with expire
+
+func newTestCoordinator(t *testing.T) *Coordinator {
+	saved := InitialRebalanceDelay
+	InitialRebalanceDelay = time.Second
+	t.Cleanup(func() { InitialRebalanceDelay = saved })
+	return NewCoordinator()
+}
+
+func sendJoin(c *Coordinator, groupID string, memberID string) (JoinResult, chan JoinResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.joinGroup(JoinParams{
+		GroupID:            groupID,
+		MemberID:           memberID,
+		ClientID:           "client",
+		SessionTimeoutMs:   10000,
+		RebalanceTimeoutMs: 60000,
+		ProtocolType:       "consumer",
+		Protocols:          []Protocol{{Name: "range", Metadata: []byte("subscription")}},
+	})
+}
+
+func sendSync(c *Coordinator, groupID string, generationID int32, memberID string, assignments map[string][]byte) (SyncResult, chan SyncResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.syncGroup(SyncParams{GroupID: groupID, GenerationID: generationID, MemberID: memberID, Assignments: assignments})
+}
+
+// answered returns the response sent on wait, failing if there is none yet
+func answered[T any](t *testing.T, wait chan T) T {
+	t.Helper()
+	select {
+	case result := <-wait:
+		return result
+	default:
+		t.Fatal("request is still parked")
+		panic("unreachable")
+	}
+}
+
+// parked fails if a response was sent on wait
+func parked[T any](t *testing.T, wait chan T) {
+	t.Helper()
+	select {
+	case result := <-wait:
+		t.Fatalf("parked request answered with %+v", result)
+	default:
+	}
+}
+
+func checkState(t *testing.T, c *Coordinator, groupID string, want GroupState) {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	got := Dead
+	if group, exists := c.groups[groupID]; exists {
+		got = group.State
+	}
+	if got != want {
+		t.Fatalf("group %s is %v, want %v", groupID, got, want)
+	}
+}
+
+// passInitialDelay runs the expiry loop once the initial rebalance delay is over
+func passInitialDelay(c *Coordinator) {
+	c.expire(time.Now().Add(InitialRebalanceDelay + time.Millisecond))
+}
+
+// stableGroup joins n new members to groupID and syncs them. It returns the
+// member IDs, leader first, and the generation.
+func stableGroup(t *testing.T, c *Coordinator, groupID string, n int) ([]string, int32) {
+	t.Helper()
+	waits := make([]chan JoinResult, n)
+	for i := range waits {
+		_, waits[i] = sendJoin(c, groupID, "")
+	}
+	passInitialDelay(c)
+
+	var leader string
+	var followers []string
+	var generation int32
+	for _, wait := range waits {
+		result := answered(t, wait)
+		leader, generation = result.LeaderID, result.GenerationID
+		if result.MemberID != result.LeaderID {
+			followers = append(followers, result.MemberID)
+		}
+	}
+	sort.Strings(followers)
+	members := append([]string{leader}, followers...)
+
+	assignments := make(map[string][]byte)
+	for _, id := range members {
+		assignments[id] = []byte("assignment of " + id)
+	}
+	syncWaits := make([]chan SyncResult, n)
+	for i := n - 1; i >= 0; i-- {
+		_, syncWaits[i] = sendSync(c, groupID, generation, members[i], assignments)
+	}
+	for _, wait := range syncWaits {
+		answered(t, wait)
+	}
+	checkState(t, c, groupID, Stable)
+	return members, generation
+}
+
+func TestRebalanceToStable(t *testing.T) {
+	c := newTestCoordinator(t)
+
+	// Empty -> PreparingRebalance: the first members wait out the initial delay
+	_, waitA := sendJoin(c, "group", "")
+	_, waitB := sendJoin(c, "group", "")
+	checkState(t, c, "group", PreparingRebalance)
+	c.expire(time.Now())
+	parked(t, waitA)
+	parked(t, waitB)
+
+	// PreparingRebalance -> CompletingRebalance
+	passInitialDelay(c)
+	checkState(t, c, "group", CompletingRebalance)
+	joinA, joinB := answered(t, waitA), answered(t, waitB)
+	if joinA.ErrorCode != ErrNone || joinB.ErrorCode != ErrNone {
+		t.Fatalf("join errors %d and %d", joinA.ErrorCode, joinB.ErrorCode)
+	}
+	if joinA.GenerationID != 1 || joinB.GenerationID != 1 || joinA.LeaderID != joinB.LeaderID || joinA.ProtocolName != "range" {
+		t.Fatalf("members joined different generations: %+v and %+v", joinA, joinB)
+	}
+	leader, follower := joinA, joinB
+	if joinB.MemberID == joinB.LeaderID {
+		leader, follower = joinB, joinA
+	}
+	if len(leader.Members) != 2 || len(follower.Members) != 0 {
+		t.Errorf("leader got %d members and follower %d, want 2 and 0", len(leader.Members), len(follower.Members))
+	}
+
+	// CompletingRebalance -> Stable once the leader sends the assignment
+	assignments := map[string][]byte{leader.MemberID: []byte("leader"), follower.MemberID: []byte("follower")}
+	_, leaderWait := sendSync(c, "group", 1, leader.MemberID, assignments)
+	checkState(t, c, "group", Stable)
+	if got := answered(t, leaderWait); got.ErrorCode != ErrNone || string(got.Assignment) != "leader" {
+		t.Errorf("leader sync = %+v", got)
+	}
+	// A member syncing after the group is stable is answered at once
+	if got, wait := sendSync(c, "group", 1, follower.MemberID, nil); wait != nil || string(got.Assignment) != "follower" {
+		t.Errorf("follower sync = %+v, parked %v", got, wait != nil)
+	}
+	if errorCode := c.Heartbeat("group", 1, follower.MemberID); errorCode != ErrNone {
+		t.Errorf("heartbeat error %d", errorCode)
+	}
+}
+
+func TestSyncFromFollowerBeforeLeader(t *testing.T) {
+	c := newTestCoordinator(t)
+	_, waitA := sendJoin(c, "group", "")
+	_, waitB := sendJoin(c, "group", "")
+	passInitialDelay(c)
+	leader, follower := answered(t, waitA), answered(t, waitB)
+	if follower.MemberID == follower.LeaderID {
+		leader, follower = follower, leader
+	}
+
+	// The follower's sync is parked until the leader's assignment arrives
+	_, followerWait := sendSync(c, "group", 1, follower.MemberID, nil)
+	parked(t, followerWait)
+	checkState(t, c, "group", CompletingRebalance)
+
+	assignments := map[string][]byte{leader.MemberID: []byte("leader"), follower.MemberID: []byte("follower")}
+	_, leaderWait := sendSync(c, "group", 1, leader.MemberID, assignments)
+	checkState(t, c, "group", Stable)
+	if got := answered(t, followerWait); got.ErrorCode != ErrNone || string(got.Assignment) != "follower" {
+		t.Errorf("follower sync = %+v", got)
+	}
+	if got := answered(t, leaderWait); got.ErrorCode != ErrNone || string(got.Assignment) != "leader" {
+		t.Errorf("leader sync = %+v", got)
+	}
+}
+
+func TestJoinDuringRebalance(t *testing.T) {
+	c := newTestCoordinator(t)
+	members, generation := stableGroup(t, c, "group", 2)
+
+	// A new member moves the stable group back to PreparingRebalance
+	_, newWait := sendJoin(c, "group", "")
+	checkState(t, c, "group", PreparingRebalance)
+	parked(t, newWait)
+	for _, id := range members {
+		if errorCode := c.Heartbeat("group", generation, id); errorCode != REBALANCE_IN_PROGRESS {
+			t.Errorf("heartbeat of %s error %d, want REBALANCE_IN_PROGRESS", id, errorCode)
+		}
+	}
+
+	// The join phase completes once every existing member has rejoined
+	_, leaderWait := sendJoin(c, "group", members[0])
+	parked(t, leaderWait)
+	_, followerWait := sendJoin(c, "group", members[1])
+	checkState(t, c, "group", CompletingRebalance)
+	for _, wait := range []chan JoinResult{newWait, leaderWait, followerWait} {
+		result := answered(t, wait)
+		if result.ErrorCode != ErrNone || result.GenerationID != generation+1 || result.LeaderID != members[0] {
+			t.Errorf("join result %+v, want generation %d led by %s", result, generation+1, members[0])
+		}
+	}
+
+	// A member joining while the group waits for the assignment fails parked syncs
+	_, syncWait := sendSync(c, "group", generation+1, members[1], nil)
+	parked(t, syncWait)
+	sendJoin(c, "group", "")
+	checkState(t, c, "group", PreparingRebalance)
+	if got := answered(t, syncWait); got.ErrorCode != REBALANCE_IN_PROGRESS {
+		t.Errorf("parked sync error %d, want REBALANCE_IN_PROGRESS", got.ErrorCode)
+	}
+}
+
+func TestSessionTimeoutExpiresMembers(t *testing.T) {
+	tests := []struct {
+		name      string
+		expired   []int // indexes into the member IDs, leader first
+		wantState GroupState
+	}{
+		{"follower expires", []int{1}, PreparingRebalance},
+		{"leader expires", []int{0}, PreparingRebalance},
+		{"every member expires", []int{0, 1}, Dead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCoordinator(t)
+			members, generation := stableGroup(t, c, "group", 2)
+
+			c.mu.Lock()
+			for _, i := range tt.expired {
+				c.groups["group"].Members[members[i]].lastHeartbeat = time.Now().Add(-11 * time.Second)
+			}
+			c.mu.Unlock()
+			c.expire(time.Now())
+			checkState(t, c, "group", tt.wantState)
+			if tt.wantState == Dead {
+				return
+			}
+
+			for _, i := range tt.expired {
+				if errorCode := c.Heartbeat("group", generation, members[i]); errorCode != UNKNOWN_MEMBER_ID {
+					t.Errorf("heartbeat of expired member error %d, want UNKNOWN_MEMBER_ID", errorCode)
+				}
+			}
+			survivor := members[1-tt.expired[0]]
+			_, wait := sendJoin(c, "group", survivor)
+			result := answered(t, wait)
+			if result.GenerationID != generation+1 || result.LeaderID != survivor || len(result.Members) != 1 {
+				t.Errorf("join result %+v, want generation %d led by %s alone", result, generation+1, survivor)
+			}
+		})
+	}
+}
+
+func TestLeaveGroupTriggersRebalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		leaving int // index into the member IDs, leader first
+	}{
+		{"follower leaves", 1},
+		{"leader leaves", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCoordinator(t)
+			members, generation := stableGroup(t, c, "group", 2)
+
+			errorCode, memberErrors := c.LeaveGroup("group", []LeavingMember{{MemberID: members[tt.leaving]}, {MemberID: "unknown"}})
+			if errorCode != ErrNone || memberErrors[0] != ErrNone || memberErrors[1] != UNKNOWN_MEMBER_ID {
+				t.Fatalf("LeaveGroup errors %d %v", errorCode, memberErrors)
+			}
+			checkState(t, c, "group", PreparingRebalance)
+
+			survivor := members[1-tt.leaving]
+			if errorCode := c.Heartbeat("group", generation, survivor); errorCode != REBALANCE_IN_PROGRESS {
+				t.Errorf("heartbeat error %d, want REBALANCE_IN_PROGRESS", errorCode)
+			}
+			_, wait := sendJoin(c, "group", survivor)
+			checkState(t, c, "group", CompletingRebalance)
+			result := answered(t, wait)
+			if result.GenerationID != generation+1 || result.LeaderID != survivor || len(result.Members) != 1 {
+				t.Errorf("join result %+v, want generation %d led by %s alone", result, generation+1, survivor)
+			}
+
+			// The last member leaving empties and removes the group
+			c.LeaveGroup("group", []LeavingMember{{MemberID: survivor}})
+			checkState(t, c, "group", Dead)
+		})
+	}
+}
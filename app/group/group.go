@@ -0,0 +1,206 @@
+package group
+
+import (
+	"time"
+)
+
+// GroupState follows the classic rebalance protocol state machine
+type GroupState int
+
+const (
+	// Empty: no members, but committed offsets may remain
+	Empty GroupState = iota
+	// PreparingRebalance: waiting for members to (re)join
+	PreparingRebalance
+	// CompletingRebalance: join finished, waiting for the leader's assignment
+	CompletingRebalance
+	// Stable: every member has its assignment
+	Stable
+	// Dead: the group has been removed
+	Dead
+)
+
+func (s GroupState) String() string {
+	switch s {
+	case Empty:
+		return "Empty"
+	case PreparingRebalance:
+		return "PreparingRebalance"
+	case CompletingRebalance:
+		return "CompletingRebalance"
+	case Stable:
+		return "Stable"
+	case Dead:
+		return "Dead"
+	}
+	return "Unknown"
+}
+
+// Protocol is one assignment strategy a member supports, with its subscription metadata
+type Protocol struct {
+	Name     string
+	Metadata []byte
+}
+
+// JoinResult is what a member receives once the join phase completes
+type JoinResult struct {
+	ErrorCode    int16
+	GenerationID int32
+	ProtocolType string
+	ProtocolName string
+	LeaderID     string
+	MemberID     string
+	// Members is only filled in for the leader, which computes the assignment
+	Members []MemberMetadata
+}
+
+// MemberMetadata is a member's subscription as forwarded to the leader
+type MemberMetadata struct {
+	MemberID        string
+	GroupInstanceID string
+	Metadata        []byte
+}
+
+// SyncResult carries a member's assignment once the leader has sent it
+type SyncResult struct {
+	ErrorCode    int16
+	ProtocolType string
+	ProtocolName string
+	Assignment   []byte
+}
+
+// Member is a consumer that joined the group
+type Member struct {
+	MemberID         string
+	GroupInstanceID  string
+	ClientID         string
+	SessionTimeout   time.Duration
+	RebalanceTimeout time.Duration
+	ProtocolType     string
+	Protocols        []Protocol
+	Assignment       []byte
+
+	lastHeartbeat time.Time
+	// awaitingJoin and awaitingSync deliver the response of a parked JoinGroup/SyncGroup
+	awaitingJoin chan JoinResult
+	awaitingSync chan SyncResult
+}
+
+// Group is a consumer group and its current generation
+type Group struct {
+	ID           string
+	State        GroupState
+	ProtocolType string
+	ProtocolName string
+	GenerationID int32
+	LeaderID     string
+	Members      map[string]*Member
+
+	// pendingMembers were handed a member ID (MEMBER_ID_REQUIRED) and must rejoin with it
+	pendingMembers map[string]time.Time
+
+	// rebalanceDeadline is when a PreparingRebalance gives up on members that have not rejoined
+	rebalanceDeadline time.Time
+	// initialRebalance marks the first rebalance of an empty group, which waits
+	// InitialRebalanceDelay for more members even once everyone has joined
+	initialRebalance bool
+}
+
+func newGroup(groupID string) *Group {
+	return &Group{
+		ID:             groupID,
+		State:          Empty,
+		Members:        make(map[string]*Member),
+		pendingMembers: make(map[string]time.Time),
+	}
+}
+
+// supportsProtocols reports whether a joining member can take part in the group's protocol
+func (g *Group) supportsProtocols(protocolType string, protocols []Protocol) bool {
+	if len(g.Members) == 0 {
+		return protocolType != "" && len(protocols) > 0
+	}
+	if protocolType != g.ProtocolType {
+		return false
+	}
+	for _, candidate := range g.candidateProtocols() {
+		for _, protocol := range protocols {
+			if protocol.Name == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// candidateProtocols returns the protocols every current member supports
+func (g *Group) candidateProtocols() []string {
+	counts := make(map[string]int)
+	for _, member := range g.Members {
+		for _, protocol := range member.Protocols {
+			counts[protocol.Name]++
+		}
+	}
+	candidates := make([]string, 0)
+	for name, count := range counts {
+		if count == len(g.Members) {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// selectProtocol picks the common protocol with the most first-choice votes,
+// breaking ties by name
+func (g *Group) selectProtocol() string {
+	candidates := make(map[string]bool)
+	for _, name := range g.candidateProtocols() {
+		candidates[name] = true
+	}
+
+	votes := make(map[string]int)
+	for _, member := range g.Members {
+		for _, protocol := range member.Protocols {
+			if candidates[protocol.Name] {
+				votes[protocol.Name]++
+				break
+			}
+		}
+	}
+
+	selected := ""
+	for name, count := range votes {
+		if selected == "" || count > votes[selected] || (count == votes[selected] && name < selected) {
+			selected = name
+		}
+	}
+	return selected
+}
+
+// allMembersJoined reports whether every known member has a JoinGroup parked
+func (g *Group) allMembersJoined() bool {
+	for _, member := range g.Members {
+		if member.awaitingJoin == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// maxRebalanceTimeout is how long PreparingRebalance waits for members to rejoin
+func (g *Group) maxRebalanceTimeout() time.Duration {
+	timeout := time.Duration(0)
+	for _, member := range g.Members {
+		timeout = max(timeout, member.RebalanceTimeout)
+	}
+	return timeout
+}
+
+func (m *Member) metadataFor(protocolName string) []byte {
+	for _, protocol := range m.Protocols {
+		if protocol.Name == protocolName {
+			return protocol.Metadata
+		}
+	}
+	return nil
+}
@@ -0,0 +1,420 @@
+package group
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"kafgo/app/metadata"
+	"kafgo/app/storage"
+)
+
+// ConsumerOffsetsTopic is the internal topic committed offsets are written to.
+// Every group is stored in partition 0.
+const ConsumerOffsetsTopic = "__consumer_offsets"
+
+// Error codes used by OffsetCommit/OffsetFetch
+const (
+	OFFSET_METADATA_TOO_LARGE int16 = 12
+)
+
+// OffsetMetadataMaxBytes limits the metadata string stored with a commit (offset.metadata.max.bytes)
+var OffsetMetadataMaxBytes = 4096
+
+// __consumer_offsets record versions, as written by Kafka
+const (
+	offsetCommitKeyVersion   int16 = 1
+	offsetCommitValueVersion int16 = 3
+)
+
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// OffsetAndMetadata is a committed position of a group in one partition
+type OffsetAndMetadata struct {
+	Offset          int64
+	LeaderEpoch     int32
+	Metadata        string
+	CommitTimestamp int64
+}
+
+// CommitParams is a decoded OffsetCommit request
+type CommitParams struct {
+	GroupID         string
+	GenerationID    int32
+	MemberID        string
+	GroupInstanceID string
+	Offsets         map[TopicPartition]OffsetAndMetadata
+}
+
+// CommitOffsets validates the committing member, appends the offsets to
+// __consumer_offsets and then updates the cache. It returns an error code per
+// partition. The coordinator lock is released during the append, which may
+// roll a segment or flush, so other groups' requests do not wait on the disk.
+func (c *Coordinator) CommitOffsets(params CommitParams) map[TopicPartition]int16 {
+	unlock := c.lockCommits(params.GroupID)
+	defer unlock()
+
+	errorCodes := make(map[TopicPartition]int16, len(params.Offsets))
+	setAll := func(errorCode int16) map[TopicPartition]int16 {
+		for tp := range params.Offsets {
+			errorCodes[tp] = errorCode
+		}
+		return errorCodes
+	}
+
+	c.mu.Lock()
+	errorCode := c.validateCommit(params)
+	c.mu.Unlock()
+	if errorCode != ErrNone {
+		return setAll(errorCode)
+	}
+
+	now := time.Now().UnixMilli()
+	records := make([]metadata.Record, 0, len(params.Offsets))
+	committed := make(map[TopicPartition]OffsetAndMetadata, len(params.Offsets))
+	for tp, offset := range params.Offsets {
+		if len(offset.Metadata) > OffsetMetadataMaxBytes {
+			errorCodes[tp] = OFFSET_METADATA_TOO_LARGE
+			continue
+		}
+		offset.CommitTimestamp = now
+		records = append(records, metadata.Record{
+			Key:   encodeOffsetKey(params.GroupID, tp),
+			Value: encodeOffsetValue(offset),
+		})
+		committed[tp] = offset
+	}
+	if len(records) == 0 {
+		return errorCodes
+	}
+
+	if err := appendOffsetRecords(records, now); err != nil {
+		fmt.Printf("Failed to write offsets for group %s: %v\n", params.GroupID, err)
+		for tp := range committed {
+			errorCodes[tp] = COORDINATOR_NOT_AVAILABLE
+		}
+		return errorCodes
+	}
+
+	c.mu.Lock()
+	// The group may have rebalanced or lost the member during the append. The
+	// commit is then refused, and the records already in the log are undone by
+	// rewriting the cached offsets, or tombstones where there were none.
+	if errorCode := c.validateCommit(params); errorCode != ErrNone {
+		reverts := c.revertOffsetRecords(params.GroupID, committed)
+		c.mu.Unlock()
+
+		if err := appendOffsetRecords(reverts, time.Now().UnixMilli()); err != nil {
+			fmt.Printf("Failed to undo refused offsets of group %s: %v\n", params.GroupID, err)
+		}
+		for tp := range committed {
+			errorCodes[tp] = errorCode
+		}
+		return errorCodes
+	}
+	for tp, offset := range committed {
+		c.storeOffset(params.GroupID, tp, offset)
+		errorCodes[tp] = ErrNone
+	}
+	c.mu.Unlock()
+
+	fmt.Printf("Group %s committed %d offsets\n", params.GroupID, len(committed))
+	return errorCodes
+}
+
+// commitLock serializes the appends of one group's offset commits
+type commitLock struct {
+	sync.Mutex
+	refs int // holders and waiters; the lock is dropped at zero
+}
+
+// lockCommits takes the commit lock of a group and returns its release.
+// c.mu must not be held.
+func (c *Coordinator) lockCommits(groupID string) func() {
+	c.mu.Lock()
+	lock := c.commitLocks[groupID]
+	if lock == nil {
+		lock = &commitLock{}
+		c.commitLocks[groupID] = lock
+	}
+	lock.refs++
+	c.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		c.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(c.commitLocks, groupID)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// revertOffsetRecords returns records that restore the cached offsets of the
+// partitions in committed: the cached offset, or a tombstone if there is none
+func (c *Coordinator) revertOffsetRecords(groupID string, committed map[TopicPartition]OffsetAndMetadata) []metadata.Record {
+	records := make([]metadata.Record, 0, len(committed))
+	for tp := range committed {
+		record := metadata.Record{Key: encodeOffsetKey(groupID, tp)}
+		if previous, exists := c.offsets[groupID][tp]; exists {
+			record.Value = encodeOffsetValue(previous)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// validateCommit checks that the committer may commit for the group. Commits
+// with a negative generation come from consumers that do not use group management.
+func (c *Coordinator) validateCommit(params CommitParams) int16 {
+	if params.GroupID == "" {
+		return INVALID_GROUP_ID
+	}
+
+	group, exists := c.groups[params.GroupID]
+	if !exists {
+		if params.GenerationID < 0 {
+			return ErrNone
+		}
+		return ILLEGAL_GENERATION
+	}
+	if params.GenerationID < 0 && group.State == Empty {
+		return ErrNone
+	}
+
+	member, known := group.Members[params.MemberID]
+	if !known {
+		return UNKNOWN_MEMBER_ID
+	}
+	if params.GenerationID != group.GenerationID {
+		return ILLEGAL_GENERATION
+	}
+	if group.State == CompletingRebalance {
+		return REBALANCE_IN_PROGRESS
+	}
+	// A commit proves the member is alive
+	member.lastHeartbeat = time.Now()
+	return ErrNone
+}
+
+// FetchOffsets returns the committed offsets of a group. A nil partitions slice
+// returns every partition the group has committed.
+func (c *Coordinator) FetchOffsets(groupID string, partitions []TopicPartition) map[TopicPartition]OffsetAndMetadata {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make(map[TopicPartition]OffsetAndMetadata)
+	if partitions == nil {
+		for tp, offset := range c.offsets[groupID] {
+			result[tp] = offset
+		}
+		return result
+	}
+
+	for _, tp := range partitions {
+		offset, exists := c.offsets[groupID][tp]
+		if !exists {
+			offset = OffsetAndMetadata{Offset: -1, LeaderEpoch: -1}
+		}
+		result[tp] = offset
+	}
+	return result
+}
+
+func (c *Coordinator) storeOffset(groupID string, tp TopicPartition, offset OffsetAndMetadata) {
+	if c.offsets[groupID] == nil {
+		c.offsets[groupID] = make(map[TopicPartition]OffsetAndMetadata)
+	}
+	c.offsets[groupID][tp] = offset
+}
+
+// LoadOffsets rebuilds the offset cache by replaying __consumer_offsets from the start
+func (c *Coordinator) LoadOffsets() error {
+	log, err := storage.GetLog(ConsumerOffsetsTopic, 0)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	loaded := 0
+	offset := log.LogStartOffset()
+	for {
+		data, err := log.Read(offset, 1024*1024, true)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			break
+		}
+
+		reader := bytes.NewReader(data)
+		for reader.Len() > 0 {
+			batch, err := metadata.ReadRecordBatch(reader)
+			if err != nil {
+				return fmt.Errorf("invalid batch in %s at offset %d: %w", ConsumerOffsetsTopic, offset, err)
+			}
+			records, err := metadata.DecodeRecords(batch)
+			if err != nil {
+				return fmt.Errorf("invalid batch in %s at offset %d: %w", ConsumerOffsetsTopic, batch.BaseOffset, err)
+			}
+			if batch.IsControl() {
+				controlType, err := metadata.ControlRecordType(batch)
+				if err != nil {
+					return fmt.Errorf("invalid marker in %s at offset %d: %w", ConsumerOffsetsTopic, batch.BaseOffset, err)
+				}
+				c.completeTxn(batch.ProducerID, controlType == metadata.ControlTypeCommit)
+				offset = batch.LastOffset() + 1
+				continue
+			}
+			for _, record := range records {
+				if batch.IsTransactional() {
+					c.replayPendingOffsetRecord(batch.ProducerID, record)
+				} else if c.replayOffsetRecord(record) {
+					loaded++
+				}
+			}
+			offset = batch.LastOffset() + 1
+		}
+	}
+
+	fmt.Printf("Loaded %d offset commits for %d groups from %s\n", loaded, len(c.offsets), ConsumerOffsetsTopic)
+	return nil
+}
+
+// replayOffsetRecord applies one __consumer_offsets record to the cache; a null value deletes the offset
+func (c *Coordinator) replayOffsetRecord(record metadata.Record) bool {
+	groupID, tp, ok := decodeOffsetKey(record.Key)
+	if !ok {
+		// Group metadata and other record kinds are not stored here
+		return false
+	}
+	if record.Value == nil {
+		delete(c.offsets[groupID], tp)
+		if len(c.offsets[groupID]) == 0 {
+			delete(c.offsets, groupID)
+		}
+		return true
+	}
+	offset, ok := decodeOffsetValue(record.Value)
+	if !ok {
+		return false
+	}
+	c.storeOffset(groupID, tp, offset)
+	return true
+}
+
+func appendOffsetRecords(records []metadata.Record, timestamp int64) error {
+	log, err := storage.GetLog(ConsumerOffsetsTopic, 0)
+	if err != nil {
+		return err
+	}
+	_, err = log.Append(metadata.NewRecordBatch(records, timestamp).Encode())
+	return err
+}
+
+// encodeOffsetKey writes an OffsetCommitKey: version, group, topic, partition
+func encodeOffsetKey(groupID string, tp TopicPartition) []byte {
+	buf := make([]byte, 0, 2+2+len(groupID)+2+len(tp.Topic)+4)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(offsetCommitKeyVersion))
+	buf = appendString(buf, groupID)
+	buf = appendString(buf, tp.Topic)
+	return binary.BigEndian.AppendUint32(buf, uint32(tp.Partition))
+}
+
+func decodeOffsetKey(data []byte) (string, TopicPartition, bool) {
+	var tp TopicPartition
+	if len(data) < 2 {
+		return "", tp, false
+	}
+	// Versions 0 and 1 are offset commits, 2 is group metadata
+	version := int16(binary.BigEndian.Uint16(data[0:2]))
+	if version != 0 && version != 1 {
+		return "", tp, false
+	}
+	offset := 2
+
+	groupID, offset, ok := readString(data, offset)
+	if !ok {
+		return "", tp, false
+	}
+	tp.Topic, offset, ok = readString(data, offset)
+	if !ok || offset+4 > len(data) {
+		return "", tp, false
+	}
+	tp.Partition = int32(binary.BigEndian.Uint32(data[offset : offset+4]))
+	return groupID, tp, true
+}
+
+// encodeOffsetValue writes an OffsetCommitValue v3: offset, leader epoch, metadata, commit timestamp
+func encodeOffsetValue(offset OffsetAndMetadata) []byte {
+	buf := make([]byte, 0, 2+8+4+2+len(offset.Metadata)+8)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(offsetCommitValueVersion))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(offset.Offset))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(offset.LeaderEpoch))
+	buf = appendString(buf, offset.Metadata)
+	return binary.BigEndian.AppendUint64(buf, uint64(offset.CommitTimestamp))
+}
+
+func decodeOffsetValue(data []byte) (OffsetAndMetadata, bool) {
+	offset := OffsetAndMetadata{LeaderEpoch: -1}
+	if len(data) < 2+8 {
+		return offset, false
+	}
+	version := int16(binary.BigEndian.Uint16(data[0:2]))
+	pos := 2
+
+	// Offset (INT64)
+	offset.Offset = int64(binary.BigEndian.Uint64(data[pos : pos+8]))
+	pos += 8
+
+	// LeaderEpoch (INT32) from v3
+	if version >= 3 {
+		if pos+4 > len(data) {
+			return offset, false
+		}
+		offset.LeaderEpoch = int32(binary.BigEndian.Uint32(data[pos : pos+4]))
+		pos += 4
+	}
+
+	// Metadata (STRING)
+	var ok bool
+	offset.Metadata, pos, ok = readString(data, pos)
+	if !ok {
+		return offset, false
+	}
+
+	// CommitTimestamp (INT64)
+	if pos+8 <= len(data) {
+		offset.CommitTimestamp = int64(binary.BigEndian.Uint64(data[pos : pos+8]))
+	}
+	return offset, true
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+func readString(data []byte, offset int) (string, int, bool) {
+	if offset+2 > len(data) {
+		return "", offset, false
+	}
+	length := int(int16(binary.BigEndian.Uint16(data[offset : offset+2])))
+	offset += 2
+	if length < 0 {
+		return "", offset, true
+	}
+	if offset+length > len(data) {
+		return "", offset, false
+	}
+	return string(data[offset : offset+length]), offset + length, true
+}
@@ -0,0 +1,67 @@
+package group
+
+import (
+	"testing"
+	"time"
+
+	"kafgo/app/metadata"
+)
+
+// TestRefusedCommitUndoneOnReload appends a commit the way CommitOffsets does
+// before its second member check fails, then the revert records, and checks
+// that replaying __consumer_offsets keeps the offsets from before the commit
+func TestRefusedCommitUndoneOnReload(t *testing.T) {
+	const groupID = "group-refused"
+	kept := TopicPartition{Topic: "orders", Partition: 0}
+	added := TopicPartition{Topic: "orders", Partition: 1}
+
+	c := NewCoordinator()
+	errorCodes := c.CommitOffsets(CommitParams{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Offsets:      map[TopicPartition]OffsetAndMetadata{kept: {Offset: 10, LeaderEpoch: -1}},
+	})
+	if errorCodes[kept] != ErrNone {
+		t.Fatalf("CommitOffsets error %d", errorCodes[kept])
+	}
+	if len(c.commitLocks) != 0 {
+		t.Errorf("%d commit locks left after the commit", len(c.commitLocks))
+	}
+
+	refused := map[TopicPartition]OffsetAndMetadata{
+		kept:  {Offset: 99, LeaderEpoch: -1},
+		added: {Offset: 99, LeaderEpoch: -1},
+	}
+	records := make([]metadata.Record, 0, len(refused))
+	for tp, offset := range refused {
+		records = append(records, metadata.Record{Key: encodeOffsetKey(groupID, tp), Value: encodeOffsetValue(offset)})
+	}
+	now := time.Now().UnixMilli()
+	if err := appendOffsetRecords(records, now); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	reverts := c.revertOffsetRecords(groupID, refused)
+	c.mu.Unlock()
+	if err := appendOffsetRecords(reverts, now); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewCoordinator()
+	if err := reloaded.LoadOffsets(); err != nil {
+		t.Fatal(err)
+	}
+	offsets := reloaded.FetchOffsets(groupID, []TopicPartition{kept, added})
+	tests := []struct {
+		tp   TopicPartition
+		want int64
+	}{
+		{kept, 10},
+		{added, -1},
+	}
+	for _, tt := range tests {
+		if offset := offsets[tt.tp].Offset; offset != tt.want {
+			t.Errorf("%v: offset %d after reloading, want %d", tt.tp, offset, tt.want)
+		}
+	}
+}
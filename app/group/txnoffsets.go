@@ -0,0 +1,186 @@
+package group
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"kafgo/app/metadata"
+	"kafgo/app/storage"
+)
+
+// Error codes used by TxnOffsetCommit
+const (
+	INVALID_PRODUCER_EPOCH int16 = 47
+)
+
+// TxnCommitParams is a decoded TxnOffsetCommit request
+type TxnCommitParams struct {
+	TransactionalID string
+	GroupID         string
+	ProducerID      int64
+	ProducerEpoch   int16
+	// GenerationID, MemberID and GroupInstanceID are sent from v3; a negative
+	// generation without a member ID skips the member check
+	GenerationID    int32
+	MemberID        string
+	GroupInstanceID string
+	Offsets         map[TopicPartition]OffsetAndMetadata
+}
+
+// TxnVerifier checks with the transaction coordinator that the producer's
+// transaction includes __consumer_offsets, which AddOffsetsToTxn adds. It
+// returns an error code.
+type TxnVerifier func(transactionalID string, producerID int64, producerEpoch int16) int16
+
+// SetTxnVerifier sets how CommitTxnOffsets checks transactions. Without one
+// every TxnOffsetCommit fails with COORDINATOR_NOT_AVAILABLE.
+func (c *Coordinator) SetTxnVerifier(verify TxnVerifier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.verifyTxn = verify
+}
+
+// CommitTxnOffsets appends offsets as part of the producer's transaction. They
+// are written to __consumer_offsets in a transactional batch, but only become
+// visible to OffsetFetch once the transaction's COMMIT marker is written
+// (CompleteTxn). It returns an error code per partition. As in CommitOffsets,
+// the coordinator lock is released during the append.
+func (c *Coordinator) CommitTxnOffsets(params TxnCommitParams) map[TopicPartition]int16 {
+	unlock := c.lockCommits(params.GroupID)
+	defer unlock()
+
+	errorCodes := make(map[TopicPartition]int16, len(params.Offsets))
+	setAll := func(errorCode int16) map[TopicPartition]int16 {
+		for tp := range params.Offsets {
+			errorCodes[tp] = errorCode
+		}
+		return errorCodes
+	}
+
+	if params.GroupID == "" {
+		return setAll(INVALID_GROUP_ID)
+	}
+
+	c.mu.Lock()
+	verify := c.verifyTxn
+	errorCode := ErrNone
+	if params.GenerationID >= 0 || params.MemberID != "" {
+		errorCode = c.validateCommit(CommitParams{
+			GroupID:         params.GroupID,
+			GenerationID:    params.GenerationID,
+			MemberID:        params.MemberID,
+			GroupInstanceID: params.GroupInstanceID,
+		})
+	}
+	c.mu.Unlock()
+	if errorCode != ErrNone {
+		return setAll(errorCode)
+	}
+
+	// The offsets may only join a transaction that AddOffsetsToTxn added the group to
+	if verify == nil {
+		return setAll(COORDINATOR_NOT_AVAILABLE)
+	}
+	if errorCode := verify(params.TransactionalID, params.ProducerID, params.ProducerEpoch); errorCode != ErrNone {
+		return setAll(errorCode)
+	}
+
+	now := time.Now().UnixMilli()
+	records := make([]metadata.Record, 0, len(params.Offsets))
+	pending := make(map[TopicPartition]OffsetAndMetadata, len(params.Offsets))
+	for tp, offset := range params.Offsets {
+		if len(offset.Metadata) > OffsetMetadataMaxBytes {
+			errorCodes[tp] = OFFSET_METADATA_TOO_LARGE
+			continue
+		}
+		offset.CommitTimestamp = now
+		records = append(records, metadata.Record{
+			Key:   encodeOffsetKey(params.GroupID, tp),
+			Value: encodeOffsetValue(offset),
+		})
+		pending[tp] = offset
+	}
+	if len(records) == 0 {
+		return errorCodes
+	}
+
+	if err := appendTxnOffsetRecords(records, params.ProducerID, params.ProducerEpoch, now); err != nil {
+		fmt.Printf("Failed to write transactional offsets for group %s: %v\n", params.GroupID, err)
+		errorCode := COORDINATOR_NOT_AVAILABLE
+		if errors.Is(err, storage.ErrInvalidProducerEpoch) {
+			errorCode = INVALID_PRODUCER_EPOCH
+		}
+		for tp := range pending {
+			errorCodes[tp] = errorCode
+		}
+		return errorCodes
+	}
+
+	c.mu.Lock()
+	for tp, offset := range pending {
+		c.storePendingOffset(params.ProducerID, params.GroupID, tp, offset)
+		errorCodes[tp] = ErrNone
+	}
+	c.mu.Unlock()
+
+	fmt.Printf("Group %s has %d pending offsets in the transaction of %s (producer %d)\n",
+		params.GroupID, len(pending), params.TransactionalID, params.ProducerID)
+	return errorCodes
+}
+
+// CompleteTxn is called once a transaction marker of producerID is written to
+// __consumer_offsets: a commit makes its pending offsets the committed ones, an
+// abort discards them
+func (c *Coordinator) CompleteTxn(producerID int64, commit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.completeTxn(producerID, commit)
+}
+
+func (c *Coordinator) completeTxn(producerID int64, commit bool) {
+	if commit {
+		for groupID, offsets := range c.pendingTxnOffsets[producerID] {
+			for tp, offset := range offsets {
+				c.storeOffset(groupID, tp, offset)
+			}
+		}
+	}
+	delete(c.pendingTxnOffsets, producerID)
+}
+
+func (c *Coordinator) storePendingOffset(producerID int64, groupID string, tp TopicPartition, offset OffsetAndMetadata) {
+	if c.pendingTxnOffsets[producerID] == nil {
+		c.pendingTxnOffsets[producerID] = make(map[string]map[TopicPartition]OffsetAndMetadata)
+	}
+	if c.pendingTxnOffsets[producerID][groupID] == nil {
+		c.pendingTxnOffsets[producerID][groupID] = make(map[TopicPartition]OffsetAndMetadata)
+	}
+	c.pendingTxnOffsets[producerID][groupID][tp] = offset
+}
+
+// replayPendingOffsetRecord applies one record of a transactional __consumer_offsets batch
+func (c *Coordinator) replayPendingOffsetRecord(producerID int64, record metadata.Record) {
+	groupID, tp, ok := decodeOffsetKey(record.Key)
+	if !ok || record.Value == nil {
+		return
+	}
+	if offset, ok := decodeOffsetValue(record.Value); ok {
+		c.storePendingOffset(producerID, groupID, tp, offset)
+	}
+}
+
+// appendTxnOffsetRecords writes records to __consumer_offsets as a transactional
+// batch of the producer, so the transaction's marker covers them
+func appendTxnOffsetRecords(records []metadata.Record, producerID int64, producerEpoch int16, timestamp int64) error {
+	log, err := storage.GetLog(ConsumerOffsetsTopic, 0)
+	if err != nil {
+		return err
+	}
+	batch := metadata.NewRecordBatch(records, timestamp)
+	batch.Attributes |= metadata.AttributeTransactional
+	batch.ProducerID = producerID
+	batch.ProducerEpoch = producerEpoch
+	_, err = log.AppendAsCoordinator(batch.Encode())
+	return err
+}
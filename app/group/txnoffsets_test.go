@@ -0,0 +1,132 @@
+package group
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"kafgo/app/storage"
+)
+
+// TestMain points __consumer_offsets at a scratch directory shared by the package's tests
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "group-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	storage.LogDirs = []string{dir}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// acceptTxn is a TxnVerifier for transactions that AddOffsetsToTxn added the group to
+func acceptTxn(transactionalID string, producerID int64, producerEpoch int16) int16 {
+	return ErrNone
+}
+
+func TestTxnOffsetsVisibleOnlyAfterCommit(t *testing.T) {
+	tests := []struct {
+		name       string
+		producerID int64
+		marker     string // "commit", "abort" or "" to leave the transaction open
+		wantOffset int64
+	}{
+		{"commit", 1000, "commit", 42},
+		{"abort", 1001, "abort", -1},
+		{"open", 1002, "", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groupID := "group-" + tt.name
+			tp := TopicPartition{Topic: "orders", Partition: 0}
+			fetch := func(c *Coordinator) int64 {
+				return c.FetchOffsets(groupID, []TopicPartition{tp})[tp].Offset
+			}
+
+			c := NewCoordinator()
+			c.SetTxnVerifier(acceptTxn)
+			errorCodes := c.CommitTxnOffsets(TxnCommitParams{
+				TransactionalID: "txn-" + tt.name,
+				GroupID:         groupID,
+				ProducerID:      tt.producerID,
+				ProducerEpoch:   0,
+				GenerationID:    -1,
+				Offsets:         map[TopicPartition]OffsetAndMetadata{tp: {Offset: 42, LeaderEpoch: -1}},
+			})
+			if errorCodes[tp] != ErrNone {
+				t.Fatalf("CommitTxnOffsets error %d", errorCodes[tp])
+			}
+			if offset := fetch(c); offset != -1 {
+				t.Fatalf("offset %d visible before the transaction ended", offset)
+			}
+
+			if tt.marker != "" {
+				log, err := storage.GetLog(ConsumerOffsetsTopic, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := log.AppendControlMarker(tt.producerID, 0, 0, tt.marker == "commit"); err != nil {
+					t.Fatal(err)
+				}
+				c.CompleteTxn(tt.producerID, tt.marker == "commit")
+			}
+			if offset := fetch(c); offset != tt.wantOffset {
+				t.Errorf("offset %d after the transaction, want %d", offset, tt.wantOffset)
+			}
+
+			// Replaying __consumer_offsets ends in the same state
+			reloaded := NewCoordinator()
+			if err := reloaded.LoadOffsets(); err != nil {
+				t.Fatal(err)
+			}
+			if offset := fetch(reloaded); offset != tt.wantOffset {
+				t.Errorf("offset %d after reloading, want %d", offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestTxnOffsetsRejectedOutsideTheTransaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		verify TxnVerifier
+		want   int16
+	}{
+		{"no transaction coordinator", nil, COORDINATOR_NOT_AVAILABLE},
+		{"group not added with AddOffsetsToTxn", func(string, int64, int16) int16 { return 48 }, 48}, // INVALID_TXN_STATE
+		{"fenced producer", func(string, int64, int16) int16 { return INVALID_PRODUCER_EPOCH }, INVALID_PRODUCER_EPOCH},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groupID := "group-rejected-" + tt.name
+			tp := TopicPartition{Topic: "orders", Partition: 0}
+			log, err := storage.GetLog(ConsumerOffsetsTopic, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			end := log.LogEndOffset()
+
+			c := NewCoordinator()
+			c.SetTxnVerifier(tt.verify)
+			errorCodes := c.CommitTxnOffsets(TxnCommitParams{
+				TransactionalID: "txn-rejected",
+				GroupID:         groupID,
+				ProducerID:      2000,
+				ProducerEpoch:   0,
+				GenerationID:    -1,
+				Offsets:         map[TopicPartition]OffsetAndMetadata{tp: {Offset: 42, LeaderEpoch: -1}},
+			})
+			if errorCodes[tp] != tt.want {
+				t.Errorf("CommitTxnOffsets error %d, want %d", errorCodes[tp], tt.want)
+			}
+			if newEnd := log.LogEndOffset(); newEnd != end {
+				t.Errorf("%d records written for a rejected commit", newEnd-end)
+			}
+			if len(c.pendingTxnOffsets) != 0 {
+				t.Errorf("pending offsets %+v after a rejected commit", c.pendingTxnOffsets)
+			}
+		})
+	}
+}
@@ -4,23 +4,85 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"kafgo/app/config"
 	"kafgo/app/metadata"
 	"kafgo/app/server"
+	"kafgo/app/storage"
 )
 
 func main() {
-	fmt.Println("Kafka-like broker started on :9092")
+	// Apply server.properties, KAFKA_* environment variables and --override flags
+	if err := config.Load(os.Args[1:]); err != nil {
+		fmt.Println("Invalid configuration:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Kafka-like broker %d started on %s\n", server.BrokerNodeID, strings.Join(server.Listeners, ", "))
 
-	// Load metadata at startup
+	// Load metadata at startup, then apply batches other processes append to
+	// the metadata log in the background
 	metadata.LoadClusterMetadata()
+	metadata.StartMetadataLogFollower()
 
-	listener, err := net.Listen("tcp", "0.0.0.0:9092")
-	if err != nil {
-		fmt.Println("Failed to bind to port 9092:", err)
+	// Open every partition log, recovering them if the broker did not shut down cleanly
+	if err := storage.LoadLogs(); err != nil {
+		fmt.Println("Failed to load logs:", err)
 		os.Exit(1)
 	}
 
+	// Remove log directories of deleted topics and segments past their
+	// retention, compact compacted topics and flush logs per the flush
+	// policy, in the background
+	storage.StartLogDeleter()
+	storage.StartLogRetention()
+	storage.StartLogCleaner()
+	storage.StartLogFlusher()
+
+	// Rebuild committed offsets from __consumer_offsets, then expire
+	// consumer group sessions in the background
+	if err := server.GroupCoordinator.LoadOffsets(); err != nil {
+		fmt.Println("Failed to load committed offsets:", err)
+	}
+	server.GroupCoordinator.Start()
+
+	// Rebuild transaction state from __transaction_state, finishing transactions
+	// interrupted mid-commit, then abort timed-out transactions in the background
+	if err := server.TxnCoordinator.Load(); err != nil {
+		fmt.Println("Failed to load transaction state:", err)
+	}
+	server.TxnCoordinator.Start()
+
+	// Snapshot producer state and close the logs on a clean shutdown
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Println("Shutting down")
+		storage.Shutdown()
+		os.Exit(0)
+	}()
+
+	listeners := make([]net.Listener, 0, len(server.Listeners))
+	for _, address := range server.Listeners {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			fmt.Printf("Failed to bind to %s: %v\n", address, err)
+			os.Exit(1)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	for _, listener := range listeners[1:] {
+		go acceptConnections(listener)
+	}
+	acceptConnections(listeners[0])
+}
+
+// acceptConnections serves every connection accepted by listener
+func acceptConnections(listener net.Listener) {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
@@ -2,66 +2,196 @@ package metadata
 
 import (
 	"encoding/binary"
+	"fmt"
+	"hash/crc32"
 	"io"
+
+	"kafgo/app/compress"
 )
 
 func ReadRecordBatch(r io.Reader) (*RecordBatch, error) {
-	batch := &RecordBatch{}
-
-	// Read base offset (8 bytes)
-	if err := binary.Read(r, binary.BigEndian, &batch.BaseOffset); err != nil {
+	header := make([]byte, batchHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
 		return nil, err
 	}
-
-	// Read batch length (4 bytes)
-	if err := binary.Read(r, binary.BigEndian, &batch.BatchLength); err != nil {
-		return nil, err
+	batchLength := int32(binary.BigEndian.Uint32(header[8:12]))
+	if batchLength < RecordBatchHeaderSize-batchHeaderSize {
+		return nil, fmt.Errorf("invalid batch length %d", batchLength)
 	}
 
 	// Read the rest of the batch header and records
-	batchData := make([]byte, batch.BatchLength)
-	if _, err := io.ReadFull(r, batchData); err != nil {
+	batchData := make([]byte, batchHeaderSize+int(batchLength))
+	copy(batchData, header)
+	if _, err := io.ReadFull(r, batchData[batchHeaderSize:]); err != nil {
 		return nil, err
 	}
 
+	return ParseRecordBatch(batchData)
+}
+
+// ParseRecordBatch decodes a batch from data, which must hold at least the
+// batch header. Records is set to whatever follows the header in data.
+func ParseRecordBatch(data []byte) (*RecordBatch, error) {
+	if len(data) < RecordBatchHeaderSize {
+		return nil, fmt.Errorf("record batch header needs %d bytes, have %d", RecordBatchHeaderSize, len(data))
+	}
+	batch := &RecordBatch{}
 	offset := 0
 
+	// Read base offset (8 bytes)
+	batch.BaseOffset = int64(binary.BigEndian.Uint64(data[offset : offset+8]))
+	offset += 8
+
+	// Read batch length (4 bytes)
+	batch.BatchLength = int32(binary.BigEndian.Uint32(data[offset : offset+4]))
+	offset += 4
+
 	// Parse batch header
-	batch.PartitionLeaderEpoch = int32(binary.BigEndian.Uint32(batchData[offset : offset+4]))
+	batch.PartitionLeaderEpoch = int32(binary.BigEndian.Uint32(data[offset : offset+4]))
 	offset += 4
 
-	batch.Magic = int8(batchData[offset])
+	batch.Magic = int8(data[offset])
 	offset += 1
 
-	batch.CRC = binary.BigEndian.Uint32(batchData[offset : offset+4])
+	batch.CRC = binary.BigEndian.Uint32(data[offset : offset+4])
 	offset += 4
 
-	batch.Attributes = int16(binary.BigEndian.Uint16(batchData[offset : offset+2]))
+	batch.Attributes = int16(binary.BigEndian.Uint16(data[offset : offset+2]))
 	offset += 2
 
-	batch.LastOffsetDelta = int32(binary.BigEndian.Uint32(batchData[offset : offset+4]))
+	batch.LastOffsetDelta = int32(binary.BigEndian.Uint32(data[offset : offset+4]))
 	offset += 4
 
-	batch.BaseTimestamp = int64(binary.BigEndian.Uint64(batchData[offset : offset+8]))
+	batch.BaseTimestamp = int64(binary.BigEndian.Uint64(data[offset : offset+8]))
 	offset += 8
 
-	batch.MaxTimestamp = int64(binary.BigEndian.Uint64(batchData[offset : offset+8]))
+	batch.MaxTimestamp = int64(binary.BigEndian.Uint64(data[offset : offset+8]))
 	offset += 8
 
-	batch.ProducerID = int64(binary.BigEndian.Uint64(batchData[offset : offset+8]))
+	batch.ProducerID = int64(binary.BigEndian.Uint64(data[offset : offset+8]))
 	offset += 8
 
-	batch.ProducerEpoch = int16(binary.BigEndian.Uint16(batchData[offset : offset+2]))
+	batch.ProducerEpoch = int16(binary.BigEndian.Uint16(data[offset : offset+2]))
 	offset += 2
 
-	batch.BaseSequence = int32(binary.BigEndian.Uint32(batchData[offset : offset+4]))
+	batch.BaseSequence = int32(binary.BigEndian.Uint32(data[offset : offset+4]))
 	offset += 4
 
-	batch.RecordCount = int32(binary.BigEndian.Uint32(batchData[offset : offset+4]))
+	batch.RecordCount = int32(binary.BigEndian.Uint32(data[offset : offset+4]))
 	offset += 4
 
 	// Store remaining data as records
-	batch.Records = batchData[offset:]
+	batch.Records = data[offset:]
 
 	return batch, nil
 }
+
+// crcHeader appends the header fields covered by the CRC, Attributes to RecordCount
+func (b *RecordBatch) crcHeader(buf []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(b.Attributes))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(b.LastOffsetDelta))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(b.BaseTimestamp))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(b.MaxTimestamp))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(b.ProducerID))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(b.ProducerEpoch))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(b.BaseSequence))
+	return binary.BigEndian.AppendUint32(buf, uint32(b.RecordCount))
+}
+
+// ComputeCRC returns the CRC-32C of everything from Attributes onwards
+func (b *RecordBatch) ComputeCRC() uint32 {
+	crc := crc32.Checksum(b.crcHeader(make([]byte, 0, 40)), crc32cTable)
+	return crc32.Update(crc, crc32cTable, b.Records)
+}
+
+// IsValid reports whether the stored CRC matches the batch contents
+func (b *RecordBatch) IsValid() bool {
+	return b.CRC == b.ComputeCRC()
+}
+
+// Compression returns the codec the batch's records are compressed with
+func (b *RecordBatch) Compression() compress.Codec {
+	return compress.Codec(b.Attributes & AttributeCompressionMask)
+}
+
+// IsTransactional reports whether the batch was written by a transactional producer
+func (b *RecordBatch) IsTransactional() bool {
+	return b.Attributes&AttributeTransactional != 0
+}
+
+// IsControl reports whether the batch holds a transaction marker instead of data
+func (b *RecordBatch) IsControl() bool {
+	return b.Attributes&AttributeControl != 0
+}
+
+// RecordData returns the records section of the batch, decompressed if needed
+func (b *RecordBatch) RecordData() ([]byte, error) {
+	if b.Compression() == compress.None {
+		return b.Records, nil
+	}
+	return compress.Decompress(b.Compression(), b.Records)
+}
+
+// SetCompression re-encodes the records section with codec and updates the
+// compression bits of Attributes. The CRC is recomputed by Encode.
+func (b *RecordBatch) SetCompression(codec compress.Codec) error {
+	if codec == b.Compression() {
+		return nil
+	}
+	data, err := b.RecordData()
+	if err != nil {
+		return err
+	}
+	records, err := compress.Compress(codec, data)
+	if err != nil {
+		return err
+	}
+	b.Records = records
+	b.Attributes = b.Attributes&^AttributeCompressionMask | int16(codec)
+	return nil
+}
+
+// Record batch attribute bits
+const (
+	AttributeCompressionMask int16 = 0x07
+	AttributeTimestampType   int16 = 0x08 // 0 = CreateTime, 1 = LogAppendTime
+	AttributeTransactional   int16 = 0x10
+	AttributeControl         int16 = 0x20
+)
+
+// batchHeaderSize is the size of BaseOffset and BatchLength, which precede the length-counted part of a batch
+const batchHeaderSize = 12
+
+// RecordBatchHeaderSize is the size of a v2 batch header up to and including RecordCount
+const RecordBatchHeaderSize = 61
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// LastOffset returns the offset of the last record in the batch
+func (b *RecordBatch) LastOffset() int64 {
+	return b.BaseOffset + int64(b.LastOffsetDelta)
+}
+
+// Size returns the number of bytes the batch occupies in a log
+func (b *RecordBatch) Size() int {
+	return batchHeaderSize + int(b.BatchLength)
+}
+
+// Encode serializes the batch in the on-disk/wire format, recomputing the
+// batch length and the CRC-32C over everything from Attributes onwards
+func (b *RecordBatch) Encode() []byte {
+	// Everything covered by the CRC
+	body := append(b.crcHeader(make([]byte, 0, 40+len(b.Records))), b.Records...)
+
+	b.CRC = crc32.Checksum(body, crc32cTable)
+	// PartitionLeaderEpoch + Magic + CRC precede the CRC-covered part
+	b.BatchLength = int32(4 + 1 + 4 + len(body))
+
+	buf := make([]byte, 0, b.Size())
+	buf = binary.BigEndian.AppendUint64(buf, uint64(b.BaseOffset))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(b.BatchLength))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(b.PartitionLeaderEpoch))
+	buf = append(buf, byte(b.Magic))
+	buf = binary.BigEndian.AppendUint32(buf, b.CRC)
+	return append(buf, body...)
+}
@@ -0,0 +1,176 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// MetadataLogPollIntervalMs is how often the follower checks the metadata log
+// for batches appended by another process
+var MetadataLogPollIntervalMs int64 = 500
+
+// ErrCorruptMetadataBatch is returned for a batch that is complete in the
+// metadata log but cannot be valid, such as one with a bad length or CRC
+var ErrCorruptMetadataBatch = errors.New("corrupt metadata batch")
+
+var (
+	// metadataLogPosition is the byte position in the metadata log of the first
+	// batch not yet applied to the image. It is guarded by metadataMu.
+	metadataLogPosition int64
+	// metadataLogErr is why the complete batch at metadataLogPosition could not
+	// be read. The follower stops there instead of rereading it on every call,
+	// until the log is replaced. It is guarded by metadataMu.
+	metadataLogErr error
+	// lastMissCatchUp is when a lookup miss last caught up with the log, in Unix ms
+	lastMissCatchUp atomic.Int64
+)
+
+// LastAppliedOffset returns the offset of the last metadata log record applied
+// to the current image, or -1 if none has been
+func LastAppliedOffset() int64 {
+	return Image().Offset()
+}
+
+// StartMetadataLogFollower starts the background task that applies batches
+// appended to the metadata log by another process. Appends made by this broker
+// are applied as they are written.
+func StartMetadataLogFollower() {
+	go func() {
+		ticker := time.NewTicker(time.Duration(MetadataLogPollIntervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			followMetadataLog()
+		}
+	}()
+}
+
+// followMetadataLog applies the batches appended to the metadata log since it
+// was last read
+func followMetadataLog() {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+
+	// An unreadable batch was reported when it was found
+	if _, _, err := applyNewBatches(); err != nil && err != metadataLogErr {
+		fmt.Printf("Failed to follow the metadata log: %v\n", err)
+	}
+}
+
+// catchUpOnMiss follows the metadata log for a lookup the current image could
+// not answer, at most once per MetadataLogPollIntervalMs, so clients asking
+// for a missing topic do not each open the log behind metadataMu
+func catchUpOnMiss() {
+	now := time.Now().UnixMilli()
+	last := lastMissCatchUp.Load()
+	if now-last < MetadataLogPollIntervalMs || !lastMissCatchUp.CompareAndSwap(last, now) {
+		return
+	}
+	followMetadataLog()
+}
+
+// applyNewBatches reads the metadata log from metadataLogPosition and publishes
+// an image with every complete batch applied. It returns the number of batches
+// applied and the size of an incomplete batch at the end of the log, which is
+// left for a later call in case it is still being written. The caller holds
+// metadataMu.
+func applyNewBatches() (int, int64, error) {
+	file, err := os.Open(MetadataLogPath())
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := info.Size()
+	base := Image()
+	replaced := size < metadataLogPosition
+	if replaced {
+		// The log was replaced by a shorter one, so it is replayed from the start
+		fmt.Printf("Metadata log shrank to %d bytes, replaying it\n", size)
+		base = emptyImage
+		metadataLogPosition = 0
+		metadataLogErr = nil
+	}
+	if metadataLogErr != nil {
+		return 0, 0, metadataLogErr
+	}
+	if size == metadataLogPosition && !replaced {
+		return 0, 0, nil
+	}
+
+	delta := NewMetadataDelta(base)
+	applied := 0
+	var readErr error
+	for metadataLogPosition < size {
+		batch, err := readMetadataBatch(file, metadataLogPosition, size)
+		if err != nil {
+			readErr = err
+			break
+		}
+		if err := ParseRecords(delta, batch); err != nil {
+			fmt.Printf("Error parsing records in metadata batch at offset %d: %v\n", batch.BaseOffset, err)
+		}
+		delta.replayOffset(batch.LastOffset())
+		metadataLogPosition += int64(batch.Size())
+		applied++
+	}
+	if applied > 0 || replaced {
+		publish(delta.Apply())
+	}
+
+	if readErr == io.ErrUnexpectedEOF {
+		return applied, size - metadataLogPosition, nil
+	}
+	if readErr != nil {
+		metadataLogErr = fmt.Errorf("metadata batch at byte %d: %w", metadataLogPosition, readErr)
+		fmt.Printf("Not following the metadata log past an unreadable batch: %v\n", metadataLogErr)
+		return applied, 0, metadataLogErr
+	}
+	return applied, 0, nil
+}
+
+// readMetadataBatch reads the batch at position, returning io.ErrUnexpectedEOF
+// if the log ends before it does and ErrCorruptMetadataBatch if it is not valid
+func readMetadataBatch(file *os.File, position int64, size int64) (*RecordBatch, error) {
+	// The length is checked against the file first, so a torn header cannot
+	// make ReadRecordBatch allocate a garbage length
+	header := make([]byte, batchHeaderSize)
+	if _, err := file.ReadAt(header, position); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	batchLength := int64(int32(binary.BigEndian.Uint32(header[8:12])))
+	if batchLength < RecordBatchHeaderSize-batchHeaderSize {
+		return nil, fmt.Errorf("%w: batch length %d", ErrCorruptMetadataBatch, batchLength)
+	}
+	if position+batchHeaderSize+batchLength > size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	batch, err := ReadRecordBatch(io.NewSectionReader(file, position, size-position))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptMetadataBatch, err)
+	}
+	if !batch.IsValid() {
+		return nil, fmt.Errorf("%w: CRC %08x, computed %08x", ErrCorruptMetadataBatch, batch.CRC, batch.ComputeCRC())
+	}
+	return batch, nil
+}
+
+// truncateMetadataLog removes everything past metadataLogPosition, such as a
+// batch torn by a crash while it was being appended. The caller holds metadataMu.
+func truncateMetadataLog() error {
+	return os.Truncate(MetadataLogPath(), metadataLogPosition)
+}
@@ -0,0 +1,231 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReadMetadataBatch(t *testing.T) {
+	valid := NewRecordBatch([]Record{{Value: []byte("value")}}, 1700000000000).Encode()
+	corrupt := func(change func(data []byte)) []byte {
+		data := append([]byte(nil), valid...)
+		change(data)
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"complete batch", valid, nil},
+		{"torn header", valid[:batchHeaderSize-2], io.ErrUnexpectedEOF},
+		{"torn records", valid[:len(valid)-3], io.ErrUnexpectedEOF},
+		{"negative length", corrupt(func(data []byte) { binary.BigEndian.PutUint32(data[8:12], 0xfffffff0) }), ErrCorruptMetadataBatch},
+		{"length shorter than the header", corrupt(func(data []byte) { binary.BigEndian.PutUint32(data[8:12], 10) }), ErrCorruptMetadataBatch},
+		{"bad CRC", corrupt(func(data []byte) { data[len(data)-1] ^= 0xff }), ErrCorruptMetadataBatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "00000000000000000000.log")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			batch, err := readMetadataBatch(file, 0, int64(len(tt.data)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readMetadataBatch error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && batch.Size() != len(valid) {
+				t.Errorf("batch of %d bytes, want %d", batch.Size(), len(valid))
+			}
+		})
+	}
+}
+
+// useMetadataLog points MetadataLogDir at an empty directory and resets the
+// follower and the current image, restoring them when the test ends
+func useMetadataLog(t *testing.T) string {
+	t.Helper()
+	oldDir, oldImage := MetadataLogDir, Image()
+	oldPosition, oldErr := metadataLogPosition, metadataLogErr
+	t.Cleanup(func() {
+		MetadataLogDir = oldDir
+		publish(oldImage)
+		metadataLogPosition, metadataLogErr = oldPosition, oldErr
+	})
+	MetadataLogDir = t.TempDir()
+	publish(emptyImage)
+	metadataLogPosition, metadataLogErr = 0, nil
+	path := MetadataLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// topicBatch encodes a batch at offset holding the TopicRecord of name
+func topicBatch(name string, offset int64) []byte {
+	batch := NewRecordBatch([]Record{{Value: EncodeTopicRecord(name, [16]byte{name[0]})}}, 1700000000000)
+	batch.BaseOffset = offset
+	return batch.Encode()
+}
+
+// imageTopics returns the names of the current image's topics, sorted
+func imageTopics() []string {
+	names := make([]string, 0)
+	for name := range Image().Topics() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestApplyNewBatches(t *testing.T) {
+	a, b, c := topicBatch("a", 0), topicBatch("b", 1), topicBatch("c", 2)
+	badCRC := slices.Clone(b)
+	badCRC[len(badCRC)-1] ^= 0xff
+
+	// step appends data to the log, or replaces the log with it, then follows it
+	type step struct {
+		data        []byte
+		replace     bool
+		wantApplied int
+		wantTorn    int64
+		wantErr     error
+		wantTopics  []string
+		wantOffset  int64
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "complete batches",
+			steps: []step{{data: slices.Concat(a, b), wantApplied: 2, wantTopics: []string{"a", "b"}, wantOffset: 1}},
+		},
+		{
+			name: "nothing appended",
+			steps: []step{
+				{data: a, wantApplied: 1, wantTopics: []string{"a"}},
+				{wantTopics: []string{"a"}},
+			},
+		},
+		{
+			name: "torn records completed later",
+			steps: []step{
+				{data: slices.Concat(a, b[:len(b)-4]), wantApplied: 1, wantTorn: int64(len(b) - 4), wantTopics: []string{"a"}},
+				{data: b[len(b)-4:], wantApplied: 1, wantTopics: []string{"a", "b"}, wantOffset: 1},
+			},
+		},
+		{
+			name: "torn header completed later",
+			steps: []step{
+				{data: slices.Concat(a, b[:5]), wantApplied: 1, wantTorn: 5, wantTopics: []string{"a"}},
+				{data: slices.Concat(b[5:], c), wantApplied: 2, wantTopics: []string{"a", "b", "c"}, wantOffset: 2},
+			},
+		},
+		{
+			name: "corrupt batch stops the follower",
+			steps: []step{
+				{data: slices.Concat(a, badCRC), wantApplied: 1, wantErr: ErrCorruptMetadataBatch, wantTopics: []string{"a"}},
+				{data: c, wantErr: ErrCorruptMetadataBatch, wantTopics: []string{"a"}},
+			},
+		},
+		{
+			name: "log replaced by a shorter one",
+			steps: []step{
+				{data: slices.Concat(a, b), wantApplied: 2, wantTopics: []string{"a", "b"}, wantOffset: 1},
+				{data: topicBatch("c", 0), replace: true, wantApplied: 1, wantTopics: []string{"c"}},
+			},
+		},
+		{
+			name: "log emptied after a corrupt batch",
+			steps: []step{
+				{data: slices.Concat(a, badCRC), wantApplied: 1, wantErr: ErrCorruptMetadataBatch, wantTopics: []string{"a"}},
+				{replace: true, wantTopics: []string{}, wantOffset: -1},
+				{data: topicBatch("c", 0), wantApplied: 1, wantTopics: []string{"c"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useMetadataLog(t)
+			for i, step := range tt.steps {
+				flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+				if step.replace {
+					flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+				}
+				file, err := os.OpenFile(path, flags, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = file.Write(step.data)
+				file.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				metadataMu.Lock()
+				applied, torn, err := applyNewBatches()
+				metadataMu.Unlock()
+				if applied != step.wantApplied || torn != step.wantTorn || !errors.Is(err, step.wantErr) {
+					t.Errorf("step %d: applied %d with %d torn bytes and error %v, want %d, %d and %v",
+						i, applied, torn, err, step.wantApplied, step.wantTorn, step.wantErr)
+				}
+				if topics := imageTopics(); !slices.Equal(topics, step.wantTopics) {
+					t.Errorf("step %d: topics %v, want %v", i, topics, step.wantTopics)
+				}
+				if offset := LastAppliedOffset(); offset != step.wantOffset {
+					t.Errorf("step %d: last applied offset %d, want %d", i, offset, step.wantOffset)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadClusterMetadataTruncatesTornBatch(t *testing.T) {
+	path := useMetadataLog(t)
+	a, b := topicBatch("a", 0), topicBatch("b", 1)
+	if err := os.WriteFile(path, slices.Concat(a, b[:len(b)-4]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	LoadClusterMetadata()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(a)) {
+		t.Fatalf("metadata log of %d bytes, want %d", info.Size(), len(a))
+	}
+	if topics := imageTopics(); !slices.Equal(topics, []string{"a"}) {
+		t.Errorf("topics %v, want [a]", topics)
+	}
+
+	// Appends continue from the truncated position
+	if err := CreateTopic("b", [16]byte{'b'}, []PartitionMetadata{{PartitionIndex: 0}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	publish(emptyImage)
+	metadataLogPosition = 0
+	metadataMu.Lock()
+	applied, torn, err := applyNewBatches()
+	metadataMu.Unlock()
+	if applied != 2 || torn != 0 || err != nil {
+		t.Errorf("replay applied %d with %d torn bytes and error %v, want 2, 0 and none", applied, torn, err)
+	}
+	if topics := imageTopics(); !slices.Equal(topics, []string{"a", "b"}) || LastAppliedOffset() != 2 {
+		t.Errorf("replayed topics %v to offset %d, want [a b] to 2", topics, LastAppliedOffset())
+	}
+}
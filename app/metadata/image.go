@@ -0,0 +1,272 @@
+package metadata
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// MetadataImage is an immutable snapshot of the cluster metadata. Readers get
+// the current one from Image() and may use it for as long as they like
+// without locking; changes build a new image from a MetadataDelta and publish
+// it atomically. Nothing reachable from an image, including the returned
+// topics and their partitions, may be modified.
+type MetadataImage struct {
+	topicsByName map[string]*TopicMetadata
+	topicsByID   map[[16]byte]*TopicMetadata
+	// nextProducerID is the first producer ID not yet handed out in a block,
+	// from the last ProducerIdsRecord
+	nextProducerID int64
+	// offset is the metadata log offset of the last record applied, or -1
+	offset int64
+}
+
+// emptyImage is the image before any metadata record is applied
+var emptyImage = &MetadataImage{
+	topicsByName: make(map[string]*TopicMetadata),
+	topicsByID:   make(map[[16]byte]*TopicMetadata),
+	offset:       -1,
+}
+
+var currentImage atomic.Pointer[MetadataImage]
+
+func init() {
+	currentImage.Store(emptyImage)
+}
+
+// Image returns the current metadata image
+func Image() *MetadataImage {
+	return currentImage.Load()
+}
+
+// publish makes image the current metadata image
+func publish(image *MetadataImage) {
+	currentImage.Store(image)
+}
+
+// Offset returns the metadata log offset of the last record applied to the
+// image, or -1 for the empty image
+func (img *MetadataImage) Offset() int64 {
+	return img.offset
+}
+
+// Topics returns every topic by name. The map is shared by all readers of the
+// image and must not be modified.
+func (img *MetadataImage) Topics() map[string]*TopicMetadata {
+	return img.topicsByName
+}
+
+// TopicByName looks a topic up by name
+func (img *MetadataImage) TopicByName(name string) (*TopicMetadata, bool) {
+	topic, exists := img.topicsByName[name]
+	return topic, exists
+}
+
+// TopicByID looks a topic up by topic ID
+func (img *MetadataImage) TopicByID(topicID [16]byte) (*TopicMetadata, bool) {
+	topic, exists := img.topicsByID[topicID]
+	return topic, exists
+}
+
+// Partition looks a partition of a topic up by index
+func (img *MetadataImage) Partition(topicName string, partitionIndex int32) (*PartitionMetadata, bool) {
+	topic, exists := img.topicsByName[topicName]
+	if !exists {
+		return nil, false
+	}
+	return topic.Partition(partitionIndex)
+}
+
+// Partition returns the partition with the given index. Partitions are kept
+// sorted by index, so it is a binary search.
+func (t *TopicMetadata) Partition(partitionIndex int32) (*PartitionMetadata, bool) {
+	i := sort.Search(len(t.Partitions), func(i int) bool {
+		return t.Partitions[i].PartitionIndex >= partitionIndex
+	})
+	if i < len(t.Partitions) && t.Partitions[i].PartitionIndex == partitionIndex {
+		return &t.Partitions[i], true
+	}
+	return nil, false
+}
+
+// MetadataDelta collects the changes of metadata records on top of a base
+// image. Topics are copied the first time a record changes them, so the base
+// image is never modified; Apply builds the new image.
+type MetadataDelta struct {
+	base *MetadataImage
+	// changed holds the topics created or modified by the delta, by topic ID
+	changed map[[16]byte]*TopicMetadata
+	// changedNames indexes changed by topic name
+	changedNames map[string][16]byte
+	// removed holds the topics deleted by the delta
+	removed map[[16]byte]bool
+	// nextProducerID is set once a ProducerIdsRecord is replayed
+	nextProducerID *int64
+	// offset is the metadata log offset of the last record replayed
+	offset int64
+}
+
+// NewMetadataDelta starts an empty delta on top of base
+func NewMetadataDelta(base *MetadataImage) *MetadataDelta {
+	return &MetadataDelta{
+		base:         base,
+		changed:      make(map[[16]byte]*TopicMetadata),
+		changedNames: make(map[string][16]byte),
+		removed:      make(map[[16]byte]bool),
+		offset:       base.offset,
+	}
+}
+
+// topicByID returns the topic as the delta sees it
+func (d *MetadataDelta) topicByID(topicID [16]byte) (*TopicMetadata, bool) {
+	if d.removed[topicID] {
+		return nil, false
+	}
+	if topic, exists := d.changed[topicID]; exists {
+		return topic, true
+	}
+	return d.base.TopicByID(topicID)
+}
+
+// topicByName returns the topic as the delta sees it
+func (d *MetadataDelta) topicByName(name string) (*TopicMetadata, bool) {
+	if topicID, exists := d.changedNames[name]; exists {
+		return d.changed[topicID], true
+	}
+	topic, exists := d.base.TopicByName(name)
+	if !exists || d.removed[topic.TopicID] {
+		return nil, false
+	}
+	return topic, true
+}
+
+// mutableTopic returns the delta's own copy of a topic, copying it from the
+// base image on first use
+func (d *MetadataDelta) mutableTopic(topicID [16]byte) (*TopicMetadata, bool) {
+	if topic, exists := d.changed[topicID]; exists {
+		return topic, true
+	}
+	topic, exists := d.topicByID(topicID)
+	if !exists {
+		return nil, false
+	}
+	copied := &TopicMetadata{
+		Name:       topic.Name,
+		TopicID:    topic.TopicID,
+		Partitions: append([]PartitionMetadata(nil), topic.Partitions...),
+	}
+	if topic.Configs != nil {
+		copied.Configs = make(map[string]string, len(topic.Configs))
+		for name, value := range topic.Configs {
+			copied.Configs[name] = value
+		}
+	}
+	d.changed[topicID] = copied
+	d.changedNames[copied.Name] = topicID
+	return copied, true
+}
+
+// replayTopic creates a topic, replacing any topic with the same name
+func (d *MetadataDelta) replayTopic(name string, topicID [16]byte) {
+	if existing, exists := d.topicByName(name); exists && existing.TopicID != topicID {
+		d.replayRemoveTopic(existing.TopicID)
+	}
+	if previous, exists := d.changed[topicID]; exists && previous.Name != name {
+		delete(d.changedNames, previous.Name)
+	}
+	delete(d.removed, topicID)
+	d.changed[topicID] = &TopicMetadata{
+		Name:       name,
+		TopicID:    topicID,
+		Partitions: []PartitionMetadata{},
+	}
+	d.changedNames[name] = topicID
+}
+
+// replayPartition adds or replaces a partition of a topic, keeping the
+// partitions sorted by index
+func (d *MetadataDelta) replayPartition(topicID [16]byte, partition PartitionMetadata) (*TopicMetadata, bool) {
+	topic, exists := d.mutableTopic(topicID)
+	if !exists {
+		return nil, false
+	}
+	i := sort.Search(len(topic.Partitions), func(i int) bool {
+		return topic.Partitions[i].PartitionIndex >= partition.PartitionIndex
+	})
+	if i < len(topic.Partitions) && topic.Partitions[i].PartitionIndex == partition.PartitionIndex {
+		topic.Partitions[i] = partition
+	} else {
+		topic.Partitions = append(topic.Partitions, PartitionMetadata{})
+		copy(topic.Partitions[i+1:], topic.Partitions[i:])
+		topic.Partitions[i] = partition
+	}
+	return topic, true
+}
+
+// replayRemoveTopic deletes a topic
+func (d *MetadataDelta) replayRemoveTopic(topicID [16]byte) (*TopicMetadata, bool) {
+	topic, exists := d.topicByID(topicID)
+	if !exists {
+		return nil, false
+	}
+	delete(d.changed, topicID)
+	if d.changedNames[topic.Name] == topicID {
+		delete(d.changedNames, topic.Name)
+	}
+	d.removed[topicID] = true
+	return topic, true
+}
+
+// replayTopicConfig sets a topic config, or removes it when value is nil
+func (d *MetadataDelta) replayTopicConfig(topicName string, name string, value *string) bool {
+	topic, exists := d.topicByName(topicName)
+	if !exists {
+		return false
+	}
+	topic, _ = d.mutableTopic(topic.TopicID)
+	if value == nil {
+		delete(topic.Configs, name)
+		return true
+	}
+	if topic.Configs == nil {
+		topic.Configs = make(map[string]string)
+	}
+	topic.Configs[name] = *value
+	return true
+}
+
+func (d *MetadataDelta) replayProducerIds(nextProducerID int64) {
+	d.nextProducerID = &nextProducerID
+}
+
+// replayOffset records that the records up to offset are in the delta
+func (d *MetadataDelta) replayOffset(offset int64) {
+	d.offset = offset
+}
+
+// Apply builds the image that results from applying the delta to its base
+func (d *MetadataDelta) Apply() *MetadataImage {
+	image := &MetadataImage{
+		topicsByName:   make(map[string]*TopicMetadata, len(d.base.topicsByName)+len(d.changed)),
+		topicsByID:     make(map[[16]byte]*TopicMetadata, len(d.base.topicsByID)+len(d.changed)),
+		nextProducerID: d.base.nextProducerID,
+		offset:         d.offset,
+	}
+	for topicID, topic := range d.base.topicsByID {
+		if d.removed[topicID] {
+			continue
+		}
+		if _, replaced := d.changed[topicID]; replaced {
+			continue
+		}
+		image.topicsByName[topic.Name] = topic
+		image.topicsByID[topicID] = topic
+	}
+	for topicID, topic := range d.changed {
+		image.topicsByName[topic.Name] = topic
+		image.topicsByID[topicID] = topic
+	}
+	if d.nextProducerID != nil {
+		image.nextProducerID = *d.nextProducerID
+	}
+	return image
+}
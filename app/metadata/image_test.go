@@ -0,0 +1,164 @@
+package metadata
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// describeImage lists an image's topics as "name/id [index:leader ...] configs",
+// sorted by name, after checking that its name and ID indexes agree
+func describeImage(t *testing.T, image *MetadataImage) string {
+	t.Helper()
+	if len(image.topicsByName) != len(image.topicsByID) {
+		t.Errorf("%d topics by name, %d by ID", len(image.topicsByName), len(image.topicsByID))
+	}
+	topics := make([]string, 0, len(image.topicsByName))
+	for name, topic := range image.topicsByName {
+		if byID, _ := image.TopicByID(topic.TopicID); byID != topic || topic.Name != name {
+			t.Errorf("topic %s is indexed as %v by ID", name, byID)
+		}
+		partitions := make([]string, 0, len(topic.Partitions))
+		for _, partition := range topic.Partitions {
+			partitions = append(partitions, fmt.Sprintf("%d:%d", partition.PartitionIndex, partition.LeaderID))
+		}
+		topics = append(topics, fmt.Sprintf("%s/%d [%s] %v", name, topic.TopicID[0], strings.Join(partitions, " "), topic.Configs))
+	}
+	sort.Strings(topics)
+	return strings.Join(topics, ", ")
+}
+
+func TestMetadataDeltaApply(t *testing.T) {
+	ordersID, paymentsID := [16]byte{1}, [16]byte{2}
+	retention := "1000"
+	setup := NewMetadataDelta(emptyImage)
+	setup.replayTopic("orders", ordersID)
+	setup.replayPartition(ordersID, PartitionMetadata{PartitionIndex: 1, LeaderID: 1})
+	setup.replayPartition(ordersID, PartitionMetadata{PartitionIndex: 0, LeaderID: 1})
+	setup.replayTopicConfig("orders", "retention.ms", &retention)
+	setup.replayTopic("payments", paymentsID)
+	setup.replayPartition(paymentsID, PartitionMetadata{PartitionIndex: 0, LeaderID: 1})
+	setup.replayOffset(10)
+	base := setup.Apply()
+	const baseTopics = "orders/1 [0:1 1:1] map[retention.ms:1000], payments/2 [0:1] map[]"
+	if got := describeImage(t, base); got != baseTopics {
+		t.Fatalf("base image %s, want %s", got, baseTopics)
+	}
+
+	t.Cleanup(func() { publish(emptyImage) })
+	tests := []struct {
+		name   string
+		replay func(d *MetadataDelta)
+		want   string
+		// shared are the topics the new image shares with the base image
+		shared []string
+	}{
+		{
+			name:   "no changes",
+			replay: func(d *MetadataDelta) {},
+			want:   baseTopics,
+			shared: []string{"orders", "payments"},
+		},
+		{
+			name: "new topic",
+			replay: func(d *MetadataDelta) {
+				d.replayTopic("refunds", [16]byte{3})
+				d.replayPartition([16]byte{3}, PartitionMetadata{PartitionIndex: 0, LeaderID: 2})
+			},
+			want:   "orders/1 [0:1 1:1] map[retention.ms:1000], payments/2 [0:1] map[], refunds/3 [0:2] map[]",
+			shared: []string{"orders", "payments"},
+		},
+		{
+			name: "partitions replaced and inserted in order",
+			replay: func(d *MetadataDelta) {
+				d.replayPartition(ordersID, PartitionMetadata{PartitionIndex: 0, LeaderID: 2})
+				d.replayPartition(ordersID, PartitionMetadata{PartitionIndex: 3, LeaderID: 1})
+				d.replayPartition(ordersID, PartitionMetadata{PartitionIndex: 2, LeaderID: 1})
+			},
+			want:   "orders/1 [0:2 1:1 2:1 3:1] map[retention.ms:1000], payments/2 [0:1] map[]",
+			shared: []string{"payments"},
+		},
+		{
+			name: "partition of an unknown topic",
+			replay: func(d *MetadataDelta) {
+				d.replayPartition([16]byte{9}, PartitionMetadata{PartitionIndex: 0, LeaderID: 2})
+			},
+			want:   baseTopics,
+			shared: []string{"orders", "payments"},
+		},
+		{
+			name:   "topic removed",
+			replay: func(d *MetadataDelta) { d.replayRemoveTopic(paymentsID) },
+			want:   "orders/1 [0:1 1:1] map[retention.ms:1000]",
+			shared: []string{"orders"},
+		},
+		{
+			name: "topic removed and created again",
+			replay: func(d *MetadataDelta) {
+				d.replayRemoveTopic(paymentsID)
+				d.replayTopic("payments", paymentsID)
+			},
+			want:   "orders/1 [0:1 1:1] map[retention.ms:1000], payments/2 [] map[]",
+			shared: []string{"orders"},
+		},
+		{
+			name:   "name reused by a new topic ID",
+			replay: func(d *MetadataDelta) { d.replayTopic("payments", [16]byte{4}) },
+			want:   "orders/1 [0:1 1:1] map[retention.ms:1000], payments/4 [] map[]",
+			shared: []string{"orders"},
+		},
+		{
+			name: "configs set and removed",
+			replay: func(d *MetadataDelta) {
+				segment := "60000"
+				d.replayTopicConfig("orders", "retention.ms", nil)
+				d.replayTopicConfig("orders", "segment.ms", &segment)
+			},
+			want:   "orders/1 [0:1 1:1] map[segment.ms:60000], payments/2 [0:1] map[]",
+			shared: []string{"payments"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := NewMetadataDelta(base)
+			tt.replay(delta)
+			image := delta.Apply()
+			publish(image)
+
+			if Image() != image {
+				t.Error("Apply result not published")
+			}
+			if got := describeImage(t, image); got != tt.want {
+				t.Errorf("image %s, want %s", got, tt.want)
+			}
+			// Readers still holding the base image must not see the change
+			if got := describeImage(t, base); got != baseTopics {
+				t.Errorf("base image changed to %s", got)
+			}
+			for name, topic := range image.Topics() {
+				baseTopic, _ := base.TopicByName(name)
+				if shared := topic == baseTopic; shared != slices.Contains(tt.shared, name) {
+					t.Errorf("topic %s shared with the base image %v, want %v", name, shared, !shared)
+				}
+			}
+			if image.Offset() != 10 {
+				t.Errorf("offset %d, want the base image's 10", image.Offset())
+			}
+		})
+	}
+
+	t.Run("producer IDs and offset", func(t *testing.T) {
+		delta := NewMetadataDelta(base)
+		delta.replayProducerIds(5000)
+		delta.replayOffset(42)
+		image := delta.Apply()
+		if image.nextProducerID != 5000 || image.Offset() != 42 {
+			t.Errorf("next producer ID %d and offset %d, want 5000 and 42", image.nextProducerID, image.Offset())
+		}
+		if next := NewMetadataDelta(image).Apply(); next.nextProducerID != 5000 || next.Offset() != 42 {
+			t.Errorf("next producer ID %d and offset %d carried over, want 5000 and 42", next.nextProducerID, next.Offset())
+		}
+	})
+}
@@ -5,159 +5,47 @@ import (
 	"fmt"
 )
 
-func ParseRecords(batch *RecordBatch) error {
-	data := batch.Records
-	offset := 0
-
-	fmt.Printf("Parsing batch with %d records, data len=%d\n", batch.RecordCount, len(data))
-
-	for i := int32(0); i < batch.RecordCount; i++ {
-		// Read record length (varint)
-		recordLen, n := binary.Varint(data[offset:])
-		if n <= 0 {
-			fmt.Printf("Failed to read record length at offset %d\n", offset)
-			return fmt.Errorf("failed to read record length")
-		}
-		offset += n
-
-		fmt.Printf("Record %d: length=%d, offset=%d\n", i, recordLen, offset)
-
-		if offset+int(recordLen) > len(data) {
-			fmt.Printf("Record length %d exceeds remaining data %d\n", recordLen, len(data)-offset)
-			return fmt.Errorf("record length exceeds data")
-		}
-
-		recordData := data[offset : offset+int(recordLen)]
-		offset += int(recordLen)
+// ParseRecords replays every record of a metadata batch into delta. A record
+// that cannot be applied is reported and skipped.
+func ParseRecords(delta *MetadataDelta, batch *RecordBatch) error {
+	// Compressed metadata batches are unpacked before their records are split
+	records, err := DecodeRecords(batch)
+	if err != nil {
+		return fmt.Errorf("failed to decode %v records: %w", batch.Compression(), err)
+	}
 
-		// Parse the record
-		if err := ParseRecord(recordData); err != nil {
+	for i, record := range records {
+		if err := ParseRecord(delta, record); err != nil {
 			fmt.Printf("Error parsing record %d: %v\n", i, err)
-			continue
 		}
 	}
 
 	return nil
 }
 
-func ParseRecord(data []byte) error {
-	offset := 0
-
-	fmt.Printf("Parsing record, data len=%d, hex=%x\n", len(data), data[:min(len(data), 20)])
-
-	// Read attributes (int8)
-	offset += 1
-
-	// Read timestamp delta (varint)
-	_, n := binary.Varint(data[offset:])
-	if n <= 0 {
-		return fmt.Errorf("failed to read timestamp delta")
+// ParseRecord applies one metadata record, whose value holds the frame version,
+// the record type and the record itself, to delta
+func ParseRecord(delta *MetadataDelta, record Record) error {
+	value := record.Value
+	if len(value) <= 2 {
+		return nil
 	}
-	offset += n
 
-	// Read offset delta (varint)
-	_, n = binary.Varint(data[offset:])
-	if n <= 0 {
-		return fmt.Errorf("failed to read offset delta")
-	}
-	offset += n
-
-	// Read key length (varint)
-	keyLen, n := binary.Varint(data[offset:])
-	if n <= 0 {
-		return fmt.Errorf("failed to read key length")
-	}
-	offset += n
-
-	fmt.Printf("Key length: %d, offset now: %d\n", keyLen, offset)
-
-	// Read key (keyLen can be -1 for null)
-	var key []byte
-	if keyLen == -1 {
-		// Null key
-		fmt.Printf("Key is null\n")
-	} else if keyLen > 0 {
-		if offset+int(keyLen) > len(data) {
-			return fmt.Errorf("key length exceeds data")
-		}
-		key = data[offset : offset+int(keyLen)]
-		offset += int(keyLen)
-		fmt.Printf("Key: %x\n", key)
+	switch recordType := parseRecordTypeFromValue(value); recordType {
+	case 2: // TopicRecord
+		return ParseTopicRecordFromValue(delta, value[2:])
+	case 3: // PartitionRecord
+		return ParsePartitionRecordFromValue(delta, value[2:])
+	case 9: // RemoveTopicRecord
+		return ParseRemoveTopicRecordFromValue(delta, value[2:])
+	case 15: // ProducerIdsRecord
+		return ParseProducerIdsRecordFromValue(delta, value[2:])
+	case 16: // ConfigRecord
+		return ParseConfigRecordFromValue(delta, value[2:])
+	default:
+		fmt.Printf("Unknown or unsupported record type: %d\n", recordType)
+		return nil
 	}
-
-	// Read value length (varint)
-	valueLen, n := binary.Varint(data[offset:])
-	if n <= 0 {
-		return fmt.Errorf("failed to read value length")
-	}
-	offset += n
-
-	fmt.Printf("Value length: %d\n", valueLen)
-
-	// Read value
-	var value []byte
-	if valueLen == -1 {
-		// Null value
-		fmt.Printf("Value is null\n")
-	} else if valueLen > 0 {
-		if offset+int(valueLen) > len(data) {
-			return fmt.Errorf("value length exceeds data")
-		}
-		value = data[offset : offset+int(valueLen)]
-		offset += int(valueLen)
-	}
-
-	// Read headers count (varint)
-	headersCount, n := binary.Varint(data[offset:])
-	if n <= 0 {
-		return fmt.Errorf("failed to read headers count")
-	}
-	offset += n
-
-	fmt.Printf("Headers count: %d\n", headersCount)
-
-	// Skip headers for now
-	for i := int64(0); i < headersCount; i++ {
-		// Read header key length
-		headerKeyLen, n := binary.Varint(data[offset:])
-		if n <= 0 {
-			return fmt.Errorf("failed to read header key length")
-		}
-		offset += n
-		offset += int(headerKeyLen) // Skip header key
-
-		// Read header value length
-		headerValueLen, n := binary.Varint(data[offset:])
-		if n <= 0 {
-			return fmt.Errorf("failed to read header value length")
-		}
-		offset += n
-		offset += int(headerValueLen) // Skip header value
-	}
-
-	// Parse the actual record from the value
-	if value != nil && len(value) > 0 {
-		recordType := parseRecordTypeFromValue(value)
-		fmt.Printf("Record type from value: %d\n", recordType)
-
-		// Parse based on record type
-		switch recordType {
-		case 2: // TopicRecord
-			fmt.Printf("Parsing TopicRecord...\n")
-			if len(value) > 2 {
-				ParseTopicRecordFromValue(value[2:])
-			}
-		case 3: // PartitionRecord
-			fmt.Printf("Parsing PartitionRecord...\n")
-			if len(value) > 2 {
-				ParsePartitionRecordFromValue(value[2:])
-			}
-		default:
-			fmt.Printf("Unknown or unsupported record type: %d\n", recordType)
-		}
-	}
-
-	return nil
 }
 
 func parseRecordTypeFromValue(value []byte) int8 {
@@ -168,11 +56,9 @@ func parseRecordTypeFromValue(value []byte) int8 {
 	return -1
 }
 
-func ParseTopicRecordFromValue(data []byte) error {
+func ParseTopicRecordFromValue(delta *MetadataDelta, data []byte) error {
 	offset := 0
 
-	fmt.Printf("parseTopicRecord: data len=%d, hex=%x\n", len(data), data)
-
 	// Skip TAG_BUFFER at the beginning (for flexible versions)
 	if offset < len(data) {
 		offset++ // Skip TAG_BUFFER byte
@@ -186,16 +72,12 @@ func ParseTopicRecordFromValue(data []byte) error {
 	offset += n
 	nameLen-- // Compact string encoding: length = N + 1
 
-	fmt.Printf("Topic name length: %d (after decoding), offset: %d\n", nameLen, offset)
-
 	if offset+nameLen > len(data) {
 		return fmt.Errorf("name length %d exceeds data: offset=%d, data len=%d", nameLen, offset, len(data))
 	}
 	name := string(data[offset : offset+nameLen])
 	offset += nameLen
 
-	fmt.Printf("Topic name: %s, offset now: %d\n", name, offset)
-
 	// Read topic ID (UUID - 16 bytes)
 	if offset+16 > len(data) {
 		return fmt.Errorf("not enough data for topic ID: need %d, have %d", offset+16, len(data))
@@ -203,22 +85,14 @@ func ParseTopicRecordFromValue(data []byte) error {
 	var topicID [16]byte
 	copy(topicID[:], data[offset:offset+16])
 
-	// Store the topic metadata
-	TopicsMetadata[name] = &TopicMetadata{
-		Name:       name,
-		TopicID:    topicID,
-		Partitions: []PartitionMetadata{},
-	}
+	delta.replayTopic(name, topicID)
 
-	fmt.Printf("Parsed TopicRecord: name=%s, topicID=%x\n", name, topicID)
 	return nil
 }
 
-func ParsePartitionRecordFromValue(data []byte) error {
+func ParsePartitionRecordFromValue(delta *MetadataDelta, data []byte) error {
 	offset := 0
 
-	fmt.Printf("parsePartitionRecord: data len=%d, hex=%x\n", len(data), data[:min(len(data), 40)])
-
 	// Skip TAG_BUFFER at the beginning (for flexible versions)
 	if offset < len(data) {
 		offset++ // Skip TAG_BUFFER byte
@@ -231,8 +105,6 @@ func ParsePartitionRecordFromValue(data []byte) error {
 	partitionID := int32(binary.BigEndian.Uint32(data[offset : offset+4]))
 	offset += 4
 
-	fmt.Printf("Partition ID: %d\n", partitionID)
-
 	// Read topic ID (UUID - 16 bytes)
 	if offset+16 > len(data) {
 		return fmt.Errorf("not enough data for topic ID")
@@ -248,6 +120,9 @@ func ParsePartitionRecordFromValue(data []byte) error {
 	}
 	offset += n
 	replicasLen-- // Compact array encoding: length = N + 1
+	if replicasLen < 0 || replicasLen > (len(data)-offset)/4 {
+		return fmt.Errorf("invalid replicas length %d", replicasLen)
+	}
 
 	replicas := make([]int32, replicasLen)
 	for i := 0; i < replicasLen; i++ {
@@ -265,6 +140,9 @@ func ParsePartitionRecordFromValue(data []byte) error {
 	}
 	offset += n
 	isrLen-- // Compact array encoding: length = N + 1
+	if isrLen < 0 || isrLen > (len(data)-offset)/4 {
+		return fmt.Errorf("invalid ISR length %d", isrLen)
+	}
 
 	isr := make([]int32, isrLen)
 	for i := 0; i < isrLen; i++ {
@@ -282,6 +160,9 @@ func ParsePartitionRecordFromValue(data []byte) error {
 	}
 	offset += n
 	removingLen-- // Compact array encoding: length = N + 1
+	if removingLen < 0 || removingLen > (len(data)-offset)/4 {
+		return fmt.Errorf("invalid removing replicas length %d", removingLen)
+	}
 	offset += removingLen * 4
 
 	// Skip adding replicas (COMPACT_ARRAY - uses unsigned varint)
@@ -291,6 +172,9 @@ func ParsePartitionRecordFromValue(data []byte) error {
 	}
 	offset += n
 	addingLen-- // Compact array encoding: length = N + 1
+	if addingLen < 0 || addingLen > (len(data)-offset)/4 {
+		return fmt.Errorf("invalid adding replicas length %d", addingLen)
+	}
 	offset += addingLen * 4
 
 	// Read leader (int32)
@@ -300,38 +184,129 @@ func ParsePartitionRecordFromValue(data []byte) error {
 	leader := int32(binary.BigEndian.Uint32(data[offset : offset+4]))
 	offset += 4
 
-	// Skip leader recovery state (int8)
-	offset += 1
-
 	// Read leader epoch (int32)
+	// LeaderRecoveryState is a tagged field, so the epoch follows the leader directly
 	if offset+4 > len(data) {
 		return fmt.Errorf("not enough data for leader epoch")
 	}
-	// Find the topic and add partition
-	for _, topic := range TopicsMetadata {
-		if topic.TopicID == topicID {
-			topic.Partitions = append(topic.Partitions, PartitionMetadata{
-				PartitionIndex: partitionID,
-				LeaderID:       leader,
-				ReplicaNodes:   replicas,
-				IsrNodes:       isr,
-			})
-			fmt.Printf("Added partition %d to topic %s (leader: %d)\n", partitionID, topic.Name, leader)
-			break
-		}
+	leaderEpoch := int32(binary.BigEndian.Uint32(data[offset : offset+4]))
+
+	topic, exists := delta.replayPartition(topicID, PartitionMetadata{
+		PartitionIndex: partitionID,
+		LeaderID:       leader,
+		LeaderEpoch:    leaderEpoch,
+		ReplicaNodes:   replicas,
+		IsrNodes:       isr,
+	})
+	if exists {
+		fmt.Printf("Added partition %d to topic %s (leader: %d)\n", partitionID, topic.Name, leader)
 	}
 
 	return nil
 }
 
-func readUvarint(data []byte) (int, int) {
-	v, n := binary.Uvarint(data)
-	return int(v), n
+func ParseRemoveTopicRecordFromValue(delta *MetadataDelta, data []byte) error {
+	offset := 0
+
+	// Skip TAG_BUFFER at the beginning (for flexible versions)
+	if offset < len(data) {
+		offset++ // Skip TAG_BUFFER byte
+	}
+
+	// Read topic ID (UUID - 16 bytes)
+	if offset+16 > len(data) {
+		return fmt.Errorf("not enough data for topic ID")
+	}
+	var topicID [16]byte
+	copy(topicID[:], data[offset:offset+16])
+
+	delta.replayRemoveTopic(topicID)
+	return nil
+}
+
+// ParseProducerIdsRecordFromValue records the end of the last allocated producer ID block
+func ParseProducerIdsRecordFromValue(delta *MetadataDelta, data []byte) error {
+	offset := 0
+
+	// Skip the record version
+	if offset < len(data) {
+		offset++
+	}
+
+	// Read broker ID (int32) and broker epoch (int64)
+	if offset+12 > len(data) {
+		return fmt.Errorf("not enough data for broker ID and epoch")
+	}
+	offset += 12
+
+	// Read next producer ID (int64)
+	if offset+8 > len(data) {
+		return fmt.Errorf("not enough data for next producer ID")
+	}
+	nextProducerID := int64(binary.BigEndian.Uint64(data[offset : offset+8]))
+	delta.replayProducerIds(nextProducerID)
+	return nil
+}
+
+// ParseConfigRecordFromValue applies a topic ConfigRecord to its topic's Configs.
+// Configs of other resource types (brokers, the cluster) are ignored.
+func ParseConfigRecordFromValue(delta *MetadataDelta, data []byte) error {
+	offset := 0
+
+	// Skip the record version
+	if offset < len(data) {
+		offset++
+	}
+
+	// Read resource type (int8)
+	if offset >= len(data) {
+		return fmt.Errorf("not enough data for resource type")
+	}
+	resourceType := int8(data[offset])
+	offset++
+
+	// Read resource name and config name (COMPACT_STRING)
+	resourceName, n, ok := readCompactNullableString(data[offset:])
+	if !ok || resourceName == nil {
+		return fmt.Errorf("failed to read resource name")
+	}
+	offset += n
+
+	name, n, ok := readCompactNullableString(data[offset:])
+	if !ok || name == nil {
+		return fmt.Errorf("failed to read config name")
+	}
+	offset += n
+
+	// Read value (COMPACT_NULLABLE_STRING, null = config removed)
+	value, _, ok := readCompactNullableString(data[offset:])
+	if !ok {
+		return fmt.Errorf("failed to read config value")
+	}
+
+	if resourceType != TopicResourceType {
+		return nil
+	}
+	if !delta.replayTopicConfig(*resourceName, *name, value) {
+		return fmt.Errorf("config %s for unknown topic %s", *name, *resourceName)
+	}
+	return nil
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// readCompactNullableString reads an unsigned varint length N+1 followed by N bytes, returning nil for length 0
+func readCompactNullableString(data []byte) (*string, int, bool) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || length > uint64(len(data)-n)+1 {
+		return nil, 0, false
+	}
+	if length == 0 {
+		return nil, n, true
 	}
-	return b
+	value := string(data[n : n+int(length)-1])
+	return &value, n + int(length) - 1, true
+}
+
+func readUvarint(data []byte) (int, int) {
+	v, n := binary.Uvarint(data)
+	return int(v), n
 }
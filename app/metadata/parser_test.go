@@ -0,0 +1,32 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseRecordsRecordLengths(t *testing.T) {
+	valid := NewRecordBatch([]Record{{Value: []byte{0, 99}}}, 1700000000000)
+	tests := []struct {
+		name    string
+		records []byte
+		count   int32
+		wantErr bool
+	}{
+		{"valid record", valid.Records, 1, false},
+		{"negative length", binary.AppendVarint(nil, -5), 1, true},
+		{"length past the end", append(binary.AppendVarint(nil, 20), 0, 0), 1, true},
+		{"missing length", nil, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := *valid
+			batch.Records = tt.records
+			batch.RecordCount = tt.count
+			err := ParseRecords(NewMetadataDelta(emptyImage), &batch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRecords error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
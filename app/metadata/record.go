@@ -0,0 +1,219 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Record is a single record inside a v2 record batch
+type Record struct {
+	Attributes     int8
+	TimestampDelta int64
+	OffsetDelta    int32
+	Key            []byte // nil = null key
+	Value          []byte // nil = null value (a tombstone)
+	Headers        []RecordHeader
+}
+
+type RecordHeader struct {
+	Key   string
+	Value []byte
+}
+
+// DecodeRecords decompresses the records section of a batch and splits it into individual records
+func DecodeRecords(batch *RecordBatch) ([]Record, error) {
+	data, err := batch.RecordData()
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	// Every record takes at least one byte, so a larger count cannot be right
+	if batch.RecordCount < 0 || int(batch.RecordCount) > len(data) {
+		return nil, fmt.Errorf("record count %d does not fit in %d bytes", batch.RecordCount, len(data))
+	}
+	records := make([]Record, 0, batch.RecordCount)
+
+	for i := int32(0); i < batch.RecordCount; i++ {
+		// Record length (varint)
+		recordLen, n := binary.Varint(data[offset:])
+		if n <= 0 || recordLen < 0 || offset+n+int(recordLen) > len(data) {
+			return nil, fmt.Errorf("invalid length for record %d", i)
+		}
+		offset += n
+
+		record, err := DecodeRecord(data[offset : offset+int(recordLen)])
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		records = append(records, record)
+		offset += int(recordLen)
+	}
+
+	return records, nil
+}
+
+// DecodeRecord decodes one record, without its length prefix
+func DecodeRecord(data []byte) (Record, error) {
+	var record Record
+	offset := 0
+
+	if len(data) < 1 {
+		return record, fmt.Errorf("record is empty")
+	}
+	// Attributes (INT8)
+	record.Attributes = int8(data[offset])
+	offset++
+
+	// TimestampDelta (VARLONG)
+	timestampDelta, n := binary.Varint(data[offset:])
+	if n <= 0 {
+		return record, fmt.Errorf("failed to read timestamp delta")
+	}
+	record.TimestampDelta = timestampDelta
+	offset += n
+
+	// OffsetDelta (VARINT)
+	offsetDelta, n := binary.Varint(data[offset:])
+	if n <= 0 {
+		return record, fmt.Errorf("failed to read offset delta")
+	}
+	record.OffsetDelta = int32(offsetDelta)
+	offset += n
+
+	// Key (VARINT length, -1 = null)
+	key, n, err := readVarbytes(data[offset:])
+	if err != nil {
+		return record, fmt.Errorf("key: %w", err)
+	}
+	record.Key = key
+	offset += n
+
+	// Value (VARINT length, -1 = null)
+	value, n, err := readVarbytes(data[offset:])
+	if err != nil {
+		return record, fmt.Errorf("value: %w", err)
+	}
+	record.Value = value
+	offset += n
+
+	// Headers (VARINT count)
+	headersCount, n := binary.Varint(data[offset:])
+	if n <= 0 || headersCount < 0 {
+		return record, fmt.Errorf("failed to read headers count")
+	}
+	offset += n
+
+	for i := int64(0); i < headersCount; i++ {
+		headerKey, n, err := readVarbytes(data[offset:])
+		if err != nil {
+			return record, fmt.Errorf("header key: %w", err)
+		}
+		offset += n
+
+		headerValue, n, err := readVarbytes(data[offset:])
+		if err != nil {
+			return record, fmt.Errorf("header value: %w", err)
+		}
+		offset += n
+
+		record.Headers = append(record.Headers, RecordHeader{Key: string(headerKey), Value: headerValue})
+	}
+
+	return record, nil
+}
+
+// readVarbytes reads a varint length followed by that many bytes, returning nil for length -1
+func readVarbytes(data []byte) ([]byte, int, error) {
+	length, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, 0, fmt.Errorf("failed to read length")
+	}
+	if length < 0 {
+		return nil, n, nil
+	}
+	if n+int(length) > len(data) {
+		return nil, 0, fmt.Errorf("length %d exceeds data", length)
+	}
+	return data[n : n+int(length)], n + int(length), nil
+}
+
+// EncodeRecord serializes a record including its length prefix
+func EncodeRecord(record Record) []byte {
+	body := make([]byte, 0, 16+len(record.Key)+len(record.Value))
+	body = append(body, byte(record.Attributes))
+	body = binary.AppendVarint(body, record.TimestampDelta)
+	body = binary.AppendVarint(body, int64(record.OffsetDelta))
+	body = appendVarbytes(body, record.Key)
+	body = appendVarbytes(body, record.Value)
+	body = binary.AppendVarint(body, int64(len(record.Headers)))
+	for _, header := range record.Headers {
+		body = appendVarbytes(body, []byte(header.Key))
+		body = appendVarbytes(body, header.Value)
+	}
+
+	buf := binary.AppendVarint(make([]byte, 0, len(body)+5), int64(len(body)))
+	return append(buf, body...)
+}
+
+func appendVarbytes(buf []byte, data []byte) []byte {
+	if data == nil {
+		return binary.AppendVarint(buf, -1)
+	}
+	buf = binary.AppendVarint(buf, int64(len(data)))
+	return append(buf, data...)
+}
+
+// NewRecordBatch builds an uncompressed batch holding records, all stamped with timestamp.
+// Offset deltas are assigned in order; the base offset is left for the log to assign.
+func NewRecordBatch(records []Record, timestamp int64) *RecordBatch {
+	batch := &RecordBatch{
+		Magic:           2,
+		LastOffsetDelta: int32(len(records) - 1),
+		BaseTimestamp:   timestamp,
+		MaxTimestamp:    timestamp,
+		ProducerID:      -1,
+		ProducerEpoch:   -1,
+		BaseSequence:    -1,
+		RecordCount:     int32(len(records)),
+	}
+	for i, record := range records {
+		record.OffsetDelta = int32(i)
+		record.TimestampDelta = 0
+		batch.Records = append(batch.Records, EncodeRecord(record)...)
+	}
+	return batch
+}
+
+// Control record types, stored in the key of a transaction marker
+const (
+	ControlTypeAbort  int16 = 0
+	ControlTypeCommit int16 = 1
+)
+
+// NewControlBatch builds a transaction marker for a producer: a transactional
+// control batch holding one record whose key is the control type (version 0)
+// and whose value is the coordinator epoch (version 0)
+func NewControlBatch(producerID int64, producerEpoch int16, coordinatorEpoch int32, controlType int16, timestamp int64) *RecordBatch {
+	key := binary.BigEndian.AppendUint16(make([]byte, 0, 4), 0)
+	key = binary.BigEndian.AppendUint16(key, uint16(controlType))
+	value := binary.BigEndian.AppendUint16(make([]byte, 0, 6), 0)
+	value = binary.BigEndian.AppendUint32(value, uint32(coordinatorEpoch))
+
+	batch := NewRecordBatch([]Record{{Key: key, Value: value}}, timestamp)
+	batch.Attributes = AttributeTransactional | AttributeControl
+	batch.ProducerID = producerID
+	batch.ProducerEpoch = producerEpoch
+	return batch
+}
+
+// ControlRecordType returns the control type of a transaction marker
+func ControlRecordType(batch *RecordBatch) (int16, error) {
+	records, err := DecodeRecords(batch)
+	if err != nil {
+		return -1, err
+	}
+	if len(records) != 1 || len(records[0].Key) < 4 {
+		return -1, fmt.Errorf("control batch does not hold a single control record")
+	}
+	return int16(binary.BigEndian.Uint16(records[0].Key[2:4])), nil
+}
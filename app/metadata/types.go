@@ -1,14 +1,17 @@
 package metadata
 
+// TopicMetadata is a topic in a MetadataImage; Partitions are sorted by index
 type TopicMetadata struct {
 	Name       string
 	TopicID    [16]byte
 	Partitions []PartitionMetadata
+	Configs    map[string]string // topic-level overrides from ConfigRecords
 }
 
 type PartitionMetadata struct {
 	PartitionIndex int32
 	LeaderID       int32
+	LeaderEpoch    int32
 	ReplicaNodes   []int32
 	IsrNodes       []int32
 }
@@ -30,8 +33,21 @@ type RecordBatch struct {
 	Records              []byte
 }
 
-var TopicsMetadata = make(map[string]*TopicMetadata)
+// ClusterID is read from meta.properties in the log directory at startup
+var ClusterID string
 
+// GetTopicMetadata returns every topic of the current metadata image by name.
+// The map belongs to the image and must not be modified.
 func GetTopicMetadata() map[string]*TopicMetadata {
-	return TopicsMetadata
+	return Image().Topics()
+}
+
+// TopicConfig returns the topic-level value of a config, if the topic overrides it
+func TopicConfig(topic string, name string) (string, bool) {
+	topicMeta, exists := Image().TopicByName(topic)
+	if !exists {
+		return "", false
+	}
+	value, found := topicMeta.Configs[name]
+	return value, found
 }
@@ -1,17 +1,303 @@
 package metadata
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 )
 
-func WriteRecordsToLog(topic string, partition int32, records []byte) error {
-	logFile, err := os.OpenFile(fmt.Sprintf("/tmp/kraft-combined-logs/%s-%d/00000000000000000000.log", topic, partition), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// MetadataLogDir holds meta.properties and the __cluster_metadata log. It
+// defaults to the first of the broker's log.dirs (metadata.log.dir).
+var MetadataLogDir = "/tmp/kraft-combined-logs"
+
+// MetadataLogPath returns the single segment of the __cluster_metadata log
+func MetadataLogPath() string {
+	return filepath.Join(MetadataLogDir, "__cluster_metadata-0", "00000000000000000000.log")
+}
+
+// KRaft metadata record types
+const (
+	TopicRecordType       int8 = 2
+	PartitionRecordType   int8 = 3
+	RemoveTopicRecordType int8 = 9
+	ProducerIdsRecordType int8 = 15
+	ConfigRecordType      int8 = 16
+)
+
+// TopicResourceType is the ConfigRecord resource type of topic configs
+const TopicResourceType int8 = 2
+
+// metadataRecordFrameVersion is the frame version prefixed to every metadata record value
+const metadataRecordFrameVersion = 1
+
+// ErrTopicAlreadyExists is returned by CreateTopic when the name is taken
+var ErrTopicAlreadyExists = errors.New("topic already exists")
+
+// ErrUnknownTopic is returned by DeleteTopic when no topic has the given ID
+var ErrUnknownTopic = errors.New("unknown topic")
+
+// metadataMu serializes reads and writes of the metadata log and the images they publish
+var metadataMu sync.Mutex
+
+// CreateTopic appends a TopicRecord, a ConfigRecord per topic config and one
+// PartitionRecord per partition to the metadata log as a single batch, then
+// publishes the image with them applied
+func CreateTopic(name string, topicID [16]byte, partitions []PartitionMetadata, configs map[string]string) error {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+
+	if _, exists := Image().TopicByName(name); exists {
+		return fmt.Errorf("%w: %s", ErrTopicAlreadyExists, name)
+	}
+
+	values := [][]byte{EncodeTopicRecord(name, topicID)}
+	configNames := make([]string, 0, len(configs))
+	for configName := range configs {
+		configNames = append(configNames, configName)
+	}
+	sort.Strings(configNames)
+	for _, configName := range configNames {
+		value := configs[configName]
+		values = append(values, EncodeConfigRecord(TopicResourceType, name, configName, &value))
+	}
+	for _, partition := range partitions {
+		values = append(values, EncodePartitionRecord(topicID, partition))
+	}
+	if err := appendMetadataRecords(values); err != nil {
+		return err
+	}
+	fmt.Printf("Created topic %s with %d partitions\n", name, len(partitions))
+	return nil
+}
+
+// DeleteTopic appends a RemoveTopicRecord to the metadata log and publishes the
+// image without the topic, returning what was removed
+func DeleteTopic(topicID [16]byte) (TopicMetadata, error) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+
+	deleted, exists := Image().TopicByID(topicID)
+	if !exists {
+		return TopicMetadata{}, fmt.Errorf("%w: %x", ErrUnknownTopic, topicID)
+	}
+
+	value := EncodeRemoveTopicRecord(topicID)
+	if err := appendMetadataRecords([][]byte{value}); err != nil {
+		return TopicMetadata{}, err
+	}
+	fmt.Printf("Deleted topic %s\n", deleted.Name)
+	return *deleted, nil
+}
+
+// AllocateProducerIDs reserves the next blockSize producer IDs for brokerID by
+// appending a ProducerIdsRecord, and returns the first ID of the block
+func AllocateProducerIDs(brokerID int32, blockSize int64) (int64, error) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+
+	start := Image().nextProducerID
+	// There is no broker registration, so the broker epoch is always 0
+	value := EncodeProducerIdsRecord(brokerID, 0, start+blockSize)
+	if err := appendMetadataRecords([][]byte{value}); err != nil {
+		return 0, err
+	}
+	fmt.Printf("Allocated producer IDs %d-%d to broker %d\n", start, start+blockSize-1, brokerID)
+	return start, nil
+}
+
+// appendMetadataRecords writes one batch holding a record per value to the end
+// of the metadata log, then publishes the image with them applied
+func appendMetadataRecords(values [][]byte) error {
+	// Apply what another process appended first, so the batch continues its offsets
+	if _, torn, err := applyNewBatches(); err != nil {
+		return err
+	} else if torn > 0 {
+		return fmt.Errorf("metadata log ends in an incomplete batch of %d bytes", torn)
+	}
+
+	records := make([]Record, 0, len(values))
+	for _, value := range values {
+		records = append(records, Record{Value: value})
+	}
+	batch := NewRecordBatch(records, time.Now().UnixMilli())
+	batch.BaseOffset = Image().Offset() + 1
+	encoded := batch.Encode()
+
+	if err := os.MkdirAll(filepath.Dir(MetadataLogPath()), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(MetadataLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
-	defer logFile.Close()
+	defer file.Close()
+
+	if err := writeMetadataBatch(file, encoded); err != nil {
+		// The log was caught up above, so metadataLogPosition is where the
+		// batch started; a partial batch left there would fail every later append
+		if truncateErr := file.Truncate(metadataLogPosition); truncateErr != nil {
+			return fmt.Errorf("%w (truncating the partial batch failed: %v)", err, truncateErr)
+		}
+		return err
+	}
+
+	// Apply through the same parsers used at startup so the log and memory
+	// cannot diverge, without reading back what was just written
+	delta := NewMetadataDelta(Image())
+	for _, value := range values {
+		applyRecordValue(delta, value)
+	}
+	delta.replayOffset(batch.LastOffset())
+	publish(delta.Apply())
+	metadataLogPosition += int64(len(encoded))
+	return nil
+}
+
+// writeMetadataBatch appends an encoded batch and syncs it: metadata changes
+// must survive a crash before they are acknowledged
+func writeMetadataBatch(file *os.File, encoded []byte) error {
+	if _, err := file.Write(encoded); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// applyRecordValue replays one metadata record value into delta
+func applyRecordValue(delta *MetadataDelta, value []byte) {
+	if len(value) <= 2 {
+		return
+	}
+	switch parseRecordTypeFromValue(value) {
+	case TopicRecordType:
+		ParseTopicRecordFromValue(delta, value[2:])
+	case PartitionRecordType:
+		ParsePartitionRecordFromValue(delta, value[2:])
+	case RemoveTopicRecordType:
+		ParseRemoveTopicRecordFromValue(delta, value[2:])
+	case ProducerIdsRecordType:
+		ParseProducerIdsRecordFromValue(delta, value[2:])
+	case ConfigRecordType:
+		ParseConfigRecordFromValue(delta, value[2:])
+	}
+}
+
+// EncodeTopicRecord builds the value of a TopicRecord (v0)
+func EncodeTopicRecord(name string, topicID [16]byte) []byte {
+	buf := metadataRecordHeader(TopicRecordType, 0)
+
+	// Name (COMPACT_STRING)
+	buf = binary.AppendUvarint(buf, uint64(len(name)+1))
+	buf = append(buf, name...)
+
+	// TopicID (UUID)
+	buf = append(buf, topicID[:]...)
+
+	// TAG_BUFFER
+	return append(buf, 0x00)
+}
+
+// EncodePartitionRecord builds the value of a PartitionRecord (v0)
+func EncodePartitionRecord(topicID [16]byte, partition PartitionMetadata) []byte {
+	buf := metadataRecordHeader(PartitionRecordType, 0)
+
+	// PartitionID (INT32)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(partition.PartitionIndex))
+
+	// TopicID (UUID)
+	buf = append(buf, topicID[:]...)
+
+	// Replicas (COMPACT_ARRAY)
+	buf = appendInt32Array(buf, partition.ReplicaNodes)
+
+	// ISR (COMPACT_ARRAY)
+	buf = appendInt32Array(buf, partition.IsrNodes)
+
+	// RemovingReplicas and AddingReplicas (COMPACT_ARRAY) - empty
+	buf = append(buf, 0x01, 0x01)
+
+	// Leader (INT32)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(partition.LeaderID))
+
+	// LeaderEpoch (INT32)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(partition.LeaderEpoch))
+
+	// PartitionEpoch (INT32)
+	buf = binary.BigEndian.AppendUint32(buf, 0)
+
+	// TAG_BUFFER
+	return append(buf, 0x00)
+}
+
+// EncodeRemoveTopicRecord builds the value of a RemoveTopicRecord (v0)
+func EncodeRemoveTopicRecord(topicID [16]byte) []byte {
+	buf := metadataRecordHeader(RemoveTopicRecordType, 0)
+
+	// TopicID (UUID)
+	buf = append(buf, topicID[:]...)
+
+	// TAG_BUFFER
+	return append(buf, 0x00)
+}
+
+// EncodeProducerIdsRecord builds the value of a ProducerIdsRecord (v0)
+func EncodeProducerIdsRecord(brokerID int32, brokerEpoch int64, nextProducerID int64) []byte {
+	buf := metadataRecordHeader(ProducerIdsRecordType, 0)
+
+	// BrokerId (INT32)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(brokerID))
+
+	// BrokerEpoch (INT64)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(brokerEpoch))
+
+	// NextProducerId (INT64)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(nextProducerID))
+
+	// TAG_BUFFER
+	return append(buf, 0x00)
+}
+
+// EncodeConfigRecord builds the value of a ConfigRecord (v0). A nil value
+// removes the config.
+func EncodeConfigRecord(resourceType int8, resourceName string, name string, value *string) []byte {
+	buf := metadataRecordHeader(ConfigRecordType, 0)
+
+	// ResourceType (INT8)
+	buf = append(buf, byte(resourceType))
+
+	// ResourceName (COMPACT_STRING)
+	buf = binary.AppendUvarint(buf, uint64(len(resourceName)+1))
+	buf = append(buf, resourceName...)
+
+	// Name (COMPACT_STRING)
+	buf = binary.AppendUvarint(buf, uint64(len(name)+1))
+	buf = append(buf, name...)
+
+	// Value (COMPACT_NULLABLE_STRING)
+	if value == nil {
+		buf = append(buf, 0x00)
+	} else {
+		buf = binary.AppendUvarint(buf, uint64(len(*value)+1))
+		buf = append(buf, *value...)
+	}
 
-	_, err = logFile.Write(records)
-	return err
+	// TAG_BUFFER
+	return append(buf, 0x00)
+}
+
+// metadataRecordHeader starts a record value: frame version, record type, record version
+func metadataRecordHeader(recordType int8, version int8) []byte {
+	return []byte{metadataRecordFrameVersion, byte(recordType), byte(version)}
+}
+
+func appendInt32Array(buf []byte, values []int32) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(values)+1))
+	for _, value := range values {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(value))
+	}
+	return buf
 }
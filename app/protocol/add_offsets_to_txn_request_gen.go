@@ -0,0 +1,72 @@
+// Code generated by gen from schemas/AddOffsetsToTxnRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// AddOffsetsToTxnRequest is a request of API key 25, versions 0-3
+type AddOffsetsToTxnRequest struct {
+	// The transactional id corresponding to the transaction.
+	TransactionalId string
+	// Current producer id in use by the transactional id.
+	ProducerId int64
+	// Current epoch associated with the producer id.
+	ProducerEpoch int16
+	// The unique group identifier.
+	GroupId string
+}
+
+func (m *AddOffsetsToTxnRequest) ApiKey() int16 { return 25 }
+
+func (m *AddOffsetsToTxnRequest) MinVersion() int16 { return 0 }
+
+func (m *AddOffsetsToTxnRequest) MaxVersion() int16 { return 3 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *AddOffsetsToTxnRequest) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *AddOffsetsToTxnRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *AddOffsetsToTxnRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported AddOffsetsToTxnRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *AddOffsetsToTxnRequest) Default() {
+	*m = AddOffsetsToTxnRequest{}
+}
+
+func (m *AddOffsetsToTxnRequest) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.String(m.TransactionalId, flexible)
+	w.Int64(m.ProducerId)
+	w.Int16(m.ProducerEpoch)
+	w.String(m.GroupId, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *AddOffsetsToTxnRequest) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.TransactionalId = r.String(flexible)
+	m.ProducerId = r.Int64()
+	m.ProducerEpoch = r.Int16()
+	m.GroupId = r.String(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
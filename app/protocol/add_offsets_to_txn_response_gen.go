@@ -0,0 +1,64 @@
+// Code generated by gen from schemas/AddOffsetsToTxnResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// AddOffsetsToTxnResponse is a response of API key 25, versions 0-3
+type AddOffsetsToTxnResponse struct {
+	// Duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The response error code, or 0 if there was no error.
+	ErrorCode int16
+}
+
+func (m *AddOffsetsToTxnResponse) ApiKey() int16 { return 25 }
+
+func (m *AddOffsetsToTxnResponse) MinVersion() int16 { return 0 }
+
+func (m *AddOffsetsToTxnResponse) MaxVersion() int16 { return 3 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *AddOffsetsToTxnResponse) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *AddOffsetsToTxnResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *AddOffsetsToTxnResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported AddOffsetsToTxnResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *AddOffsetsToTxnResponse) Default() {
+	*m = AddOffsetsToTxnResponse{}
+}
+
+func (m *AddOffsetsToTxnResponse) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.Int32(m.ThrottleTimeMs)
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *AddOffsetsToTxnResponse) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.ThrottleTimeMs = r.Int32()
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,130 @@
+// Code generated by gen from schemas/AddPartitionsToTxnRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// AddPartitionsToTxnRequest is a request of API key 24, versions 0-3
+type AddPartitionsToTxnRequest struct {
+	// The transactional id corresponding to the transaction.
+	TransactionalId string
+	// Current producer id in use by the transactional id.
+	ProducerId int64
+	// Current epoch associated with the producer id.
+	ProducerEpoch int16
+	// The partitions to add to the transaction.
+	Topics []AddPartitionsToTxnRequestAddPartitionsToTxnTopic
+}
+
+type AddPartitionsToTxnRequestAddPartitionsToTxnTopic struct {
+	// The name of the topic.
+	Name string
+	// The partition indexes to add to the transaction
+	Partitions []int32
+}
+
+func (m *AddPartitionsToTxnRequest) ApiKey() int16 { return 24 }
+
+func (m *AddPartitionsToTxnRequest) MinVersion() int16 { return 0 }
+
+func (m *AddPartitionsToTxnRequest) MaxVersion() int16 { return 3 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *AddPartitionsToTxnRequest) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *AddPartitionsToTxnRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *AddPartitionsToTxnRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported AddPartitionsToTxnRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *AddPartitionsToTxnRequest) Default() {
+	*m = AddPartitionsToTxnRequest{}
+}
+
+func (m *AddPartitionsToTxnRequest) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.String(m.TransactionalId, flexible)
+	w.Int64(m.ProducerId)
+	w.Int16(m.ProducerEpoch)
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *AddPartitionsToTxnRequest) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.TransactionalId = r.String(flexible)
+	m.ProducerId = r.Int64()
+	m.ProducerEpoch = r.Int16()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]AddPartitionsToTxnRequestAddPartitionsToTxnTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *AddPartitionsToTxnRequestAddPartitionsToTxnTopic) Default() {
+	*m = AddPartitionsToTxnRequestAddPartitionsToTxnTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *AddPartitionsToTxnRequestAddPartitionsToTxnTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *AddPartitionsToTxnRequestAddPartitionsToTxnTopic) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		w.Int32(m.Partitions[i])
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *AddPartitionsToTxnRequestAddPartitionsToTxnTopic) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]int32, n)
+		for i := range m.Partitions {
+			m.Partitions[i] = r.Int32()
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
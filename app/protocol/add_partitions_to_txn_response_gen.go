@@ -0,0 +1,160 @@
+// Code generated by gen from schemas/AddPartitionsToTxnResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// AddPartitionsToTxnResponse is a response of API key 24, versions 0-3
+type AddPartitionsToTxnResponse struct {
+	// Duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The results for each topic.
+	Results []AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult
+}
+
+type AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult struct {
+	// The topic name.
+	Name string
+	// The results for each partition
+	Results []AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult
+}
+
+type AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult struct {
+	// The partition indexes.
+	PartitionIndex int32
+	// The response error code.
+	ErrorCode int16
+}
+
+func (m *AddPartitionsToTxnResponse) ApiKey() int16 { return 24 }
+
+func (m *AddPartitionsToTxnResponse) MinVersion() int16 { return 0 }
+
+func (m *AddPartitionsToTxnResponse) MaxVersion() int16 { return 3 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *AddPartitionsToTxnResponse) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *AddPartitionsToTxnResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *AddPartitionsToTxnResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported AddPartitionsToTxnResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *AddPartitionsToTxnResponse) Default() {
+	*m = AddPartitionsToTxnResponse{}
+}
+
+func (m *AddPartitionsToTxnResponse) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.Int32(m.ThrottleTimeMs)
+	w.ArrayLen(len(m.Results), flexible)
+	for i := range m.Results {
+		m.Results[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *AddPartitionsToTxnResponse) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.ThrottleTimeMs = r.Int32()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Results = make([]AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult, n)
+		for i := range m.Results {
+			m.Results[i].Default()
+			m.Results[i].decode(r, version)
+		}
+	} else {
+		m.Results = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult) Default() {
+	*m = AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Results) == 0
+}
+
+func (m *AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Results), flexible)
+	for i := range m.Results {
+		m.Results[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Results = make([]AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult, n)
+		for i := range m.Results {
+			m.Results[i].Default()
+			m.Results[i].decode(r, version)
+		}
+	} else {
+		m.Results = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult) Default() {
+	*m = AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.ErrorCode == 0
+}
+
+func (m *AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.Int32(m.PartitionIndex)
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.PartitionIndex = r.Int32()
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
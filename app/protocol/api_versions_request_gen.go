@@ -0,0 +1,72 @@
+// Code generated by gen from schemas/ApiVersionsRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// ApiVersionsRequest is a request of API key 18, versions 0-4
+type ApiVersionsRequest struct {
+	// The name of the client.
+	ClientSoftwareName string
+	// The version of the client.
+	ClientSoftwareVersion string
+}
+
+func (m *ApiVersionsRequest) ApiKey() int16 { return 18 }
+
+func (m *ApiVersionsRequest) MinVersion() int16 { return 0 }
+
+func (m *ApiVersionsRequest) MaxVersion() int16 { return 4 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *ApiVersionsRequest) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *ApiVersionsRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *ApiVersionsRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported ApiVersionsRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *ApiVersionsRequest) Default() {
+	*m = ApiVersionsRequest{}
+}
+
+func (m *ApiVersionsRequest) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	if version >= 3 {
+		w.String(m.ClientSoftwareName, flexible)
+	}
+	if version >= 3 {
+		w.String(m.ClientSoftwareVersion, flexible)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ApiVersionsRequest) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	if version >= 3 {
+		m.ClientSoftwareName = r.String(flexible)
+	}
+	if version >= 3 {
+		m.ClientSoftwareVersion = r.String(flexible)
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,288 @@
+// Code generated by gen from schemas/ApiVersionsResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// ApiVersionsResponse is a response of API key 18, versions 0-4
+type ApiVersionsResponse struct {
+	// The top-level error code.
+	ErrorCode int16
+	// The APIs supported by the broker.
+	ApiKeys []ApiVersionsResponseApiVersion
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// Features supported by the broker. Note: in v0-v3, features with MinSupportedVersion = 0 are omitted.
+	SupportedFeatures []ApiVersionsResponseSupportedFeatureKey
+	// The monotonically increasing epoch for the finalized features information. Valid values are >= 0. A value of -1 is special and represents unknown epoch.
+	FinalizedFeaturesEpoch int64
+	// List of cluster-wide finalized features. The information is valid only if FinalizedFeaturesEpoch >= 0.
+	FinalizedFeatures []ApiVersionsResponseFinalizedFeatureKey
+	// Set by a KRaft controller if the required configurations for ZK migration are present
+	ZkMigrationReady bool
+}
+
+type ApiVersionsResponseApiVersion struct {
+	// The API index.
+	ApiKey int16
+	// The minimum supported version, inclusive.
+	MinVersion int16
+	// The maximum supported version, inclusive.
+	MaxVersion int16
+}
+
+type ApiVersionsResponseSupportedFeatureKey struct {
+	// The name of the feature.
+	Name string
+	// The minimum supported version for the feature.
+	MinVersion int16
+	// The maximum supported version for the feature.
+	MaxVersion int16
+}
+
+type ApiVersionsResponseFinalizedFeatureKey struct {
+	// The name of the feature.
+	Name string
+	// The cluster-wide finalized max version level for the feature.
+	MaxVersionLevel int16
+	// The cluster-wide finalized min version level for the feature.
+	MinVersionLevel int16
+}
+
+func (m *ApiVersionsResponse) ApiKey() int16 { return 18 }
+
+func (m *ApiVersionsResponse) MinVersion() int16 { return 0 }
+
+func (m *ApiVersionsResponse) MaxVersion() int16 { return 4 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *ApiVersionsResponse) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *ApiVersionsResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *ApiVersionsResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported ApiVersionsResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *ApiVersionsResponse) Default() {
+	*m = ApiVersionsResponse{}
+	m.FinalizedFeaturesEpoch = -1
+}
+
+func (m *ApiVersionsResponse) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.Int16(m.ErrorCode)
+	w.ArrayLen(len(m.ApiKeys), flexible)
+	for i := range m.ApiKeys {
+		m.ApiKeys[i].encode(w, version)
+	}
+	if version >= 1 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	if flexible {
+		var tagged []TaggedField
+		if version >= 3 && len(m.SupportedFeatures) > 0 {
+			tw := NewWriter()
+			tw.ArrayLen(len(m.SupportedFeatures), flexible)
+			for i := range m.SupportedFeatures {
+				m.SupportedFeatures[i].encode(tw, version)
+			}
+			tagged = append(tagged, TaggedField{Tag: 0, Data: tw.Buf()})
+		}
+		if version >= 3 && m.FinalizedFeaturesEpoch != -1 {
+			tw := NewWriter()
+			tw.Int64(m.FinalizedFeaturesEpoch)
+			tagged = append(tagged, TaggedField{Tag: 1, Data: tw.Buf()})
+		}
+		if version >= 3 && len(m.FinalizedFeatures) > 0 {
+			tw := NewWriter()
+			tw.ArrayLen(len(m.FinalizedFeatures), flexible)
+			for i := range m.FinalizedFeatures {
+				m.FinalizedFeatures[i].encode(tw, version)
+			}
+			tagged = append(tagged, TaggedField{Tag: 2, Data: tw.Buf()})
+		}
+		if version >= 3 && m.ZkMigrationReady {
+			tw := NewWriter()
+			tw.Bool(m.ZkMigrationReady)
+			tagged = append(tagged, TaggedField{Tag: 3, Data: tw.Buf()})
+		}
+		w.TaggedFields(tagged)
+	}
+}
+
+func (m *ApiVersionsResponse) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.ErrorCode = r.Int16()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.ApiKeys = make([]ApiVersionsResponseApiVersion, n)
+		for i := range m.ApiKeys {
+			m.ApiKeys[i].Default()
+			m.ApiKeys[i].decode(r, version)
+		}
+	} else {
+		m.ApiKeys = nil
+	}
+	if version >= 1 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if flexible {
+		for _, field := range r.TaggedFields() {
+			switch {
+			case field.Tag == 0 && version >= 3:
+				tr := NewReader(field.Data)
+				if n := tr.ArrayLen(flexible); n >= 0 {
+					m.SupportedFeatures = make([]ApiVersionsResponseSupportedFeatureKey, n)
+					for i := range m.SupportedFeatures {
+						m.SupportedFeatures[i].Default()
+						m.SupportedFeatures[i].decode(tr, version)
+					}
+				} else {
+					m.SupportedFeatures = nil
+				}
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			case field.Tag == 1 && version >= 3:
+				tr := NewReader(field.Data)
+				m.FinalizedFeaturesEpoch = tr.Int64()
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			case field.Tag == 2 && version >= 3:
+				tr := NewReader(field.Data)
+				if n := tr.ArrayLen(flexible); n >= 0 {
+					m.FinalizedFeatures = make([]ApiVersionsResponseFinalizedFeatureKey, n)
+					for i := range m.FinalizedFeatures {
+						m.FinalizedFeatures[i].Default()
+						m.FinalizedFeatures[i].decode(tr, version)
+					}
+				} else {
+					m.FinalizedFeatures = nil
+				}
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			case field.Tag == 3 && version >= 3:
+				tr := NewReader(field.Data)
+				m.ZkMigrationReady = tr.Bool()
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			}
+		}
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ApiVersionsResponseApiVersion) Default() {
+	*m = ApiVersionsResponseApiVersion{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ApiVersionsResponseApiVersion) isDefault() bool {
+	return m.ApiKey == 0 &&
+		m.MinVersion == 0 &&
+		m.MaxVersion == 0
+}
+
+func (m *ApiVersionsResponseApiVersion) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.Int16(m.ApiKey)
+	w.Int16(m.MinVersion)
+	w.Int16(m.MaxVersion)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ApiVersionsResponseApiVersion) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.ApiKey = r.Int16()
+	m.MinVersion = r.Int16()
+	m.MaxVersion = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ApiVersionsResponseSupportedFeatureKey) Default() {
+	*m = ApiVersionsResponseSupportedFeatureKey{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ApiVersionsResponseSupportedFeatureKey) isDefault() bool {
+	return m.Name == "" &&
+		m.MinVersion == 0 &&
+		m.MaxVersion == 0
+}
+
+func (m *ApiVersionsResponseSupportedFeatureKey) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.Name, flexible)
+	w.Int16(m.MinVersion)
+	w.Int16(m.MaxVersion)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ApiVersionsResponseSupportedFeatureKey) decode(r *Reader, version int16) {
+	flexible := true
+	m.Name = r.String(flexible)
+	m.MinVersion = r.Int16()
+	m.MaxVersion = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ApiVersionsResponseFinalizedFeatureKey) Default() {
+	*m = ApiVersionsResponseFinalizedFeatureKey{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ApiVersionsResponseFinalizedFeatureKey) isDefault() bool {
+	return m.Name == "" &&
+		m.MaxVersionLevel == 0 &&
+		m.MinVersionLevel == 0
+}
+
+func (m *ApiVersionsResponseFinalizedFeatureKey) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.Name, flexible)
+	w.Int16(m.MaxVersionLevel)
+	w.Int16(m.MinVersionLevel)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ApiVersionsResponseFinalizedFeatureKey) decode(r *Reader, version int16) {
+	flexible := true
+	m.Name = r.String(flexible)
+	m.MaxVersionLevel = r.Int16()
+	m.MinVersionLevel = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,350 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// TaggedField is one entry of a flexible version's TAG_BUFFER
+type TaggedField struct {
+	Tag  uint64
+	Data []byte
+}
+
+// Writer appends Kafka primitive types to a buffer
+type Writer struct {
+	buf []byte
+}
+
+func NewWriter() *Writer {
+	return &Writer{buf: make([]byte, 0, 256)}
+}
+
+// Buf returns everything written so far
+func (w *Writer) Buf() []byte {
+	return w.buf
+}
+
+func (w *Writer) Int8(v int8) {
+	w.buf = append(w.buf, byte(v))
+}
+
+func (w *Writer) Int16(v int16) {
+	w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(v))
+}
+
+func (w *Writer) Uint16(v uint16) {
+	w.buf = binary.BigEndian.AppendUint16(w.buf, v)
+}
+
+func (w *Writer) Int32(v int32) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v))
+}
+
+func (w *Writer) Int64(v int64) {
+	w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(v))
+}
+
+func (w *Writer) Float64(v float64) {
+	w.buf = binary.BigEndian.AppendUint64(w.buf, math.Float64bits(v))
+}
+
+func (w *Writer) Bool(v bool) {
+	if v {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *Writer) UUID(v [16]byte) {
+	w.buf = append(w.buf, v[:]...)
+}
+
+func (w *Writer) Uvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+// String writes a STRING, or a COMPACT_STRING in flexible versions
+func (w *Writer) String(s string, flexible bool) {
+	if flexible {
+		w.Uvarint(uint64(len(s)) + 1)
+	} else {
+		w.Int16(int16(len(s)))
+	}
+	w.buf = append(w.buf, s...)
+}
+
+// NullableString writes a NULLABLE_STRING, or a COMPACT_NULLABLE_STRING in flexible versions
+func (w *Writer) NullableString(s *string, flexible bool) {
+	if s != nil {
+		w.String(*s, flexible)
+	} else if flexible {
+		w.Uvarint(0)
+	} else {
+		w.Int16(-1)
+	}
+}
+
+// Bytes writes BYTES, or COMPACT_BYTES in flexible versions
+func (w *Writer) Bytes(b []byte, flexible bool) {
+	if flexible {
+		w.Uvarint(uint64(len(b)) + 1)
+	} else {
+		w.Int32(int32(len(b)))
+	}
+	w.buf = append(w.buf, b...)
+}
+
+// NullableBytes writes NULLABLE_BYTES (also used for RECORDS), encoding nil as null
+func (w *Writer) NullableBytes(b []byte, flexible bool) {
+	if b != nil {
+		w.Bytes(b, flexible)
+	} else if flexible {
+		w.Uvarint(0)
+	} else {
+		w.Int32(-1)
+	}
+}
+
+// ArrayLen writes the length prefix of an ARRAY, or a COMPACT_ARRAY in flexible versions
+func (w *Writer) ArrayLen(n int, flexible bool) {
+	if flexible {
+		w.Uvarint(uint64(n) + 1)
+	} else {
+		w.Int32(int32(n))
+	}
+}
+
+// NullArray writes a null array
+func (w *Writer) NullArray(flexible bool) {
+	if flexible {
+		w.Uvarint(0)
+	} else {
+		w.Int32(-1)
+	}
+}
+
+// TaggedFields writes a TAG_BUFFER; fields must be sorted by tag
+func (w *Writer) TaggedFields(fields []TaggedField) {
+	w.Uvarint(uint64(len(fields)))
+	for _, field := range fields {
+		w.Uvarint(field.Tag)
+		w.Uvarint(uint64(len(field.Data)))
+		w.buf = append(w.buf, field.Data...)
+	}
+}
+
+// Reader decodes Kafka primitive types. Every read is bounds-checked: the
+// first failure is remembered in Err and all later reads return zero values,
+// so a decoder can read a whole message and check the error once.
+type Reader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+// Err returns the first decoding error, if any
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Fail records err unless an earlier error is already recorded
+func (r *Reader) Fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// Remaining returns the number of unread bytes
+func (r *Reader) Remaining() int {
+	return len(r.data) - r.off
+}
+
+// Offset returns the number of bytes consumed so far
+func (r *Reader) Offset() int {
+	return r.off
+}
+
+// next consumes n bytes, or fails if fewer remain
+func (r *Reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > r.Remaining() {
+		r.Fail(fmt.Errorf("protocol: need %d bytes at offset %d, have %d", n, r.off, r.Remaining()))
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *Reader) Int8() int8 {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (r *Reader) Int16() int16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *Reader) Uint16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *Reader) Int32() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *Reader) Int64() int64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (r *Reader) Float64() float64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b))
+}
+
+func (r *Reader) Bool() bool {
+	return r.Int8() != 0
+}
+
+func (r *Reader) UUID() [16]byte {
+	var v [16]byte
+	copy(v[:], r.next(16))
+	return v
+}
+
+func (r *Reader) Uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data[r.off:])
+	if n <= 0 {
+		r.Fail(fmt.Errorf("protocol: invalid varint at offset %d", r.off))
+		return 0
+	}
+	r.off += n
+	return v
+}
+
+// length reads a length prefix: an INT16/INT32 or an unsigned varint holding N+1.
+// -1 means null.
+func (r *Reader) length(flexible bool, wide bool) int {
+	if flexible {
+		v := r.Uvarint()
+		if v > uint64(r.Remaining())+1 {
+			r.Fail(fmt.Errorf("protocol: length %d exceeds remaining %d bytes", v-1, r.Remaining()))
+			return 0
+		}
+		return int(v) - 1
+	}
+	if wide {
+		return int(r.Int32())
+	}
+	return int(r.Int16())
+}
+
+// String reads a STRING, or a COMPACT_STRING in flexible versions; null reads as ""
+func (r *Reader) String(flexible bool) string {
+	s := r.NullableString(flexible)
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// NullableString reads a NULLABLE_STRING, or a COMPACT_NULLABLE_STRING in flexible versions
+func (r *Reader) NullableString(flexible bool) *string {
+	n := r.length(flexible, false)
+	if n < 0 || r.err != nil {
+		return nil
+	}
+	s := string(r.next(n))
+	return &s
+}
+
+// Bytes reads BYTES, or COMPACT_BYTES in flexible versions; null reads as empty
+func (r *Reader) Bytes(flexible bool) []byte {
+	b := r.NullableBytes(flexible)
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
+
+// NullableBytes reads NULLABLE_BYTES (also used for RECORDS), returning nil for null
+func (r *Reader) NullableBytes(flexible bool) []byte {
+	n := r.length(flexible, true)
+	if n < 0 || r.err != nil {
+		return nil
+	}
+	b := r.next(n)
+	if b == nil {
+		return nil
+	}
+	// Copy so the message does not pin the whole request buffer
+	return append(make([]byte, 0, n), b...)
+}
+
+// ArrayLen reads the length prefix of an ARRAY or COMPACT_ARRAY, returning -1 for null.
+// Every element takes at least one byte, so longer lengths are rejected.
+func (r *Reader) ArrayLen(flexible bool) int {
+	n := r.length(flexible, true)
+	if r.err != nil {
+		return -1
+	}
+	if n > r.Remaining() {
+		r.Fail(fmt.Errorf("protocol: array length %d exceeds remaining %d bytes", n, r.Remaining()))
+		return -1
+	}
+	return n
+}
+
+// TaggedFields reads a TAG_BUFFER
+func (r *Reader) TaggedFields() []TaggedField {
+	count := r.Uvarint()
+	if count > uint64(r.Remaining()) {
+		r.Fail(fmt.Errorf("protocol: tagged field count %d exceeds remaining %d bytes", count, r.Remaining()))
+		return nil
+	}
+	var fields []TaggedField
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		tag := r.Uvarint()
+		size := r.Uvarint()
+		if size > uint64(r.Remaining()) {
+			r.Fail(fmt.Errorf("protocol: tagged field size %d exceeds remaining %d bytes", size, r.Remaining()))
+			return nil
+		}
+		fields = append(fields, TaggedField{Tag: tag, Data: r.next(int(size))})
+	}
+	return fields
+}
@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+// message is implemented by every generated top-level message
+type message interface {
+	MinVersion() int16
+	MaxVersion() int16
+	Default()
+	Encode(version int16) []byte
+	Decode(data []byte, version int16) error
+}
+
+// fuzzDecode checks that Decode of any input returns instead of panicking, and
+// that whatever it accepts encodes to bytes it decodes back to the same encoding.
+// The corpus is seeded with a default message at every version.
+func fuzzDecode[T any, M interface {
+	*T
+	message
+}](f *testing.F) {
+	var proto M = new(T)
+	for version := proto.MinVersion(); version <= proto.MaxVersion(); version++ {
+		var m M = new(T)
+		m.Default()
+		f.Add(version, m.Encode(version))
+	}
+
+	f.Fuzz(func(t *testing.T, version int16, data []byte) {
+		if version < proto.MinVersion() || version > proto.MaxVersion() {
+			t.Skip()
+		}
+		var m M = new(T)
+		if err := m.Decode(data, version); err != nil {
+			return
+		}
+		encoded := m.Encode(version)
+
+		var again M = new(T)
+		if err := again.Decode(encoded, version); err != nil {
+			t.Fatalf("v%d: re-decoding %x: %v", version, encoded, err)
+		}
+		if reencoded := again.Encode(version); !bytes.Equal(encoded, reencoded) {
+			t.Fatalf("v%d: encoding changed after a round trip:\n%x\n%x", version, encoded, reencoded)
+		}
+	})
+}
+
+func FuzzProduceRequestDecode(f *testing.F)             { fuzzDecode[ProduceRequest](f) }
+func FuzzProduceResponseDecode(f *testing.F)            { fuzzDecode[ProduceResponse](f) }
+func FuzzFetchRequestDecode(f *testing.F)               { fuzzDecode[FetchRequest](f) }
+func FuzzFetchResponseDecode(f *testing.F)              { fuzzDecode[FetchResponse](f) }
+func FuzzListOffsetsRequestDecode(f *testing.F)         { fuzzDecode[ListOffsetsRequest](f) }
+func FuzzListOffsetsResponseDecode(f *testing.F)        { fuzzDecode[ListOffsetsResponse](f) }
+func FuzzMetadataRequestDecode(f *testing.F)            { fuzzDecode[MetadataRequest](f) }
+func FuzzMetadataResponseDecode(f *testing.F)           { fuzzDecode[MetadataResponse](f) }
+func FuzzOffsetCommitRequestDecode(f *testing.F)        { fuzzDecode[OffsetCommitRequest](f) }
+func FuzzOffsetCommitResponseDecode(f *testing.F)       { fuzzDecode[OffsetCommitResponse](f) }
+func FuzzOffsetFetchRequestDecode(f *testing.F)         { fuzzDecode[OffsetFetchRequest](f) }
+func FuzzOffsetFetchResponseDecode(f *testing.F)        { fuzzDecode[OffsetFetchResponse](f) }
+func FuzzFindCoordinatorRequestDecode(f *testing.F)     { fuzzDecode[FindCoordinatorRequest](f) }
+func FuzzFindCoordinatorResponseDecode(f *testing.F)    { fuzzDecode[FindCoordinatorResponse](f) }
+func FuzzJoinGroupRequestDecode(f *testing.F)           { fuzzDecode[JoinGroupRequest](f) }
+func FuzzJoinGroupResponseDecode(f *testing.F)          { fuzzDecode[JoinGroupResponse](f) }
+func FuzzHeartbeatRequestDecode(f *testing.F)           { fuzzDecode[HeartbeatRequest](f) }
+func FuzzHeartbeatResponseDecode(f *testing.F)          { fuzzDecode[HeartbeatResponse](f) }
+func FuzzLeaveGroupRequestDecode(f *testing.F)          { fuzzDecode[LeaveGroupRequest](f) }
+func FuzzLeaveGroupResponseDecode(f *testing.F)         { fuzzDecode[LeaveGroupResponse](f) }
+func FuzzSyncGroupRequestDecode(f *testing.F)           { fuzzDecode[SyncGroupRequest](f) }
+func FuzzSyncGroupResponseDecode(f *testing.F)          { fuzzDecode[SyncGroupResponse](f) }
+func FuzzApiVersionsRequestDecode(f *testing.F)         { fuzzDecode[ApiVersionsRequest](f) }
+func FuzzApiVersionsResponseDecode(f *testing.F)        { fuzzDecode[ApiVersionsResponse](f) }
+func FuzzCreateTopicsRequestDecode(f *testing.F)        { fuzzDecode[CreateTopicsRequest](f) }
+func FuzzCreateTopicsResponseDecode(f *testing.F)       { fuzzDecode[CreateTopicsResponse](f) }
+func FuzzDeleteTopicsRequestDecode(f *testing.F)        { fuzzDecode[DeleteTopicsRequest](f) }
+func FuzzDeleteTopicsResponseDecode(f *testing.F)       { fuzzDecode[DeleteTopicsResponse](f) }
+func FuzzInitProducerIdRequestDecode(f *testing.F)      { fuzzDecode[InitProducerIdRequest](f) }
+func FuzzInitProducerIdResponseDecode(f *testing.F)     { fuzzDecode[InitProducerIdResponse](f) }
+func FuzzAddPartitionsToTxnRequestDecode(f *testing.F)  { fuzzDecode[AddPartitionsToTxnRequest](f) }
+func FuzzAddPartitionsToTxnResponseDecode(f *testing.F) { fuzzDecode[AddPartitionsToTxnResponse](f) }
+func FuzzAddOffsetsToTxnRequestDecode(f *testing.F)     { fuzzDecode[AddOffsetsToTxnRequest](f) }
+func FuzzAddOffsetsToTxnResponseDecode(f *testing.F)    { fuzzDecode[AddOffsetsToTxnResponse](f) }
+func FuzzEndTxnRequestDecode(f *testing.F)              { fuzzDecode[EndTxnRequest](f) }
+func FuzzEndTxnResponseDecode(f *testing.F)             { fuzzDecode[EndTxnResponse](f) }
+func FuzzTxnOffsetCommitRequestDecode(f *testing.F)     { fuzzDecode[TxnOffsetCommitRequest](f) }
+func FuzzTxnOffsetCommitResponseDecode(f *testing.F)    { fuzzDecode[TxnOffsetCommitResponse](f) }
+func FuzzDescribeLogDirsRequestDecode(f *testing.F)     { fuzzDecode[DescribeLogDirsRequest](f) }
+func FuzzDescribeLogDirsResponseDecode(f *testing.F)    { fuzzDecode[DescribeLogDirsResponse](f) }
+func FuzzDescribeTopicPartitionsRequestDecode(f *testing.F) {
+	fuzzDecode[DescribeTopicPartitionsRequest](f)
+}
+func FuzzDescribeTopicPartitionsResponseDecode(f *testing.F) {
+	fuzzDecode[DescribeTopicPartitionsResponse](f)
+}
@@ -0,0 +1,242 @@
+// Code generated by gen from schemas/CreateTopicsRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// CreateTopicsRequest is a request of API key 19, versions 0-7
+type CreateTopicsRequest struct {
+	// The topics to create.
+	Topics []CreateTopicsRequestCreatableTopic
+	// How long to wait in milliseconds before timing out the request.
+	TimeoutMs int32
+	// If true, check that the topics can be created as specified, but don't create anything.
+	ValidateOnly bool
+}
+
+type CreateTopicsRequestCreatableTopic struct {
+	// The topic name.
+	Name string
+	// The number of partitions to create in the topic, or -1 if we are either specifying a manual partition assignment or using the default partitions.
+	NumPartitions int32
+	// The number of replicas to create for each partition in the topic, or -1 if we are either specifying a manual partition assignment or using the default replication factor.
+	ReplicationFactor int16
+	// The manual partition assignment, or the empty array if we are using automatic assignment.
+	Assignments []CreateTopicsRequestCreatableReplicaAssignment
+	// The custom topic configurations to set.
+	Configs []CreateTopicsRequestCreatableTopicConfig
+}
+
+type CreateTopicsRequestCreatableReplicaAssignment struct {
+	// The partition index.
+	PartitionIndex int32
+	// The brokers to place the partition on.
+	BrokerIds []int32
+}
+
+type CreateTopicsRequestCreatableTopicConfig struct {
+	// The configuration name.
+	Name string
+	// The configuration value.
+	Value *string
+}
+
+func (m *CreateTopicsRequest) ApiKey() int16 { return 19 }
+
+func (m *CreateTopicsRequest) MinVersion() int16 { return 0 }
+
+func (m *CreateTopicsRequest) MaxVersion() int16 { return 7 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *CreateTopicsRequest) IsFlexible(version int16) bool { return version >= 5 }
+
+// Encode serializes the message at the given version
+func (m *CreateTopicsRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *CreateTopicsRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported CreateTopicsRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *CreateTopicsRequest) Default() {
+	*m = CreateTopicsRequest{}
+	m.TimeoutMs = 60000
+}
+
+func (m *CreateTopicsRequest) encode(w *Writer, version int16) {
+	flexible := version >= 5
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	w.Int32(m.TimeoutMs)
+	if version >= 1 {
+		w.Bool(m.ValidateOnly)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *CreateTopicsRequest) decode(r *Reader, version int16) {
+	flexible := version >= 5
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]CreateTopicsRequestCreatableTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	m.TimeoutMs = r.Int32()
+	if version >= 1 {
+		m.ValidateOnly = r.Bool()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *CreateTopicsRequestCreatableTopic) Default() {
+	*m = CreateTopicsRequestCreatableTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *CreateTopicsRequestCreatableTopic) isDefault() bool {
+	return m.Name == "" &&
+		m.NumPartitions == 0 &&
+		m.ReplicationFactor == 0 &&
+		len(m.Assignments) == 0 &&
+		len(m.Configs) == 0
+}
+
+func (m *CreateTopicsRequestCreatableTopic) encode(w *Writer, version int16) {
+	flexible := version >= 5
+	w.String(m.Name, flexible)
+	w.Int32(m.NumPartitions)
+	w.Int16(m.ReplicationFactor)
+	w.ArrayLen(len(m.Assignments), flexible)
+	for i := range m.Assignments {
+		m.Assignments[i].encode(w, version)
+	}
+	w.ArrayLen(len(m.Configs), flexible)
+	for i := range m.Configs {
+		m.Configs[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *CreateTopicsRequestCreatableTopic) decode(r *Reader, version int16) {
+	flexible := version >= 5
+	m.Name = r.String(flexible)
+	m.NumPartitions = r.Int32()
+	m.ReplicationFactor = r.Int16()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Assignments = make([]CreateTopicsRequestCreatableReplicaAssignment, n)
+		for i := range m.Assignments {
+			m.Assignments[i].Default()
+			m.Assignments[i].decode(r, version)
+		}
+	} else {
+		m.Assignments = nil
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Configs = make([]CreateTopicsRequestCreatableTopicConfig, n)
+		for i := range m.Configs {
+			m.Configs[i].Default()
+			m.Configs[i].decode(r, version)
+		}
+	} else {
+		m.Configs = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *CreateTopicsRequestCreatableReplicaAssignment) Default() {
+	*m = CreateTopicsRequestCreatableReplicaAssignment{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *CreateTopicsRequestCreatableReplicaAssignment) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		len(m.BrokerIds) == 0
+}
+
+func (m *CreateTopicsRequestCreatableReplicaAssignment) encode(w *Writer, version int16) {
+	flexible := version >= 5
+	w.Int32(m.PartitionIndex)
+	w.ArrayLen(len(m.BrokerIds), flexible)
+	for i := range m.BrokerIds {
+		w.Int32(m.BrokerIds[i])
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *CreateTopicsRequestCreatableReplicaAssignment) decode(r *Reader, version int16) {
+	flexible := version >= 5
+	m.PartitionIndex = r.Int32()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.BrokerIds = make([]int32, n)
+		for i := range m.BrokerIds {
+			m.BrokerIds[i] = r.Int32()
+		}
+	} else {
+		m.BrokerIds = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *CreateTopicsRequestCreatableTopicConfig) Default() {
+	*m = CreateTopicsRequestCreatableTopicConfig{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *CreateTopicsRequestCreatableTopicConfig) isDefault() bool {
+	return m.Name == "" &&
+		m.Value == nil
+}
+
+func (m *CreateTopicsRequestCreatableTopicConfig) encode(w *Writer, version int16) {
+	flexible := version >= 5
+	w.String(m.Name, flexible)
+	w.NullableString(m.Value, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *CreateTopicsRequestCreatableTopicConfig) decode(r *Reader, version int16) {
+	flexible := version >= 5
+	m.Name = r.String(flexible)
+	m.Value = r.NullableString(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
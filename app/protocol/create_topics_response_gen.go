@@ -0,0 +1,249 @@
+// Code generated by gen from schemas/CreateTopicsResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// CreateTopicsResponse is a response of API key 19, versions 0-7
+type CreateTopicsResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// Results for each topic we tried to create.
+	Topics []CreateTopicsResponseCreatableTopicResult
+}
+
+type CreateTopicsResponseCreatableTopicResult struct {
+	// The topic name.
+	Name string
+	// The unique topic ID
+	TopicId [16]byte
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The error message, or null if there was no error.
+	ErrorMessage *string
+	// Optional topic config error returned if configs are not returned in the response.
+	TopicConfigErrorCode int16
+	// Number of partitions of the topic.
+	NumPartitions int32
+	// Replication factor of the topic.
+	ReplicationFactor int16
+	// Configuration of the topic.
+	Configs []CreateTopicsResponseCreatableTopicConfigs
+}
+
+type CreateTopicsResponseCreatableTopicConfigs struct {
+	// The configuration name.
+	Name string
+	// The configuration value.
+	Value *string
+	// True if the configuration is read-only.
+	ReadOnly bool
+	// The configuration source.
+	ConfigSource int8
+	// True if this configuration is sensitive.
+	IsSensitive bool
+}
+
+func (m *CreateTopicsResponse) ApiKey() int16 { return 19 }
+
+func (m *CreateTopicsResponse) MinVersion() int16 { return 0 }
+
+func (m *CreateTopicsResponse) MaxVersion() int16 { return 7 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *CreateTopicsResponse) IsFlexible(version int16) bool { return version >= 5 }
+
+// Encode serializes the message at the given version
+func (m *CreateTopicsResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *CreateTopicsResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported CreateTopicsResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *CreateTopicsResponse) Default() {
+	*m = CreateTopicsResponse{}
+}
+
+func (m *CreateTopicsResponse) encode(w *Writer, version int16) {
+	flexible := version >= 5
+	if version >= 2 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *CreateTopicsResponse) decode(r *Reader, version int16) {
+	flexible := version >= 5
+	if version >= 2 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]CreateTopicsResponseCreatableTopicResult, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *CreateTopicsResponseCreatableTopicResult) Default() {
+	*m = CreateTopicsResponseCreatableTopicResult{}
+	m.NumPartitions = -1
+	m.ReplicationFactor = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *CreateTopicsResponseCreatableTopicResult) isDefault() bool {
+	return m.Name == "" &&
+		m.TopicId == [16]byte{} &&
+		m.ErrorCode == 0 &&
+		m.ErrorMessage == nil &&
+		m.TopicConfigErrorCode == 0 &&
+		m.NumPartitions == -1 &&
+		m.ReplicationFactor == -1 &&
+		m.Configs == nil
+}
+
+func (m *CreateTopicsResponseCreatableTopicResult) encode(w *Writer, version int16) {
+	flexible := version >= 5
+	w.String(m.Name, flexible)
+	if version >= 7 {
+		w.UUID(m.TopicId)
+	}
+	w.Int16(m.ErrorCode)
+	if version >= 1 {
+		w.NullableString(m.ErrorMessage, flexible)
+	}
+	if version >= 5 {
+		w.Int32(m.NumPartitions)
+	}
+	if version >= 5 {
+		w.Int16(m.ReplicationFactor)
+	}
+	if version >= 5 {
+		if m.Configs == nil {
+			w.NullArray(flexible)
+		} else {
+			w.ArrayLen(len(m.Configs), flexible)
+			for i := range m.Configs {
+				m.Configs[i].encode(w, version)
+			}
+		}
+	}
+	if flexible {
+		var tagged []TaggedField
+		if version >= 5 && m.TopicConfigErrorCode != 0 {
+			tw := NewWriter()
+			tw.Int16(m.TopicConfigErrorCode)
+			tagged = append(tagged, TaggedField{Tag: 0, Data: tw.Buf()})
+		}
+		w.TaggedFields(tagged)
+	}
+}
+
+func (m *CreateTopicsResponseCreatableTopicResult) decode(r *Reader, version int16) {
+	flexible := version >= 5
+	m.Name = r.String(flexible)
+	if version >= 7 {
+		m.TopicId = r.UUID()
+	}
+	m.ErrorCode = r.Int16()
+	if version >= 1 {
+		m.ErrorMessage = r.NullableString(flexible)
+	}
+	if version >= 5 {
+		m.NumPartitions = r.Int32()
+	}
+	if version >= 5 {
+		m.ReplicationFactor = r.Int16()
+	}
+	if version >= 5 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Configs = make([]CreateTopicsResponseCreatableTopicConfigs, n)
+			for i := range m.Configs {
+				m.Configs[i].Default()
+				m.Configs[i].decode(r, version)
+			}
+		} else {
+			m.Configs = nil
+		}
+	}
+	if flexible {
+		for _, field := range r.TaggedFields() {
+			switch {
+			case field.Tag == 0 && version >= 5:
+				tr := NewReader(field.Data)
+				m.TopicConfigErrorCode = tr.Int16()
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			}
+		}
+	}
+}
+
+// Default sets every field to its schema default
+func (m *CreateTopicsResponseCreatableTopicConfigs) Default() {
+	*m = CreateTopicsResponseCreatableTopicConfigs{}
+	m.ConfigSource = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *CreateTopicsResponseCreatableTopicConfigs) isDefault() bool {
+	return m.Name == "" &&
+		m.Value == nil &&
+		!m.ReadOnly &&
+		m.ConfigSource == -1 &&
+		!m.IsSensitive
+}
+
+func (m *CreateTopicsResponseCreatableTopicConfigs) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.Name, flexible)
+	w.NullableString(m.Value, flexible)
+	w.Bool(m.ReadOnly)
+	w.Int8(m.ConfigSource)
+	w.Bool(m.IsSensitive)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *CreateTopicsResponseCreatableTopicConfigs) decode(r *Reader, version int16) {
+	flexible := true
+	m.Name = r.String(flexible)
+	m.Value = r.NullableString(flexible)
+	m.ReadOnly = r.Bool()
+	m.ConfigSource = r.Int8()
+	m.IsSensitive = r.Bool()
+	if flexible {
+		r.TaggedFields()
+	}
+}
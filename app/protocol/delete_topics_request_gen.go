@@ -0,0 +1,134 @@
+// Code generated by gen from schemas/DeleteTopicsRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// DeleteTopicsRequest is a request of API key 20, versions 0-6
+type DeleteTopicsRequest struct {
+	// The name or topic ID of the topic
+	Topics []DeleteTopicsRequestDeleteTopicState
+	// The names of the topics to delete
+	TopicNames []string
+	// The length of time in milliseconds to wait for the deletions to complete.
+	TimeoutMs int32
+}
+
+type DeleteTopicsRequestDeleteTopicState struct {
+	// The topic name
+	Name *string
+	// The unique topic ID
+	TopicId [16]byte
+}
+
+func (m *DeleteTopicsRequest) ApiKey() int16 { return 20 }
+
+func (m *DeleteTopicsRequest) MinVersion() int16 { return 0 }
+
+func (m *DeleteTopicsRequest) MaxVersion() int16 { return 6 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *DeleteTopicsRequest) IsFlexible(version int16) bool { return version >= 4 }
+
+// Encode serializes the message at the given version
+func (m *DeleteTopicsRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *DeleteTopicsRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported DeleteTopicsRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *DeleteTopicsRequest) Default() {
+	*m = DeleteTopicsRequest{}
+}
+
+func (m *DeleteTopicsRequest) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	if version >= 6 {
+		w.ArrayLen(len(m.Topics), flexible)
+		for i := range m.Topics {
+			m.Topics[i].encode(w, version)
+		}
+	}
+	if version <= 5 {
+		w.ArrayLen(len(m.TopicNames), flexible)
+		for i := range m.TopicNames {
+			w.String(m.TopicNames[i], flexible)
+		}
+	}
+	w.Int32(m.TimeoutMs)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DeleteTopicsRequest) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	if version >= 6 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Topics = make([]DeleteTopicsRequestDeleteTopicState, n)
+			for i := range m.Topics {
+				m.Topics[i].Default()
+				m.Topics[i].decode(r, version)
+			}
+		} else {
+			m.Topics = nil
+		}
+	}
+	if version <= 5 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.TopicNames = make([]string, n)
+			for i := range m.TopicNames {
+				m.TopicNames[i] = r.String(flexible)
+			}
+		} else {
+			m.TopicNames = nil
+		}
+	}
+	m.TimeoutMs = r.Int32()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DeleteTopicsRequestDeleteTopicState) Default() {
+	*m = DeleteTopicsRequestDeleteTopicState{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DeleteTopicsRequestDeleteTopicState) isDefault() bool {
+	return m.Name == nil &&
+		m.TopicId == [16]byte{}
+}
+
+func (m *DeleteTopicsRequestDeleteTopicState) encode(w *Writer, version int16) {
+	flexible := true
+	w.NullableString(m.Name, flexible)
+	w.UUID(m.TopicId)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DeleteTopicsRequestDeleteTopicState) decode(r *Reader, version int16) {
+	flexible := true
+	m.Name = r.NullableString(flexible)
+	m.TopicId = r.UUID()
+	if flexible {
+		r.TaggedFields()
+	}
+}
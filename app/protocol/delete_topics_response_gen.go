@@ -0,0 +1,149 @@
+// Code generated by gen from schemas/DeleteTopicsResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// DeleteTopicsResponse is a response of API key 20, versions 0-6
+type DeleteTopicsResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The results for each topic we tried to delete.
+	Responses []DeleteTopicsResponseDeletableTopicResult
+}
+
+type DeleteTopicsResponseDeletableTopicResult struct {
+	// The topic name
+	Name *string
+	// the unique topic ID
+	TopicId [16]byte
+	// The deletion error, or 0 if the deletion succeeded.
+	ErrorCode int16
+	// The error message, or null if there was no error.
+	ErrorMessage *string
+}
+
+func (m *DeleteTopicsResponse) ApiKey() int16 { return 20 }
+
+func (m *DeleteTopicsResponse) MinVersion() int16 { return 0 }
+
+func (m *DeleteTopicsResponse) MaxVersion() int16 { return 6 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *DeleteTopicsResponse) IsFlexible(version int16) bool { return version >= 4 }
+
+// Encode serializes the message at the given version
+func (m *DeleteTopicsResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *DeleteTopicsResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported DeleteTopicsResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *DeleteTopicsResponse) Default() {
+	*m = DeleteTopicsResponse{}
+}
+
+func (m *DeleteTopicsResponse) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	if version >= 1 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.ArrayLen(len(m.Responses), flexible)
+	for i := range m.Responses {
+		m.Responses[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DeleteTopicsResponse) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	if version >= 1 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Responses = make([]DeleteTopicsResponseDeletableTopicResult, n)
+		for i := range m.Responses {
+			m.Responses[i].Default()
+			m.Responses[i].decode(r, version)
+		}
+	} else {
+		m.Responses = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DeleteTopicsResponseDeletableTopicResult) Default() {
+	*m = DeleteTopicsResponseDeletableTopicResult{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DeleteTopicsResponseDeletableTopicResult) isDefault() bool {
+	return m.Name == nil &&
+		m.TopicId == [16]byte{} &&
+		m.ErrorCode == 0 &&
+		m.ErrorMessage == nil
+}
+
+func (m *DeleteTopicsResponseDeletableTopicResult) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	if version >= 6 {
+		w.NullableString(m.Name, flexible)
+	} else {
+		if m.Name == nil {
+			w.String("", flexible)
+		} else {
+			w.String(*m.Name, flexible)
+		}
+	}
+	if version >= 6 {
+		w.UUID(m.TopicId)
+	}
+	w.Int16(m.ErrorCode)
+	if version >= 5 {
+		w.NullableString(m.ErrorMessage, flexible)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DeleteTopicsResponseDeletableTopicResult) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	if version >= 6 {
+		m.Name = r.NullableString(flexible)
+	} else {
+		{
+			s := r.String(flexible)
+			m.Name = &s
+		}
+	}
+	if version >= 6 {
+		m.TopicId = r.UUID()
+	}
+	m.ErrorCode = r.Int16()
+	if version >= 5 {
+		m.ErrorMessage = r.NullableString(flexible)
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
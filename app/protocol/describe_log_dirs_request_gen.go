@@ -0,0 +1,122 @@
+// Code generated by gen from schemas/DescribeLogDirsRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// DescribeLogDirsRequest is a request of API key 35, versions 0-4
+type DescribeLogDirsRequest struct {
+	// Each topic that we want to describe log directories for, or null for all topics.
+	Topics []DescribeLogDirsRequestDescribableLogDirTopic
+}
+
+type DescribeLogDirsRequestDescribableLogDirTopic struct {
+	// The topic name
+	Topic string
+	// The partition indexes.
+	Partitions []int32
+}
+
+func (m *DescribeLogDirsRequest) ApiKey() int16 { return 35 }
+
+func (m *DescribeLogDirsRequest) MinVersion() int16 { return 0 }
+
+func (m *DescribeLogDirsRequest) MaxVersion() int16 { return 4 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *DescribeLogDirsRequest) IsFlexible(version int16) bool { return version >= 2 }
+
+// Encode serializes the message at the given version
+func (m *DescribeLogDirsRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *DescribeLogDirsRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported DescribeLogDirsRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *DescribeLogDirsRequest) Default() {
+	*m = DescribeLogDirsRequest{}
+}
+
+func (m *DescribeLogDirsRequest) encode(w *Writer, version int16) {
+	flexible := version >= 2
+	if m.Topics == nil {
+		w.NullArray(flexible)
+	} else {
+		w.ArrayLen(len(m.Topics), flexible)
+		for i := range m.Topics {
+			m.Topics[i].encode(w, version)
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeLogDirsRequest) decode(r *Reader, version int16) {
+	flexible := version >= 2
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]DescribeLogDirsRequestDescribableLogDirTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeLogDirsRequestDescribableLogDirTopic) Default() {
+	*m = DescribeLogDirsRequestDescribableLogDirTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeLogDirsRequestDescribableLogDirTopic) isDefault() bool {
+	return m.Topic == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *DescribeLogDirsRequestDescribableLogDirTopic) encode(w *Writer, version int16) {
+	flexible := version >= 2
+	w.String(m.Topic, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		w.Int32(m.Partitions[i])
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeLogDirsRequestDescribableLogDirTopic) decode(r *Reader, version int16) {
+	flexible := version >= 2
+	m.Topic = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]int32, n)
+		for i := range m.Partitions {
+			m.Partitions[i] = r.Int32()
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,251 @@
+// Code generated by gen from schemas/DescribeLogDirsResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// DescribeLogDirsResponse is a response of API key 35, versions 0-4
+type DescribeLogDirsResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The log directories.
+	Results []DescribeLogDirsResponseDescribeLogDirsResult
+}
+
+type DescribeLogDirsResponseDescribeLogDirsResult struct {
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The absolute log directory path.
+	LogDir string
+	// Each topic.
+	Topics []DescribeLogDirsResponseDescribeLogDirsTopic
+	// The total size in bytes of the volume the log directory is in.
+	TotalBytes int64
+	// The usable size in bytes of the volume the log directory is in.
+	UsableBytes int64
+}
+
+type DescribeLogDirsResponseDescribeLogDirsTopic struct {
+	// The topic name.
+	Name string
+	// Each partition.
+	Partitions []DescribeLogDirsResponseDescribeLogDirsPartition
+}
+
+type DescribeLogDirsResponseDescribeLogDirsPartition struct {
+	// The partition index.
+	PartitionIndex int32
+	// The size of the log segments in this partition in bytes.
+	PartitionSize int64
+	// The lag of the log's LEO w.r.t. partition's HW (if it is the current log for the partition) or current replica's LEO (if it is the future log for the partition)
+	OffsetLag int64
+	// True if this log is created by AlterReplicaLogDirsRequest and will replace the current log of the replica in the future.
+	IsFutureKey bool
+}
+
+func (m *DescribeLogDirsResponse) ApiKey() int16 { return 35 }
+
+func (m *DescribeLogDirsResponse) MinVersion() int16 { return 0 }
+
+func (m *DescribeLogDirsResponse) MaxVersion() int16 { return 4 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *DescribeLogDirsResponse) IsFlexible(version int16) bool { return version >= 2 }
+
+// Encode serializes the message at the given version
+func (m *DescribeLogDirsResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *DescribeLogDirsResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported DescribeLogDirsResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *DescribeLogDirsResponse) Default() {
+	*m = DescribeLogDirsResponse{}
+}
+
+func (m *DescribeLogDirsResponse) encode(w *Writer, version int16) {
+	flexible := version >= 2
+	w.Int32(m.ThrottleTimeMs)
+	if version >= 3 {
+		w.Int16(m.ErrorCode)
+	}
+	w.ArrayLen(len(m.Results), flexible)
+	for i := range m.Results {
+		m.Results[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeLogDirsResponse) decode(r *Reader, version int16) {
+	flexible := version >= 2
+	m.ThrottleTimeMs = r.Int32()
+	if version >= 3 {
+		m.ErrorCode = r.Int16()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Results = make([]DescribeLogDirsResponseDescribeLogDirsResult, n)
+		for i := range m.Results {
+			m.Results[i].Default()
+			m.Results[i].decode(r, version)
+		}
+	} else {
+		m.Results = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeLogDirsResponseDescribeLogDirsResult) Default() {
+	*m = DescribeLogDirsResponseDescribeLogDirsResult{}
+	m.TotalBytes = -1
+	m.UsableBytes = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeLogDirsResponseDescribeLogDirsResult) isDefault() bool {
+	return m.ErrorCode == 0 &&
+		m.LogDir == "" &&
+		len(m.Topics) == 0 &&
+		m.TotalBytes == -1 &&
+		m.UsableBytes == -1
+}
+
+func (m *DescribeLogDirsResponseDescribeLogDirsResult) encode(w *Writer, version int16) {
+	flexible := version >= 2
+	w.Int16(m.ErrorCode)
+	w.String(m.LogDir, flexible)
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if version >= 4 {
+		w.Int64(m.TotalBytes)
+	}
+	if version >= 4 {
+		w.Int64(m.UsableBytes)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeLogDirsResponseDescribeLogDirsResult) decode(r *Reader, version int16) {
+	flexible := version >= 2
+	m.ErrorCode = r.Int16()
+	m.LogDir = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]DescribeLogDirsResponseDescribeLogDirsTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if version >= 4 {
+		m.TotalBytes = r.Int64()
+	}
+	if version >= 4 {
+		m.UsableBytes = r.Int64()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeLogDirsResponseDescribeLogDirsTopic) Default() {
+	*m = DescribeLogDirsResponseDescribeLogDirsTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeLogDirsResponseDescribeLogDirsTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *DescribeLogDirsResponseDescribeLogDirsTopic) encode(w *Writer, version int16) {
+	flexible := version >= 2
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeLogDirsResponseDescribeLogDirsTopic) decode(r *Reader, version int16) {
+	flexible := version >= 2
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]DescribeLogDirsResponseDescribeLogDirsPartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeLogDirsResponseDescribeLogDirsPartition) Default() {
+	*m = DescribeLogDirsResponseDescribeLogDirsPartition{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeLogDirsResponseDescribeLogDirsPartition) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.PartitionSize == 0 &&
+		m.OffsetLag == 0 &&
+		!m.IsFutureKey
+}
+
+func (m *DescribeLogDirsResponseDescribeLogDirsPartition) encode(w *Writer, version int16) {
+	flexible := version >= 2
+	w.Int32(m.PartitionIndex)
+	w.Int64(m.PartitionSize)
+	w.Int64(m.OffsetLag)
+	w.Bool(m.IsFutureKey)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeLogDirsResponseDescribeLogDirsPartition) decode(r *Reader, version int16) {
+	flexible := version >= 2
+	m.PartitionIndex = r.Int32()
+	m.PartitionSize = r.Int64()
+	m.OffsetLag = r.Int64()
+	m.IsFutureKey = r.Bool()
+	if flexible {
+		r.TaggedFields()
+	}
+}
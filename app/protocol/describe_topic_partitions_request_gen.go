@@ -0,0 +1,160 @@
+// Code generated by gen from schemas/DescribeTopicPartitionsRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// DescribeTopicPartitionsRequest is a request of API key 75, versions 0
+type DescribeTopicPartitionsRequest struct {
+	// The topics to fetch details for.
+	Topics []DescribeTopicPartitionsRequestTopicRequest
+	// The maximum number of partitions included in the response.
+	ResponsePartitionLimit int32
+	// The first topic and partition index to fetch details for.
+	Cursor *DescribeTopicPartitionsRequestCursor
+}
+
+type DescribeTopicPartitionsRequestTopicRequest struct {
+	// The topic name
+	Name string
+}
+
+type DescribeTopicPartitionsRequestCursor struct {
+	// The name for the first topic to process
+	TopicName string
+	// The partition index to start with
+	PartitionIndex int32
+}
+
+func (m *DescribeTopicPartitionsRequest) ApiKey() int16 { return 75 }
+
+func (m *DescribeTopicPartitionsRequest) MinVersion() int16 { return 0 }
+
+func (m *DescribeTopicPartitionsRequest) MaxVersion() int16 { return 0 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *DescribeTopicPartitionsRequest) IsFlexible(version int16) bool { return version >= 0 }
+
+// Encode serializes the message at the given version
+func (m *DescribeTopicPartitionsRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *DescribeTopicPartitionsRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported DescribeTopicPartitionsRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *DescribeTopicPartitionsRequest) Default() {
+	*m = DescribeTopicPartitionsRequest{}
+	m.ResponsePartitionLimit = 2000
+}
+
+func (m *DescribeTopicPartitionsRequest) encode(w *Writer, version int16) {
+	flexible := true
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	w.Int32(m.ResponsePartitionLimit)
+	if m.Cursor == nil {
+		w.Int8(-1)
+	} else {
+		w.Int8(1)
+		m.Cursor.encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeTopicPartitionsRequest) decode(r *Reader, version int16) {
+	flexible := true
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]DescribeTopicPartitionsRequestTopicRequest, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	m.ResponsePartitionLimit = r.Int32()
+	if r.Int8() < 0 {
+		m.Cursor = nil
+	} else {
+		m.Cursor = new(DescribeTopicPartitionsRequestCursor)
+		m.Cursor.Default()
+		m.Cursor.decode(r, version)
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeTopicPartitionsRequestTopicRequest) Default() {
+	*m = DescribeTopicPartitionsRequestTopicRequest{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeTopicPartitionsRequestTopicRequest) isDefault() bool {
+	return m.Name == ""
+}
+
+func (m *DescribeTopicPartitionsRequestTopicRequest) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.Name, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeTopicPartitionsRequestTopicRequest) decode(r *Reader, version int16) {
+	flexible := true
+	m.Name = r.String(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeTopicPartitionsRequestCursor) Default() {
+	*m = DescribeTopicPartitionsRequestCursor{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeTopicPartitionsRequestCursor) isDefault() bool {
+	return m.TopicName == "" &&
+		m.PartitionIndex == 0
+}
+
+func (m *DescribeTopicPartitionsRequestCursor) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.TopicName, flexible)
+	w.Int32(m.PartitionIndex)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeTopicPartitionsRequestCursor) decode(r *Reader, version int16) {
+	flexible := true
+	m.TopicName = r.String(flexible)
+	m.PartitionIndex = r.Int32()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,327 @@
+// Code generated by gen from schemas/DescribeTopicPartitionsResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// DescribeTopicPartitionsResponse is a response of API key 75, versions 0
+type DescribeTopicPartitionsResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// Each topic in the response.
+	Topics []DescribeTopicPartitionsResponseTopic
+	// The next topic and partition index to fetch details for.
+	NextCursor *DescribeTopicPartitionsResponseCursor
+}
+
+type DescribeTopicPartitionsResponseTopic struct {
+	// The topic error, or 0 if there was no error.
+	ErrorCode int16
+	// The topic name.
+	Name *string
+	// The topic id.
+	TopicId [16]byte
+	// True if the topic is internal.
+	IsInternal bool
+	// Each partition in the topic.
+	Partitions []DescribeTopicPartitionsResponsePartition
+	// 32-bit bitfield to represent authorized operations for this topic.
+	TopicAuthorizedOperations int32
+}
+
+type DescribeTopicPartitionsResponsePartition struct {
+	// The partition error, or 0 if there was no error.
+	ErrorCode int16
+	// The partition index.
+	PartitionIndex int32
+	// The ID of the leader broker.
+	LeaderId int32
+	// The leader epoch of this partition.
+	LeaderEpoch int32
+	// The set of all nodes that host this partition.
+	ReplicaNodes []int32
+	// The set of nodes that are in sync with the leader for this partition.
+	IsrNodes []int32
+	// The new eligible leader replicas otherwise.
+	EligibleLeaderReplicas []int32
+	// The last known ELR.
+	LastKnownElr []int32
+	// The set of offline replicas of this partition.
+	OfflineReplicas []int32
+}
+
+type DescribeTopicPartitionsResponseCursor struct {
+	// The name for the first topic to process
+	TopicName string
+	// The partition index to start with
+	PartitionIndex int32
+}
+
+func (m *DescribeTopicPartitionsResponse) ApiKey() int16 { return 75 }
+
+func (m *DescribeTopicPartitionsResponse) MinVersion() int16 { return 0 }
+
+func (m *DescribeTopicPartitionsResponse) MaxVersion() int16 { return 0 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *DescribeTopicPartitionsResponse) IsFlexible(version int16) bool { return version >= 0 }
+
+// Encode serializes the message at the given version
+func (m *DescribeTopicPartitionsResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *DescribeTopicPartitionsResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported DescribeTopicPartitionsResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *DescribeTopicPartitionsResponse) Default() {
+	*m = DescribeTopicPartitionsResponse{}
+}
+
+func (m *DescribeTopicPartitionsResponse) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int32(m.ThrottleTimeMs)
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if m.NextCursor == nil {
+		w.Int8(-1)
+	} else {
+		w.Int8(1)
+		m.NextCursor.encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeTopicPartitionsResponse) decode(r *Reader, version int16) {
+	flexible := true
+	m.ThrottleTimeMs = r.Int32()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]DescribeTopicPartitionsResponseTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if r.Int8() < 0 {
+		m.NextCursor = nil
+	} else {
+		m.NextCursor = new(DescribeTopicPartitionsResponseCursor)
+		m.NextCursor.Default()
+		m.NextCursor.decode(r, version)
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeTopicPartitionsResponseTopic) Default() {
+	*m = DescribeTopicPartitionsResponseTopic{}
+	m.TopicAuthorizedOperations = -2147483648
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeTopicPartitionsResponseTopic) isDefault() bool {
+	return m.ErrorCode == 0 &&
+		m.Name == nil &&
+		m.TopicId == [16]byte{} &&
+		!m.IsInternal &&
+		len(m.Partitions) == 0 &&
+		m.TopicAuthorizedOperations == -2147483648
+}
+
+func (m *DescribeTopicPartitionsResponseTopic) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int16(m.ErrorCode)
+	w.NullableString(m.Name, flexible)
+	w.UUID(m.TopicId)
+	w.Bool(m.IsInternal)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	w.Int32(m.TopicAuthorizedOperations)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeTopicPartitionsResponseTopic) decode(r *Reader, version int16) {
+	flexible := true
+	m.ErrorCode = r.Int16()
+	m.Name = r.NullableString(flexible)
+	m.TopicId = r.UUID()
+	m.IsInternal = r.Bool()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]DescribeTopicPartitionsResponsePartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	m.TopicAuthorizedOperations = r.Int32()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeTopicPartitionsResponsePartition) Default() {
+	*m = DescribeTopicPartitionsResponsePartition{}
+	m.LeaderEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeTopicPartitionsResponsePartition) isDefault() bool {
+	return m.ErrorCode == 0 &&
+		m.PartitionIndex == 0 &&
+		m.LeaderId == 0 &&
+		m.LeaderEpoch == -1 &&
+		len(m.ReplicaNodes) == 0 &&
+		len(m.IsrNodes) == 0 &&
+		m.EligibleLeaderReplicas == nil &&
+		m.LastKnownElr == nil &&
+		len(m.OfflineReplicas) == 0
+}
+
+func (m *DescribeTopicPartitionsResponsePartition) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int16(m.ErrorCode)
+	w.Int32(m.PartitionIndex)
+	w.Int32(m.LeaderId)
+	w.Int32(m.LeaderEpoch)
+	w.ArrayLen(len(m.ReplicaNodes), flexible)
+	for i := range m.ReplicaNodes {
+		w.Int32(m.ReplicaNodes[i])
+	}
+	w.ArrayLen(len(m.IsrNodes), flexible)
+	for i := range m.IsrNodes {
+		w.Int32(m.IsrNodes[i])
+	}
+	if m.EligibleLeaderReplicas == nil {
+		w.NullArray(flexible)
+	} else {
+		w.ArrayLen(len(m.EligibleLeaderReplicas), flexible)
+		for i := range m.EligibleLeaderReplicas {
+			w.Int32(m.EligibleLeaderReplicas[i])
+		}
+	}
+	if m.LastKnownElr == nil {
+		w.NullArray(flexible)
+	} else {
+		w.ArrayLen(len(m.LastKnownElr), flexible)
+		for i := range m.LastKnownElr {
+			w.Int32(m.LastKnownElr[i])
+		}
+	}
+	w.ArrayLen(len(m.OfflineReplicas), flexible)
+	for i := range m.OfflineReplicas {
+		w.Int32(m.OfflineReplicas[i])
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeTopicPartitionsResponsePartition) decode(r *Reader, version int16) {
+	flexible := true
+	m.ErrorCode = r.Int16()
+	m.PartitionIndex = r.Int32()
+	m.LeaderId = r.Int32()
+	m.LeaderEpoch = r.Int32()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.ReplicaNodes = make([]int32, n)
+		for i := range m.ReplicaNodes {
+			m.ReplicaNodes[i] = r.Int32()
+		}
+	} else {
+		m.ReplicaNodes = nil
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.IsrNodes = make([]int32, n)
+		for i := range m.IsrNodes {
+			m.IsrNodes[i] = r.Int32()
+		}
+	} else {
+		m.IsrNodes = nil
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.EligibleLeaderReplicas = make([]int32, n)
+		for i := range m.EligibleLeaderReplicas {
+			m.EligibleLeaderReplicas[i] = r.Int32()
+		}
+	} else {
+		m.EligibleLeaderReplicas = nil
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.LastKnownElr = make([]int32, n)
+		for i := range m.LastKnownElr {
+			m.LastKnownElr[i] = r.Int32()
+		}
+	} else {
+		m.LastKnownElr = nil
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.OfflineReplicas = make([]int32, n)
+		for i := range m.OfflineReplicas {
+			m.OfflineReplicas[i] = r.Int32()
+		}
+	} else {
+		m.OfflineReplicas = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *DescribeTopicPartitionsResponseCursor) Default() {
+	*m = DescribeTopicPartitionsResponseCursor{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *DescribeTopicPartitionsResponseCursor) isDefault() bool {
+	return m.TopicName == "" &&
+		m.PartitionIndex == 0
+}
+
+func (m *DescribeTopicPartitionsResponseCursor) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.TopicName, flexible)
+	w.Int32(m.PartitionIndex)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *DescribeTopicPartitionsResponseCursor) decode(r *Reader, version int16) {
+	flexible := true
+	m.TopicName = r.String(flexible)
+	m.PartitionIndex = r.Int32()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,72 @@
+// Code generated by gen from schemas/EndTxnRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// EndTxnRequest is a request of API key 26, versions 0-3
+type EndTxnRequest struct {
+	// The ID of the transaction to end.
+	TransactionalId string
+	// The producer ID.
+	ProducerId int64
+	// The current epoch associated with the producer.
+	ProducerEpoch int16
+	// True if the transaction was committed, false if it was aborted.
+	Committed bool
+}
+
+func (m *EndTxnRequest) ApiKey() int16 { return 26 }
+
+func (m *EndTxnRequest) MinVersion() int16 { return 0 }
+
+func (m *EndTxnRequest) MaxVersion() int16 { return 3 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *EndTxnRequest) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *EndTxnRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *EndTxnRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported EndTxnRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *EndTxnRequest) Default() {
+	*m = EndTxnRequest{}
+}
+
+func (m *EndTxnRequest) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.String(m.TransactionalId, flexible)
+	w.Int64(m.ProducerId)
+	w.Int16(m.ProducerEpoch)
+	w.Bool(m.Committed)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *EndTxnRequest) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.TransactionalId = r.String(flexible)
+	m.ProducerId = r.Int64()
+	m.ProducerEpoch = r.Int16()
+	m.Committed = r.Bool()
+	if flexible {
+		r.TaggedFields()
+	}
+}
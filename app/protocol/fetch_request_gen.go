@@ -0,0 +1,412 @@
+// Code generated by gen from schemas/FetchRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// FetchRequest is a request of API key 1, versions 0-16
+type FetchRequest struct {
+	// The clusterId if known. This is used to validate metadata fetches prior to broker registration.
+	ClusterId *string
+	// The broker ID of the follower, of -1 if this request is from a consumer.
+	ReplicaId int32
+	// The state of the replica in the follower.
+	ReplicaState FetchRequestReplicaState
+	// The maximum time in milliseconds to wait for the response.
+	MaxWaitMs int32
+	// The minimum bytes to accumulate in the response.
+	MinBytes int32
+	// The maximum bytes to fetch.  See KIP-74 for cases where this limit may not be honored.
+	MaxBytes int32
+	// This setting controls the visibility of transactional records. Using READ_UNCOMMITTED (isolation_level = 0) makes all records visible. With READ_COMMITTED (isolation_level = 1), non-transactional and COMMITTED transactional records are visible. To be more concrete, READ_COMMITTED returns all data from offsets smaller than the current LSO (last stable offset), and enables the inclusion of the list of aborted transactions in the result, which allows consumers to discard ABORTED transactional records
+	IsolationLevel int8
+	// The fetch session ID.
+	SessionId int32
+	// The fetch session epoch, which is used for ordering requests in a session.
+	SessionEpoch int32
+	// The topics to fetch.
+	Topics []FetchRequestFetchTopic
+	// In an incremental fetch request, the partitions to remove.
+	ForgottenTopicsData []FetchRequestForgottenTopic
+	// Rack ID of the consumer making this request
+	RackId string
+}
+
+type FetchRequestReplicaState struct {
+	// The replica ID of the follower, or -1 if this request is from a consumer.
+	ReplicaId int32
+	// The epoch of this follower, or -1 if not available.
+	ReplicaEpoch int64
+}
+
+type FetchRequestFetchTopic struct {
+	// The name of the topic to fetch.
+	Topic string
+	// The unique topic ID
+	TopicId [16]byte
+	// The partitions to fetch.
+	Partitions []FetchRequestFetchPartition
+}
+
+type FetchRequestFetchPartition struct {
+	// The partition index.
+	Partition int32
+	// The current leader epoch of the partition.
+	CurrentLeaderEpoch int32
+	// The message offset.
+	FetchOffset int64
+	// The epoch of the last fetched record or -1 if there is none
+	LastFetchedEpoch int32
+	// The earliest available offset of the follower replica.  The field is only used when the request is sent by the follower.
+	LogStartOffset int64
+	// The maximum bytes to fetch from this partition.  See KIP-74 for cases where this limit may not be honored.
+	PartitionMaxBytes int32
+}
+
+type FetchRequestForgottenTopic struct {
+	// The topic name.
+	Topic string
+	// The unique topic ID
+	TopicId [16]byte
+	// The partitions indexes to forget.
+	Partitions []int32
+}
+
+func (m *FetchRequest) ApiKey() int16 { return 1 }
+
+func (m *FetchRequest) MinVersion() int16 { return 0 }
+
+func (m *FetchRequest) MaxVersion() int16 { return 16 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *FetchRequest) IsFlexible(version int16) bool { return version >= 12 }
+
+// Encode serializes the message at the given version
+func (m *FetchRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *FetchRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported FetchRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *FetchRequest) Default() {
+	*m = FetchRequest{}
+	m.ReplicaId = -1
+	m.ReplicaState.Default()
+	m.MaxBytes = 0x7fffffff
+	m.SessionEpoch = -1
+}
+
+func (m *FetchRequest) encode(w *Writer, version int16) {
+	flexible := version >= 12
+	if version <= 14 {
+		w.Int32(m.ReplicaId)
+	}
+	w.Int32(m.MaxWaitMs)
+	w.Int32(m.MinBytes)
+	if version >= 3 {
+		w.Int32(m.MaxBytes)
+	}
+	if version >= 4 {
+		w.Int8(m.IsolationLevel)
+	}
+	if version >= 7 {
+		w.Int32(m.SessionId)
+	}
+	if version >= 7 {
+		w.Int32(m.SessionEpoch)
+	}
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if version >= 7 {
+		w.ArrayLen(len(m.ForgottenTopicsData), flexible)
+		for i := range m.ForgottenTopicsData {
+			m.ForgottenTopicsData[i].encode(w, version)
+		}
+	}
+	if version >= 11 {
+		w.String(m.RackId, flexible)
+	}
+	if flexible {
+		var tagged []TaggedField
+		if version >= 12 && m.ClusterId != nil {
+			tw := NewWriter()
+			tw.NullableString(m.ClusterId, flexible)
+			tagged = append(tagged, TaggedField{Tag: 0, Data: tw.Buf()})
+		}
+		if version >= 15 && !m.ReplicaState.isDefault() {
+			tw := NewWriter()
+			m.ReplicaState.encode(tw, version)
+			tagged = append(tagged, TaggedField{Tag: 1, Data: tw.Buf()})
+		}
+		w.TaggedFields(tagged)
+	}
+}
+
+func (m *FetchRequest) decode(r *Reader, version int16) {
+	flexible := version >= 12
+	if version <= 14 {
+		m.ReplicaId = r.Int32()
+	}
+	m.MaxWaitMs = r.Int32()
+	m.MinBytes = r.Int32()
+	if version >= 3 {
+		m.MaxBytes = r.Int32()
+	}
+	if version >= 4 {
+		m.IsolationLevel = r.Int8()
+	}
+	if version >= 7 {
+		m.SessionId = r.Int32()
+	}
+	if version >= 7 {
+		m.SessionEpoch = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]FetchRequestFetchTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if version >= 7 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.ForgottenTopicsData = make([]FetchRequestForgottenTopic, n)
+			for i := range m.ForgottenTopicsData {
+				m.ForgottenTopicsData[i].Default()
+				m.ForgottenTopicsData[i].decode(r, version)
+			}
+		} else {
+			m.ForgottenTopicsData = nil
+		}
+	}
+	if version >= 11 {
+		m.RackId = r.String(flexible)
+	}
+	if flexible {
+		for _, field := range r.TaggedFields() {
+			switch {
+			case field.Tag == 0 && version >= 12:
+				tr := NewReader(field.Data)
+				m.ClusterId = tr.NullableString(flexible)
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			case field.Tag == 1 && version >= 15:
+				tr := NewReader(field.Data)
+				m.ReplicaState.decode(tr, version)
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			}
+		}
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchRequestReplicaState) Default() {
+	*m = FetchRequestReplicaState{}
+	m.ReplicaId = -1
+	m.ReplicaEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchRequestReplicaState) isDefault() bool {
+	return m.ReplicaId == -1 &&
+		m.ReplicaEpoch == -1
+}
+
+func (m *FetchRequestReplicaState) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int32(m.ReplicaId)
+	w.Int64(m.ReplicaEpoch)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchRequestReplicaState) decode(r *Reader, version int16) {
+	flexible := true
+	m.ReplicaId = r.Int32()
+	m.ReplicaEpoch = r.Int64()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchRequestFetchTopic) Default() {
+	*m = FetchRequestFetchTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchRequestFetchTopic) isDefault() bool {
+	return m.Topic == "" &&
+		m.TopicId == [16]byte{} &&
+		len(m.Partitions) == 0
+}
+
+func (m *FetchRequestFetchTopic) encode(w *Writer, version int16) {
+	flexible := version >= 12
+	if version <= 12 {
+		w.String(m.Topic, flexible)
+	}
+	if version >= 13 {
+		w.UUID(m.TopicId)
+	}
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchRequestFetchTopic) decode(r *Reader, version int16) {
+	flexible := version >= 12
+	if version <= 12 {
+		m.Topic = r.String(flexible)
+	}
+	if version >= 13 {
+		m.TopicId = r.UUID()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]FetchRequestFetchPartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchRequestFetchPartition) Default() {
+	*m = FetchRequestFetchPartition{}
+	m.CurrentLeaderEpoch = -1
+	m.LastFetchedEpoch = -1
+	m.LogStartOffset = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchRequestFetchPartition) isDefault() bool {
+	return m.Partition == 0 &&
+		m.CurrentLeaderEpoch == -1 &&
+		m.FetchOffset == 0 &&
+		m.LastFetchedEpoch == -1 &&
+		m.LogStartOffset == -1 &&
+		m.PartitionMaxBytes == 0
+}
+
+func (m *FetchRequestFetchPartition) encode(w *Writer, version int16) {
+	flexible := version >= 12
+	w.Int32(m.Partition)
+	if version >= 9 {
+		w.Int32(m.CurrentLeaderEpoch)
+	}
+	w.Int64(m.FetchOffset)
+	if version >= 12 {
+		w.Int32(m.LastFetchedEpoch)
+	}
+	if version >= 5 {
+		w.Int64(m.LogStartOffset)
+	}
+	w.Int32(m.PartitionMaxBytes)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchRequestFetchPartition) decode(r *Reader, version int16) {
+	flexible := version >= 12
+	m.Partition = r.Int32()
+	if version >= 9 {
+		m.CurrentLeaderEpoch = r.Int32()
+	}
+	m.FetchOffset = r.Int64()
+	if version >= 12 {
+		m.LastFetchedEpoch = r.Int32()
+	}
+	if version >= 5 {
+		m.LogStartOffset = r.Int64()
+	}
+	m.PartitionMaxBytes = r.Int32()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchRequestForgottenTopic) Default() {
+	*m = FetchRequestForgottenTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchRequestForgottenTopic) isDefault() bool {
+	return m.Topic == "" &&
+		m.TopicId == [16]byte{} &&
+		len(m.Partitions) == 0
+}
+
+func (m *FetchRequestForgottenTopic) encode(w *Writer, version int16) {
+	flexible := version >= 12
+	if version <= 12 {
+		w.String(m.Topic, flexible)
+	}
+	if version >= 13 {
+		w.UUID(m.TopicId)
+	}
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		w.Int32(m.Partitions[i])
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchRequestForgottenTopic) decode(r *Reader, version int16) {
+	flexible := version >= 12
+	if version <= 12 {
+		m.Topic = r.String(flexible)
+	}
+	if version >= 13 {
+		m.TopicId = r.UUID()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]int32, n)
+		for i := range m.Partitions {
+			m.Partitions[i] = r.Int32()
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,535 @@
+// Code generated by gen from schemas/FetchResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// FetchResponse is a response of API key 1, versions 0-16
+type FetchResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The top level response error code.
+	ErrorCode int16
+	// The fetch session ID, or 0 if this is not part of a fetch session.
+	SessionId int32
+	// The response topics.
+	Responses []FetchResponseFetchableTopicResponse
+	// Endpoints for all current-leaders enumerated in PartitionData, with errors NOT_LEADER_OR_FOLLOWER & FENCED_LEADER_EPOCH.
+	NodeEndpoints []FetchResponseNodeEndpoint
+}
+
+type FetchResponseFetchableTopicResponse struct {
+	// The topic name.
+	Topic string
+	// The unique topic ID
+	TopicId [16]byte
+	// The topic partitions.
+	Partitions []FetchResponsePartitionData
+}
+
+type FetchResponsePartitionData struct {
+	// The partition index.
+	PartitionIndex int32
+	// The error code, or 0 if there was no fetch error.
+	ErrorCode int16
+	// The current high water mark.
+	HighWatermark int64
+	// The last stable offset (or LSO) of the partition. This is the last offset such that the state of all transactional records prior to this offset have been decided (ABORTED or COMMITTED)
+	LastStableOffset int64
+	// The current log start offset.
+	LogStartOffset int64
+	// In case divergence is detected based on the `LastFetchedEpoch` and `FetchOffset` in the request, this field indicates the largest epoch and its end offset such that subsequent records are known to diverge
+	DivergingEpoch FetchResponseEpochEndOffset
+	// The current leader of the partition.
+	CurrentLeader FetchResponseLeaderIdAndEpoch
+	// In the case of fetching an offset less than the LogStartOffset, this is the end offset and epoch that should be used in the FetchSnapshot request.
+	SnapshotId FetchResponseSnapshotId
+	// The aborted transactions.
+	AbortedTransactions []FetchResponseAbortedTransaction
+	// The preferred read replica for the consumer to use on its next fetch request
+	PreferredReadReplica int32
+	// The record data.
+	Records []byte
+}
+
+type FetchResponseEpochEndOffset struct {
+	// The largest epoch.
+	Epoch int32
+	// The end offset of the epoch.
+	EndOffset int64
+}
+
+type FetchResponseLeaderIdAndEpoch struct {
+	// The ID of the current leader or -1 if the leader is unknown.
+	LeaderId int32
+	// The latest known leader epoch
+	LeaderEpoch int32
+}
+
+type FetchResponseSnapshotId struct {
+	// The end offset of the snapshot.
+	EndOffset int64
+	// The epoch of the snapshot.
+	Epoch int32
+}
+
+type FetchResponseAbortedTransaction struct {
+	// The producer id associated with the aborted transaction.
+	ProducerId int64
+	// The first offset in the aborted transaction.
+	FirstOffset int64
+}
+
+type FetchResponseNodeEndpoint struct {
+	// The ID of the associated node.
+	NodeId int32
+	// The node's hostname.
+	Host string
+	// The node's port.
+	Port int32
+	// The rack of the node, or null if it has not been assigned to a rack.
+	Rack *string
+}
+
+func (m *FetchResponse) ApiKey() int16 { return 1 }
+
+func (m *FetchResponse) MinVersion() int16 { return 0 }
+
+func (m *FetchResponse) MaxVersion() int16 { return 16 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *FetchResponse) IsFlexible(version int16) bool { return version >= 12 }
+
+// Encode serializes the message at the given version
+func (m *FetchResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *FetchResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported FetchResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *FetchResponse) Default() {
+	*m = FetchResponse{}
+}
+
+func (m *FetchResponse) encode(w *Writer, version int16) {
+	flexible := version >= 12
+	if version >= 1 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	if version >= 7 {
+		w.Int16(m.ErrorCode)
+	}
+	if version >= 7 {
+		w.Int32(m.SessionId)
+	}
+	w.ArrayLen(len(m.Responses), flexible)
+	for i := range m.Responses {
+		m.Responses[i].encode(w, version)
+	}
+	if flexible {
+		var tagged []TaggedField
+		if version >= 16 && len(m.NodeEndpoints) > 0 {
+			tw := NewWriter()
+			tw.ArrayLen(len(m.NodeEndpoints), flexible)
+			for i := range m.NodeEndpoints {
+				m.NodeEndpoints[i].encode(tw, version)
+			}
+			tagged = append(tagged, TaggedField{Tag: 0, Data: tw.Buf()})
+		}
+		w.TaggedFields(tagged)
+	}
+}
+
+func (m *FetchResponse) decode(r *Reader, version int16) {
+	flexible := version >= 12
+	if version >= 1 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if version >= 7 {
+		m.ErrorCode = r.Int16()
+	}
+	if version >= 7 {
+		m.SessionId = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Responses = make([]FetchResponseFetchableTopicResponse, n)
+		for i := range m.Responses {
+			m.Responses[i].Default()
+			m.Responses[i].decode(r, version)
+		}
+	} else {
+		m.Responses = nil
+	}
+	if flexible {
+		for _, field := range r.TaggedFields() {
+			switch {
+			case field.Tag == 0 && version >= 16:
+				tr := NewReader(field.Data)
+				if n := tr.ArrayLen(flexible); n >= 0 {
+					m.NodeEndpoints = make([]FetchResponseNodeEndpoint, n)
+					for i := range m.NodeEndpoints {
+						m.NodeEndpoints[i].Default()
+						m.NodeEndpoints[i].decode(tr, version)
+					}
+				} else {
+					m.NodeEndpoints = nil
+				}
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			}
+		}
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchResponseFetchableTopicResponse) Default() {
+	*m = FetchResponseFetchableTopicResponse{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchResponseFetchableTopicResponse) isDefault() bool {
+	return m.Topic == "" &&
+		m.TopicId == [16]byte{} &&
+		len(m.Partitions) == 0
+}
+
+func (m *FetchResponseFetchableTopicResponse) encode(w *Writer, version int16) {
+	flexible := version >= 12
+	if version <= 12 {
+		w.String(m.Topic, flexible)
+	}
+	if version >= 13 {
+		w.UUID(m.TopicId)
+	}
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchResponseFetchableTopicResponse) decode(r *Reader, version int16) {
+	flexible := version >= 12
+	if version <= 12 {
+		m.Topic = r.String(flexible)
+	}
+	if version >= 13 {
+		m.TopicId = r.UUID()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]FetchResponsePartitionData, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchResponsePartitionData) Default() {
+	*m = FetchResponsePartitionData{}
+	m.LastStableOffset = -1
+	m.LogStartOffset = -1
+	m.DivergingEpoch.Default()
+	m.CurrentLeader.Default()
+	m.SnapshotId.Default()
+	m.PreferredReadReplica = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchResponsePartitionData) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.ErrorCode == 0 &&
+		m.HighWatermark == 0 &&
+		m.LastStableOffset == -1 &&
+		m.LogStartOffset == -1 &&
+		m.DivergingEpoch.isDefault() &&
+		m.CurrentLeader.isDefault() &&
+		m.SnapshotId.isDefault() &&
+		m.AbortedTransactions == nil &&
+		m.PreferredReadReplica == -1 &&
+		m.Records == nil
+}
+
+func (m *FetchResponsePartitionData) encode(w *Writer, version int16) {
+	flexible := version >= 12
+	w.Int32(m.PartitionIndex)
+	w.Int16(m.ErrorCode)
+	w.Int64(m.HighWatermark)
+	if version >= 4 {
+		w.Int64(m.LastStableOffset)
+	}
+	if version >= 5 {
+		w.Int64(m.LogStartOffset)
+	}
+	if version >= 4 {
+		if m.AbortedTransactions == nil {
+			w.NullArray(flexible)
+		} else {
+			w.ArrayLen(len(m.AbortedTransactions), flexible)
+			for i := range m.AbortedTransactions {
+				m.AbortedTransactions[i].encode(w, version)
+			}
+		}
+	}
+	if version >= 11 {
+		w.Int32(m.PreferredReadReplica)
+	}
+	w.NullableBytes(m.Records, flexible)
+	if flexible {
+		var tagged []TaggedField
+		if version >= 12 && !m.DivergingEpoch.isDefault() {
+			tw := NewWriter()
+			m.DivergingEpoch.encode(tw, version)
+			tagged = append(tagged, TaggedField{Tag: 0, Data: tw.Buf()})
+		}
+		if version >= 12 && !m.CurrentLeader.isDefault() {
+			tw := NewWriter()
+			m.CurrentLeader.encode(tw, version)
+			tagged = append(tagged, TaggedField{Tag: 1, Data: tw.Buf()})
+		}
+		if version >= 12 && !m.SnapshotId.isDefault() {
+			tw := NewWriter()
+			m.SnapshotId.encode(tw, version)
+			tagged = append(tagged, TaggedField{Tag: 2, Data: tw.Buf()})
+		}
+		w.TaggedFields(tagged)
+	}
+}
+
+func (m *FetchResponsePartitionData) decode(r *Reader, version int16) {
+	flexible := version >= 12
+	m.PartitionIndex = r.Int32()
+	m.ErrorCode = r.Int16()
+	m.HighWatermark = r.Int64()
+	if version >= 4 {
+		m.LastStableOffset = r.Int64()
+	}
+	if version >= 5 {
+		m.LogStartOffset = r.Int64()
+	}
+	if version >= 4 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.AbortedTransactions = make([]FetchResponseAbortedTransaction, n)
+			for i := range m.AbortedTransactions {
+				m.AbortedTransactions[i].Default()
+				m.AbortedTransactions[i].decode(r, version)
+			}
+		} else {
+			m.AbortedTransactions = nil
+		}
+	}
+	if version >= 11 {
+		m.PreferredReadReplica = r.Int32()
+	}
+	m.Records = r.NullableBytes(flexible)
+	if flexible {
+		for _, field := range r.TaggedFields() {
+			switch {
+			case field.Tag == 0 && version >= 12:
+				tr := NewReader(field.Data)
+				m.DivergingEpoch.decode(tr, version)
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			case field.Tag == 1 && version >= 12:
+				tr := NewReader(field.Data)
+				m.CurrentLeader.decode(tr, version)
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			case field.Tag == 2 && version >= 12:
+				tr := NewReader(field.Data)
+				m.SnapshotId.decode(tr, version)
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			}
+		}
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchResponseEpochEndOffset) Default() {
+	*m = FetchResponseEpochEndOffset{}
+	m.Epoch = -1
+	m.EndOffset = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchResponseEpochEndOffset) isDefault() bool {
+	return m.Epoch == -1 &&
+		m.EndOffset == -1
+}
+
+func (m *FetchResponseEpochEndOffset) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int32(m.Epoch)
+	w.Int64(m.EndOffset)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchResponseEpochEndOffset) decode(r *Reader, version int16) {
+	flexible := true
+	m.Epoch = r.Int32()
+	m.EndOffset = r.Int64()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchResponseLeaderIdAndEpoch) Default() {
+	*m = FetchResponseLeaderIdAndEpoch{}
+	m.LeaderId = -1
+	m.LeaderEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchResponseLeaderIdAndEpoch) isDefault() bool {
+	return m.LeaderId == -1 &&
+		m.LeaderEpoch == -1
+}
+
+func (m *FetchResponseLeaderIdAndEpoch) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int32(m.LeaderId)
+	w.Int32(m.LeaderEpoch)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchResponseLeaderIdAndEpoch) decode(r *Reader, version int16) {
+	flexible := true
+	m.LeaderId = r.Int32()
+	m.LeaderEpoch = r.Int32()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchResponseSnapshotId) Default() {
+	*m = FetchResponseSnapshotId{}
+	m.EndOffset = -1
+	m.Epoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchResponseSnapshotId) isDefault() bool {
+	return m.EndOffset == -1 &&
+		m.Epoch == -1
+}
+
+func (m *FetchResponseSnapshotId) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int64(m.EndOffset)
+	w.Int32(m.Epoch)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchResponseSnapshotId) decode(r *Reader, version int16) {
+	flexible := true
+	m.EndOffset = r.Int64()
+	m.Epoch = r.Int32()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchResponseAbortedTransaction) Default() {
+	*m = FetchResponseAbortedTransaction{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchResponseAbortedTransaction) isDefault() bool {
+	return m.ProducerId == 0 &&
+		m.FirstOffset == 0
+}
+
+func (m *FetchResponseAbortedTransaction) encode(w *Writer, version int16) {
+	flexible := version >= 12
+	w.Int64(m.ProducerId)
+	w.Int64(m.FirstOffset)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchResponseAbortedTransaction) decode(r *Reader, version int16) {
+	flexible := version >= 12
+	m.ProducerId = r.Int64()
+	m.FirstOffset = r.Int64()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FetchResponseNodeEndpoint) Default() {
+	*m = FetchResponseNodeEndpoint{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FetchResponseNodeEndpoint) isDefault() bool {
+	return m.NodeId == 0 &&
+		m.Host == "" &&
+		m.Port == 0 &&
+		m.Rack == nil
+}
+
+func (m *FetchResponseNodeEndpoint) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int32(m.NodeId)
+	w.String(m.Host, flexible)
+	w.Int32(m.Port)
+	w.NullableString(m.Rack, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FetchResponseNodeEndpoint) decode(r *Reader, version int16) {
+	flexible := true
+	m.NodeId = r.Int32()
+	m.Host = r.String(flexible)
+	m.Port = r.Int32()
+	m.Rack = r.NullableString(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
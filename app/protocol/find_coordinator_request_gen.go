@@ -0,0 +1,90 @@
+// Code generated by gen from schemas/FindCoordinatorRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// FindCoordinatorRequest is a request of API key 10, versions 0-4
+type FindCoordinatorRequest struct {
+	// The coordinator key.
+	Key string
+	// The coordinator key type. (Group, transaction, etc.)
+	KeyType int8
+	// The coordinator keys.
+	CoordinatorKeys []string
+}
+
+func (m *FindCoordinatorRequest) ApiKey() int16 { return 10 }
+
+func (m *FindCoordinatorRequest) MinVersion() int16 { return 0 }
+
+func (m *FindCoordinatorRequest) MaxVersion() int16 { return 4 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *FindCoordinatorRequest) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *FindCoordinatorRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *FindCoordinatorRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported FindCoordinatorRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *FindCoordinatorRequest) Default() {
+	*m = FindCoordinatorRequest{}
+}
+
+func (m *FindCoordinatorRequest) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	if version <= 3 {
+		w.String(m.Key, flexible)
+	}
+	if version >= 1 {
+		w.Int8(m.KeyType)
+	}
+	if version >= 4 {
+		w.ArrayLen(len(m.CoordinatorKeys), flexible)
+		for i := range m.CoordinatorKeys {
+			w.String(m.CoordinatorKeys[i], flexible)
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FindCoordinatorRequest) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	if version <= 3 {
+		m.Key = r.String(flexible)
+	}
+	if version >= 1 {
+		m.KeyType = r.Int8()
+	}
+	if version >= 4 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.CoordinatorKeys = make([]string, n)
+			for i := range m.CoordinatorKeys {
+				m.CoordinatorKeys[i] = r.String(flexible)
+			}
+		} else {
+			m.CoordinatorKeys = nil
+		}
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,180 @@
+// Code generated by gen from schemas/FindCoordinatorResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// FindCoordinatorResponse is a response of API key 10, versions 0-4
+type FindCoordinatorResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The error message, or null if there was no error.
+	ErrorMessage *string
+	// The node id.
+	NodeId int32
+	// The host name.
+	Host string
+	// The port.
+	Port int32
+	// Each coordinator result in the response
+	Coordinators []FindCoordinatorResponseCoordinator
+}
+
+type FindCoordinatorResponseCoordinator struct {
+	// The coordinator key.
+	Key string
+	// The node id.
+	NodeId int32
+	// The host name.
+	Host string
+	// The port.
+	Port int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The error message, or null if there was no error.
+	ErrorMessage *string
+}
+
+func (m *FindCoordinatorResponse) ApiKey() int16 { return 10 }
+
+func (m *FindCoordinatorResponse) MinVersion() int16 { return 0 }
+
+func (m *FindCoordinatorResponse) MaxVersion() int16 { return 4 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *FindCoordinatorResponse) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *FindCoordinatorResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *FindCoordinatorResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported FindCoordinatorResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *FindCoordinatorResponse) Default() {
+	*m = FindCoordinatorResponse{}
+}
+
+func (m *FindCoordinatorResponse) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	if version >= 1 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	if version <= 3 {
+		w.Int16(m.ErrorCode)
+	}
+	if version >= 1 && version <= 3 {
+		w.NullableString(m.ErrorMessage, flexible)
+	}
+	if version <= 3 {
+		w.Int32(m.NodeId)
+	}
+	if version <= 3 {
+		w.String(m.Host, flexible)
+	}
+	if version <= 3 {
+		w.Int32(m.Port)
+	}
+	if version >= 4 {
+		w.ArrayLen(len(m.Coordinators), flexible)
+		for i := range m.Coordinators {
+			m.Coordinators[i].encode(w, version)
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FindCoordinatorResponse) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	if version >= 1 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if version <= 3 {
+		m.ErrorCode = r.Int16()
+	}
+	if version >= 1 && version <= 3 {
+		m.ErrorMessage = r.NullableString(flexible)
+	}
+	if version <= 3 {
+		m.NodeId = r.Int32()
+	}
+	if version <= 3 {
+		m.Host = r.String(flexible)
+	}
+	if version <= 3 {
+		m.Port = r.Int32()
+	}
+	if version >= 4 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Coordinators = make([]FindCoordinatorResponseCoordinator, n)
+			for i := range m.Coordinators {
+				m.Coordinators[i].Default()
+				m.Coordinators[i].decode(r, version)
+			}
+		} else {
+			m.Coordinators = nil
+		}
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *FindCoordinatorResponseCoordinator) Default() {
+	*m = FindCoordinatorResponseCoordinator{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *FindCoordinatorResponseCoordinator) isDefault() bool {
+	return m.Key == "" &&
+		m.NodeId == 0 &&
+		m.Host == "" &&
+		m.Port == 0 &&
+		m.ErrorCode == 0 &&
+		m.ErrorMessage == nil
+}
+
+func (m *FindCoordinatorResponseCoordinator) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.Key, flexible)
+	w.Int32(m.NodeId)
+	w.String(m.Host, flexible)
+	w.Int32(m.Port)
+	w.Int16(m.ErrorCode)
+	w.NullableString(m.ErrorMessage, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *FindCoordinatorResponseCoordinator) decode(r *Reader, version int16) {
+	flexible := true
+	m.Key = r.String(flexible)
+	m.NodeId = r.Int32()
+	m.Host = r.String(flexible)
+	m.Port = r.Int32()
+	m.ErrorCode = r.Int16()
+	m.ErrorMessage = r.NullableString(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,879 @@
+// Command gen turns Kafka's JSON message definitions (the files under
+// clients/src/main/resources/common/message in the Kafka repository) into Go
+// structs with versioned Encode and Decode methods.
+//
+//	go run ./gen -schemas schemas -out .
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Message is the top level object of a schema file
+type Message struct {
+	ApiKey           int16     `json:"apiKey"`
+	Type             string    `json:"type"`
+	Name             string    `json:"name"`
+	ValidVersions    string    `json:"validVersions"`
+	FlexibleVersions string    `json:"flexibleVersions"`
+	Fields           []*Field  `json:"fields"`
+	CommonStructs    []*Struct `json:"commonStructs"`
+}
+
+// Struct is an entry of commonStructs, referenced by name from fields
+type Struct struct {
+	Name     string   `json:"name"`
+	Versions string   `json:"versions"`
+	Fields   []*Field `json:"fields"`
+}
+
+type Field struct {
+	Name             string          `json:"name"`
+	Type             string          `json:"type"`
+	Versions         string          `json:"versions"`
+	NullableVersions string          `json:"nullableVersions"`
+	TaggedVersions   string          `json:"taggedVersions"`
+	Tag              *int            `json:"tag"`
+	Default          json.RawMessage `json:"default"`
+	About            string          `json:"about"`
+	Fields           []*Field        `json:"fields"`
+
+	versions versionRange
+	nullable versionRange
+	tagged   versionRange
+}
+
+// versionRange is an inclusive range of versions; "3+" is {3, MaxInt16}
+type versionRange struct {
+	min, max int16
+	none     bool
+}
+
+var noVersions = versionRange{none: true}
+
+func parseVersions(s string) (versionRange, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "none" {
+		return noVersions, nil
+	}
+	if strings.HasSuffix(s, "+") {
+		min, err := strconv.ParseInt(s[:len(s)-1], 10, 16)
+		return versionRange{min: int16(min), max: math.MaxInt16}, err
+	}
+	if lo, hi, found := strings.Cut(s, "-"); found {
+		min, err := strconv.ParseInt(lo, 10, 16)
+		if err != nil {
+			return noVersions, err
+		}
+		max, err := strconv.ParseInt(hi, 10, 16)
+		return versionRange{min: int16(min), max: int16(max)}, err
+	}
+	v, err := strconv.ParseInt(s, 10, 16)
+	return versionRange{min: int16(v), max: int16(v)}, err
+}
+
+func (v versionRange) intersect(o versionRange) versionRange {
+	if v.none || o.none {
+		return noVersions
+	}
+	r := versionRange{min: max(v.min, o.min), max: min(v.max, o.max)}
+	if r.min > r.max {
+		return noVersions
+	}
+	return r
+}
+
+// condition returns the Go expression that is true for the versions of v
+// within ctx, or "" if every version of ctx is in v
+func (v versionRange) condition(ctx versionRange) string {
+	r := v.intersect(ctx)
+	if r.none {
+		return "false"
+	}
+	var parts []string
+	if r.min > ctx.min {
+		parts = append(parts, fmt.Sprintf("version >= %d", r.min))
+	}
+	if r.max < ctx.max {
+		parts = append(parts, fmt.Sprintf("version <= %d", r.max))
+	}
+	return strings.Join(parts, " && ")
+}
+
+var primitiveTypes = map[string]string{
+	"bool":    "bool",
+	"int8":    "int8",
+	"int16":   "int16",
+	"uint16":  "uint16",
+	"int32":   "int32",
+	"int64":   "int64",
+	"float64": "float64",
+	"uuid":    "[16]byte",
+	"string":  "string",
+	"bytes":   "[]byte",
+	"records": "[]byte",
+}
+
+// writerMethods names the Writer/Reader method of each fixed-size primitive
+var writerMethods = map[string]string{
+	"bool":    "Bool",
+	"int8":    "Int8",
+	"int16":   "Int16",
+	"uint16":  "Uint16",
+	"int32":   "Int32",
+	"int64":   "Int64",
+	"float64": "Float64",
+	"uuid":    "UUID",
+}
+
+// structInfo is a Go struct to emit: the message itself or a nested struct
+type structInfo struct {
+	goName   string
+	fields   []*Field
+	versions versionRange
+	topLevel bool
+}
+
+type generator struct {
+	msg      *Message
+	source   string
+	valid    versionRange
+	flexible versionRange
+	common   map[string]*Struct
+	structs  []*structInfo
+	seen     map[string]bool
+	out      bytes.Buffer
+}
+
+func main() {
+	schemaDir := flag.String("schemas", "schemas", "directory holding the JSON message definitions")
+	outDir := flag.String("out", ".", "directory to write the generated files to")
+	flag.Parse()
+
+	files, err := filepath.Glob(filepath.Join(*schemaDir, "*.json"))
+	if err != nil || len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "gen: no schemas found in %s\n", *schemaDir)
+		os.Exit(1)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		if err := generateFile(file, *outDir); err != nil {
+			fmt.Fprintf(os.Stderr, "gen: %s: %v\n", file, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func generateFile(path string, outDir string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var msg Message
+	if err := json.Unmarshal(stripComments(data), &msg); err != nil {
+		return err
+	}
+
+	g := &generator{
+		msg:    &msg,
+		source: filepath.ToSlash(path),
+		common: make(map[string]*Struct),
+		seen:   make(map[string]bool),
+	}
+	if g.valid, err = parseVersions(msg.ValidVersions); err != nil {
+		return fmt.Errorf("validVersions: %w", err)
+	}
+	if g.flexible, err = parseVersions(msg.FlexibleVersions); err != nil {
+		return fmt.Errorf("flexibleVersions: %w", err)
+	}
+	for _, common := range msg.CommonStructs {
+		g.common[common.Name] = common
+	}
+
+	if err := g.resolveFields(msg.Fields); err != nil {
+		return err
+	}
+	for _, common := range msg.CommonStructs {
+		if err := g.resolveFields(common.Fields); err != nil {
+			return err
+		}
+	}
+	g.collectStructs(&structInfo{goName: msg.Name, fields: msg.Fields, versions: g.valid, topLevel: true})
+
+	src, err := g.generate()
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(outDir, snakeCase(msg.Name)+"_gen.go")
+	return os.WriteFile(out, src, 0644)
+}
+
+// stripComments drops the // comment lines Kafka puts in its schema files
+func stripComments(data []byte) []byte {
+	var out bytes.Buffer
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "//") {
+			continue
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+var wordBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+func snakeCase(name string) string {
+	return strings.ToLower(wordBoundary.ReplaceAllString(name, "${1}_${2}"))
+}
+
+func (g *generator) resolveFields(fields []*Field) error {
+	for _, f := range fields {
+		var err error
+		if f.versions, err = parseVersions(f.Versions); err != nil {
+			return fmt.Errorf("field %s: versions: %w", f.Name, err)
+		}
+		if f.nullable, err = parseVersions(f.NullableVersions); err != nil {
+			return fmt.Errorf("field %s: nullableVersions: %w", f.Name, err)
+		}
+		if f.tagged, err = parseVersions(f.TaggedVersions); err != nil {
+			return fmt.Errorf("field %s: taggedVersions: %w", f.Name, err)
+		}
+		if !f.tagged.none && f.Tag == nil {
+			return fmt.Errorf("field %s: taggedVersions without a tag", f.Name)
+		}
+		if !f.nullable.none && !isArray(f.Type) && !isStruct(f.Type) && f.Type != "string" && f.Type != "bytes" && f.Type != "records" {
+			return fmt.Errorf("field %s: type %s cannot be nullable", f.Name, f.Type)
+		}
+		if err := g.resolveFields(f.Fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectStructs records s and, depth first, every struct its fields use
+func (g *generator) collectStructs(s *structInfo) {
+	g.structs = append(g.structs, s)
+	g.seen[s.goName] = true
+	for _, f := range s.fields {
+		name := elemType(f.Type)
+		if _, primitive := primitiveTypes[name]; primitive {
+			continue
+		}
+		goName := g.structName(name)
+		if g.seen[goName] {
+			continue
+		}
+		fields := f.Fields
+		if len(fields) == 0 && g.common[name] != nil {
+			fields = g.common[name].Fields
+		}
+		g.collectStructs(&structInfo{goName: goName, fields: fields, versions: f.versions.intersect(s.versions)})
+	}
+}
+
+func isArray(t string) bool {
+	return strings.HasPrefix(t, "[]")
+}
+
+func elemType(t string) string {
+	return strings.TrimPrefix(t, "[]")
+}
+
+func isStruct(t string) bool {
+	_, primitive := primitiveTypes[elemType(t)]
+	return !primitive
+}
+
+// structName prefixes nested struct names with the message name, since several
+// messages define structs with the same name (LeaderIdAndEpoch, NodeEndpoint, ...).
+// Names that already start with it (MetadataResponseTopic, ...) are kept.
+func (g *generator) structName(t string) string {
+	if strings.HasPrefix(t, g.msg.Name) {
+		return t
+	}
+	return g.msg.Name + t
+}
+
+func (g *generator) goType(f *Field) string {
+	t := elemType(f.Type)
+	goType, primitive := primitiveTypes[t]
+	if !primitive {
+		goType = g.structName(t)
+	}
+	if isArray(f.Type) {
+		return "[]" + goType
+	}
+	if (t == "string" || !primitive) && !f.nullable.none {
+		return "*" + goType
+	}
+	return goType
+}
+
+// regularVersions are the versions in which f is written in place rather than as a tagged field
+func (f *Field) regularVersions() versionRange {
+	if f.tagged.none {
+		return f.versions
+	}
+	if f.tagged.min <= f.versions.min {
+		return noVersions
+	}
+	return versionRange{min: f.versions.min, max: f.tagged.min - 1}.intersect(f.versions)
+}
+
+// defaultValue returns the schema default as written in the JSON file
+func (f *Field) defaultValue() string {
+	if len(f.Default) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(f.Default, &s); err == nil {
+		return s
+	}
+	return string(f.Default)
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.out, format, args...)
+}
+
+func (g *generator) generate() ([]byte, error) {
+	for _, s := range g.structs {
+		g.writeStruct(s)
+	}
+	top := g.structs[0]
+	g.writeTopLevelMethods(top)
+	for _, s := range g.structs {
+		if err := g.writeMethods(s); err != nil {
+			return nil, err
+		}
+	}
+
+	var file bytes.Buffer
+	fmt.Fprintf(&file, "// Code generated by gen from %s. DO NOT EDIT.\n\n", g.source)
+	fmt.Fprintf(&file, "package protocol\n\n")
+	fmt.Fprintf(&file, "import \"fmt\"\n\n")
+	file.Write(g.out.Bytes())
+
+	src, err := format.Source(file.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, file.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) writeStruct(s *structInfo) {
+	if s.topLevel {
+		kind := "request"
+		if g.msg.Type == "response" {
+			kind = "response"
+		}
+		g.printf("// %s is a %s of API key %d, versions %s\n", s.goName, kind, g.msg.ApiKey, g.msg.ValidVersions)
+	}
+	g.printf("type %s struct {\n", s.goName)
+	for _, f := range s.fields {
+		if f.About != "" {
+			g.printf("// %s\n", f.About)
+		}
+		g.printf("%s %s\n", f.Name, g.goType(f))
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) writeTopLevelMethods(s *structInfo) {
+	flexible := "false"
+	if !g.flexible.none {
+		flexible = fmt.Sprintf("version >= %d", g.flexible.min)
+	}
+	g.printf("func (m *%s) ApiKey() int16 { return %d }\n\n", s.goName, g.msg.ApiKey)
+	g.printf("func (m *%s) MinVersion() int16 { return %d }\n\n", s.goName, g.valid.min)
+	g.printf("func (m *%s) MaxVersion() int16 { return %d }\n\n", s.goName, g.valid.max)
+	g.printf("// IsFlexible reports whether version uses compact encodings and tagged fields\n")
+	g.printf("func (m *%s) IsFlexible(version int16) bool { return %s }\n\n", s.goName, flexible)
+
+	g.printf("// Encode serializes the message at the given version\n")
+	g.printf("func (m *%s) Encode(version int16) []byte {\n", s.goName)
+	g.printf("w := NewWriter()\n")
+	g.printf("m.encode(w, version)\n")
+	g.printf("return w.Buf()\n")
+	g.printf("}\n\n")
+
+	g.printf("// Decode parses data encoded at the given version. Fields absent from that\n")
+	g.printf("// version keep their default values.\n")
+	g.printf("func (m *%s) Decode(data []byte, version int16) error {\n", s.goName)
+	g.printf("if version < m.MinVersion() || version > m.MaxVersion() {\n")
+	g.printf("return fmt.Errorf(\"protocol: unsupported %s version %%d\", version)\n", s.goName)
+	g.printf("}\n")
+	g.printf("r := NewReader(data)\n")
+	g.printf("m.Default()\n")
+	g.printf("m.decode(r, version)\n")
+	g.printf("return r.Err()\n")
+	g.printf("}\n\n")
+}
+
+func (g *generator) writeMethods(s *structInfo) error {
+	g.writeDefault(s)
+	if !s.topLevel {
+		g.writeIsDefault(s)
+	}
+	if err := g.writeEncode(s); err != nil {
+		return err
+	}
+	return g.writeDecode(s)
+}
+
+func (g *generator) flexibleCondition(s *structInfo) string {
+	cond := g.flexible.condition(s.versions)
+	if cond == "" {
+		return "true"
+	}
+	return cond
+}
+
+// taggedFields returns the fields of s that are tagged in some version, sorted by tag
+func taggedFields(s *structInfo) []*Field {
+	var tagged []*Field
+	for _, f := range s.fields {
+		if !f.tagged.none {
+			tagged = append(tagged, f)
+		}
+	}
+	sort.SliceStable(tagged, func(i, j int) bool { return *tagged[i].Tag < *tagged[j].Tag })
+	return tagged
+}
+
+func (g *generator) writeDefault(s *structInfo) {
+	g.printf("// Default sets every field to its schema default\n")
+	g.printf("func (m *%s) Default() {\n", s.goName)
+	g.printf("*m = %s{}\n", s.goName)
+	for _, f := range s.fields {
+		target := "m." + f.Name
+		def := f.defaultValue()
+		switch {
+		case isArray(f.Type):
+			// Arrays default to empty or null, both of which are the zero value
+		case isStruct(f.Type) && !f.nullable.none:
+			// Nullable structs default to null
+		case isStruct(f.Type):
+			g.printf("%s.Default()\n", target)
+		case f.Type == "string":
+			if def == "" || def == "null" {
+				continue
+			}
+			if f.nullable.none {
+				g.printf("%s = %q\n", target, def)
+			} else {
+				g.printf("{\ns := %q\n%s = &s\n}\n", def, target)
+			}
+		case f.Type == "bool":
+			if def == "true" {
+				g.printf("%s = true\n", target)
+			}
+		case f.Type == "uuid", f.Type == "bytes", f.Type == "records":
+			// Zero UUID and empty/null bytes are the zero value
+		default:
+			if def == "" {
+				continue
+			}
+			if v, err := strconv.ParseFloat(def, 64); err == nil && v == 0 {
+				continue
+			}
+			if v, err := strconv.ParseInt(def, 0, 64); err == nil && v == 0 {
+				continue
+			}
+			g.printf("%s = %s\n", target, def)
+		}
+	}
+	g.printf("}\n\n")
+}
+
+// defaultCheck returns an expression that is true when expr holds the default
+// of f, or when it does not if changed is set
+func (g *generator) defaultCheck(f *Field, expr string, changed bool) string {
+	eq, empty := "==", "== 0"
+	if changed {
+		eq, empty = "!=", "> 0"
+	}
+	def := f.defaultValue()
+	switch {
+	case isArray(f.Type):
+		if !f.nullable.none && (def == "" || def == "null") {
+			return fmt.Sprintf("%s %s nil", expr, eq)
+		}
+		return fmt.Sprintf("len(%s) %s", expr, empty)
+	case isStruct(f.Type) && !f.nullable.none:
+		return fmt.Sprintf("%s %s nil", expr, eq)
+	case isStruct(f.Type):
+		if changed {
+			return "!" + expr + ".isDefault()"
+		}
+		return expr + ".isDefault()"
+	case f.Type == "string":
+		if f.nullable.none {
+			return fmt.Sprintf("%s %s %q", expr, eq, def)
+		}
+		if def == "null" || len(f.Default) == 0 {
+			return fmt.Sprintf("%s %s nil", expr, eq)
+		}
+		if changed {
+			return fmt.Sprintf("(%s == nil || *%s != %q)", expr, expr, def)
+		}
+		return fmt.Sprintf("%s != nil && *%s == %q", expr, expr, def)
+	case f.Type == "bytes", f.Type == "records":
+		if !f.nullable.none {
+			return fmt.Sprintf("%s %s nil", expr, eq)
+		}
+		return fmt.Sprintf("len(%s) %s", expr, empty)
+	case f.Type == "uuid":
+		return fmt.Sprintf("%s %s [16]byte{}", expr, eq)
+	case f.Type == "bool":
+		if changed == (def == "true") {
+			return "!" + expr
+		}
+		return expr
+	default:
+		if def == "" {
+			def = "0"
+		}
+		return fmt.Sprintf("%s %s %s", expr, eq, def)
+	}
+}
+
+func (g *generator) writeIsDefault(s *structInfo) {
+	var checks []string
+	for _, f := range s.fields {
+		checks = append(checks, g.defaultCheck(f, "m."+f.Name, false))
+	}
+	g.printf("// isDefault reports whether every field holds its default, in which case a\n")
+	g.printf("// tagged field of this type is left out\n")
+	g.printf("func (m *%s) isDefault() bool {\n", s.goName)
+	if len(checks) == 0 {
+		g.printf("return true\n")
+	} else {
+		g.printf("return %s\n", strings.Join(checks, " &&\n"))
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) writeEncode(s *structInfo) error {
+	g.printf("func (m *%s) encode(w *Writer, version int16) {\n", s.goName)
+	g.printf("flexible := %s\n", g.flexibleCondition(s))
+	for _, f := range s.fields {
+		regular := f.regularVersions().intersect(s.versions)
+		if regular.none {
+			continue
+		}
+		lines, err := g.encodeValue(f, "m."+f.Name, "w", regular)
+		if err != nil {
+			return err
+		}
+		g.writeConditional(regular.condition(s.versions), lines)
+	}
+
+	tagged := taggedFields(s)
+	g.printf("if flexible {\n")
+	if len(tagged) == 0 {
+		g.printf("w.TaggedFields(nil)\n")
+	} else {
+		g.printf("var tagged []TaggedField\n")
+		for _, f := range tagged {
+			versions := f.tagged.intersect(s.versions)
+			if versions.none {
+				continue
+			}
+			lines, err := g.encodeValue(f, "m."+f.Name, "tw", versions)
+			if err != nil {
+				return err
+			}
+			cond := g.defaultCheck(f, "m."+f.Name, true)
+			if versionCond := versions.condition(s.versions); versionCond != "" {
+				cond = versionCond + " && " + cond
+			}
+			g.printf("if %s {\n", cond)
+			g.printf("tw := NewWriter()\n")
+			for _, line := range lines {
+				g.printf("%s\n", line)
+			}
+			g.printf("tagged = append(tagged, TaggedField{Tag: %d, Data: tw.Buf()})\n", *f.Tag)
+			g.printf("}\n")
+		}
+		g.printf("w.TaggedFields(tagged)\n")
+	}
+	g.printf("}\n")
+	g.printf("}\n\n")
+	return nil
+}
+
+func (g *generator) writeConditional(cond string, lines []string) {
+	if cond != "" {
+		g.printf("if %s {\n", cond)
+	}
+	for _, line := range lines {
+		g.printf("%s\n", line)
+	}
+	if cond != "" {
+		g.printf("}\n")
+	}
+}
+
+// nullableSplit returns the versions of ctx in which f is nullable, and
+// whether that is only some of them. Only arrays and strings may be nullable
+// in some of their versions.
+func nullableSplit(f *Field, ctx versionRange) (versionRange, bool, error) {
+	nullable := f.nullable.intersect(ctx)
+	if nullable.none || nullable == ctx {
+		return nullable, false, nil
+	}
+	if !isArray(f.Type) && f.Type != "string" {
+		return nullable, false, fmt.Errorf("field %s: nullable in only some versions is not supported for %s", f.Name, f.Type)
+	}
+	return nullable, true, nil
+}
+
+// splitNullable wraps the lines for the nullable and the non-nullable versions
+// of ctx in a version check
+func splitNullable(nullable versionRange, ctx versionRange, nullLines []string, lines []string) []string {
+	out := []string{fmt.Sprintf("if %s {", nullable.condition(ctx))}
+	out = append(out, nullLines...)
+	out = append(out, "} else {")
+	out = append(out, lines...)
+	return append(out, "}")
+}
+
+func (g *generator) encodeValue(f *Field, expr string, w string, ctx versionRange) ([]string, error) {
+	nullable, partial, err := nullableSplit(f, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !partial {
+		return g.encodeValueIn(f, expr, w, !nullable.none)
+	}
+	nullLines, err := g.encodeValueIn(f, expr, w, true)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := g.encodeValueIn(f, expr, w, false)
+	if err != nil {
+		return nil, err
+	}
+	return splitNullable(nullable, ctx, nullLines, lines), nil
+}
+
+// encodeValueIn returns the lines writing f in versions where it is nullable or not
+func (g *generator) encodeValueIn(f *Field, expr string, w string, nullable bool) ([]string, error) {
+	if isArray(f.Type) {
+		var lines []string
+		elem := elemType(f.Type)
+		if nullable {
+			lines = append(lines, fmt.Sprintf("if %s == nil {", expr), fmt.Sprintf("%s.NullArray(flexible)", w), "} else {")
+		}
+		lines = append(lines, fmt.Sprintf("%s.ArrayLen(len(%s), flexible)", w, expr))
+		lines = append(lines, fmt.Sprintf("for i := range %s {", expr))
+		if isStruct(elem) {
+			lines = append(lines, fmt.Sprintf("%s[i].encode(%s, version)", expr, w))
+		} else {
+			line, err := encodePrimitive(elem, expr+"[i]", w, false)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+		}
+		lines = append(lines, "}")
+		if nullable {
+			lines = append(lines, "}")
+		}
+		return lines, nil
+	}
+
+	if isStruct(f.Type) && nullable {
+		// A nullable struct is prefixed with an INT8: -1 for null, 1 otherwise
+		return []string{
+			fmt.Sprintf("if %s == nil {", expr), fmt.Sprintf("%s.Int8(-1)", w), "} else {",
+			fmt.Sprintf("%s.Int8(1)", w), fmt.Sprintf("%s.encode(%s, version)", expr, w), "}",
+		}, nil
+	}
+	if isStruct(f.Type) {
+		return []string{fmt.Sprintf("%s.encode(%s, version)", expr, w)}, nil
+	}
+	if f.Type == "string" && !nullable && !f.nullable.none {
+		// A *string in a version where it is not nullable: null is written as ""
+		return []string{
+			fmt.Sprintf("if %s == nil {", expr), fmt.Sprintf("%s.String(\"\", flexible)", w), "} else {",
+			fmt.Sprintf("%s.String(*%s, flexible)", w, expr), "}",
+		}, nil
+	}
+	line, err := encodePrimitive(f.Type, expr, w, nullable)
+	if err != nil {
+		return nil, err
+	}
+	return []string{line}, nil
+}
+
+func encodePrimitive(t string, expr string, w string, nullable bool) (string, error) {
+	switch t {
+	case "string":
+		if nullable {
+			return fmt.Sprintf("%s.NullableString(%s, flexible)", w, expr), nil
+		}
+		return fmt.Sprintf("%s.String(%s, flexible)", w, expr), nil
+	case "bytes", "records":
+		if nullable {
+			return fmt.Sprintf("%s.NullableBytes(%s, flexible)", w, expr), nil
+		}
+		return fmt.Sprintf("%s.Bytes(%s, flexible)", w, expr), nil
+	}
+	method, ok := writerMethods[t]
+	if !ok {
+		return "", fmt.Errorf("unsupported type %s", t)
+	}
+	return fmt.Sprintf("%s.%s(%s)", w, method, expr), nil
+}
+
+func (g *generator) writeDecode(s *structInfo) error {
+	g.printf("func (m *%s) decode(r *Reader, version int16) {\n", s.goName)
+	g.printf("flexible := %s\n", g.flexibleCondition(s))
+	for _, f := range s.fields {
+		regular := f.regularVersions().intersect(s.versions)
+		if regular.none {
+			continue
+		}
+		lines, err := g.decodeValue(f, "m."+f.Name, "r", regular)
+		if err != nil {
+			return err
+		}
+		g.writeConditional(regular.condition(s.versions), lines)
+	}
+
+	tagged := taggedFields(s)
+	g.printf("if flexible {\n")
+	if len(tagged) == 0 {
+		// Unknown tagged fields are skipped
+		g.printf("r.TaggedFields()\n")
+	} else {
+		g.printf("for _, field := range r.TaggedFields() {\n")
+		g.printf("switch {\n")
+		for _, f := range tagged {
+			versions := f.tagged.intersect(s.versions)
+			if versions.none {
+				continue
+			}
+			lines, err := g.decodeValue(f, "m."+f.Name, "tr", versions)
+			if err != nil {
+				return err
+			}
+			cond := fmt.Sprintf("field.Tag == %d", *f.Tag)
+			if versionCond := versions.condition(s.versions); versionCond != "" {
+				cond += " && " + versionCond
+			}
+			g.printf("case %s:\n", cond)
+			g.printf("tr := NewReader(field.Data)\n")
+			for _, line := range lines {
+				g.printf("%s\n", line)
+			}
+			g.printf("if err := tr.Err(); err != nil {\n")
+			g.printf("r.Fail(err)\n")
+			g.printf("}\n")
+		}
+		g.printf("}\n")
+		g.printf("}\n")
+	}
+	g.printf("}\n")
+	g.printf("}\n\n")
+	return nil
+}
+
+func (g *generator) decodeValue(f *Field, expr string, r string, ctx versionRange) ([]string, error) {
+	nullable, partial, err := nullableSplit(f, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !partial {
+		return g.decodeValueIn(f, expr, r, !nullable.none)
+	}
+	nullLines, err := g.decodeValueIn(f, expr, r, true)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := g.decodeValueIn(f, expr, r, false)
+	if err != nil {
+		return nil, err
+	}
+	return splitNullable(nullable, ctx, nullLines, lines), nil
+}
+
+// decodeValueIn returns the lines reading f in versions where it is nullable or not
+func (g *generator) decodeValueIn(f *Field, expr string, r string, nullable bool) ([]string, error) {
+	if isArray(f.Type) {
+		elem := elemType(f.Type)
+		goElem := strings.TrimPrefix(g.goType(f), "[]")
+		lines := []string{
+			fmt.Sprintf("if n := %s.ArrayLen(flexible); n >= 0 {", r),
+			fmt.Sprintf("%s = make([]%s, n)", expr, goElem),
+			fmt.Sprintf("for i := range %s {", expr),
+		}
+		if isStruct(elem) {
+			lines = append(lines, fmt.Sprintf("%s[i].Default()", expr), fmt.Sprintf("%s[i].decode(%s, version)", expr, r))
+		} else {
+			call, err := decodePrimitive(elem, r, false)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, fmt.Sprintf("%s[i] = %s", expr, call))
+		}
+		lines = append(lines, "}", "} else {", fmt.Sprintf("%s = nil", expr), "}")
+		return lines, nil
+	}
+
+	if isStruct(f.Type) && nullable {
+		return []string{
+			fmt.Sprintf("if %s.Int8() < 0 {", r), fmt.Sprintf("%s = nil", expr), "} else {",
+			fmt.Sprintf("%s = new(%s)", expr, strings.TrimPrefix(g.goType(f), "*")),
+			fmt.Sprintf("%s.Default()", expr), fmt.Sprintf("%s.decode(%s, version)", expr, r), "}",
+		}, nil
+	}
+	if isStruct(f.Type) {
+		return []string{fmt.Sprintf("%s.decode(%s, version)", expr, r)}, nil
+	}
+	if f.Type == "string" && !nullable && !f.nullable.none {
+		return []string{"{", fmt.Sprintf("s := %s.String(flexible)", r), fmt.Sprintf("%s = &s", expr), "}"}, nil
+	}
+	call, err := decodePrimitive(f.Type, r, nullable)
+	if err != nil {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("%s = %s", expr, call)}, nil
+}
+
+func decodePrimitive(t string, r string, nullable bool) (string, error) {
+	switch t {
+	case "string":
+		if nullable {
+			return r + ".NullableString(flexible)", nil
+		}
+		return r + ".String(flexible)", nil
+	case "bytes", "records":
+		if nullable {
+			return r + ".NullableBytes(flexible)", nil
+		}
+		return r + ".Bytes(flexible)", nil
+	}
+	method, ok := writerMethods[t]
+	if !ok {
+		return "", fmt.Errorf("unsupported type %s", t)
+	}
+	return fmt.Sprintf("%s.%s()", r, method), nil
+}
@@ -0,0 +1,7 @@
+// Package protocol holds the Kafka request and response types generated from
+// Kafka's JSON message definitions in schemas/, along with the Reader and
+// Writer they encode with. Every message can be encoded and decoded at any of
+// its valid versions.
+package protocol
+
+//go:generate go run ./gen -schemas schemas -out .
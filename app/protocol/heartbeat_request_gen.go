@@ -0,0 +1,76 @@
+// Code generated by gen from schemas/HeartbeatRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// HeartbeatRequest is a request of API key 12, versions 0-4
+type HeartbeatRequest struct {
+	// The group id.
+	GroupId string
+	// The generation of the group.
+	GenerationId int32
+	// The member ID.
+	MemberId string
+	// The unique identifier of the consumer instance provided by end user.
+	GroupInstanceId *string
+}
+
+func (m *HeartbeatRequest) ApiKey() int16 { return 12 }
+
+func (m *HeartbeatRequest) MinVersion() int16 { return 0 }
+
+func (m *HeartbeatRequest) MaxVersion() int16 { return 4 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *HeartbeatRequest) IsFlexible(version int16) bool { return version >= 4 }
+
+// Encode serializes the message at the given version
+func (m *HeartbeatRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *HeartbeatRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported HeartbeatRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *HeartbeatRequest) Default() {
+	*m = HeartbeatRequest{}
+}
+
+func (m *HeartbeatRequest) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	w.String(m.GroupId, flexible)
+	w.Int32(m.GenerationId)
+	w.String(m.MemberId, flexible)
+	if version >= 3 {
+		w.NullableString(m.GroupInstanceId, flexible)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *HeartbeatRequest) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	m.GroupId = r.String(flexible)
+	m.GenerationId = r.Int32()
+	m.MemberId = r.String(flexible)
+	if version >= 3 {
+		m.GroupInstanceId = r.NullableString(flexible)
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
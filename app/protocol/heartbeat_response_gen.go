@@ -0,0 +1,68 @@
+// Code generated by gen from schemas/HeartbeatResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// HeartbeatResponse is a response of API key 12, versions 0-4
+type HeartbeatResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+}
+
+func (m *HeartbeatResponse) ApiKey() int16 { return 12 }
+
+func (m *HeartbeatResponse) MinVersion() int16 { return 0 }
+
+func (m *HeartbeatResponse) MaxVersion() int16 { return 4 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *HeartbeatResponse) IsFlexible(version int16) bool { return version >= 4 }
+
+// Encode serializes the message at the given version
+func (m *HeartbeatResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *HeartbeatResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported HeartbeatResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *HeartbeatResponse) Default() {
+	*m = HeartbeatResponse{}
+}
+
+func (m *HeartbeatResponse) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	if version >= 1 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *HeartbeatResponse) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	if version >= 1 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,82 @@
+// Code generated by gen from schemas/InitProducerIdRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// InitProducerIdRequest is a request of API key 22, versions 0-5
+type InitProducerIdRequest struct {
+	// The transactional id, or null if the producer is not transactional.
+	TransactionalId *string
+	// The time in ms to wait before aborting idle transactions sent by this producer. This is only relevant if a TransactionalId has been defined.
+	TransactionTimeoutMs int32
+	// The producer id. This is used to disambiguate requests if a transactional id is reused following its expiration.
+	ProducerId int64
+	// The producer's current epoch. This will be checked against the producer epoch on the broker, and the request will return an error if they do not match.
+	ProducerEpoch int16
+}
+
+func (m *InitProducerIdRequest) ApiKey() int16 { return 22 }
+
+func (m *InitProducerIdRequest) MinVersion() int16 { return 0 }
+
+func (m *InitProducerIdRequest) MaxVersion() int16 { return 5 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *InitProducerIdRequest) IsFlexible(version int16) bool { return version >= 2 }
+
+// Encode serializes the message at the given version
+func (m *InitProducerIdRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *InitProducerIdRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported InitProducerIdRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *InitProducerIdRequest) Default() {
+	*m = InitProducerIdRequest{}
+	m.ProducerId = -1
+	m.ProducerEpoch = -1
+}
+
+func (m *InitProducerIdRequest) encode(w *Writer, version int16) {
+	flexible := version >= 2
+	w.NullableString(m.TransactionalId, flexible)
+	w.Int32(m.TransactionTimeoutMs)
+	if version >= 3 {
+		w.Int64(m.ProducerId)
+	}
+	if version >= 3 {
+		w.Int16(m.ProducerEpoch)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *InitProducerIdRequest) decode(r *Reader, version int16) {
+	flexible := version >= 2
+	m.TransactionalId = r.NullableString(flexible)
+	m.TransactionTimeoutMs = r.Int32()
+	if version >= 3 {
+		m.ProducerId = r.Int64()
+	}
+	if version >= 3 {
+		m.ProducerEpoch = r.Int16()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,73 @@
+// Code generated by gen from schemas/InitProducerIdResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// InitProducerIdResponse is a response of API key 22, versions 0-5
+type InitProducerIdResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The current producer id.
+	ProducerId int64
+	// The current epoch associated with the producer id.
+	ProducerEpoch int16
+}
+
+func (m *InitProducerIdResponse) ApiKey() int16 { return 22 }
+
+func (m *InitProducerIdResponse) MinVersion() int16 { return 0 }
+
+func (m *InitProducerIdResponse) MaxVersion() int16 { return 5 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *InitProducerIdResponse) IsFlexible(version int16) bool { return version >= 2 }
+
+// Encode serializes the message at the given version
+func (m *InitProducerIdResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *InitProducerIdResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported InitProducerIdResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *InitProducerIdResponse) Default() {
+	*m = InitProducerIdResponse{}
+	m.ProducerId = -1
+}
+
+func (m *InitProducerIdResponse) encode(w *Writer, version int16) {
+	flexible := version >= 2
+	w.Int32(m.ThrottleTimeMs)
+	w.Int16(m.ErrorCode)
+	w.Int64(m.ProducerId)
+	w.Int16(m.ProducerEpoch)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *InitProducerIdResponse) decode(r *Reader, version int16) {
+	flexible := version >= 2
+	m.ThrottleTimeMs = r.Int32()
+	m.ErrorCode = r.Int16()
+	m.ProducerId = r.Int64()
+	m.ProducerEpoch = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,149 @@
+// Code generated by gen from schemas/JoinGroupRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// JoinGroupRequest is a request of API key 11, versions 0-9
+type JoinGroupRequest struct {
+	// The group identifier.
+	GroupId string
+	// The coordinator considers the consumer dead if it receives no heartbeat after this timeout in milliseconds.
+	SessionTimeoutMs int32
+	// The maximum time in milliseconds that the coordinator will wait for each member to rejoin when rebalancing the group.
+	RebalanceTimeoutMs int32
+	// The member id assigned by the group coordinator.
+	MemberId string
+	// The unique identifier of the consumer instance provided by end user.
+	GroupInstanceId *string
+	// The unique name the for class of protocols implemented by the group we want to join.
+	ProtocolType string
+	// The list of protocols that the member supports.
+	Protocols []JoinGroupRequestProtocol
+	// The reason why the member (re-)joins the group.
+	Reason *string
+}
+
+type JoinGroupRequestProtocol struct {
+	// The protocol name.
+	Name string
+	// The protocol metadata.
+	Metadata []byte
+}
+
+func (m *JoinGroupRequest) ApiKey() int16 { return 11 }
+
+func (m *JoinGroupRequest) MinVersion() int16 { return 0 }
+
+func (m *JoinGroupRequest) MaxVersion() int16 { return 9 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *JoinGroupRequest) IsFlexible(version int16) bool { return version >= 6 }
+
+// Encode serializes the message at the given version
+func (m *JoinGroupRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *JoinGroupRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported JoinGroupRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *JoinGroupRequest) Default() {
+	*m = JoinGroupRequest{}
+	m.RebalanceTimeoutMs = -1
+}
+
+func (m *JoinGroupRequest) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.String(m.GroupId, flexible)
+	w.Int32(m.SessionTimeoutMs)
+	if version >= 1 {
+		w.Int32(m.RebalanceTimeoutMs)
+	}
+	w.String(m.MemberId, flexible)
+	if version >= 5 {
+		w.NullableString(m.GroupInstanceId, flexible)
+	}
+	w.String(m.ProtocolType, flexible)
+	w.ArrayLen(len(m.Protocols), flexible)
+	for i := range m.Protocols {
+		m.Protocols[i].encode(w, version)
+	}
+	if version >= 8 {
+		w.NullableString(m.Reason, flexible)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *JoinGroupRequest) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.GroupId = r.String(flexible)
+	m.SessionTimeoutMs = r.Int32()
+	if version >= 1 {
+		m.RebalanceTimeoutMs = r.Int32()
+	}
+	m.MemberId = r.String(flexible)
+	if version >= 5 {
+		m.GroupInstanceId = r.NullableString(flexible)
+	}
+	m.ProtocolType = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Protocols = make([]JoinGroupRequestProtocol, n)
+		for i := range m.Protocols {
+			m.Protocols[i].Default()
+			m.Protocols[i].decode(r, version)
+		}
+	} else {
+		m.Protocols = nil
+	}
+	if version >= 8 {
+		m.Reason = r.NullableString(flexible)
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *JoinGroupRequestProtocol) Default() {
+	*m = JoinGroupRequestProtocol{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *JoinGroupRequestProtocol) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Metadata) == 0
+}
+
+func (m *JoinGroupRequestProtocol) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.String(m.Name, flexible)
+	w.Bytes(m.Metadata, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *JoinGroupRequestProtocol) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.Name = r.String(flexible)
+	m.Metadata = r.Bytes(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,176 @@
+// Code generated by gen from schemas/JoinGroupResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// JoinGroupResponse is a response of API key 11, versions 0-9
+type JoinGroupResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The generation ID of the group.
+	GenerationId int32
+	// The group protocol name.
+	ProtocolType *string
+	// The group protocol selected by the coordinator.
+	ProtocolName *string
+	// The leader of the group.
+	Leader string
+	// True if the leader must skip running the assignment.
+	SkipAssignment bool
+	// The member ID assigned by the group coordinator.
+	MemberId string
+	Members  []JoinGroupResponseMember
+}
+
+type JoinGroupResponseMember struct {
+	// The group member ID.
+	MemberId string
+	// The unique identifier of the consumer instance provided by end user.
+	GroupInstanceId *string
+	// The group member metadata.
+	Metadata []byte
+}
+
+func (m *JoinGroupResponse) ApiKey() int16 { return 11 }
+
+func (m *JoinGroupResponse) MinVersion() int16 { return 0 }
+
+func (m *JoinGroupResponse) MaxVersion() int16 { return 9 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *JoinGroupResponse) IsFlexible(version int16) bool { return version >= 6 }
+
+// Encode serializes the message at the given version
+func (m *JoinGroupResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *JoinGroupResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported JoinGroupResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *JoinGroupResponse) Default() {
+	*m = JoinGroupResponse{}
+	m.GenerationId = -1
+}
+
+func (m *JoinGroupResponse) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	if version >= 2 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.Int16(m.ErrorCode)
+	w.Int32(m.GenerationId)
+	if version >= 7 {
+		w.NullableString(m.ProtocolType, flexible)
+	}
+	if version >= 7 {
+		w.NullableString(m.ProtocolName, flexible)
+	} else {
+		if m.ProtocolName == nil {
+			w.String("", flexible)
+		} else {
+			w.String(*m.ProtocolName, flexible)
+		}
+	}
+	w.String(m.Leader, flexible)
+	if version >= 9 {
+		w.Bool(m.SkipAssignment)
+	}
+	w.String(m.MemberId, flexible)
+	w.ArrayLen(len(m.Members), flexible)
+	for i := range m.Members {
+		m.Members[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *JoinGroupResponse) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	if version >= 2 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	m.ErrorCode = r.Int16()
+	m.GenerationId = r.Int32()
+	if version >= 7 {
+		m.ProtocolType = r.NullableString(flexible)
+	}
+	if version >= 7 {
+		m.ProtocolName = r.NullableString(flexible)
+	} else {
+		{
+			s := r.String(flexible)
+			m.ProtocolName = &s
+		}
+	}
+	m.Leader = r.String(flexible)
+	if version >= 9 {
+		m.SkipAssignment = r.Bool()
+	}
+	m.MemberId = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Members = make([]JoinGroupResponseMember, n)
+		for i := range m.Members {
+			m.Members[i].Default()
+			m.Members[i].decode(r, version)
+		}
+	} else {
+		m.Members = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *JoinGroupResponseMember) Default() {
+	*m = JoinGroupResponseMember{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *JoinGroupResponseMember) isDefault() bool {
+	return m.MemberId == "" &&
+		m.GroupInstanceId == nil &&
+		len(m.Metadata) == 0
+}
+
+func (m *JoinGroupResponseMember) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.String(m.MemberId, flexible)
+	if version >= 5 {
+		w.NullableString(m.GroupInstanceId, flexible)
+	}
+	w.Bytes(m.Metadata, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *JoinGroupResponseMember) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.MemberId = r.String(flexible)
+	if version >= 5 {
+		m.GroupInstanceId = r.NullableString(flexible)
+	}
+	m.Metadata = r.Bytes(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,133 @@
+// Code generated by gen from schemas/LeaveGroupRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// LeaveGroupRequest is a request of API key 13, versions 0-5
+type LeaveGroupRequest struct {
+	// The ID of the group to leave.
+	GroupId string
+	// The member ID to remove from the group.
+	MemberId string
+	// List of leaving member identities.
+	Members []LeaveGroupRequestMemberIdentity
+}
+
+type LeaveGroupRequestMemberIdentity struct {
+	// The member ID to remove from the group.
+	MemberId string
+	// The group instance ID to remove from the group.
+	GroupInstanceId *string
+	// The reason why the member left the group.
+	Reason *string
+}
+
+func (m *LeaveGroupRequest) ApiKey() int16 { return 13 }
+
+func (m *LeaveGroupRequest) MinVersion() int16 { return 0 }
+
+func (m *LeaveGroupRequest) MaxVersion() int16 { return 5 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *LeaveGroupRequest) IsFlexible(version int16) bool { return version >= 4 }
+
+// Encode serializes the message at the given version
+func (m *LeaveGroupRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *LeaveGroupRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported LeaveGroupRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *LeaveGroupRequest) Default() {
+	*m = LeaveGroupRequest{}
+}
+
+func (m *LeaveGroupRequest) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	w.String(m.GroupId, flexible)
+	if version <= 2 {
+		w.String(m.MemberId, flexible)
+	}
+	if version >= 3 {
+		w.ArrayLen(len(m.Members), flexible)
+		for i := range m.Members {
+			m.Members[i].encode(w, version)
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *LeaveGroupRequest) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	m.GroupId = r.String(flexible)
+	if version <= 2 {
+		m.MemberId = r.String(flexible)
+	}
+	if version >= 3 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Members = make([]LeaveGroupRequestMemberIdentity, n)
+			for i := range m.Members {
+				m.Members[i].Default()
+				m.Members[i].decode(r, version)
+			}
+		} else {
+			m.Members = nil
+		}
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *LeaveGroupRequestMemberIdentity) Default() {
+	*m = LeaveGroupRequestMemberIdentity{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *LeaveGroupRequestMemberIdentity) isDefault() bool {
+	return m.MemberId == "" &&
+		m.GroupInstanceId == nil &&
+		m.Reason == nil
+}
+
+func (m *LeaveGroupRequestMemberIdentity) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	w.String(m.MemberId, flexible)
+	w.NullableString(m.GroupInstanceId, flexible)
+	if version >= 5 {
+		w.NullableString(m.Reason, flexible)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *LeaveGroupRequestMemberIdentity) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	m.MemberId = r.String(flexible)
+	m.GroupInstanceId = r.NullableString(flexible)
+	if version >= 5 {
+		m.Reason = r.NullableString(flexible)
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,129 @@
+// Code generated by gen from schemas/LeaveGroupResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// LeaveGroupResponse is a response of API key 13, versions 0-5
+type LeaveGroupResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// List of leaving member responses.
+	Members []LeaveGroupResponseMemberResponse
+}
+
+type LeaveGroupResponseMemberResponse struct {
+	// The member ID to remove from the group.
+	MemberId string
+	// The group instance ID to remove from the group.
+	GroupInstanceId *string
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+}
+
+func (m *LeaveGroupResponse) ApiKey() int16 { return 13 }
+
+func (m *LeaveGroupResponse) MinVersion() int16 { return 0 }
+
+func (m *LeaveGroupResponse) MaxVersion() int16 { return 5 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *LeaveGroupResponse) IsFlexible(version int16) bool { return version >= 4 }
+
+// Encode serializes the message at the given version
+func (m *LeaveGroupResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *LeaveGroupResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported LeaveGroupResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *LeaveGroupResponse) Default() {
+	*m = LeaveGroupResponse{}
+}
+
+func (m *LeaveGroupResponse) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	if version >= 1 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.Int16(m.ErrorCode)
+	if version >= 3 {
+		w.ArrayLen(len(m.Members), flexible)
+		for i := range m.Members {
+			m.Members[i].encode(w, version)
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *LeaveGroupResponse) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	if version >= 1 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	m.ErrorCode = r.Int16()
+	if version >= 3 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Members = make([]LeaveGroupResponseMemberResponse, n)
+			for i := range m.Members {
+				m.Members[i].Default()
+				m.Members[i].decode(r, version)
+			}
+		} else {
+			m.Members = nil
+		}
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *LeaveGroupResponseMemberResponse) Default() {
+	*m = LeaveGroupResponseMemberResponse{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *LeaveGroupResponseMemberResponse) isDefault() bool {
+	return m.MemberId == "" &&
+		m.GroupInstanceId == nil &&
+		m.ErrorCode == 0
+}
+
+func (m *LeaveGroupResponseMemberResponse) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	w.String(m.MemberId, flexible)
+	w.NullableString(m.GroupInstanceId, flexible)
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *LeaveGroupResponseMemberResponse) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	m.MemberId = r.String(flexible)
+	m.GroupInstanceId = r.NullableString(flexible)
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,188 @@
+// Code generated by gen from schemas/ListOffsetsRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// ListOffsetsRequest is a request of API key 2, versions 0-8
+type ListOffsetsRequest struct {
+	// The broker ID of the requester, or -1 if this request is being made by a normal consumer.
+	ReplicaId int32
+	// This setting controls the visibility of transactional records. Using READ_UNCOMMITTED (isolation_level = 0) makes all records visible. With READ_COMMITTED (isolation_level = 1), non-transactional and COMMITTED transactional records are visible. To be more concrete, READ_COMMITTED returns all data from offsets smaller than the current LSO (last stable offset), and enables the inclusion of the list of aborted transactions in the result, which allows consumers to discard ABORTED transactional records
+	IsolationLevel int8
+	// Each topic in the request.
+	Topics []ListOffsetsRequestListOffsetsTopic
+}
+
+type ListOffsetsRequestListOffsetsTopic struct {
+	// The topic name.
+	Name string
+	// Each partition in the request.
+	Partitions []ListOffsetsRequestListOffsetsPartition
+}
+
+type ListOffsetsRequestListOffsetsPartition struct {
+	// The partition index.
+	PartitionIndex int32
+	// The current leader epoch.
+	CurrentLeaderEpoch int32
+	// The current timestamp.
+	Timestamp int64
+	// The maximum number of offsets to report.
+	MaxNumOffsets int32
+}
+
+func (m *ListOffsetsRequest) ApiKey() int16 { return 2 }
+
+func (m *ListOffsetsRequest) MinVersion() int16 { return 0 }
+
+func (m *ListOffsetsRequest) MaxVersion() int16 { return 8 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *ListOffsetsRequest) IsFlexible(version int16) bool { return version >= 6 }
+
+// Encode serializes the message at the given version
+func (m *ListOffsetsRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *ListOffsetsRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported ListOffsetsRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *ListOffsetsRequest) Default() {
+	*m = ListOffsetsRequest{}
+}
+
+func (m *ListOffsetsRequest) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.Int32(m.ReplicaId)
+	if version >= 2 {
+		w.Int8(m.IsolationLevel)
+	}
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ListOffsetsRequest) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.ReplicaId = r.Int32()
+	if version >= 2 {
+		m.IsolationLevel = r.Int8()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]ListOffsetsRequestListOffsetsTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ListOffsetsRequestListOffsetsTopic) Default() {
+	*m = ListOffsetsRequestListOffsetsTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ListOffsetsRequestListOffsetsTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *ListOffsetsRequestListOffsetsTopic) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ListOffsetsRequestListOffsetsTopic) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]ListOffsetsRequestListOffsetsPartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ListOffsetsRequestListOffsetsPartition) Default() {
+	*m = ListOffsetsRequestListOffsetsPartition{}
+	m.CurrentLeaderEpoch = -1
+	m.MaxNumOffsets = 1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ListOffsetsRequestListOffsetsPartition) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.CurrentLeaderEpoch == -1 &&
+		m.Timestamp == 0 &&
+		m.MaxNumOffsets == 1
+}
+
+func (m *ListOffsetsRequestListOffsetsPartition) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.Int32(m.PartitionIndex)
+	if version >= 4 {
+		w.Int32(m.CurrentLeaderEpoch)
+	}
+	w.Int64(m.Timestamp)
+	if version <= 0 {
+		w.Int32(m.MaxNumOffsets)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ListOffsetsRequestListOffsetsPartition) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.PartitionIndex = r.Int32()
+	if version >= 4 {
+		m.CurrentLeaderEpoch = r.Int32()
+	}
+	m.Timestamp = r.Int64()
+	if version <= 0 {
+		m.MaxNumOffsets = r.Int32()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,213 @@
+// Code generated by gen from schemas/ListOffsetsResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// ListOffsetsResponse is a response of API key 2, versions 0-8
+type ListOffsetsResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// Each topic in the response.
+	Topics []ListOffsetsResponseListOffsetsTopicResponse
+}
+
+type ListOffsetsResponseListOffsetsTopicResponse struct {
+	// The topic name
+	Name string
+	// Each partition in the response.
+	Partitions []ListOffsetsResponseListOffsetsPartitionResponse
+}
+
+type ListOffsetsResponseListOffsetsPartitionResponse struct {
+	// The partition index.
+	PartitionIndex int32
+	// The partition error code, or 0 if there was no error.
+	ErrorCode int16
+	// The result offsets.
+	OldStyleOffsets []int64
+	// The timestamp associated with the returned offset.
+	Timestamp int64
+	// The returned offset.
+	Offset int64
+	// The leader epoch associated with the returned offset.
+	LeaderEpoch int32
+}
+
+func (m *ListOffsetsResponse) ApiKey() int16 { return 2 }
+
+func (m *ListOffsetsResponse) MinVersion() int16 { return 0 }
+
+func (m *ListOffsetsResponse) MaxVersion() int16 { return 8 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *ListOffsetsResponse) IsFlexible(version int16) bool { return version >= 6 }
+
+// Encode serializes the message at the given version
+func (m *ListOffsetsResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *ListOffsetsResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported ListOffsetsResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *ListOffsetsResponse) Default() {
+	*m = ListOffsetsResponse{}
+}
+
+func (m *ListOffsetsResponse) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	if version >= 2 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ListOffsetsResponse) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	if version >= 2 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]ListOffsetsResponseListOffsetsTopicResponse, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ListOffsetsResponseListOffsetsTopicResponse) Default() {
+	*m = ListOffsetsResponseListOffsetsTopicResponse{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ListOffsetsResponseListOffsetsTopicResponse) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *ListOffsetsResponseListOffsetsTopicResponse) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ListOffsetsResponseListOffsetsTopicResponse) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]ListOffsetsResponseListOffsetsPartitionResponse, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ListOffsetsResponseListOffsetsPartitionResponse) Default() {
+	*m = ListOffsetsResponseListOffsetsPartitionResponse{}
+	m.Timestamp = -1
+	m.Offset = -1
+	m.LeaderEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ListOffsetsResponseListOffsetsPartitionResponse) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.ErrorCode == 0 &&
+		len(m.OldStyleOffsets) == 0 &&
+		m.Timestamp == -1 &&
+		m.Offset == -1 &&
+		m.LeaderEpoch == -1
+}
+
+func (m *ListOffsetsResponseListOffsetsPartitionResponse) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.Int32(m.PartitionIndex)
+	w.Int16(m.ErrorCode)
+	if version <= 0 {
+		w.ArrayLen(len(m.OldStyleOffsets), flexible)
+		for i := range m.OldStyleOffsets {
+			w.Int64(m.OldStyleOffsets[i])
+		}
+	}
+	if version >= 1 {
+		w.Int64(m.Timestamp)
+	}
+	if version >= 1 {
+		w.Int64(m.Offset)
+	}
+	if version >= 4 {
+		w.Int32(m.LeaderEpoch)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ListOffsetsResponseListOffsetsPartitionResponse) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.PartitionIndex = r.Int32()
+	m.ErrorCode = r.Int16()
+	if version <= 0 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.OldStyleOffsets = make([]int64, n)
+			for i := range m.OldStyleOffsets {
+				m.OldStyleOffsets[i] = r.Int64()
+			}
+		} else {
+			m.OldStyleOffsets = nil
+		}
+	}
+	if version >= 1 {
+		m.Timestamp = r.Int64()
+	}
+	if version >= 1 {
+		m.Offset = r.Int64()
+	}
+	if version >= 4 {
+		m.LeaderEpoch = r.Int32()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,175 @@
+// Code generated by gen from schemas/MetadataRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// MetadataRequest is a request of API key 3, versions 0-12
+type MetadataRequest struct {
+	// The topics to fetch metadata for.
+	Topics []MetadataRequestTopic
+	// If this is true, the broker may auto-create topics that we requested which do not already exist, if it is configured to do so.
+	AllowAutoTopicCreation bool
+	// Whether to include cluster authorized operations.
+	IncludeClusterAuthorizedOperations bool
+	// Whether to include topic authorized operations.
+	IncludeTopicAuthorizedOperations bool
+}
+
+type MetadataRequestTopic struct {
+	// The topic id.
+	TopicId [16]byte
+	// The topic name.
+	Name *string
+}
+
+func (m *MetadataRequest) ApiKey() int16 { return 3 }
+
+func (m *MetadataRequest) MinVersion() int16 { return 0 }
+
+func (m *MetadataRequest) MaxVersion() int16 { return 12 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *MetadataRequest) IsFlexible(version int16) bool { return version >= 9 }
+
+// Encode serializes the message at the given version
+func (m *MetadataRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *MetadataRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported MetadataRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *MetadataRequest) Default() {
+	*m = MetadataRequest{}
+	m.AllowAutoTopicCreation = true
+}
+
+func (m *MetadataRequest) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	if version >= 1 {
+		if m.Topics == nil {
+			w.NullArray(flexible)
+		} else {
+			w.ArrayLen(len(m.Topics), flexible)
+			for i := range m.Topics {
+				m.Topics[i].encode(w, version)
+			}
+		}
+	} else {
+		w.ArrayLen(len(m.Topics), flexible)
+		for i := range m.Topics {
+			m.Topics[i].encode(w, version)
+		}
+	}
+	if version >= 4 {
+		w.Bool(m.AllowAutoTopicCreation)
+	}
+	if version >= 8 && version <= 10 {
+		w.Bool(m.IncludeClusterAuthorizedOperations)
+	}
+	if version >= 8 {
+		w.Bool(m.IncludeTopicAuthorizedOperations)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *MetadataRequest) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	if version >= 1 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Topics = make([]MetadataRequestTopic, n)
+			for i := range m.Topics {
+				m.Topics[i].Default()
+				m.Topics[i].decode(r, version)
+			}
+		} else {
+			m.Topics = nil
+		}
+	} else {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Topics = make([]MetadataRequestTopic, n)
+			for i := range m.Topics {
+				m.Topics[i].Default()
+				m.Topics[i].decode(r, version)
+			}
+		} else {
+			m.Topics = nil
+		}
+	}
+	if version >= 4 {
+		m.AllowAutoTopicCreation = r.Bool()
+	}
+	if version >= 8 && version <= 10 {
+		m.IncludeClusterAuthorizedOperations = r.Bool()
+	}
+	if version >= 8 {
+		m.IncludeTopicAuthorizedOperations = r.Bool()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *MetadataRequestTopic) Default() {
+	*m = MetadataRequestTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *MetadataRequestTopic) isDefault() bool {
+	return m.TopicId == [16]byte{} &&
+		m.Name == nil
+}
+
+func (m *MetadataRequestTopic) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	if version >= 10 {
+		w.UUID(m.TopicId)
+	}
+	if version >= 10 {
+		w.NullableString(m.Name, flexible)
+	} else {
+		if m.Name == nil {
+			w.String("", flexible)
+		} else {
+			w.String(*m.Name, flexible)
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *MetadataRequestTopic) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	if version >= 10 {
+		m.TopicId = r.UUID()
+	}
+	if version >= 10 {
+		m.Name = r.NullableString(flexible)
+	} else {
+		{
+			s := r.String(flexible)
+			m.Name = &s
+		}
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,368 @@
+// Code generated by gen from schemas/MetadataResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// MetadataResponse is a response of API key 3, versions 0-12
+type MetadataResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// A list of brokers present in the cluster.
+	Brokers []MetadataResponseBroker
+	// The cluster ID that responding broker belongs to.
+	ClusterId *string
+	// The ID of the controller broker.
+	ControllerId int32
+	// Each topic in the response.
+	Topics []MetadataResponseTopic
+	// 32-bit bitfield to represent authorized operations for this cluster.
+	ClusterAuthorizedOperations int32
+}
+
+type MetadataResponseBroker struct {
+	// The broker ID.
+	NodeId int32
+	// The broker hostname.
+	Host string
+	// The broker port.
+	Port int32
+	// The rack of the broker, or null if it has not been assigned to a rack.
+	Rack *string
+}
+
+type MetadataResponseTopic struct {
+	// The topic error, or 0 if there was no error.
+	ErrorCode int16
+	// The topic name. Null for non-existing topics queried by ID. This is never null when ErrorCode is zero. One of Name and TopicId is always populated.
+	Name *string
+	// The topic id. Zero for non-existing topics queried by name. This is never zero when ErrorCode is zero. One of Name and TopicId is always populated.
+	TopicId [16]byte
+	// True if the topic is internal.
+	IsInternal bool
+	// Each partition in the topic.
+	Partitions []MetadataResponsePartition
+	// 32-bit bitfield to represent authorized operations for this topic.
+	TopicAuthorizedOperations int32
+}
+
+type MetadataResponsePartition struct {
+	// The partition error, or 0 if there was no error.
+	ErrorCode int16
+	// The partition index.
+	PartitionIndex int32
+	// The ID of the leader broker.
+	LeaderId int32
+	// The leader epoch of this partition.
+	LeaderEpoch int32
+	// The set of all nodes that host this partition.
+	ReplicaNodes []int32
+	// The set of nodes that are in sync with the leader for this partition.
+	IsrNodes []int32
+	// The set of offline replicas of this partition.
+	OfflineReplicas []int32
+}
+
+func (m *MetadataResponse) ApiKey() int16 { return 3 }
+
+func (m *MetadataResponse) MinVersion() int16 { return 0 }
+
+func (m *MetadataResponse) MaxVersion() int16 { return 12 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *MetadataResponse) IsFlexible(version int16) bool { return version >= 9 }
+
+// Encode serializes the message at the given version
+func (m *MetadataResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *MetadataResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported MetadataResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *MetadataResponse) Default() {
+	*m = MetadataResponse{}
+	m.ControllerId = -1
+	m.ClusterAuthorizedOperations = -2147483648
+}
+
+func (m *MetadataResponse) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	if version >= 3 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.ArrayLen(len(m.Brokers), flexible)
+	for i := range m.Brokers {
+		m.Brokers[i].encode(w, version)
+	}
+	if version >= 2 {
+		w.NullableString(m.ClusterId, flexible)
+	}
+	if version >= 1 {
+		w.Int32(m.ControllerId)
+	}
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if version >= 8 && version <= 10 {
+		w.Int32(m.ClusterAuthorizedOperations)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *MetadataResponse) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	if version >= 3 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Brokers = make([]MetadataResponseBroker, n)
+		for i := range m.Brokers {
+			m.Brokers[i].Default()
+			m.Brokers[i].decode(r, version)
+		}
+	} else {
+		m.Brokers = nil
+	}
+	if version >= 2 {
+		m.ClusterId = r.NullableString(flexible)
+	}
+	if version >= 1 {
+		m.ControllerId = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]MetadataResponseTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if version >= 8 && version <= 10 {
+		m.ClusterAuthorizedOperations = r.Int32()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *MetadataResponseBroker) Default() {
+	*m = MetadataResponseBroker{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *MetadataResponseBroker) isDefault() bool {
+	return m.NodeId == 0 &&
+		m.Host == "" &&
+		m.Port == 0 &&
+		m.Rack == nil
+}
+
+func (m *MetadataResponseBroker) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.Int32(m.NodeId)
+	w.String(m.Host, flexible)
+	w.Int32(m.Port)
+	if version >= 1 {
+		w.NullableString(m.Rack, flexible)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *MetadataResponseBroker) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	m.NodeId = r.Int32()
+	m.Host = r.String(flexible)
+	m.Port = r.Int32()
+	if version >= 1 {
+		m.Rack = r.NullableString(flexible)
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *MetadataResponseTopic) Default() {
+	*m = MetadataResponseTopic{}
+	m.TopicAuthorizedOperations = -2147483648
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *MetadataResponseTopic) isDefault() bool {
+	return m.ErrorCode == 0 &&
+		m.Name == nil &&
+		m.TopicId == [16]byte{} &&
+		!m.IsInternal &&
+		len(m.Partitions) == 0 &&
+		m.TopicAuthorizedOperations == -2147483648
+}
+
+func (m *MetadataResponseTopic) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.Int16(m.ErrorCode)
+	if version >= 12 {
+		w.NullableString(m.Name, flexible)
+	} else {
+		if m.Name == nil {
+			w.String("", flexible)
+		} else {
+			w.String(*m.Name, flexible)
+		}
+	}
+	if version >= 10 {
+		w.UUID(m.TopicId)
+	}
+	if version >= 1 {
+		w.Bool(m.IsInternal)
+	}
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if version >= 8 {
+		w.Int32(m.TopicAuthorizedOperations)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *MetadataResponseTopic) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	m.ErrorCode = r.Int16()
+	if version >= 12 {
+		m.Name = r.NullableString(flexible)
+	} else {
+		{
+			s := r.String(flexible)
+			m.Name = &s
+		}
+	}
+	if version >= 10 {
+		m.TopicId = r.UUID()
+	}
+	if version >= 1 {
+		m.IsInternal = r.Bool()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]MetadataResponsePartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if version >= 8 {
+		m.TopicAuthorizedOperations = r.Int32()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *MetadataResponsePartition) Default() {
+	*m = MetadataResponsePartition{}
+	m.LeaderEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *MetadataResponsePartition) isDefault() bool {
+	return m.ErrorCode == 0 &&
+		m.PartitionIndex == 0 &&
+		m.LeaderId == 0 &&
+		m.LeaderEpoch == -1 &&
+		len(m.ReplicaNodes) == 0 &&
+		len(m.IsrNodes) == 0 &&
+		len(m.OfflineReplicas) == 0
+}
+
+func (m *MetadataResponsePartition) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.Int16(m.ErrorCode)
+	w.Int32(m.PartitionIndex)
+	w.Int32(m.LeaderId)
+	if version >= 7 {
+		w.Int32(m.LeaderEpoch)
+	}
+	w.ArrayLen(len(m.ReplicaNodes), flexible)
+	for i := range m.ReplicaNodes {
+		w.Int32(m.ReplicaNodes[i])
+	}
+	w.ArrayLen(len(m.IsrNodes), flexible)
+	for i := range m.IsrNodes {
+		w.Int32(m.IsrNodes[i])
+	}
+	if version >= 5 {
+		w.ArrayLen(len(m.OfflineReplicas), flexible)
+		for i := range m.OfflineReplicas {
+			w.Int32(m.OfflineReplicas[i])
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *MetadataResponsePartition) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	m.ErrorCode = r.Int16()
+	m.PartitionIndex = r.Int32()
+	m.LeaderId = r.Int32()
+	if version >= 7 {
+		m.LeaderEpoch = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.ReplicaNodes = make([]int32, n)
+		for i := range m.ReplicaNodes {
+			m.ReplicaNodes[i] = r.Int32()
+		}
+	} else {
+		m.ReplicaNodes = nil
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.IsrNodes = make([]int32, n)
+		for i := range m.IsrNodes {
+			m.IsrNodes[i] = r.Int32()
+		}
+	} else {
+		m.IsrNodes = nil
+	}
+	if version >= 5 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.OfflineReplicas = make([]int32, n)
+			for i := range m.OfflineReplicas {
+				m.OfflineReplicas[i] = r.Int32()
+			}
+		} else {
+			m.OfflineReplicas = nil
+		}
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,219 @@
+// Code generated by gen from schemas/OffsetCommitRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// OffsetCommitRequest is a request of API key 8, versions 0-9
+type OffsetCommitRequest struct {
+	// The unique group identifier.
+	GroupId string
+	// The generation of the group if using the classic group protocol or the member epoch if using the consumer protocol.
+	GenerationIdOrMemberEpoch int32
+	// The member ID assigned by the group coordinator.
+	MemberId string
+	// The unique identifier of the consumer instance provided by end user.
+	GroupInstanceId *string
+	// The time period in ms to retain the offset.
+	RetentionTimeMs int64
+	// The topics to commit offsets for.
+	Topics []OffsetCommitRequestTopic
+}
+
+type OffsetCommitRequestTopic struct {
+	// The topic name.
+	Name string
+	// Each partition to commit offsets for.
+	Partitions []OffsetCommitRequestPartition
+}
+
+type OffsetCommitRequestPartition struct {
+	// The partition index.
+	PartitionIndex int32
+	// The message offset to be committed.
+	CommittedOffset int64
+	// The leader epoch of this partition.
+	CommittedLeaderEpoch int32
+	// The timestamp of the commit.
+	CommitTimestamp int64
+	// Any associated metadata the client wants to keep.
+	CommittedMetadata *string
+}
+
+func (m *OffsetCommitRequest) ApiKey() int16 { return 8 }
+
+func (m *OffsetCommitRequest) MinVersion() int16 { return 0 }
+
+func (m *OffsetCommitRequest) MaxVersion() int16 { return 9 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *OffsetCommitRequest) IsFlexible(version int16) bool { return version >= 8 }
+
+// Encode serializes the message at the given version
+func (m *OffsetCommitRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *OffsetCommitRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported OffsetCommitRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *OffsetCommitRequest) Default() {
+	*m = OffsetCommitRequest{}
+	m.GenerationIdOrMemberEpoch = -1
+	m.RetentionTimeMs = -1
+}
+
+func (m *OffsetCommitRequest) encode(w *Writer, version int16) {
+	flexible := version >= 8
+	w.String(m.GroupId, flexible)
+	if version >= 1 {
+		w.Int32(m.GenerationIdOrMemberEpoch)
+	}
+	if version >= 1 {
+		w.String(m.MemberId, flexible)
+	}
+	if version >= 7 {
+		w.NullableString(m.GroupInstanceId, flexible)
+	}
+	if version >= 2 && version <= 4 {
+		w.Int64(m.RetentionTimeMs)
+	}
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetCommitRequest) decode(r *Reader, version int16) {
+	flexible := version >= 8
+	m.GroupId = r.String(flexible)
+	if version >= 1 {
+		m.GenerationIdOrMemberEpoch = r.Int32()
+	}
+	if version >= 1 {
+		m.MemberId = r.String(flexible)
+	}
+	if version >= 7 {
+		m.GroupInstanceId = r.NullableString(flexible)
+	}
+	if version >= 2 && version <= 4 {
+		m.RetentionTimeMs = r.Int64()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]OffsetCommitRequestTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetCommitRequestTopic) Default() {
+	*m = OffsetCommitRequestTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetCommitRequestTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *OffsetCommitRequestTopic) encode(w *Writer, version int16) {
+	flexible := version >= 8
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetCommitRequestTopic) decode(r *Reader, version int16) {
+	flexible := version >= 8
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]OffsetCommitRequestPartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetCommitRequestPartition) Default() {
+	*m = OffsetCommitRequestPartition{}
+	m.CommittedLeaderEpoch = -1
+	m.CommitTimestamp = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetCommitRequestPartition) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.CommittedOffset == 0 &&
+		m.CommittedLeaderEpoch == -1 &&
+		m.CommitTimestamp == -1 &&
+		m.CommittedMetadata == nil
+}
+
+func (m *OffsetCommitRequestPartition) encode(w *Writer, version int16) {
+	flexible := version >= 8
+	w.Int32(m.PartitionIndex)
+	w.Int64(m.CommittedOffset)
+	if version >= 6 {
+		w.Int32(m.CommittedLeaderEpoch)
+	}
+	if version >= 1 && version <= 1 {
+		w.Int64(m.CommitTimestamp)
+	}
+	w.NullableString(m.CommittedMetadata, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetCommitRequestPartition) decode(r *Reader, version int16) {
+	flexible := version >= 8
+	m.PartitionIndex = r.Int32()
+	m.CommittedOffset = r.Int64()
+	if version >= 6 {
+		m.CommittedLeaderEpoch = r.Int32()
+	}
+	if version >= 1 && version <= 1 {
+		m.CommitTimestamp = r.Int64()
+	}
+	m.CommittedMetadata = r.NullableString(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,164 @@
+// Code generated by gen from schemas/OffsetCommitResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// OffsetCommitResponse is a response of API key 8, versions 0-9
+type OffsetCommitResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The responses for each topic.
+	Topics []OffsetCommitResponseTopic
+}
+
+type OffsetCommitResponseTopic struct {
+	// The topic name.
+	Name string
+	// The responses for each partition in the topic.
+	Partitions []OffsetCommitResponsePartition
+}
+
+type OffsetCommitResponsePartition struct {
+	// The partition index.
+	PartitionIndex int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+}
+
+func (m *OffsetCommitResponse) ApiKey() int16 { return 8 }
+
+func (m *OffsetCommitResponse) MinVersion() int16 { return 0 }
+
+func (m *OffsetCommitResponse) MaxVersion() int16 { return 9 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *OffsetCommitResponse) IsFlexible(version int16) bool { return version >= 8 }
+
+// Encode serializes the message at the given version
+func (m *OffsetCommitResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *OffsetCommitResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported OffsetCommitResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *OffsetCommitResponse) Default() {
+	*m = OffsetCommitResponse{}
+}
+
+func (m *OffsetCommitResponse) encode(w *Writer, version int16) {
+	flexible := version >= 8
+	if version >= 3 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetCommitResponse) decode(r *Reader, version int16) {
+	flexible := version >= 8
+	if version >= 3 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]OffsetCommitResponseTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetCommitResponseTopic) Default() {
+	*m = OffsetCommitResponseTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetCommitResponseTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *OffsetCommitResponseTopic) encode(w *Writer, version int16) {
+	flexible := version >= 8
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetCommitResponseTopic) decode(r *Reader, version int16) {
+	flexible := version >= 8
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]OffsetCommitResponsePartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetCommitResponsePartition) Default() {
+	*m = OffsetCommitResponsePartition{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetCommitResponsePartition) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.ErrorCode == 0
+}
+
+func (m *OffsetCommitResponsePartition) encode(w *Writer, version int16) {
+	flexible := version >= 8
+	w.Int32(m.PartitionIndex)
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetCommitResponsePartition) decode(r *Reader, version int16) {
+	flexible := version >= 8
+	m.PartitionIndex = r.Int32()
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,298 @@
+// Code generated by gen from schemas/OffsetFetchRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// OffsetFetchRequest is a request of API key 9, versions 0-9
+type OffsetFetchRequest struct {
+	// The group to fetch offsets for.
+	GroupId string
+	// Each topic we would like to fetch offsets for, or null to fetch offsets for all topics.
+	Topics []OffsetFetchRequestTopic
+	// Each group we would like to fetch offsets for
+	Groups []OffsetFetchRequestGroup
+	// Whether broker should hold on returning unstable offsets but set a retriable error code for the partitions.
+	RequireStable bool
+}
+
+type OffsetFetchRequestTopic struct {
+	// The topic name.
+	Name string
+	// The partition indexes we would like to fetch offsets for.
+	PartitionIndexes []int32
+}
+
+type OffsetFetchRequestGroup struct {
+	// The group ID.
+	GroupId string
+	// The member ID assigned by the group coordinator if using the new consumer protocol (KIP-848).
+	MemberId *string
+	// The member epoch if using the new consumer protocol (KIP-848).
+	MemberEpoch int32
+	// Each topic we would like to fetch offsets for, or null to fetch offsets for all topics.
+	Topics []OffsetFetchRequestTopics
+}
+
+type OffsetFetchRequestTopics struct {
+	// The topic name.
+	Name string
+	// The partition indexes we would like to fetch offsets for.
+	PartitionIndexes []int32
+}
+
+func (m *OffsetFetchRequest) ApiKey() int16 { return 9 }
+
+func (m *OffsetFetchRequest) MinVersion() int16 { return 0 }
+
+func (m *OffsetFetchRequest) MaxVersion() int16 { return 9 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *OffsetFetchRequest) IsFlexible(version int16) bool { return version >= 6 }
+
+// Encode serializes the message at the given version
+func (m *OffsetFetchRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *OffsetFetchRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported OffsetFetchRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchRequest) Default() {
+	*m = OffsetFetchRequest{}
+}
+
+func (m *OffsetFetchRequest) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	if version <= 7 {
+		w.String(m.GroupId, flexible)
+	}
+	if version <= 7 {
+		if version >= 2 {
+			if m.Topics == nil {
+				w.NullArray(flexible)
+			} else {
+				w.ArrayLen(len(m.Topics), flexible)
+				for i := range m.Topics {
+					m.Topics[i].encode(w, version)
+				}
+			}
+		} else {
+			w.ArrayLen(len(m.Topics), flexible)
+			for i := range m.Topics {
+				m.Topics[i].encode(w, version)
+			}
+		}
+	}
+	if version >= 8 {
+		w.ArrayLen(len(m.Groups), flexible)
+		for i := range m.Groups {
+			m.Groups[i].encode(w, version)
+		}
+	}
+	if version >= 7 {
+		w.Bool(m.RequireStable)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchRequest) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	if version <= 7 {
+		m.GroupId = r.String(flexible)
+	}
+	if version <= 7 {
+		if version >= 2 {
+			if n := r.ArrayLen(flexible); n >= 0 {
+				m.Topics = make([]OffsetFetchRequestTopic, n)
+				for i := range m.Topics {
+					m.Topics[i].Default()
+					m.Topics[i].decode(r, version)
+				}
+			} else {
+				m.Topics = nil
+			}
+		} else {
+			if n := r.ArrayLen(flexible); n >= 0 {
+				m.Topics = make([]OffsetFetchRequestTopic, n)
+				for i := range m.Topics {
+					m.Topics[i].Default()
+					m.Topics[i].decode(r, version)
+				}
+			} else {
+				m.Topics = nil
+			}
+		}
+	}
+	if version >= 8 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Groups = make([]OffsetFetchRequestGroup, n)
+			for i := range m.Groups {
+				m.Groups[i].Default()
+				m.Groups[i].decode(r, version)
+			}
+		} else {
+			m.Groups = nil
+		}
+	}
+	if version >= 7 {
+		m.RequireStable = r.Bool()
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchRequestTopic) Default() {
+	*m = OffsetFetchRequestTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetFetchRequestTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.PartitionIndexes) == 0
+}
+
+func (m *OffsetFetchRequestTopic) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.PartitionIndexes), flexible)
+	for i := range m.PartitionIndexes {
+		w.Int32(m.PartitionIndexes[i])
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchRequestTopic) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.PartitionIndexes = make([]int32, n)
+		for i := range m.PartitionIndexes {
+			m.PartitionIndexes[i] = r.Int32()
+		}
+	} else {
+		m.PartitionIndexes = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchRequestGroup) Default() {
+	*m = OffsetFetchRequestGroup{}
+	m.MemberEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetFetchRequestGroup) isDefault() bool {
+	return m.GroupId == "" &&
+		m.MemberId == nil &&
+		m.MemberEpoch == -1 &&
+		m.Topics == nil
+}
+
+func (m *OffsetFetchRequestGroup) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.GroupId, flexible)
+	if version >= 9 {
+		w.NullableString(m.MemberId, flexible)
+	}
+	if version >= 9 {
+		w.Int32(m.MemberEpoch)
+	}
+	if m.Topics == nil {
+		w.NullArray(flexible)
+	} else {
+		w.ArrayLen(len(m.Topics), flexible)
+		for i := range m.Topics {
+			m.Topics[i].encode(w, version)
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchRequestGroup) decode(r *Reader, version int16) {
+	flexible := true
+	m.GroupId = r.String(flexible)
+	if version >= 9 {
+		m.MemberId = r.NullableString(flexible)
+	}
+	if version >= 9 {
+		m.MemberEpoch = r.Int32()
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]OffsetFetchRequestTopics, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchRequestTopics) Default() {
+	*m = OffsetFetchRequestTopics{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetFetchRequestTopics) isDefault() bool {
+	return m.Name == "" &&
+		len(m.PartitionIndexes) == 0
+}
+
+func (m *OffsetFetchRequestTopics) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.PartitionIndexes), flexible)
+	for i := range m.PartitionIndexes {
+		w.Int32(m.PartitionIndexes[i])
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchRequestTopics) decode(r *Reader, version int16) {
+	flexible := true
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.PartitionIndexes = make([]int32, n)
+		for i := range m.PartitionIndexes {
+			m.PartitionIndexes[i] = r.Int32()
+		}
+	} else {
+		m.PartitionIndexes = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
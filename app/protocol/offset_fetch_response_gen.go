@@ -0,0 +1,369 @@
+// Code generated by gen from schemas/OffsetFetchResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// OffsetFetchResponse is a response of API key 9, versions 0-9
+type OffsetFetchResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The responses per topic.
+	Topics []OffsetFetchResponseTopic
+	// The top-level error code, or 0 if there was no error.
+	ErrorCode int16
+	// The responses per group id.
+	Groups []OffsetFetchResponseGroup
+}
+
+type OffsetFetchResponseTopic struct {
+	// The topic name.
+	Name string
+	// The responses per partition
+	Partitions []OffsetFetchResponsePartition
+}
+
+type OffsetFetchResponsePartition struct {
+	// The partition index.
+	PartitionIndex int32
+	// The committed message offset.
+	CommittedOffset int64
+	// The leader epoch.
+	CommittedLeaderEpoch int32
+	// The partition metadata.
+	Metadata *string
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+}
+
+type OffsetFetchResponseGroup struct {
+	// The group ID.
+	GroupId string
+	// The responses per topic.
+	Topics []OffsetFetchResponseTopics
+	// The group-level error code, or 0 if there was no error.
+	ErrorCode int16
+}
+
+type OffsetFetchResponseTopics struct {
+	// The topic name.
+	Name string
+	// The responses per partition
+	Partitions []OffsetFetchResponsePartitions
+}
+
+type OffsetFetchResponsePartitions struct {
+	// The partition index.
+	PartitionIndex int32
+	// The committed message offset.
+	CommittedOffset int64
+	// The leader epoch.
+	CommittedLeaderEpoch int32
+	// The partition metadata.
+	Metadata *string
+	// The partition-level error code, or 0 if there was no error.
+	ErrorCode int16
+}
+
+func (m *OffsetFetchResponse) ApiKey() int16 { return 9 }
+
+func (m *OffsetFetchResponse) MinVersion() int16 { return 0 }
+
+func (m *OffsetFetchResponse) MaxVersion() int16 { return 9 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *OffsetFetchResponse) IsFlexible(version int16) bool { return version >= 6 }
+
+// Encode serializes the message at the given version
+func (m *OffsetFetchResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *OffsetFetchResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported OffsetFetchResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchResponse) Default() {
+	*m = OffsetFetchResponse{}
+}
+
+func (m *OffsetFetchResponse) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	if version >= 3 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	if version <= 7 {
+		w.ArrayLen(len(m.Topics), flexible)
+		for i := range m.Topics {
+			m.Topics[i].encode(w, version)
+		}
+	}
+	if version >= 2 && version <= 7 {
+		w.Int16(m.ErrorCode)
+	}
+	if version >= 8 {
+		w.ArrayLen(len(m.Groups), flexible)
+		for i := range m.Groups {
+			m.Groups[i].encode(w, version)
+		}
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchResponse) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	if version >= 3 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if version <= 7 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Topics = make([]OffsetFetchResponseTopic, n)
+			for i := range m.Topics {
+				m.Topics[i].Default()
+				m.Topics[i].decode(r, version)
+			}
+		} else {
+			m.Topics = nil
+		}
+	}
+	if version >= 2 && version <= 7 {
+		m.ErrorCode = r.Int16()
+	}
+	if version >= 8 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.Groups = make([]OffsetFetchResponseGroup, n)
+			for i := range m.Groups {
+				m.Groups[i].Default()
+				m.Groups[i].decode(r, version)
+			}
+		} else {
+			m.Groups = nil
+		}
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchResponseTopic) Default() {
+	*m = OffsetFetchResponseTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetFetchResponseTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *OffsetFetchResponseTopic) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchResponseTopic) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]OffsetFetchResponsePartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchResponsePartition) Default() {
+	*m = OffsetFetchResponsePartition{}
+	m.CommittedLeaderEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetFetchResponsePartition) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.CommittedOffset == 0 &&
+		m.CommittedLeaderEpoch == -1 &&
+		m.Metadata == nil &&
+		m.ErrorCode == 0
+}
+
+func (m *OffsetFetchResponsePartition) encode(w *Writer, version int16) {
+	flexible := version >= 6
+	w.Int32(m.PartitionIndex)
+	w.Int64(m.CommittedOffset)
+	if version >= 5 {
+		w.Int32(m.CommittedLeaderEpoch)
+	}
+	w.NullableString(m.Metadata, flexible)
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchResponsePartition) decode(r *Reader, version int16) {
+	flexible := version >= 6
+	m.PartitionIndex = r.Int32()
+	m.CommittedOffset = r.Int64()
+	if version >= 5 {
+		m.CommittedLeaderEpoch = r.Int32()
+	}
+	m.Metadata = r.NullableString(flexible)
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchResponseGroup) Default() {
+	*m = OffsetFetchResponseGroup{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetFetchResponseGroup) isDefault() bool {
+	return m.GroupId == "" &&
+		len(m.Topics) == 0 &&
+		m.ErrorCode == 0
+}
+
+func (m *OffsetFetchResponseGroup) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.GroupId, flexible)
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchResponseGroup) decode(r *Reader, version int16) {
+	flexible := true
+	m.GroupId = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]OffsetFetchResponseTopics, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchResponseTopics) Default() {
+	*m = OffsetFetchResponseTopics{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetFetchResponseTopics) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *OffsetFetchResponseTopics) encode(w *Writer, version int16) {
+	flexible := true
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchResponseTopics) decode(r *Reader, version int16) {
+	flexible := true
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]OffsetFetchResponsePartitions, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *OffsetFetchResponsePartitions) Default() {
+	*m = OffsetFetchResponsePartitions{}
+	m.CommittedLeaderEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *OffsetFetchResponsePartitions) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.CommittedOffset == 0 &&
+		m.CommittedLeaderEpoch == -1 &&
+		m.Metadata == nil &&
+		m.ErrorCode == 0
+}
+
+func (m *OffsetFetchResponsePartitions) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int32(m.PartitionIndex)
+	w.Int64(m.CommittedOffset)
+	w.Int32(m.CommittedLeaderEpoch)
+	w.NullableString(m.Metadata, flexible)
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *OffsetFetchResponsePartitions) decode(r *Reader, version int16) {
+	flexible := true
+	m.PartitionIndex = r.Int32()
+	m.CommittedOffset = r.Int64()
+	m.CommittedLeaderEpoch = r.Int32()
+	m.Metadata = r.NullableString(flexible)
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,172 @@
+// Code generated by gen from schemas/ProduceRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// ProduceRequest is a request of API key 0, versions 0-11
+type ProduceRequest struct {
+	// The transactional ID, or null if the producer is not transactional.
+	TransactionalId *string
+	// The number of acknowledgments the producer requires the leader to have received before considering a request complete. Allowed values: 0 for no acknowledgments, 1 for only the leader and -1 for the full ISR.
+	Acks int16
+	// The timeout to await a response in milliseconds.
+	TimeoutMs int32
+	// Each topic to produce to.
+	TopicData []ProduceRequestTopicProduceData
+}
+
+type ProduceRequestTopicProduceData struct {
+	// The topic name.
+	Name string
+	// Each partition to produce to.
+	PartitionData []ProduceRequestPartitionProduceData
+}
+
+type ProduceRequestPartitionProduceData struct {
+	// The partition index.
+	Index int32
+	// The record data to be produced.
+	Records []byte
+}
+
+func (m *ProduceRequest) ApiKey() int16 { return 0 }
+
+func (m *ProduceRequest) MinVersion() int16 { return 0 }
+
+func (m *ProduceRequest) MaxVersion() int16 { return 11 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *ProduceRequest) IsFlexible(version int16) bool { return version >= 9 }
+
+// Encode serializes the message at the given version
+func (m *ProduceRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *ProduceRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported ProduceRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *ProduceRequest) Default() {
+	*m = ProduceRequest{}
+}
+
+func (m *ProduceRequest) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	if version >= 3 {
+		w.NullableString(m.TransactionalId, flexible)
+	}
+	w.Int16(m.Acks)
+	w.Int32(m.TimeoutMs)
+	w.ArrayLen(len(m.TopicData), flexible)
+	for i := range m.TopicData {
+		m.TopicData[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ProduceRequest) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	if version >= 3 {
+		m.TransactionalId = r.NullableString(flexible)
+	}
+	m.Acks = r.Int16()
+	m.TimeoutMs = r.Int32()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.TopicData = make([]ProduceRequestTopicProduceData, n)
+		for i := range m.TopicData {
+			m.TopicData[i].Default()
+			m.TopicData[i].decode(r, version)
+		}
+	} else {
+		m.TopicData = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ProduceRequestTopicProduceData) Default() {
+	*m = ProduceRequestTopicProduceData{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ProduceRequestTopicProduceData) isDefault() bool {
+	return m.Name == "" &&
+		len(m.PartitionData) == 0
+}
+
+func (m *ProduceRequestTopicProduceData) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.PartitionData), flexible)
+	for i := range m.PartitionData {
+		m.PartitionData[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ProduceRequestTopicProduceData) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.PartitionData = make([]ProduceRequestPartitionProduceData, n)
+		for i := range m.PartitionData {
+			m.PartitionData[i].Default()
+			m.PartitionData[i].decode(r, version)
+		}
+	} else {
+		m.PartitionData = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ProduceRequestPartitionProduceData) Default() {
+	*m = ProduceRequestPartitionProduceData{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ProduceRequestPartitionProduceData) isDefault() bool {
+	return m.Index == 0 &&
+		m.Records == nil
+}
+
+func (m *ProduceRequestPartitionProduceData) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.Int32(m.Index)
+	w.NullableBytes(m.Records, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ProduceRequestPartitionProduceData) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	m.Index = r.Int32()
+	m.Records = r.NullableBytes(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
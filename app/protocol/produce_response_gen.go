@@ -0,0 +1,388 @@
+// Code generated by gen from schemas/ProduceResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// ProduceResponse is a response of API key 0, versions 0-11
+type ProduceResponse struct {
+	// Each produce response
+	Responses []ProduceResponseTopicProduceResponse
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// Endpoints for all current-leaders enumerated in PartitionProduceResponses, with errors NOT_LEADER_OR_FOLLOWER.
+	NodeEndpoints []ProduceResponseNodeEndpoint
+}
+
+type ProduceResponseTopicProduceResponse struct {
+	// The topic name
+	Name string
+	// Each partition that we produced to within the topic.
+	PartitionResponses []ProduceResponsePartitionProduceResponse
+}
+
+type ProduceResponsePartitionProduceResponse struct {
+	// The partition index.
+	Index int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The base offset.
+	BaseOffset int64
+	// The timestamp returned by broker after appending the messages. If CreateTime is used for the topic, the timestamp will be -1.  If LogAppendTime is used for the topic, the timestamp will be the broker local time when the messages are appended.
+	LogAppendTimeMs int64
+	// The log start offset.
+	LogStartOffset int64
+	// The batch indices of records that caused the batch to be dropped
+	RecordErrors []ProduceResponseBatchIndexAndErrorMessage
+	// The global error message summarizing the common root cause of the records that caused the batch to be dropped
+	ErrorMessage *string
+	// The leader broker that the producer should use for future requests.
+	CurrentLeader ProduceResponseLeaderIdAndEpoch
+}
+
+type ProduceResponseBatchIndexAndErrorMessage struct {
+	// The batch index of the record that cause the batch to be dropped
+	BatchIndex int32
+	// The error message of the record that caused the batch to be dropped
+	BatchIndexErrorMessage *string
+}
+
+type ProduceResponseLeaderIdAndEpoch struct {
+	// The ID of the current leader or -1 if the leader is unknown.
+	LeaderId int32
+	// The latest known leader epoch
+	LeaderEpoch int32
+}
+
+type ProduceResponseNodeEndpoint struct {
+	// The ID of the associated node.
+	NodeId int32
+	// The node's hostname.
+	Host string
+	// The node's port.
+	Port int32
+	// The rack of the node, or null if it has not been assigned to a rack.
+	Rack *string
+}
+
+func (m *ProduceResponse) ApiKey() int16 { return 0 }
+
+func (m *ProduceResponse) MinVersion() int16 { return 0 }
+
+func (m *ProduceResponse) MaxVersion() int16 { return 11 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *ProduceResponse) IsFlexible(version int16) bool { return version >= 9 }
+
+// Encode serializes the message at the given version
+func (m *ProduceResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *ProduceResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported ProduceResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *ProduceResponse) Default() {
+	*m = ProduceResponse{}
+}
+
+func (m *ProduceResponse) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.ArrayLen(len(m.Responses), flexible)
+	for i := range m.Responses {
+		m.Responses[i].encode(w, version)
+	}
+	if version >= 1 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	if flexible {
+		var tagged []TaggedField
+		if version >= 10 && len(m.NodeEndpoints) > 0 {
+			tw := NewWriter()
+			tw.ArrayLen(len(m.NodeEndpoints), flexible)
+			for i := range m.NodeEndpoints {
+				m.NodeEndpoints[i].encode(tw, version)
+			}
+			tagged = append(tagged, TaggedField{Tag: 0, Data: tw.Buf()})
+		}
+		w.TaggedFields(tagged)
+	}
+}
+
+func (m *ProduceResponse) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Responses = make([]ProduceResponseTopicProduceResponse, n)
+		for i := range m.Responses {
+			m.Responses[i].Default()
+			m.Responses[i].decode(r, version)
+		}
+	} else {
+		m.Responses = nil
+	}
+	if version >= 1 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	if flexible {
+		for _, field := range r.TaggedFields() {
+			switch {
+			case field.Tag == 0 && version >= 10:
+				tr := NewReader(field.Data)
+				if n := tr.ArrayLen(flexible); n >= 0 {
+					m.NodeEndpoints = make([]ProduceResponseNodeEndpoint, n)
+					for i := range m.NodeEndpoints {
+						m.NodeEndpoints[i].Default()
+						m.NodeEndpoints[i].decode(tr, version)
+					}
+				} else {
+					m.NodeEndpoints = nil
+				}
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			}
+		}
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ProduceResponseTopicProduceResponse) Default() {
+	*m = ProduceResponseTopicProduceResponse{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ProduceResponseTopicProduceResponse) isDefault() bool {
+	return m.Name == "" &&
+		len(m.PartitionResponses) == 0
+}
+
+func (m *ProduceResponseTopicProduceResponse) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.PartitionResponses), flexible)
+	for i := range m.PartitionResponses {
+		m.PartitionResponses[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ProduceResponseTopicProduceResponse) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.PartitionResponses = make([]ProduceResponsePartitionProduceResponse, n)
+		for i := range m.PartitionResponses {
+			m.PartitionResponses[i].Default()
+			m.PartitionResponses[i].decode(r, version)
+		}
+	} else {
+		m.PartitionResponses = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ProduceResponsePartitionProduceResponse) Default() {
+	*m = ProduceResponsePartitionProduceResponse{}
+	m.LogAppendTimeMs = -1
+	m.LogStartOffset = -1
+	m.CurrentLeader.Default()
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ProduceResponsePartitionProduceResponse) isDefault() bool {
+	return m.Index == 0 &&
+		m.ErrorCode == 0 &&
+		m.BaseOffset == 0 &&
+		m.LogAppendTimeMs == -1 &&
+		m.LogStartOffset == -1 &&
+		len(m.RecordErrors) == 0 &&
+		m.ErrorMessage == nil &&
+		m.CurrentLeader.isDefault()
+}
+
+func (m *ProduceResponsePartitionProduceResponse) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.Int32(m.Index)
+	w.Int16(m.ErrorCode)
+	w.Int64(m.BaseOffset)
+	if version >= 2 {
+		w.Int64(m.LogAppendTimeMs)
+	}
+	if version >= 5 {
+		w.Int64(m.LogStartOffset)
+	}
+	if version >= 8 {
+		w.ArrayLen(len(m.RecordErrors), flexible)
+		for i := range m.RecordErrors {
+			m.RecordErrors[i].encode(w, version)
+		}
+	}
+	if version >= 8 {
+		w.NullableString(m.ErrorMessage, flexible)
+	}
+	if flexible {
+		var tagged []TaggedField
+		if version >= 10 && !m.CurrentLeader.isDefault() {
+			tw := NewWriter()
+			m.CurrentLeader.encode(tw, version)
+			tagged = append(tagged, TaggedField{Tag: 0, Data: tw.Buf()})
+		}
+		w.TaggedFields(tagged)
+	}
+}
+
+func (m *ProduceResponsePartitionProduceResponse) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	m.Index = r.Int32()
+	m.ErrorCode = r.Int16()
+	m.BaseOffset = r.Int64()
+	if version >= 2 {
+		m.LogAppendTimeMs = r.Int64()
+	}
+	if version >= 5 {
+		m.LogStartOffset = r.Int64()
+	}
+	if version >= 8 {
+		if n := r.ArrayLen(flexible); n >= 0 {
+			m.RecordErrors = make([]ProduceResponseBatchIndexAndErrorMessage, n)
+			for i := range m.RecordErrors {
+				m.RecordErrors[i].Default()
+				m.RecordErrors[i].decode(r, version)
+			}
+		} else {
+			m.RecordErrors = nil
+		}
+	}
+	if version >= 8 {
+		m.ErrorMessage = r.NullableString(flexible)
+	}
+	if flexible {
+		for _, field := range r.TaggedFields() {
+			switch {
+			case field.Tag == 0 && version >= 10:
+				tr := NewReader(field.Data)
+				m.CurrentLeader.decode(tr, version)
+				if err := tr.Err(); err != nil {
+					r.Fail(err)
+				}
+			}
+		}
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ProduceResponseBatchIndexAndErrorMessage) Default() {
+	*m = ProduceResponseBatchIndexAndErrorMessage{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ProduceResponseBatchIndexAndErrorMessage) isDefault() bool {
+	return m.BatchIndex == 0 &&
+		m.BatchIndexErrorMessage == nil
+}
+
+func (m *ProduceResponseBatchIndexAndErrorMessage) encode(w *Writer, version int16) {
+	flexible := version >= 9
+	w.Int32(m.BatchIndex)
+	w.NullableString(m.BatchIndexErrorMessage, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ProduceResponseBatchIndexAndErrorMessage) decode(r *Reader, version int16) {
+	flexible := version >= 9
+	m.BatchIndex = r.Int32()
+	m.BatchIndexErrorMessage = r.NullableString(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ProduceResponseLeaderIdAndEpoch) Default() {
+	*m = ProduceResponseLeaderIdAndEpoch{}
+	m.LeaderId = -1
+	m.LeaderEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ProduceResponseLeaderIdAndEpoch) isDefault() bool {
+	return m.LeaderId == -1 &&
+		m.LeaderEpoch == -1
+}
+
+func (m *ProduceResponseLeaderIdAndEpoch) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int32(m.LeaderId)
+	w.Int32(m.LeaderEpoch)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ProduceResponseLeaderIdAndEpoch) decode(r *Reader, version int16) {
+	flexible := true
+	m.LeaderId = r.Int32()
+	m.LeaderEpoch = r.Int32()
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *ProduceResponseNodeEndpoint) Default() {
+	*m = ProduceResponseNodeEndpoint{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *ProduceResponseNodeEndpoint) isDefault() bool {
+	return m.NodeId == 0 &&
+		m.Host == "" &&
+		m.Port == 0 &&
+		m.Rack == nil
+}
+
+func (m *ProduceResponseNodeEndpoint) encode(w *Writer, version int16) {
+	flexible := true
+	w.Int32(m.NodeId)
+	w.String(m.Host, flexible)
+	w.Int32(m.Port)
+	w.NullableString(m.Rack, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *ProduceResponseNodeEndpoint) decode(r *Reader, version int16) {
+	flexible := true
+	m.NodeId = r.Int32()
+	m.Host = r.String(flexible)
+	m.Port = r.Int32()
+	m.Rack = r.NullableString(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
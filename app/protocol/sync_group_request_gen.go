@@ -0,0 +1,144 @@
+// Code generated by gen from schemas/SyncGroupRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// SyncGroupRequest is a request of API key 14, versions 0-5
+type SyncGroupRequest struct {
+	// The unique group identifier.
+	GroupId string
+	// The generation of the group.
+	GenerationId int32
+	// The member ID assigned by the group.
+	MemberId string
+	// The unique identifier of the consumer instance provided by end user.
+	GroupInstanceId *string
+	// The group protocol type.
+	ProtocolType *string
+	// The group protocol name.
+	ProtocolName *string
+	// Each assignment.
+	Assignments []SyncGroupRequestAssignment
+}
+
+type SyncGroupRequestAssignment struct {
+	// The ID of the member to assign.
+	MemberId string
+	// The member assignment.
+	Assignment []byte
+}
+
+func (m *SyncGroupRequest) ApiKey() int16 { return 14 }
+
+func (m *SyncGroupRequest) MinVersion() int16 { return 0 }
+
+func (m *SyncGroupRequest) MaxVersion() int16 { return 5 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *SyncGroupRequest) IsFlexible(version int16) bool { return version >= 4 }
+
+// Encode serializes the message at the given version
+func (m *SyncGroupRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *SyncGroupRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported SyncGroupRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *SyncGroupRequest) Default() {
+	*m = SyncGroupRequest{}
+}
+
+func (m *SyncGroupRequest) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	w.String(m.GroupId, flexible)
+	w.Int32(m.GenerationId)
+	w.String(m.MemberId, flexible)
+	if version >= 3 {
+		w.NullableString(m.GroupInstanceId, flexible)
+	}
+	if version >= 5 {
+		w.NullableString(m.ProtocolType, flexible)
+	}
+	if version >= 5 {
+		w.NullableString(m.ProtocolName, flexible)
+	}
+	w.ArrayLen(len(m.Assignments), flexible)
+	for i := range m.Assignments {
+		m.Assignments[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *SyncGroupRequest) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	m.GroupId = r.String(flexible)
+	m.GenerationId = r.Int32()
+	m.MemberId = r.String(flexible)
+	if version >= 3 {
+		m.GroupInstanceId = r.NullableString(flexible)
+	}
+	if version >= 5 {
+		m.ProtocolType = r.NullableString(flexible)
+	}
+	if version >= 5 {
+		m.ProtocolName = r.NullableString(flexible)
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Assignments = make([]SyncGroupRequestAssignment, n)
+		for i := range m.Assignments {
+			m.Assignments[i].Default()
+			m.Assignments[i].decode(r, version)
+		}
+	} else {
+		m.Assignments = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *SyncGroupRequestAssignment) Default() {
+	*m = SyncGroupRequestAssignment{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *SyncGroupRequestAssignment) isDefault() bool {
+	return m.MemberId == "" &&
+		len(m.Assignment) == 0
+}
+
+func (m *SyncGroupRequestAssignment) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	w.String(m.MemberId, flexible)
+	w.Bytes(m.Assignment, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *SyncGroupRequestAssignment) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	m.MemberId = r.String(flexible)
+	m.Assignment = r.Bytes(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
@@ -0,0 +1,88 @@
+// Code generated by gen from schemas/SyncGroupResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// SyncGroupResponse is a response of API key 14, versions 0-5
+type SyncGroupResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+	// The group protocol type.
+	ProtocolType *string
+	// The group protocol name.
+	ProtocolName *string
+	// The member assignment.
+	Assignment []byte
+}
+
+func (m *SyncGroupResponse) ApiKey() int16 { return 14 }
+
+func (m *SyncGroupResponse) MinVersion() int16 { return 0 }
+
+func (m *SyncGroupResponse) MaxVersion() int16 { return 5 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *SyncGroupResponse) IsFlexible(version int16) bool { return version >= 4 }
+
+// Encode serializes the message at the given version
+func (m *SyncGroupResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *SyncGroupResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported SyncGroupResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *SyncGroupResponse) Default() {
+	*m = SyncGroupResponse{}
+}
+
+func (m *SyncGroupResponse) encode(w *Writer, version int16) {
+	flexible := version >= 4
+	if version >= 1 {
+		w.Int32(m.ThrottleTimeMs)
+	}
+	w.Int16(m.ErrorCode)
+	if version >= 5 {
+		w.NullableString(m.ProtocolType, flexible)
+	}
+	if version >= 5 {
+		w.NullableString(m.ProtocolName, flexible)
+	}
+	w.Bytes(m.Assignment, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *SyncGroupResponse) decode(r *Reader, version int16) {
+	flexible := version >= 4
+	if version >= 1 {
+		m.ThrottleTimeMs = r.Int32()
+	}
+	m.ErrorCode = r.Int16()
+	if version >= 5 {
+		m.ProtocolType = r.NullableString(flexible)
+	}
+	if version >= 5 {
+		m.ProtocolName = r.NullableString(flexible)
+	}
+	m.Assignment = r.Bytes(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
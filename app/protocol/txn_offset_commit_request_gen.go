@@ -0,0 +1,212 @@
+// Code generated by gen from schemas/TxnOffsetCommitRequest.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// TxnOffsetCommitRequest is a request of API key 28, versions 0-3
+type TxnOffsetCommitRequest struct {
+	// The ID of the transaction.
+	TransactionalId string
+	// The ID of the group.
+	GroupId string
+	// The current producer ID in use by the transactional ID.
+	ProducerId int64
+	// The current epoch associated with the producer ID.
+	ProducerEpoch int16
+	// The generation of the consumer.
+	GenerationId int32
+	// The member ID assigned by the group coordinator.
+	MemberId string
+	// The unique identifier of the consumer instance provided by end user.
+	GroupInstanceId *string
+	// Each topic that we want to commit offsets for.
+	Topics []TxnOffsetCommitRequestTopic
+}
+
+type TxnOffsetCommitRequestTopic struct {
+	// The topic name.
+	Name string
+	// The partitions inside the topic that we want to commit offsets for.
+	Partitions []TxnOffsetCommitRequestPartition
+}
+
+type TxnOffsetCommitRequestPartition struct {
+	// The index of the partition within the topic.
+	PartitionIndex int32
+	// The message offset to be committed.
+	CommittedOffset int64
+	// The leader epoch of the last consumed record.
+	CommittedLeaderEpoch int32
+	// Any associated metadata the client wants to keep.
+	CommittedMetadata *string
+}
+
+func (m *TxnOffsetCommitRequest) ApiKey() int16 { return 28 }
+
+func (m *TxnOffsetCommitRequest) MinVersion() int16 { return 0 }
+
+func (m *TxnOffsetCommitRequest) MaxVersion() int16 { return 3 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *TxnOffsetCommitRequest) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *TxnOffsetCommitRequest) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *TxnOffsetCommitRequest) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported TxnOffsetCommitRequest version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *TxnOffsetCommitRequest) Default() {
+	*m = TxnOffsetCommitRequest{}
+	m.GenerationId = -1
+}
+
+func (m *TxnOffsetCommitRequest) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.String(m.TransactionalId, flexible)
+	w.String(m.GroupId, flexible)
+	w.Int64(m.ProducerId)
+	w.Int16(m.ProducerEpoch)
+	if version >= 3 {
+		w.Int32(m.GenerationId)
+	}
+	if version >= 3 {
+		w.String(m.MemberId, flexible)
+	}
+	if version >= 3 {
+		w.NullableString(m.GroupInstanceId, flexible)
+	}
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *TxnOffsetCommitRequest) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.TransactionalId = r.String(flexible)
+	m.GroupId = r.String(flexible)
+	m.ProducerId = r.Int64()
+	m.ProducerEpoch = r.Int16()
+	if version >= 3 {
+		m.GenerationId = r.Int32()
+	}
+	if version >= 3 {
+		m.MemberId = r.String(flexible)
+	}
+	if version >= 3 {
+		m.GroupInstanceId = r.NullableString(flexible)
+	}
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]TxnOffsetCommitRequestTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *TxnOffsetCommitRequestTopic) Default() {
+	*m = TxnOffsetCommitRequestTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *TxnOffsetCommitRequestTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *TxnOffsetCommitRequestTopic) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *TxnOffsetCommitRequestTopic) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]TxnOffsetCommitRequestPartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *TxnOffsetCommitRequestPartition) Default() {
+	*m = TxnOffsetCommitRequestPartition{}
+	m.CommittedLeaderEpoch = -1
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *TxnOffsetCommitRequestPartition) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.CommittedOffset == 0 &&
+		m.CommittedLeaderEpoch == -1 &&
+		m.CommittedMetadata == nil
+}
+
+func (m *TxnOffsetCommitRequestPartition) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.Int32(m.PartitionIndex)
+	w.Int64(m.CommittedOffset)
+	if version >= 2 {
+		w.Int32(m.CommittedLeaderEpoch)
+	}
+	w.NullableString(m.CommittedMetadata, flexible)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *TxnOffsetCommitRequestPartition) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.PartitionIndex = r.Int32()
+	m.CommittedOffset = r.Int64()
+	if version >= 2 {
+		m.CommittedLeaderEpoch = r.Int32()
+	}
+	m.CommittedMetadata = r.NullableString(flexible)
+	if flexible {
+		r.TaggedFields()
+	}
+}
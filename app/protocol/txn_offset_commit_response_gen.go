@@ -0,0 +1,160 @@
+// Code generated by gen from schemas/TxnOffsetCommitResponse.json. DO NOT EDIT.
+
+package protocol
+
+import "fmt"
+
+// TxnOffsetCommitResponse is a response of API key 28, versions 0-3
+type TxnOffsetCommitResponse struct {
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// The responses for each topic.
+	Topics []TxnOffsetCommitResponseTopic
+}
+
+type TxnOffsetCommitResponseTopic struct {
+	// The topic name.
+	Name string
+	// The responses for each partition in the topic.
+	Partitions []TxnOffsetCommitResponsePartition
+}
+
+type TxnOffsetCommitResponsePartition struct {
+	// The partition index.
+	PartitionIndex int32
+	// The error code, or 0 if there was no error.
+	ErrorCode int16
+}
+
+func (m *TxnOffsetCommitResponse) ApiKey() int16 { return 28 }
+
+func (m *TxnOffsetCommitResponse) MinVersion() int16 { return 0 }
+
+func (m *TxnOffsetCommitResponse) MaxVersion() int16 { return 3 }
+
+// IsFlexible reports whether version uses compact encodings and tagged fields
+func (m *TxnOffsetCommitResponse) IsFlexible(version int16) bool { return version >= 3 }
+
+// Encode serializes the message at the given version
+func (m *TxnOffsetCommitResponse) Encode(version int16) []byte {
+	w := NewWriter()
+	m.encode(w, version)
+	return w.Buf()
+}
+
+// Decode parses data encoded at the given version. Fields absent from that
+// version keep their default values.
+func (m *TxnOffsetCommitResponse) Decode(data []byte, version int16) error {
+	if version < m.MinVersion() || version > m.MaxVersion() {
+		return fmt.Errorf("protocol: unsupported TxnOffsetCommitResponse version %d", version)
+	}
+	r := NewReader(data)
+	m.Default()
+	m.decode(r, version)
+	return r.Err()
+}
+
+// Default sets every field to its schema default
+func (m *TxnOffsetCommitResponse) Default() {
+	*m = TxnOffsetCommitResponse{}
+}
+
+func (m *TxnOffsetCommitResponse) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.Int32(m.ThrottleTimeMs)
+	w.ArrayLen(len(m.Topics), flexible)
+	for i := range m.Topics {
+		m.Topics[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *TxnOffsetCommitResponse) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.ThrottleTimeMs = r.Int32()
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Topics = make([]TxnOffsetCommitResponseTopic, n)
+		for i := range m.Topics {
+			m.Topics[i].Default()
+			m.Topics[i].decode(r, version)
+		}
+	} else {
+		m.Topics = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *TxnOffsetCommitResponseTopic) Default() {
+	*m = TxnOffsetCommitResponseTopic{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *TxnOffsetCommitResponseTopic) isDefault() bool {
+	return m.Name == "" &&
+		len(m.Partitions) == 0
+}
+
+func (m *TxnOffsetCommitResponseTopic) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.String(m.Name, flexible)
+	w.ArrayLen(len(m.Partitions), flexible)
+	for i := range m.Partitions {
+		m.Partitions[i].encode(w, version)
+	}
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *TxnOffsetCommitResponseTopic) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.Name = r.String(flexible)
+	if n := r.ArrayLen(flexible); n >= 0 {
+		m.Partitions = make([]TxnOffsetCommitResponsePartition, n)
+		for i := range m.Partitions {
+			m.Partitions[i].Default()
+			m.Partitions[i].decode(r, version)
+		}
+	} else {
+		m.Partitions = nil
+	}
+	if flexible {
+		r.TaggedFields()
+	}
+}
+
+// Default sets every field to its schema default
+func (m *TxnOffsetCommitResponsePartition) Default() {
+	*m = TxnOffsetCommitResponsePartition{}
+}
+
+// isDefault reports whether every field holds its default, in which case a
+// tagged field of this type is left out
+func (m *TxnOffsetCommitResponsePartition) isDefault() bool {
+	return m.PartitionIndex == 0 &&
+		m.ErrorCode == 0
+}
+
+func (m *TxnOffsetCommitResponsePartition) encode(w *Writer, version int16) {
+	flexible := version >= 3
+	w.Int32(m.PartitionIndex)
+	w.Int16(m.ErrorCode)
+	if flexible {
+		w.TaggedFields(nil)
+	}
+}
+
+func (m *TxnOffsetCommitResponsePartition) decode(r *Reader, version int16) {
+	flexible := version >= 3
+	m.PartitionIndex = r.Int32()
+	m.ErrorCode = r.Int16()
+	if flexible {
+		r.TaggedFields()
+	}
+}
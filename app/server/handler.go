@@ -1,11 +1,55 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"sync"
+	"time"
+
+	"kafgo/app/group"
+	"kafgo/app/metadata"
+	"kafgo/app/protocol"
+	"kafgo/app/storage"
+	"kafgo/app/txn"
 )
 
+// GroupCoordinator serves every consumer group; this broker coordinates all of them
+var GroupCoordinator = group.NewCoordinator()
+
+// ProducerIDs hands out IDs to idempotent and transactional producers
+var ProducerIDs = txn.NewProducerIDManager(BrokerNodeID)
+
+// TxnCoordinator serves every transactional ID; this broker coordinates all of them
+var TxnCoordinator = txn.NewCoordinator(ProducerIDs, onTxnMarker)
+
+func init() {
+	// Set here rather than passed to NewCoordinator: the transaction
+	// coordinator refers back to the group coordinator through onTxnMarker
+	GroupCoordinator.SetTxnVerifier(verifyTxnOffsets)
+}
+
+// verifyTxnOffsets checks that AddOffsetsToTxn added __consumer_offsets, which
+// holds every group, to the producer's transaction
+func verifyTxnOffsets(transactionalID string, producerID int64, producerEpoch int16) int16 {
+	return TxnCoordinator.VerifyPartition(transactionalID, producerID, producerEpoch,
+		txn.TopicPartition{Topic: group.ConsumerOffsetsTopic, Partition: 0})
+}
+
+// errNoResponse is returned by handlers whose request must not be answered at all
+var errNoResponse = errors.New("request is not answered")
+
+// onTxnMarker completes the offsets of a transaction once its marker reaches
+// __consumer_offsets and wakes fetches waiting on the partition, whose last
+// stable offset may have moved
+func onTxnMarker(tp txn.TopicPartition, producerID int64, commit bool) {
+	if tp.Topic == group.ConsumerOffsetsTopic {
+		GroupCoordinator.CompleteTxn(producerID, commit)
+	}
+	fetchPurgatory.CheckAndComplete(partitionKey(tp.Topic, tp.Partition))
+}
+
 func HandleConnection(conn net.Conn) {
 	defer conn.Close()
 	fmt.Println("Connection established from", conn.RemoteAddr())
@@ -19,28 +63,74 @@ func HandleConnection(conn net.Conn) {
 			return
 		}
 
-		response := HandleRequest(header, body)
+		response, err := HandleRequest(header, body)
+		if err == errNoResponse {
+			// An acks=0 Produce is never answered
+			continue
+		}
+		if response == nil {
+			// A malformed request whose response has no top-level error code
+			// cannot be answered; close the connection like Kafka does
+			fmt.Println("Closing connection after invalid request from", conn.RemoteAddr())
+			return
+		}
 
-		if err := WriteResponse(conn, header.CorrelationID, response); err != nil {
+		if err := WriteResponse(conn, header, response); err != nil {
 			fmt.Println("Error writing response:", err)
 			return
 		}
 	}
 }
 
-func HandleRequest(header RequestHeader, body []byte) []byte {
+// HandleRequest answers one request. A nil response means the request cannot
+// be answered and the connection is closed; errNoResponse means it must not be.
+func HandleRequest(header RequestHeader, body []byte) ([]byte, error) {
 	switch header.ApiKey {
 	case 0:
 		return HandleProduce(header, body)
 	case 18:
-		return HandleApiVersions(header, body)
+		return HandleApiVersions(header, body), nil
+	case 19:
+		return HandleCreateTopics(header, body), nil
+	case 20:
+		return HandleDeleteTopics(header, body), nil
+	case 22:
+		return HandleInitProducerId(header, body), nil
+	case 24:
+		return HandleAddPartitionsToTxn(header, body), nil
+	case 25:
+		return HandleAddOffsetsToTxn(header, body), nil
+	case 26:
+		return HandleEndTxn(header, body), nil
+	case 28:
+		return HandleTxnOffsetCommit(header, body), nil
+	case 35:
+		return HandleDescribeLogDirs(header, body), nil
 	case 75:
-		return HandleDescribeTopicPartitions(header, body)
+		return HandleDescribeTopicPartitions(header, body), nil
 	case 1:
-		return HandleFetch(header, body)
+		return HandleFetch(header, body), nil
+	case 2:
+		return HandleListOffsets(header, body), nil
+	case 3:
+		return HandleMetadata(header, body), nil
+	case 8:
+		return HandleOffsetCommit(header, body), nil
+	case 9:
+		return HandleOffsetFetch(header, body), nil
+	case 10:
+		return HandleFindCoordinator(header, body), nil
+	case 11:
+		return HandleJoinGroup(header, body), nil
+	case 12:
+		return HandleHeartbeat(header, body), nil
+	case 13:
+		return HandleLeaveGroup(header, body), nil
+	case 14:
+		return HandleSyncGroup(header, body), nil
 	default:
 		fmt.Printf("Unknown API key: %d\n", header.ApiKey)
-		return BuildErrorResponse(35)
+		return BuildErrorResponse(UNSUPPORTED_VERSION), nil
 	}
 }
 
@@ -48,75 +138,601 @@ func HandleApiVersions(header RequestHeader, body []byte) []byte {
 	fmt.Printf("Received ApiVersions request (version=%d, correlation_id=%d)\n",
 		header.ApiVersion, header.CorrelationID)
 
-	var errorCode int16 = 0
-	if header.ApiVersion > 4 {
-		errorCode = 35
-	}
+	var response protocol.ApiVersionsResponse
+	response.Default()
 
-	response := make([]byte, 0)
-	response = AppendInt16(response, errorCode)
-	response = append(response, byte(len(SupportedApiKeys)+1))
+	version := header.ApiVersion
+	if version > 4 {
+		// Unsupported versions are answered with a v0 body listing what we support
+		response.ErrorCode = UNSUPPORTED_VERSION
+		version = 0
+	} else {
+		// The body only names the client software, so a client whose body
+		// does not parse still gets the version list it needs to continue
+		var request protocol.ApiVersionsRequest
+		if err := request.Decode(body, version); err != nil {
+			fmt.Printf("Invalid ApiVersions request: %v\n", err)
+		}
+	}
 
 	for _, apiKey := range SupportedApiKeys {
-		response = AppendInt16(response, apiKey.Key)
-		response = AppendInt16(response, apiKey.MinVersion)
-		response = AppendInt16(response, apiKey.MaxVersion)
-		response = append(response, 0x00)
+		response.ApiKeys = append(response.ApiKeys, protocol.ApiVersionsResponseApiVersion{
+			ApiKey:     apiKey.Key,
+			MinVersion: apiKey.MinVersion,
+			MaxVersion: apiKey.MaxVersion,
+		})
 	}
 
-	response = AppendInt32(response, 0)
-	response = append(response, 0x00)
-
-	return response
+	return response.Encode(version)
 }
 
 func HandleDescribeTopicPartitions(header RequestHeader, body []byte) []byte {
 	fmt.Printf("Received DescribeTopicPartitions request (version=%d, correlation_id=%d)\n",
 		header.ApiVersion, header.CorrelationID)
 
-	request := ParseDescribeTopicPartitionsRequest(body)
-	fmt.Printf("Parsed request: ClientID=%s, Topics=%v\n", header.ClientID.content, request.TopicNames)
+	var request protocol.DescribeTopicPartitionsRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid DescribeTopicPartitions request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed DescribeTopicPartitionsRequest: ClientID=%s, %+v\n", header.ClientID, request)
 
-	return BuildDescribeTopicPartitionsResponse(request)
+	return BuildDescribeTopicPartitionsResponse(header.ApiVersion, request)
 }
 
-func HandleFetch(header RequestHeader, body []byte) []byte {
-	fmt.Printf("Received Fetch request (version=%d, correlation_id=%d)\n",
+func HandleMetadata(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received Metadata request (version=%d, correlation_id=%d)\n",
 		header.ApiVersion, header.CorrelationID)
 
-	buf := make([]byte, 0, 1024)
+	var request protocol.MetadataRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid Metadata request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed MetadataRequest: %+v\n", request)
 
-	// ThrottleTimeMS (INT32) = 0
-	buf = append(buf, 0x00, 0x00, 0x00, 0x00)
+	return BuildMetadataResponse(header.ApiVersion, request)
+}
 
-	// ErrorCode (INT16) = 0
-	buf = append(buf, 0x00, 0x00)
+func HandleFetch(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received Fetch request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
 
-	// SessionID (INT32) = 0 (no session)
-	buf = append(buf, 0x00, 0x00, 0x00, 0x00)
-	buf = append(buf, 0x00) // TAG_BUFFER
-	fmt.Println("Hena a7oooooooooooooooooooo ")
+	var request protocol.FetchRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid Fetch request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
 	ResponseHeader := ResponseHeader{
 		ApiKey:        header.ApiKey,
 		ApiVersion:    header.ApiVersion,
 		CorrelationID: header.CorrelationID,
 	}
-	fmt.Printf("Response Bytes: % X\n", buf)
 
-	buf = BuildFetchResponse(ResponseHeader, ParseFetchRequest(body), buf)
+	buf, result := BuildFetchResponse(ResponseHeader, request)
+	if !result.canComplete(request) {
+		buf = waitForFetch(ResponseHeader, request, result)
+	}
 
 	fmt.Printf("Sent Fetch response (version=%d), body size=%d\n", header.ApiVersion, len(buf))
 	return buf
 }
 
-func HandleProduce(header RequestHeader, body []byte) []byte {
+// waitForFetch parks a Fetch in the purgatory until MinBytes are available on the
+// requested partitions or MaxWaitMs expires, then builds the final response
+func waitForFetch(header ResponseHeader, request protocol.FetchRequest, result fetchResult) []byte {
+	op := fetchPurgatory.Watch(result.partitionKeys)
+	defer fetchPurgatory.Remove(op)
+
+	timeout := time.NewTimer(time.Duration(request.MaxWaitMs) * time.Millisecond)
+	defer timeout.Stop()
+
+	fmt.Printf("Fetch waiting up to %dms for %d bytes\n", request.MaxWaitMs, request.MinBytes)
+	for {
+		select {
+		case <-op.Wake():
+			buf, result := BuildFetchResponse(header, request)
+			if result.canComplete(request) {
+				return buf
+			}
+		case <-timeout.C:
+			buf, _ := BuildFetchResponse(header, request)
+			return buf
+		}
+	}
+}
+
+func HandleProduce(header RequestHeader, body []byte) ([]byte, error) {
 	fmt.Printf("Received Produce request (version=%d, correlation_id=%d)\n",
 		header.ApiVersion, header.CorrelationID)
 
-	produceReq := ParseProduceRequest(body)
+	var produceReq protocol.ProduceRequest
+	if err := produceReq.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid Produce request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST), nil
+	}
 	fmt.Printf("Parsed ProduceRequest: %d topics\n", len(produceReq.TopicData))
 	fmt.Printf("ProduceRequest: %+v\n", produceReq)
 
-	response := BuildProduceResponse(header, produceReq)
-	return response
+	response, result := appendProduceRecords(produceReq)
+	switch produceReq.Acks {
+	case 0:
+		// The producer expects no answer; a failure closes the connection so
+		// the producer notices and refreshes its metadata, as Kafka does
+		if result.failed {
+			fmt.Printf("Produce with acks=0 failed, closing connection\n")
+			return nil, nil
+		}
+		return nil, errNoResponse
+	case -1:
+		waitForProduce(produceReq.TimeoutMs, &response, result)
+	}
+
+	buf := response.Encode(header.ApiVersion)
+	fmt.Printf("Built Produce response: %d topics, body size=%d\n", len(produceReq.TopicData), len(buf))
+	return buf, nil
+}
+
+// waitForProduce holds an acks=-1 Produce until the records of every appended
+// partition are durable or TimeoutMs expires; partitions still pending then
+// fail with REQUEST_TIMED_OUT. With a single broker the ISR is this broker
+// alone, so durable means flushed to its disk.
+func waitForProduce(timeoutMs int32, response *protocol.ProduceResponse, result produceResult) {
+	if len(result.appended) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(result.appended))
+	for _, produced := range result.appended {
+		keys = append(keys, partitionKey(produced.topic, produced.partition))
+	}
+	op := producePurgatory.Watch(keys)
+	defer producePurgatory.Remove(op)
+
+	var mu sync.Mutex
+	flushErrors := make([]error, len(result.appended))
+	for i, produced := range result.appended {
+		go func() {
+			log, err := storage.GetLog(produced.topic, produced.partition)
+			if err == nil {
+				err = log.Flush()
+			}
+			if err != nil {
+				fmt.Printf("Failed to flush %s-%d: %v\n", produced.topic, produced.partition, err)
+				mu.Lock()
+				flushErrors[i] = err
+				mu.Unlock()
+			}
+			producePurgatory.CheckAndComplete(keys[i])
+		}()
+	}
+
+	timeout := time.NewTimer(time.Duration(max(timeoutMs, 0)) * time.Millisecond)
+	defer timeout.Stop()
+
+	// complete sets the error code of every partition that is not durable and
+	// reports whether all of them are settled
+	complete := func(expired bool) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		settled := true
+		for i, produced := range result.appended {
+			partResp := &response.Responses[produced.topicIndex].PartitionResponses[produced.partitionIndex]
+			if partResp.ErrorCode != ErrNone {
+				continue
+			}
+			if flushErrors[i] != nil {
+				partResp.ErrorCode = storageErrorCode(flushErrors[i])
+				continue
+			}
+			log, err := storage.GetLog(produced.topic, produced.partition)
+			if err == nil && log.FlushedOffset() > produced.lastOffset {
+				continue
+			}
+			if expired {
+				partResp.ErrorCode = REQUEST_TIMED_OUT
+				continue
+			}
+			settled = false
+		}
+		return settled
+	}
+
+	fmt.Printf("Produce waiting up to %dms for %d partitions to be durable\n", timeoutMs, len(result.appended))
+	for !complete(false) {
+		select {
+		case <-op.Wake():
+		case <-timeout.C:
+			complete(true)
+			return
+		}
+	}
+}
+
+func HandleFindCoordinator(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received FindCoordinator request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.FindCoordinatorRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid FindCoordinator request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed FindCoordinatorRequest: %+v\n", request)
+
+	return BuildFindCoordinatorResponse(header.ApiVersion, request)
+}
+
+func HandleJoinGroup(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received JoinGroup request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.JoinGroupRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid JoinGroup request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed JoinGroupRequest: group=%s member=%s protocols=%d\n",
+		request.GroupId, request.MemberId, len(request.Protocols))
+
+	// RebalanceTimeoutMs is only sent from v1 and defaults to the session timeout
+	rebalanceTimeoutMs := request.RebalanceTimeoutMs
+	if header.ApiVersion == 0 {
+		rebalanceTimeoutMs = request.SessionTimeoutMs
+	}
+
+	protocols := make([]group.Protocol, 0, len(request.Protocols))
+	for _, protocol := range request.Protocols {
+		protocols = append(protocols, group.Protocol{Name: protocol.Name, Metadata: protocol.Metadata})
+	}
+
+	// Blocks until the rebalance this join triggered (or joined) completes
+	result := GroupCoordinator.JoinGroup(group.JoinParams{
+		GroupID:              request.GroupId,
+		MemberID:             request.MemberId,
+		GroupInstanceID:      stringValue(request.GroupInstanceId),
+		ClientID:             header.ClientID,
+		SessionTimeoutMs:     request.SessionTimeoutMs,
+		RebalanceTimeoutMs:   rebalanceTimeoutMs,
+		ProtocolType:         request.ProtocolType,
+		Protocols:            protocols,
+		RequireKnownMemberID: header.ApiVersion >= 4,
+	})
+
+	return BuildJoinGroupResponse(header.ApiVersion, result)
+}
+
+func HandleSyncGroup(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received SyncGroup request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.SyncGroupRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid SyncGroup request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed SyncGroupRequest: group=%s member=%s generation=%d assignments=%d\n",
+		request.GroupId, request.MemberId, request.GenerationId, len(request.Assignments))
+
+	assignments := make(map[string][]byte, len(request.Assignments))
+	for _, assignment := range request.Assignments {
+		assignments[assignment.MemberId] = assignment.Assignment
+	}
+
+	// Blocks until the leader has sent the assignment for this generation
+	result := GroupCoordinator.SyncGroup(group.SyncParams{
+		GroupID:      request.GroupId,
+		GenerationID: request.GenerationId,
+		MemberID:     request.MemberId,
+		ProtocolType: stringValue(request.ProtocolType),
+		ProtocolName: stringValue(request.ProtocolName),
+		Assignments:  assignments,
+	})
+
+	return BuildSyncGroupResponse(header.ApiVersion, result)
+}
+
+func HandleHeartbeat(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received Heartbeat request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.HeartbeatRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid Heartbeat request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	errorCode := GroupCoordinator.Heartbeat(request.GroupId, request.GenerationId, request.MemberId)
+
+	return BuildHeartbeatResponse(header.ApiVersion, errorCode)
+}
+
+func HandleLeaveGroup(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received LeaveGroup request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.LeaveGroupRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid LeaveGroup request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed LeaveGroupRequest: %+v\n", request)
+
+	// Up to v2 a single member leaves, batched members from v3
+	leaving := request.Members
+	if header.ApiVersion <= 2 {
+		leaving = []protocol.LeaveGroupRequestMemberIdentity{{MemberId: request.MemberId}}
+	}
+
+	members := make([]group.LeavingMember, 0, len(leaving))
+	for _, member := range leaving {
+		members = append(members, group.LeavingMember{MemberID: member.MemberId, GroupInstanceID: stringValue(member.GroupInstanceId)})
+	}
+	errorCode, memberErrors := GroupCoordinator.LeaveGroup(request.GroupId, members)
+
+	// Before v3 the single member's error is the top-level error
+	if header.ApiVersion <= 2 && memberErrors[0] != ErrNone {
+		errorCode = memberErrors[0]
+	}
+
+	return BuildLeaveGroupResponse(header.ApiVersion, leaving, errorCode, memberErrors)
+}
+
+func HandleOffsetCommit(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received OffsetCommit request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.OffsetCommitRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid OffsetCommit request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed OffsetCommitRequest: %+v\n", request)
+
+	offsets := make(map[group.TopicPartition]group.OffsetAndMetadata)
+	for _, topic := range request.Topics {
+		for _, partition := range topic.Partitions {
+			offsets[group.TopicPartition{Topic: topic.Name, Partition: partition.PartitionIndex}] = group.OffsetAndMetadata{
+				Offset:      partition.CommittedOffset,
+				LeaderEpoch: partition.CommittedLeaderEpoch,
+				Metadata:    stringValue(partition.CommittedMetadata),
+			}
+		}
+	}
+
+	errorCodes := GroupCoordinator.CommitOffsets(group.CommitParams{
+		GroupID:         request.GroupId,
+		GenerationID:    request.GenerationIdOrMemberEpoch,
+		MemberID:        request.MemberId,
+		GroupInstanceID: stringValue(request.GroupInstanceId),
+		Offsets:         offsets,
+	})
+
+	return BuildOffsetCommitResponse(header.ApiVersion, request, errorCodes)
+}
+
+func HandleOffsetFetch(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received OffsetFetch request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.OffsetFetchRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid OffsetFetch request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed OffsetFetchRequest: %+v\n", request)
+
+	groups := offsetFetchGroups(header.ApiVersion, request)
+	offsets := make([]map[group.TopicPartition]group.OffsetAndMetadata, 0, len(groups))
+	for _, fetchGroup := range groups {
+		var partitions []group.TopicPartition
+		if fetchGroup.Topics != nil {
+			partitions = make([]group.TopicPartition, 0)
+			for _, topic := range fetchGroup.Topics {
+				for _, partitionIndex := range topic.PartitionIndexes {
+					partitions = append(partitions, group.TopicPartition{Topic: topic.Name, Partition: partitionIndex})
+				}
+			}
+		}
+		offsets = append(offsets, GroupCoordinator.FetchOffsets(fetchGroup.GroupId, partitions))
+	}
+
+	return BuildOffsetFetchResponse(header.ApiVersion, groups, offsets)
+}
+
+func HandleListOffsets(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received ListOffsets request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.ListOffsetsRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid ListOffsets request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed ListOffsetsRequest: %+v\n", request)
+
+	return BuildListOffsetsResponse(header.ApiVersion, request)
+}
+
+func HandleCreateTopics(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received CreateTopics request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.CreateTopicsRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid CreateTopics request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed CreateTopicsRequest: %+v\n", request)
+
+	return BuildCreateTopicsResponse(header.ApiVersion, request)
+}
+
+func HandleDeleteTopics(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received DeleteTopics request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.DeleteTopicsRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid DeleteTopics request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed DeleteTopicsRequest: %+v\n", request)
+
+	return BuildDeleteTopicsResponse(header.ApiVersion, request)
+}
+
+func HandleInitProducerId(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received InitProducerId request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.InitProducerIdRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid InitProducerId request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+
+	if request.TransactionalId != nil {
+		result := TxnCoordinator.InitProducerId(*request.TransactionalId, request.TransactionTimeoutMs,
+			request.ProducerId, request.ProducerEpoch)
+		return BuildInitProducerIdResponse(header.ApiVersion, result.ErrorCode, result.ProducerID, result.ProducerEpoch)
+	}
+
+	// Idempotent producers always get a new producer ID at epoch 0, as in Kafka
+	producerID, err := ProducerIDs.Generate()
+	if err != nil {
+		fmt.Printf("InitProducerId failed: %v\n", err)
+		return BuildInitProducerIdResponse(header.ApiVersion, UNKNOWN_SERVER_ERROR, -1, -1)
+	}
+	fmt.Printf("Assigned producer ID %d\n", producerID)
+
+	return BuildInitProducerIdResponse(header.ApiVersion, ErrNone, producerID, 0)
+}
+
+func HandleAddPartitionsToTxn(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received AddPartitionsToTxn request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.AddPartitionsToTxnRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid AddPartitionsToTxn request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed AddPartitionsToTxnRequest: %+v\n", request)
+
+	// Unknown partitions fail the whole request: the others are not attempted
+	partitions := make([]txn.TopicPartition, 0)
+	unknown := make(map[txn.TopicPartition]bool)
+	for _, topic := range request.Topics {
+		for _, partitionIndex := range topic.Partitions {
+			tp := txn.TopicPartition{Topic: topic.Name, Partition: partitionIndex}
+			partitions = append(partitions, tp)
+			if !metadata.ValidatePartitionExists(topic.Name, partitionIndex) {
+				unknown[tp] = true
+			}
+		}
+	}
+
+	errorCodes := make(map[txn.TopicPartition]int16, len(partitions))
+	if len(unknown) > 0 {
+		for _, tp := range partitions {
+			errorCodes[tp] = OPERATION_NOT_ATTEMPTED
+			if unknown[tp] {
+				errorCodes[tp] = UNKNOWN_TOPIC_OR_PARTITION
+			}
+		}
+	} else {
+		errorCode := TxnCoordinator.AddPartitions(request.TransactionalId, request.ProducerId, request.ProducerEpoch, partitions)
+		for _, tp := range partitions {
+			errorCodes[tp] = errorCode
+		}
+	}
+
+	return BuildAddPartitionsToTxnResponse(header.ApiVersion, request, errorCodes)
+}
+
+func HandleAddOffsetsToTxn(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received AddOffsetsToTxn request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.AddOffsetsToTxnRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid AddOffsetsToTxn request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed AddOffsetsToTxnRequest: %+v\n", request)
+
+	if request.GroupId == "" {
+		return BuildAddOffsetsToTxnResponse(header.ApiVersion, group.INVALID_GROUP_ID)
+	}
+
+	// Every group's offsets live in __consumer_offsets partition 0, so that is
+	// the partition added to the transaction
+	errorCode := TxnCoordinator.AddPartitions(request.TransactionalId, request.ProducerId, request.ProducerEpoch,
+		[]txn.TopicPartition{{Topic: group.ConsumerOffsetsTopic, Partition: 0}})
+	return BuildAddOffsetsToTxnResponse(header.ApiVersion, errorCode)
+}
+
+func HandleEndTxn(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received EndTxn request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.EndTxnRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid EndTxn request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed EndTxnRequest: %+v\n", request)
+
+	errorCode := TxnCoordinator.EndTxn(request.TransactionalId, request.ProducerId, request.ProducerEpoch, request.Committed)
+	return BuildEndTxnResponse(header.ApiVersion, errorCode)
+}
+
+func HandleTxnOffsetCommit(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received TxnOffsetCommit request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.TxnOffsetCommitRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid TxnOffsetCommit request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed TxnOffsetCommitRequest: %+v\n", request)
+
+	offsets := make(map[group.TopicPartition]group.OffsetAndMetadata)
+	for _, topic := range request.Topics {
+		for _, partition := range topic.Partitions {
+			offsets[group.TopicPartition{Topic: topic.Name, Partition: partition.PartitionIndex}] = group.OffsetAndMetadata{
+				Offset:      partition.CommittedOffset,
+				LeaderEpoch: partition.CommittedLeaderEpoch,
+				Metadata:    stringValue(partition.CommittedMetadata),
+			}
+		}
+	}
+
+	errorCodes := GroupCoordinator.CommitTxnOffsets(group.TxnCommitParams{
+		TransactionalID: request.TransactionalId,
+		GroupID:         request.GroupId,
+		ProducerID:      request.ProducerId,
+		ProducerEpoch:   request.ProducerEpoch,
+		GenerationID:    request.GenerationId,
+		MemberID:        request.MemberId,
+		GroupInstanceID: stringValue(request.GroupInstanceId),
+		Offsets:         offsets,
+	})
+
+	return BuildTxnOffsetCommitResponse(header.ApiVersion, request, errorCodes)
+}
+
+func HandleDescribeLogDirs(header RequestHeader, body []byte) []byte {
+	fmt.Printf("Received DescribeLogDirs request (version=%d, correlation_id=%d)\n",
+		header.ApiVersion, header.CorrelationID)
+
+	var request protocol.DescribeLogDirsRequest
+	if err := request.Decode(body, header.ApiVersion); err != nil {
+		fmt.Printf("Invalid DescribeLogDirs request: %v\n", err)
+		return BuildRequestErrorResponse(header, INVALID_REQUEST)
+	}
+	fmt.Printf("Parsed DescribeLogDirsRequest: %+v\n", request)
+
+	return BuildDescribeLogDirsResponse(header.ApiVersion, ErrNone, describeLogDirs(request))
 }
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"kafgo/app/metadata"
+	"kafgo/app/protocol"
+	"kafgo/app/storage"
+)
+
+// handleFrame answers a request frame (without its size prefix) the way
+// HandleConnection does; a nil response means the connection is closed
+func handleFrame(t *testing.T, frame []byte) []byte {
+	t.Helper()
+	header, body, err := ParseRequestHeader(frame)
+	if err != nil {
+		return nil
+	}
+	response, err := HandleRequest(header, body)
+	if err != nil {
+		t.Fatalf("HandleRequest error %v", err)
+	}
+	return response
+}
+
+// requestFrame writes a request header of apiKey at version, followed by body
+func requestFrame(apiKey int16, version int16, body func(w *protocol.Writer)) []byte {
+	return seed(func(w *protocol.Writer) {
+		w.Int16(apiKey)
+		w.Int16(version)
+		w.Int32(7)
+		w.String("client", false)
+		if requestHeaderVersion(apiKey, version) >= 2 {
+			w.TaggedFields(nil)
+		}
+		body(w)
+	})
+}
+
+func TestHandleMalformedRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		// errorCode decodes the top-level error code of the response, nil when
+		// the connection must be closed instead
+		errorCode func(response []byte, version int16) (int16, error)
+		version   int16
+	}{
+		{
+			name: "truncated header",
+			frame: seed(func(w *protocol.Writer) {
+				w.Int16(12)
+				w.Int16(4)
+				w.Int16(0) // half a correlation ID
+			}),
+		},
+		{
+			name: "client ID past the end of the header",
+			frame: seed(func(w *protocol.Writer) {
+				w.Int16(12)
+				w.Int16(0)
+				w.Int32(7)
+				w.Int16(100)
+				w.Int32(0)
+			}),
+		},
+		{
+			name: "oversized array length",
+			frame: requestFrame(11, 0, func(w *protocol.Writer) {
+				w.String("group", false)
+				w.Int32(30000)
+				w.String("", false)
+				w.String("consumer", false)
+				w.Int32(0x7fffffff) // protocols
+			}),
+			errorCode: func(response []byte, version int16) (int16, error) {
+				var decoded protocol.JoinGroupResponse
+				err := decoded.Decode(response, version)
+				return decoded.ErrorCode, err
+			},
+			version: 0,
+		},
+		{
+			name: "bad compact string",
+			frame: requestFrame(12, 4, func(w *protocol.Writer) {
+				w.Uvarint(1001) // group ID of 1000 bytes
+				w.Int32(0)
+			}),
+			errorCode: func(response []byte, version int16) (int16, error) {
+				var decoded protocol.HeartbeatResponse
+				err := decoded.Decode(response, version)
+				return decoded.ErrorCode, err
+			},
+			version: 4,
+		},
+		{
+			name: "truncated body",
+			frame: requestFrame(1, 12, func(w *protocol.Writer) {
+				w.Int32(500) // max_wait_ms, then nothing
+			}),
+			errorCode: func(response []byte, version int16) (int16, error) {
+				var decoded protocol.FetchResponse
+				err := decoded.Decode(response, version)
+				return decoded.ErrorCode, err
+			},
+			version: 12,
+		},
+		{
+			// Metadata responses have no top-level error code to answer with
+			name: "oversized array length without an error code",
+			frame: requestFrame(3, 1, func(w *protocol.Writer) {
+				w.Int32(0x7fffffff) // topics
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := handleFrame(t, tt.frame)
+			if tt.errorCode == nil {
+				if response != nil {
+					t.Fatalf("answered with %d bytes, want the connection closed", len(response))
+				}
+				return
+			}
+			if response == nil {
+				t.Fatal("connection closed, want an INVALID_REQUEST response")
+			}
+			errorCode, err := tt.errorCode(response, tt.version)
+			if err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if errorCode != INVALID_REQUEST {
+				t.Errorf("error code %d, want INVALID_REQUEST", errorCode)
+			}
+		})
+	}
+}
+
+func TestHandleProduceAcks(t *testing.T) {
+	batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, 1700000000000).Encode()
+
+	tests := []struct {
+		name      string
+		acks      int16
+		partition int32
+		// flushIntervalMessages is log.flush.interval.messages for the test
+		flushIntervalMessages int64
+		// noResponse is set when the request must not be answered, and closed
+		// when the connection must be closed instead
+		noResponse bool
+		closed     bool
+		errorCode  int16
+		// wantEnd and wantFlushed are the log end offset and recovery point of
+		// partition 0 afterwards
+		wantEnd     int64
+		wantFlushed int64
+	}{
+		{name: "acks=0", acks: 0, flushIntervalMessages: math.MaxInt64, noResponse: true, wantEnd: 1},
+		{name: "acks=0 to an unknown partition", acks: 0, partition: 1, flushIntervalMessages: math.MaxInt64, closed: true},
+		{name: "acks=1", acks: 1, flushIntervalMessages: math.MaxInt64, wantEnd: 1},
+		{name: "acks=1 past log.flush.interval.messages", acks: 1, flushIntervalMessages: 1, wantEnd: 1, wantFlushed: 1},
+		{name: "acks=-1", acks: -1, flushIntervalMessages: math.MaxInt64, wantEnd: 1, wantFlushed: 1},
+		{name: "acks=-1 to an unknown partition", acks: -1, partition: 1, flushIntervalMessages: math.MaxInt64, errorCode: UNKNOWN_TOPIC_OR_PARTITION},
+		{name: "invalid acks", acks: 2, flushIntervalMessages: math.MaxInt64, errorCode: INVALID_REQUIRED_ACKS},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(flushIntervalMessages int64) {
+				storage.FlushIntervalMessages = flushIntervalMessages
+			}(storage.FlushIntervalMessages)
+			storage.FlushIntervalMessages = tt.flushIntervalMessages
+
+			topic := fmt.Sprintf("acks-%d", i)
+			createTestTopic(t, topic, 1)
+			req := protocol.ProduceRequest{
+				Acks:      tt.acks,
+				TimeoutMs: 5000,
+				TopicData: []protocol.ProduceRequestTopicProduceData{{
+					Name:          topic,
+					PartitionData: []protocol.ProduceRequestPartitionProduceData{{Index: tt.partition, Records: batch}},
+				}},
+			}
+			const version = 9
+			header := RequestHeader{ApiKey: 0, ApiVersion: version, CorrelationID: 7}
+
+			response, err := HandleProduce(header, req.Encode(version))
+			switch {
+			case tt.noResponse:
+				if response != nil || err != errNoResponse {
+					t.Errorf("response %v, error %v, want no response", response, err)
+				}
+			case tt.closed:
+				if response != nil || err != nil {
+					t.Errorf("response %v, error %v, want the connection closed", response, err)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				var decoded protocol.ProduceResponse
+				if err := decoded.Decode(response, version); err != nil {
+					t.Fatal(err)
+				}
+				if errorCode := decoded.Responses[0].PartitionResponses[0].ErrorCode; errorCode != tt.errorCode {
+					t.Errorf("error code %d, want %d", errorCode, tt.errorCode)
+				}
+			}
+
+			log, err := storage.GetLog(topic, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if end := log.LogEndOffset(); end != tt.wantEnd {
+				t.Errorf("log end offset %d, want %d", end, tt.wantEnd)
+			}
+			if flushed := log.FlushedOffset(); flushed != tt.wantFlushed {
+				t.Errorf("flushed offset %d, want %d", flushed, tt.wantFlushed)
+			}
+		})
+	}
+}
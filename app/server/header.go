@@ -0,0 +1,38 @@
+package server
+
+// isFlexibleVersion reports whether apiVersion of apiKey uses compact encodings
+// and tagged fields. Unknown API keys are treated as non-flexible.
+func isFlexibleVersion(apiKey int16, apiVersion int16) bool {
+	for _, api := range SupportedApiKeys {
+		if api.Key == apiKey {
+			return api.FlexibleVersion >= 0 && apiVersion >= api.FlexibleVersion
+		}
+	}
+	return false
+}
+
+// requestHeaderVersion returns the header version a client uses for a request:
+// v2 adds a TAG_BUFFER to v1 for flexible versions, and v1 adds ClientID to v0.
+// ControlledShutdown (key 7) v0 is the only request still sent with header v0.
+func requestHeaderVersion(apiKey int16, apiVersion int16) int16 {
+	if isFlexibleVersion(apiKey, apiVersion) {
+		return 2
+	}
+	if apiKey == 7 && apiVersion == 0 {
+		return 0
+	}
+	return 1
+}
+
+// responseHeaderVersion returns the header version of a response: v1 adds a
+// TAG_BUFFER to v0 for flexible versions. ApiVersions always answers with v0,
+// since a client must parse it before it knows which versions the broker speaks.
+func responseHeaderVersion(apiKey int16, apiVersion int16) int16 {
+	if apiKey == 18 {
+		return 0
+	}
+	if isFlexibleVersion(apiKey, apiVersion) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DelayedOperation is a request parked in a Purgatory until one of the
+// partitions it watches changes or it times out
+type DelayedOperation struct {
+	keys []string
+	wake chan struct{}
+}
+
+// Wake returns a channel that receives whenever a watched partition changes
+func (op *DelayedOperation) Wake() <-chan struct{} {
+	return op.wake
+}
+
+// Purgatory holds delayed operations keyed by "topic-partition"
+type Purgatory struct {
+	mu       sync.Mutex
+	watchers map[string]map[*DelayedOperation]struct{}
+}
+
+func NewPurgatory() *Purgatory {
+	return &Purgatory{watchers: make(map[string]map[*DelayedOperation]struct{})}
+}
+
+// fetchPurgatory parks Fetch requests waiting for MinBytes; Produce wakes them
+var fetchPurgatory = NewPurgatory()
+
+// producePurgatory parks acks=-1 Produce requests until their records are durable
+var producePurgatory = NewPurgatory()
+
+func partitionKey(topic string, partition int32) string {
+	return fmt.Sprintf("%s-%d", topic, partition)
+}
+
+// Watch parks a new operation on every key until Remove is called
+func (p *Purgatory) Watch(keys []string) *DelayedOperation {
+	op := &DelayedOperation{keys: keys, wake: make(chan struct{}, 1)}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, key := range keys {
+		if p.watchers[key] == nil {
+			p.watchers[key] = make(map[*DelayedOperation]struct{})
+		}
+		p.watchers[key][op] = struct{}{}
+	}
+	return op
+}
+
+// Remove takes a completed or expired operation out of the purgatory
+func (p *Purgatory) Remove(op *DelayedOperation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, key := range op.keys {
+		delete(p.watchers[key], op)
+		if len(p.watchers[key]) == 0 {
+			delete(p.watchers, key)
+		}
+	}
+}
+
+// CheckAndComplete wakes every operation watching key so it can re-check its completion condition
+func (p *Purgatory) CheckAndComplete(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for op := range p.watchers[key] {
+		// A pending wake-up already covers this change
+		select {
+		case op.wake <- struct{}{}:
+		default:
+		}
+	}
+}
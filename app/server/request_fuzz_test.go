@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+
+	"kafgo/app/protocol"
+)
+
+// Request bodies are decoded by the generated protocol messages, which are
+// fuzzed in app/protocol. The header is parsed here, so it is fuzzed here:
+//
+//	go test ./app/server -run '^$' -fuzz FuzzParseRequestHeader -fuzztime 30s
+
+// seed builds a request frame with a protocol.Writer
+func seed(write func(w *protocol.Writer)) []byte {
+	w := protocol.NewWriter()
+	write(w)
+	return w.Buf()
+}
+
+func FuzzParseRequestHeader(f *testing.F) {
+	f.Add(seed(func(w *protocol.Writer) {
+		// Metadata v1, header v1
+		w.Int16(3)
+		w.Int16(1)
+		w.Int32(7)
+		w.String("client", false)
+		w.ArrayLen(0, false)
+	}))
+	f.Add(seed(func(w *protocol.Writer) {
+		// Fetch v12, header v2
+		w.Int16(1)
+		w.Int16(12)
+		w.Int32(7)
+		w.String("client", false)
+		w.TaggedFields(nil)
+	}))
+
+	f.Fuzz(func(t *testing.T, message []byte) {
+		_, body, err := ParseRequestHeader(message)
+		if err == nil && len(body) > len(message) {
+			t.Fatalf("body of %d bytes from a %d byte message", len(body), len(message))
+		}
+	})
+}
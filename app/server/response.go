@@ -1,153 +1,122 @@
 package server
 
 import (
+	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
 	"sort"
+	"strconv"
+	"strings"
 
+	"kafgo/app/compress"
+	"kafgo/app/group"
 	"kafgo/app/metadata"
+	"kafgo/app/protocol"
+	"kafgo/app/storage"
+	"kafgo/app/txn"
 )
 
 // Kafka protocol error codes (subset)
 const (
-	ErrNone                    int16 = 0
-	UNKNOWN_TOPIC_ID           int16 = 100
-	UNKNOWN_TOPIC_OR_PARTITION int16 = 3
+	ErrNone                      int16 = 0
+	UNKNOWN_TOPIC_ID             int16 = 100
+	UNKNOWN_TOPIC_OR_PARTITION   int16 = 3
+	OFFSET_OUT_OF_RANGE          int16 = 1
+	KAFKA_STORAGE_ERROR          int16 = 56
+	INVALID_REQUEST              int16 = 42
+	CORRUPT_MESSAGE              int16 = 2
+	INVALID_RECORD               int16 = 87
+	UNKNOWN_SERVER_ERROR         int16 = -1
+	INVALID_TOPIC_EXCEPTION      int16 = 17
+	TOPIC_ALREADY_EXISTS         int16 = 36
+	INVALID_PARTITIONS           int16 = 37
+	INVALID_REPLICATION_FACTOR   int16 = 38
+	INVALID_REPLICA_ASSIGNMENT   int16 = 39
+	INVALID_CONFIG               int16 = 40
+	OUT_OF_ORDER_SEQUENCE_NUMBER int16 = 45
+	INVALID_PRODUCER_EPOCH       int16 = 47
+	INVALID_TXN_STATE            int16 = 48
+	REQUEST_TIMED_OUT            int16 = 7
+	INVALID_REQUIRED_ACKS        int16 = 21
+	OPERATION_NOT_ATTEMPTED      int16 = 55
+	UNSUPPORTED_VERSION          int16 = 35
 )
 
-func BuildDescribeTopicPartitionsResponse(request DescribeTopicPartitionsRequest) []byte {
-	response := make([]byte, 0)
+func BuildDescribeTopicPartitionsResponse(version int16, request protocol.DescribeTopicPartitionsRequest) []byte {
+	var response protocol.DescribeTopicPartitionsResponse
+	response.Default()
 	topicsMetadata := metadata.GetTopicMetadata()
 
-	// Tag Buffer
-	response = append(response, 0x00)
-	// Throttle Time (INT32)
-	response = AppendInt32(response, 0)
-
-	// If no specific topics requested, return all topics in alphabetical order
-	topicNames := request.TopicNames
+	// If no specific topics requested, return all topics; either way in alphabetical order
+	topicNames := make([]string, 0, len(request.Topics))
+	for _, topic := range request.Topics {
+		topicNames = append(topicNames, topic.Name)
+	}
 	if len(topicNames) == 0 {
-		// Get all topic names and sort them alphabetically
-		allTopicNames := make([]string, 0, len(topicsMetadata))
 		for name := range topicsMetadata {
-			allTopicNames = append(allTopicNames, name)
+			topicNames = append(topicNames, name)
 		}
-		sort.Strings(allTopicNames)
-		topicNames = allTopicNames
-	} else {
-		// Sort the requested topic names alphabetically
-		sort.Strings(topicNames)
 	}
-
-	// Topics Array (COMPACT_ARRAY)
-	numTopics := len(topicNames)
-	response = append(response, byte(numTopics+1))
+	sort.Strings(topicNames)
 
 	for _, topicName := range topicNames {
-		topic, exists := topicsMetadata[topicName]
+		var topicResp protocol.DescribeTopicPartitionsResponseTopic
+		topicResp.Default()
+		topicResp.Name = &topicName
 
+		topic, exists := topicsMetadata[topicName]
 		if !exists {
-			// Topic not found
-			response = AppendInt16(response, 3) // UNKNOWN_TOPIC_OR_PARTITION
-			response = append(response, byte(len(topicName)+1))
-			response = append(response, []byte(topicName)...)
-			response = append(response, make([]byte, 16)...) // Empty UUID
-			response = append(response, 0x00)                // is_internal = false
-			response = append(response, 0x01)                // 0 partitions
-			response = AppendInt32(response, -2147483648)
-			response = append(response, 0x00) // TAG_BUFFER
-		} else {
-			// Topic found
-			response = AppendInt16(response, 0) // No error
-
-			// Topic Name (COMPACT_STRING)
-			response = append(response, byte(len(topicName)+1))
-			response = append(response, []byte(topicName)...)
-
-			// Topic ID (UUID)
-			response = append(response, topic.TopicID[:]...)
-
-			// Is Internal (BOOLEAN)
-			response = append(response, 0x00) // false
-
-			// Partitions Array (COMPACT_ARRAY)
-			// Sort partitions by partition index
-			sort.Slice(topic.Partitions, func(i, j int) bool {
-				return topic.Partitions[i].PartitionIndex < topic.Partitions[j].PartitionIndex
-			})
-
-			response = append(response, byte(len(topic.Partitions)+1))
-
-			for _, partition := range topic.Partitions {
-				// Error Code
-				response = AppendInt16(response, 0) // No error
-
-				// Partition Index
-				response = AppendInt32(response, partition.PartitionIndex)
-
-				// Leader ID
-				response = AppendInt32(response, partition.LeaderID)
-
-				// Leader Epoch
-				response = AppendInt32(response, -1)
-
-				// Replica Nodes (COMPACT_ARRAY)
-				response = append(response, byte(len(partition.ReplicaNodes)+1))
-				for _, replica := range partition.ReplicaNodes {
-					response = AppendInt32(response, replica)
-				}
-
-				// ISR Nodes (COMPACT_ARRAY)
-				response = append(response, byte(len(partition.IsrNodes)+1))
-				for _, isr := range partition.IsrNodes {
-					response = AppendInt32(response, isr)
-				}
-
-				// Eligible Leader Replicas (COMPACT_ARRAY - empty)
-				response = append(response, 0x01)
-
-				// Last Known ELR (COMPACT_ARRAY - empty)
-				response = append(response, 0x01)
-
-				// Offline Replicas (COMPACT_ARRAY - empty)
-				response = append(response, 0x01)
-
-				// TAG_BUFFER
-				response = append(response, 0x00)
-			}
-
-			// Topic Authorized Operations
-			response = AppendInt32(response, -2147483648)
-
-			// TAG_BUFFER
-			response = append(response, 0x00)
+			topicResp.ErrorCode = UNKNOWN_TOPIC_OR_PARTITION
+			response.Topics = append(response.Topics, topicResp)
+			continue
 		}
+		topicResp.TopicId = topic.TopicID
+
+		// Partitions are already sorted by partition index in the image
+		for _, partition := range topic.Partitions {
+			var partResp protocol.DescribeTopicPartitionsResponsePartition
+			partResp.Default()
+			partResp.PartitionIndex = partition.PartitionIndex
+			partResp.LeaderId = partition.LeaderID
+			partResp.ReplicaNodes = partition.ReplicaNodes
+			partResp.IsrNodes = partition.IsrNodes
+			// Without ELR support both lists are empty rather than null
+			partResp.EligibleLeaderReplicas = []int32{}
+			partResp.LastKnownElr = []int32{}
+			topicResp.Partitions = append(topicResp.Partitions, partResp)
+		}
+		response.Topics = append(response.Topics, topicResp)
 	}
 
-	// Next Cursor (null = -1)
-	response = append(response, 0xFF)
-
-	// Final TAG_BUFFER
-	response = append(response, 0x00)
-
+	buf := response.Encode(version)
 	fmt.Printf("Sent DescribeTopicPartitions response (topics=%d, body_len=%d)\n",
-		numTopics, len(response))
+		len(topicNames), len(buf))
 
-	return response
+	return buf
 }
 
-func WriteResponse(conn net.Conn, correlationID uint32, body []byte) error {
-	response := make([]byte, 0, 8+len(body))
+func WriteResponse(conn net.Conn, header RequestHeader, body []byte) error {
+	headerSize := 4
+	if responseHeaderVersion(header.ApiKey, header.ApiVersion) >= 1 {
+		headerSize++
+	}
+	response := make([]byte, 0, 4+headerSize+len(body))
 
 	// message_size
-	response = AppendInt32(response, int32(4+len(body)))
+	response = AppendInt32(response, int32(headerSize+len(body)))
 
 	// correlation_id
 	corrBuf := make([]byte, 4)
-	binary.BigEndian.PutUint32(corrBuf, correlationID)
+	binary.BigEndian.PutUint32(corrBuf, header.CorrelationID)
 	response = append(response, corrBuf...)
 
+	// TAG_BUFFER (response header v1)
+	if headerSize > 4 {
+		response = append(response, 0x00)
+	}
+
 	// body
 	response = append(response, body...)
 
@@ -161,6 +130,62 @@ func BuildErrorResponse(errorCode int16) []byte {
 	return response
 }
 
+// BuildRequestErrorResponse answers a request that could not be decoded with
+// errorCode as the top-level error of its response. Responses without a
+// top-level error code at this version return nil, and the connection is closed.
+func BuildRequestErrorResponse(header RequestHeader, errorCode int16) []byte {
+	version := header.ApiVersion
+
+	switch header.ApiKey {
+	case 1:
+		// Fetch: ErrorCode from v7
+		if version >= 7 {
+			var response protocol.FetchResponse
+			response.Default()
+			response.ErrorCode = errorCode
+			return response.Encode(version)
+		}
+	case 9:
+		// OffsetFetch: ErrorCode v2-7, per group from v8
+		if version >= 2 && version <= 7 {
+			var response protocol.OffsetFetchResponse
+			response.Default()
+			response.ErrorCode = errorCode
+			return response.Encode(version)
+		}
+	case 10:
+		// FindCoordinator: ErrorCode v0-3, per key from v4
+		if version <= 3 {
+			var response protocol.FindCoordinatorResponse
+			response.Default()
+			response.ErrorCode = errorCode
+			response.NodeId = -1
+			response.Port = -1
+			return response.Encode(version)
+		}
+	case 11:
+		return BuildJoinGroupResponse(version, group.JoinResult{ErrorCode: errorCode, GenerationID: -1})
+	case 12:
+		return BuildHeartbeatResponse(version, errorCode)
+	case 13:
+		return BuildLeaveGroupResponse(version, nil, errorCode, nil)
+	case 14:
+		return BuildSyncGroupResponse(version, group.SyncResult{ErrorCode: errorCode})
+	case 22:
+		return BuildInitProducerIdResponse(version, errorCode, -1, -1)
+	case 25:
+		return BuildAddOffsetsToTxnResponse(version, errorCode)
+	case 26:
+		return BuildEndTxnResponse(version, errorCode)
+	case 35:
+		// DescribeLogDirs: ErrorCode from v3
+		if version >= 3 {
+			return BuildDescribeLogDirsResponse(version, errorCode, nil)
+		}
+	}
+	return nil
+}
+
 func AppendInt16(buf []byte, val int16) []byte {
 	b := make([]byte, 2)
 	binary.BigEndian.PutUint16(b, uint16(val))
@@ -173,159 +198,1107 @@ func AppendInt32(buf []byte, val int32) []byte {
 	return append(buf, b...)
 }
 
-// Helper function to append INT64
-func AppendInt64(buf []byte, val int64) []byte {
-	b := make([]byte, 8)
-	binary.BigEndian.PutUint64(b, uint64(val))
-	return append(buf, b...)
+type fetchResult struct {
+	recordBytes   int
+	hasErrors     bool
+	partitionKeys []string
 }
-func BuildFetchResponse(header ResponseHeader, req FetchRequest, buf []byte) []byte {
-	// Build a quick lookup from TopicID -> TopicMetadata
-	topicsByID := make(map[[16]byte]*metadata.TopicMetadata)
-	for _, t := range metadata.GetTopicMetadata() {
-		topicsByID[t.TopicID] = t
-	}
 
-	// Responses (COMPACT_ARRAY) - match the topics from request
-	buf = append(buf, byte(len(req.Topics)+1)) // Compact array length
-	fmt.Printf("Response Bytes from build before compact : % X\n", buf)
+// canComplete reports whether the Fetch can be answered now instead of waiting for more data
+func (r fetchResult) canComplete(req protocol.FetchRequest) bool {
+	return req.MaxWaitMs <= 0 || r.hasErrors || r.recordBytes >= int(req.MinBytes) || len(r.partitionKeys) == 0
+}
 
-	// For each topic in the request, build a response
-	for _, topicReq := range req.Topics {
-		// TopicID (UUID - 16 bytes) - same as request
-		buf = append(buf, topicReq.TopicID[:]...)
+func BuildFetchResponse(header ResponseHeader, req protocol.FetchRequest) ([]byte, fetchResult) {
+	version := header.ApiVersion
+	result := fetchResult{}
 
-		// Locate topic by ID
-		topicMeta, topicExists := topicsByID[topicReq.TopicID]
+	var response protocol.FetchResponse
+	response.Default()
 
-		// Partitions (COMPACT_ARRAY) - match partitions from request
-		buf = append(buf, byte(len(topicReq.Partitions)+1)) // Compact array length
+	// Topics are named up to v12 and identified by ID from v13
+	image := metadata.Image()
 
-		// Build a quick lookup of valid partitions if topic exists
-		validPartitions := make(map[int32]struct{})
-		if topicExists {
-			for _, p := range topicMeta.Partitions {
-				validPartitions[p.PartitionIndex] = struct{}{}
-			}
+	// MaxBytes caps the records across the whole response
+	remainingBytes := int(req.MaxBytes)
+	returnedData := false
+
+	// For each topic in the request, build a response
+	for _, topicReq := range req.Topics {
+		var topicMeta *metadata.TopicMetadata
+		unknownTopicError := UNKNOWN_TOPIC_ID
+		if version >= 13 {
+			topicMeta, _ = image.TopicByID(topicReq.TopicId)
+		} else {
+			topicMeta, _ = image.TopicByName(topicReq.Topic)
+			unknownTopicError = UNKNOWN_TOPIC_OR_PARTITION
 		}
 
+		topicResp := protocol.FetchResponseFetchableTopicResponse{Topic: topicReq.Topic, TopicId: topicReq.TopicId}
+
 		// For each partition in the request, build a partition response
 		for _, partReq := range topicReq.Partitions {
-			// PartitionIndex (INT32) - same as request
-			buf = AppendInt32(buf, partReq.Partition)
-
-			// ErrorCode (INT16)
-			errCode := ErrNone
-			if !topicExists {
-				errCode = UNKNOWN_TOPIC_ID
+			var partResp protocol.FetchResponsePartitionData
+			partResp.Default()
+			partResp.PartitionIndex = partReq.Partition
+			partResp.HighWatermark = -1
+
+			if topicMeta == nil {
+				partResp.ErrorCode = unknownTopicError
+			} else if _, ok := topicMeta.Partition(partReq.Partition); !ok {
+				partResp.ErrorCode = UNKNOWN_TOPIC_OR_PARTITION
 			} else {
-				if _, ok := validPartitions[partReq.Partition]; !ok {
-					errCode = UNKNOWN_TOPIC_ID
+				// The first partition with data may exceed the limits so consumers always make progress
+				maxBytes := min(int(partReq.PartitionMaxBytes), remainingBytes)
+				fetched := readPartitionRecords(topicMeta.Name, partReq, req.IsolationLevel, maxBytes, !returnedData)
+				partResp.ErrorCode = fetched.ErrorCode
+				partResp.HighWatermark = fetched.HighWatermark
+				partResp.LastStableOffset = fetched.LastStableOffset
+				partResp.LogStartOffset = fetched.LogStartOffset
+				partResp.AbortedTransactions = fetched.AbortedTransactions
+				partResp.Records = fetched.Records
+
+				remainingBytes -= len(fetched.Records)
+				if len(fetched.Records) > 0 {
+					returnedData = true
 				}
+				result.recordBytes += len(fetched.Records)
+				result.partitionKeys = append(result.partitionKeys, partitionKey(topicMeta.Name, partReq.Partition))
+			}
+			if partResp.ErrorCode != ErrNone {
+				result.hasErrors = true
 			}
-			buf = AppendInt16(buf, errCode)
 
-			// HighWatermark (INT64) = fetch_offset (for now)
-			buf = AppendInt64(buf, partReq.FetchOffset)
+			if partResp.Records == nil {
+				partResp.Records = []byte{}
+			}
+			topicResp.Partitions = append(topicResp.Partitions, partResp)
+		}
+		response.Responses = append(response.Responses, topicResp)
+	}
 
-			// LastStableOffset (INT64) = fetch_offset (for now)
-			buf = AppendInt64(buf, partReq.FetchOffset)
+	buf := response.Encode(version)
+	fmt.Printf("Built Fetch response with %d topics, body size=%d\n", len(req.Topics), len(buf))
+	return buf, result
+}
 
-			// LogStartOffset (INT64) = from request
-			buf = AppendInt64(buf, partReq.LogStartOffset)
+// fetchedPartition is the result of reading one partition for a Fetch
+type fetchedPartition struct {
+	ErrorCode        int16
+	HighWatermark    int64
+	LastStableOffset int64
+	LogStartOffset   int64
+	// AbortedTransactions is only listed for read_committed fetches, null otherwise
+	AbortedTransactions []protocol.FetchResponseAbortedTransaction
+	Records             []byte
+}
 
-			// AbortedTransactions (COMPACT_ARRAY) - empty
-			buf = append(buf, 0x01) // Length = 1 (0 elements)
+// readPartitionRecords reads the partition log from the requested offset, up to
+// maxBytes. read_committed fetches stop at the last stable offset and list the
+// aborted transactions in the returned range, so the consumer can drop their records.
+func readPartitionRecords(topic string, partReq protocol.FetchRequestFetchPartition, isolationLevel int8, maxBytes int, minOneBatch bool) fetchedPartition {
+	result := fetchedPartition{ErrorCode: ErrNone, HighWatermark: -1, LastStableOffset: -1, LogStartOffset: -1}
+
+	log, err := storage.GetLog(topic, partReq.Partition)
+	if err != nil {
+		fmt.Printf("Warning: Could not open partition log: %v\n", err)
+		result.ErrorCode = KAFKA_STORAGE_ERROR
+		return result
+	}
 
-			// PreferredReadReplica (INT32) = -1 (no preference)
-			buf = AppendInt32(buf, -1)
+	// Single broker: every appended record is replicated, so the high watermark is the log end offset
+	result.HighWatermark = log.LogEndOffset()
+	result.LastStableOffset = log.LastStableOffset()
+	result.LogStartOffset = log.LogStartOffset()
 
-			if errCode == ErrNone {
-				buf = append(buf, metadata.LoadPartitionMetadata(fmt.Sprintf("%x", topicReq.TopicID), int(partReq.Partition))...)
-			} else {
-				// If error, write empty record set
-				buf = append(buf, 0x00)
-			}
+	if partReq.FetchOffset < result.LogStartOffset || partReq.FetchOffset > result.HighWatermark {
+		result.ErrorCode = OFFSET_OUT_OF_RANGE
+		return result
+	}
 
-			// TAG_BUFFER for partition (flexible version)
-			buf = append(buf, uint8(0x00))
+	if isolationLevel == ReadCommitted {
+		result.Records, err = log.ReadUpTo(partReq.FetchOffset, result.LastStableOffset, max(maxBytes, 0), minOneBatch)
+		result.AbortedTransactions = make([]protocol.FetchResponseAbortedTransaction, 0)
+		for _, aborted := range log.CollectAbortedTxns(partReq.FetchOffset, result.LastStableOffset) {
+			result.AbortedTransactions = append(result.AbortedTransactions, protocol.FetchResponseAbortedTransaction{
+				ProducerId:  aborted.ProducerID,
+				FirstOffset: aborted.FirstOffset,
+			})
 		}
+	} else {
+		result.Records, err = log.Read(partReq.FetchOffset, max(maxBytes, 0), minOneBatch)
 	}
-
-	// Final TAG_BUFFER for responses array (flexible version)
-	buf = append(buf, uint8(0x00))
-	buf = append(buf, uint8(0x00))
-	fmt.Printf("Sent Fetch response with %d topics, body size=%d\n", len(req.Topics), len(buf))
-	return buf
+	if err != nil {
+		fmt.Printf("Warning: Could not read partition log: %v\n", err)
+		result.ErrorCode = storageErrorCode(err)
+		result.Records = nil
+	}
+	return result
 }
 
-func BuildProduceResponse(header RequestHeader, req ProduceRequest) []byte {
-	response := make([]byte, 0)
-	// TAG_BUFFER for main response
-	response = append(response, 0x00)
-	// TopicResponses (COMPACT_ARRAY)
-	response = append(response, byte(len(req.TopicData)+1))
+// producedPartition is a partition a Produce request appended to
+type producedPartition struct {
+	topic      string
+	partition  int32
+	lastOffset int64
+	// topicIndex and partitionIndex locate the partition in the response
+	topicIndex     int
+	partitionIndex int
+}
 
-	for _, topicReq := range req.TopicData {
-		// Topic Name (COMPACT_STRING)
-		response = append(response, byte(len(topicReq.Name)+1))
-		response = append(response, []byte(topicReq.Name)...)
+// produceResult summarizes the appends of a Produce request, so the handler
+// can apply its acks semantics
+type produceResult struct {
+	appended []producedPartition
+	failed   bool
+}
 
-		// PartitionResponses (COMPACT_ARRAY)
-		response = append(response, byte(len(topicReq.PartitionData)+1))
+// appendProduceRecords appends the records of every partition and returns the
+// response to send once the request's acks are satisfied
+func appendProduceRecords(req protocol.ProduceRequest) (protocol.ProduceResponse, produceResult) {
+	var response protocol.ProduceResponse
+	response.Default()
 
+	var result produceResult
+	validAcks := req.Acks == -1 || req.Acks == 0 || req.Acks == 1
+	for topicIndex, topicReq := range req.TopicData {
+		topicResp := protocol.ProduceResponseTopicProduceResponse{Name: topicReq.Name}
 		topicExists := metadata.ValidateTopicExists(topicReq.Name)
 
-		for _, partReq := range topicReq.PartitionData {
-			// Partition ID (INT32)
-			response = AppendInt32(response, partReq.Index)
-
-			errorCode := int16(0)
-			baseOffset := int64(0)
-			logStartOffset := int64(0)
-			if !topicExists {
-				errorCode = UNKNOWN_TOPIC_OR_PARTITION
-				baseOffset = -1
-				logStartOffset = -1
+		for partitionIndex, partReq := range topicReq.PartitionData {
+			var partResp protocol.ProduceResponsePartitionProduceResponse
+			partResp.Default()
+			partResp.Index = partReq.Index
+
+			if !validAcks {
+				partResp.ErrorCode = INVALID_REQUIRED_ACKS
+			} else if !topicExists {
+				partResp.ErrorCode = UNKNOWN_TOPIC_OR_PARTITION
 			} else if !metadata.ValidatePartitionExists(topicReq.Name, partReq.Index) {
-				errorCode = UNKNOWN_TOPIC_OR_PARTITION
-				baseOffset = -1
-				logStartOffset = -1
+				partResp.ErrorCode = UNKNOWN_TOPIC_OR_PARTITION
 			}
 
-			// ErrorCode (INT16)
-			response = AppendInt16(response, errorCode)
-			// BaseOffset (INT64)
-			response = AppendInt64(response, baseOffset)
-			// LogAppendTime (INT64)
-			response = AppendInt64(response, -1)
-			// LogStartOffset (INT64)
-			response = AppendInt64(response, logStartOffset)
-			// RecordErrors (COMPACT_ARRAY)
-			response = append(response, 0x01)
-			// ErrorMessage (COMPACT_STRING)
-			response = append(response, 0x00)
-			// TAG_BUFFER for partition response
-			response = append(response, 0x00)
-
-			if errorCode == 0 {
-				fmt.Printf("Produce to topic %s partition %d succeeded\n", topicReq.Name, partReq.Index)
-				if err := metadata.WriteRecordsToLog(topicReq.Name, partReq.Index, partReq.Records); err != nil {
+			if partResp.ErrorCode == ErrNone {
+				info, err := appendPartitionRecords(topicReq.Name, partReq)
+				var invalidRecords *storage.InvalidRecordsError
+				if errors.As(err, &invalidRecords) {
+					fmt.Printf("Rejected records for %s-%d: %v\n", topicReq.Name, partReq.Index, err)
+					partResp.ErrorCode = INVALID_RECORD
+					partResp.RecordErrors = recordErrors(invalidRecords)
+					partResp.ErrorMessage = errorMessage(err)
+				} else if errors.Is(err, storage.ErrCorruptRecords) {
+					fmt.Printf("Rejected records for %s-%d: %v\n", topicReq.Name, partReq.Index, err)
+					partResp.ErrorCode = CORRUPT_MESSAGE
+					partResp.ErrorMessage = errorMessage(err)
+				} else if errors.Is(err, storage.ErrOutOfOrderSequence) {
+					fmt.Printf("Rejected records for %s-%d: %v\n", topicReq.Name, partReq.Index, err)
+					partResp.ErrorCode = OUT_OF_ORDER_SEQUENCE_NUMBER
+					partResp.ErrorMessage = errorMessage(err)
+				} else if errors.Is(err, storage.ErrInvalidProducerEpoch) {
+					fmt.Printf("Rejected records for %s-%d: %v\n", topicReq.Name, partReq.Index, err)
+					partResp.ErrorCode = INVALID_PRODUCER_EPOCH
+					partResp.ErrorMessage = errorMessage(err)
+				} else if errors.Is(err, storage.ErrInvalidTxnState) {
+					fmt.Printf("Rejected records for %s-%d: %v\n", topicReq.Name, partReq.Index, err)
+					partResp.ErrorCode = INVALID_TXN_STATE
+					partResp.ErrorMessage = errorMessage(err)
+				} else if err != nil {
 					fmt.Printf("Failed to write records to log: %v\n", err)
+					partResp.ErrorCode = storageErrorCode(err)
+				} else {
+					fmt.Printf("Produce to topic %s partition %d succeeded at offset %d\n", topicReq.Name, partReq.Index, info.BaseOffset)
+					partResp.BaseOffset = info.BaseOffset
+					partResp.LogAppendTimeMs = info.LogAppendTime
+					partResp.LogStartOffset = info.LogStartOffset
+					fetchPurgatory.CheckAndComplete(partitionKey(topicReq.Name, partReq.Index))
+					result.appended = append(result.appended, producedPartition{
+						topic:          topicReq.Name,
+						partition:      partReq.Index,
+						lastOffset:     info.LastOffset,
+						topicIndex:     topicIndex,
+						partitionIndex: partitionIndex,
+					})
 				}
-			} else {
-				fmt.Printf("Produce to topic %s partition %d failed with error code %d\n", topicReq.Name, partReq.Index, errorCode)
 			}
+			if partResp.ErrorCode != ErrNone {
+				fmt.Printf("Produce to topic %s partition %d failed with error code %d\n", topicReq.Name, partReq.Index, partResp.ErrorCode)
+				partResp.BaseOffset = -1
+				partResp.LogStartOffset = -1
+				result.failed = true
+			}
+			topicResp.PartitionResponses = append(topicResp.PartitionResponses, partResp)
 		}
-		// TAG_BUFFER for topic response
-		response = append(response, 0x00)
+		response.Responses = append(response.Responses, topicResp)
 	}
-	// ThrottleTimeMs (INT32)
-	response = AppendInt32(response, 0)
-	// TAG_BUFFER for main response
-	response = append(response, 0x00)
+	return response, result
+}
 
-	fmt.Printf("Built Produce response: %d topics, body size=%d\n", len(req.TopicData), len(response))
-	return response
+func BuildMetadataResponse(version int16, req protocol.MetadataRequest) []byte {
+	var response protocol.MetadataResponse
+	response.Default()
+	image := metadata.Image()
+	topicsMetadata := image.Topics()
+
+	// Brokers - this broker only, which as a single node cluster is also the controller
+	response.Brokers = []protocol.MetadataResponseBroker{{NodeId: BrokerNodeID, Host: BrokerHost, Port: BrokerPort}}
+	response.ClusterId = nullableString(metadata.ClusterID)
+	response.ControllerId = BrokerNodeID
+
+	// Resolve requested topics; a null topic list, or an empty one in v0, means all topics
+	requested := req.Topics
+	if requested == nil || (version == 0 && len(requested) == 0) {
+		names := make([]string, 0, len(topicsMetadata))
+		for name := range topicsMetadata {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		requested = make([]protocol.MetadataRequestTopic, 0, len(names))
+		for _, name := range names {
+			requested = append(requested, protocol.MetadataRequestTopic{Name: &name})
+		}
+	}
+
+	for _, topicReq := range requested {
+		name := stringValue(topicReq.Name)
+		var topic *metadata.TopicMetadata
+		if name != "" {
+			topic, _ = image.TopicByName(name)
+		} else {
+			topic, _ = image.TopicByID(topicReq.TopicId)
+		}
+
+		var topicResp protocol.MetadataResponseTopic
+		topicResp.Default()
+		topicResp.TopicId = topicReq.TopicId
+		switch {
+		case topic != nil:
+			name = topic.Name
+			topicResp.TopicId = topic.TopicID
+		case name != "":
+			topicResp.ErrorCode = UNKNOWN_TOPIC_OR_PARTITION
+		default:
+			topicResp.ErrorCode = UNKNOWN_TOPIC_ID
+		}
+		// Null only for an unknown topic ID, which can't be named (v12+)
+		topicResp.Name = nullableString(name)
+
+		// Partitions sorted by partition index
+		var partitions []metadata.PartitionMetadata
+		if topic != nil {
+			partitions = append(partitions, topic.Partitions...)
+			sort.Slice(partitions, func(i, j int) bool {
+				return partitions[i].PartitionIndex < partitions[j].PartitionIndex
+			})
+		}
+		for _, partition := range partitions {
+			var partResp protocol.MetadataResponsePartition
+			partResp.Default()
+			partResp.PartitionIndex = partition.PartitionIndex
+			partResp.LeaderId = partition.LeaderID
+			partResp.LeaderEpoch = partition.LeaderEpoch
+			partResp.ReplicaNodes = partition.ReplicaNodes
+			partResp.IsrNodes = partition.IsrNodes
+			topicResp.Partitions = append(topicResp.Partitions, partResp)
+		}
+		response.Topics = append(response.Topics, topicResp)
+	}
+
+	buf := response.Encode(version)
+	fmt.Printf("Built Metadata response (version=%d, topics=%d, body_len=%d)\n",
+		version, len(requested), len(buf))
+
+	return buf
+}
+
+// nullableString returns s for a NULLABLE_STRING field, encoding "" as null
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// stringValue returns a decoded NULLABLE_STRING, reading null as ""
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// recordErrors lists the records that caused a produced record set to be dropped
+func recordErrors(err *storage.InvalidRecordsError) []protocol.ProduceResponseBatchIndexAndErrorMessage {
+	entries := make([]protocol.ProduceResponseBatchIndexAndErrorMessage, 0, len(err.RecordErrors))
+	for _, recordError := range err.RecordErrors {
+		message := recordError.Message
+		entries = append(entries, protocol.ProduceResponseBatchIndexAndErrorMessage{
+			BatchIndex:             recordError.BatchIndex,
+			BatchIndexErrorMessage: &message,
+		})
+	}
+	return entries
+}
+
+// storageErrorCode is the error code of a failed log operation: the log of a
+// topic deleted meanwhile is an unknown partition, anything else a storage error
+func storageErrorCode(err error) int16 {
+	if errors.Is(err, storage.ErrLogDeleted) {
+		return UNKNOWN_TOPIC_OR_PARTITION
+	}
+	return KAFKA_STORAGE_ERROR
+}
+
+// errorMessage returns err's text for a NULLABLE_STRING error message field
+func errorMessage(err error) *string {
+	message := err.Error()
+	return &message
+}
+
+// appendPartitionRecords writes produced records to the partition log
+func appendPartitionRecords(topic string, partReq protocol.ProduceRequestPartitionProduceData) (storage.AppendInfo, error) {
+	log, err := storage.GetLog(topic, partReq.Index)
+	if err != nil {
+		return storage.AppendInfo{}, err
+	}
+	return log.Append(partReq.Records)
+}
+
+func BuildFindCoordinatorResponse(version int16, req protocol.FindCoordinatorRequest) []byte {
+	var response protocol.FindCoordinatorResponse
+	response.Default()
+
+	// Before v4 the single key and its coordinator are at the top level
+	if version <= 3 {
+		coordinator := findCoordinator(req.KeyType, req.Key)
+		response.ErrorCode = coordinator.ErrorCode
+		response.NodeId = coordinator.NodeId
+		response.Host = coordinator.Host
+		response.Port = coordinator.Port
+		return response.Encode(version)
+	}
+
+	for _, key := range req.CoordinatorKeys {
+		response.Coordinators = append(response.Coordinators, findCoordinator(req.KeyType, key))
+	}
+	return response.Encode(version)
+}
+
+// findCoordinator answers one key of a FindCoordinator request. This broker
+// coordinates every group (key type 0) and transactional ID (key type 1).
+func findCoordinator(keyType int8, key string) protocol.FindCoordinatorResponseCoordinator {
+	coordinator := protocol.FindCoordinatorResponseCoordinator{Key: key, NodeId: BrokerNodeID, Host: BrokerHost, Port: BrokerPort}
+	if keyType != 0 && keyType != 1 {
+		coordinator.ErrorCode = INVALID_REQUEST
+	} else if key == "" && keyType == 0 {
+		coordinator.ErrorCode = group.INVALID_GROUP_ID
+	} else if key == "" {
+		coordinator.ErrorCode = INVALID_REQUEST
+	}
+	if coordinator.ErrorCode != ErrNone {
+		coordinator.NodeId, coordinator.Host, coordinator.Port = -1, "", -1
+	}
+	return coordinator
+}
+
+func BuildJoinGroupResponse(version int16, result group.JoinResult) []byte {
+	var response protocol.JoinGroupResponse
+	response.Default()
+	response.ErrorCode = result.ErrorCode
+	response.GenerationId = result.GenerationID
+	response.ProtocolType = nullableString(result.ProtocolType)
+	response.ProtocolName = nullableString(result.ProtocolName)
+	response.Leader = result.LeaderID
+	response.MemberId = result.MemberID
+	for _, member := range result.Members {
+		response.Members = append(response.Members, protocol.JoinGroupResponseMember{
+			MemberId:        member.MemberID,
+			GroupInstanceId: nullableString(member.GroupInstanceID),
+			Metadata:        member.Metadata,
+		})
+	}
+	return response.Encode(version)
+}
+
+func BuildSyncGroupResponse(version int16, result group.SyncResult) []byte {
+	var response protocol.SyncGroupResponse
+	response.Default()
+	response.ErrorCode = result.ErrorCode
+	response.ProtocolType = nullableString(result.ProtocolType)
+	response.ProtocolName = nullableString(result.ProtocolName)
+	response.Assignment = result.Assignment
+	return response.Encode(version)
+}
+
+func BuildHeartbeatResponse(version int16, errorCode int16) []byte {
+	var response protocol.HeartbeatResponse
+	response.Default()
+	response.ErrorCode = errorCode
+	return response.Encode(version)
+}
+
+// BuildLeaveGroupResponse answers the leaving members; memberErrors[i] is the
+// error of members[i]. The members are only listed from v3.
+func BuildLeaveGroupResponse(version int16, members []protocol.LeaveGroupRequestMemberIdentity, errorCode int16, memberErrors []int16) []byte {
+	var response protocol.LeaveGroupResponse
+	response.Default()
+	response.ErrorCode = errorCode
+	for i, member := range members {
+		memberResp := protocol.LeaveGroupResponseMemberResponse{MemberId: member.MemberId, GroupInstanceId: member.GroupInstanceId}
+		if i < len(memberErrors) {
+			memberResp.ErrorCode = memberErrors[i]
+		}
+		response.Members = append(response.Members, memberResp)
+	}
+	return response.Encode(version)
+}
+
+func BuildOffsetCommitResponse(version int16, req protocol.OffsetCommitRequest, errorCodes map[group.TopicPartition]int16) []byte {
+	var response protocol.OffsetCommitResponse
+	response.Default()
+	for _, topic := range req.Topics {
+		topicResp := protocol.OffsetCommitResponseTopic{Name: topic.Name}
+		for _, partition := range topic.Partitions {
+			topicResp.Partitions = append(topicResp.Partitions, protocol.OffsetCommitResponsePartition{
+				PartitionIndex: partition.PartitionIndex,
+				ErrorCode:      errorCodes[group.TopicPartition{Topic: topic.Name, Partition: partition.PartitionIndex}],
+			})
+		}
+		response.Topics = append(response.Topics, topicResp)
+	}
+	return response.Encode(version)
+}
+
+// offsetFetchGroups returns the groups of an OffsetFetch request: the single
+// v0-7 group, or the batched groups from v8
+func offsetFetchGroups(version int16, req protocol.OffsetFetchRequest) []protocol.OffsetFetchRequestGroup {
+	if version >= 8 {
+		return req.Groups
+	}
+	fetchGroup := protocol.OffsetFetchRequestGroup{GroupId: req.GroupId}
+	if req.Topics != nil {
+		fetchGroup.Topics = make([]protocol.OffsetFetchRequestTopics, 0, len(req.Topics))
+		for _, topic := range req.Topics {
+			fetchGroup.Topics = append(fetchGroup.Topics, protocol.OffsetFetchRequestTopics(topic))
+		}
+	}
+	return []protocol.OffsetFetchRequestGroup{fetchGroup}
+}
+
+// BuildOffsetFetchResponse encodes the committed offsets of each requested group;
+// offsets[i] holds the result for groups[i]
+func BuildOffsetFetchResponse(version int16, groups []protocol.OffsetFetchRequestGroup, offsets []map[group.TopicPartition]group.OffsetAndMetadata) []byte {
+	var response protocol.OffsetFetchResponse
+	response.Default()
+	for i, fetchGroup := range groups {
+		groupResp := protocol.OffsetFetchResponseGroup{GroupId: fetchGroup.GroupId}
+		if fetchGroup.GroupId == "" {
+			groupResp.ErrorCode = group.INVALID_GROUP_ID
+		}
+		for _, topic := range offsetFetchTopics(fetchGroup, offsets[i]) {
+			topicResp := protocol.OffsetFetchResponseTopics{Name: topic.Name}
+			for _, partitionIndex := range topic.PartitionIndexes {
+				offset := offsets[i][group.TopicPartition{Topic: topic.Name, Partition: partitionIndex}]
+				topicResp.Partitions = append(topicResp.Partitions, protocol.OffsetFetchResponsePartitions{
+					PartitionIndex:       partitionIndex,
+					CommittedOffset:      offset.Offset,
+					CommittedLeaderEpoch: offset.LeaderEpoch,
+					Metadata:             &offset.Metadata,
+				})
+			}
+			groupResp.Topics = append(groupResp.Topics, topicResp)
+		}
+		response.Groups = append(response.Groups, groupResp)
+	}
+
+	// Before v8 the single group is answered at the top level
+	if version <= 7 {
+		groupResp := response.Groups[0]
+		response.ErrorCode = groupResp.ErrorCode
+		for _, topic := range groupResp.Topics {
+			topicResp := protocol.OffsetFetchResponseTopic{Name: topic.Name}
+			for _, partition := range topic.Partitions {
+				topicResp.Partitions = append(topicResp.Partitions, protocol.OffsetFetchResponsePartition(partition))
+			}
+			response.Topics = append(response.Topics, topicResp)
+		}
+	}
+
+	return response.Encode(version)
+}
+
+// offsetFetchTopics returns the topics to answer for a group. When the request
+// asked for every partition (nil topics) they are listed sorted by topic and partition.
+func offsetFetchTopics(fetchGroup protocol.OffsetFetchRequestGroup, offsets map[group.TopicPartition]group.OffsetAndMetadata) []protocol.OffsetFetchRequestTopics {
+	if fetchGroup.Topics != nil {
+		return fetchGroup.Topics
+	}
+
+	partitionsByTopic := make(map[string][]int32)
+	for tp := range offsets {
+		partitionsByTopic[tp.Topic] = append(partitionsByTopic[tp.Topic], tp.Partition)
+	}
+	topics := make([]protocol.OffsetFetchRequestTopics, 0, len(partitionsByTopic))
+	for name, partitions := range partitionsByTopic {
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+		topics = append(topics, protocol.OffsetFetchRequestTopics{Name: name, PartitionIndexes: partitions})
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+	return topics
+}
+
+func BuildListOffsetsResponse(version int16, req protocol.ListOffsetsRequest) []byte {
+	var response protocol.ListOffsetsResponse
+	response.Default()
+	topicsMetadata := metadata.GetTopicMetadata()
+
+	for _, topic := range req.Topics {
+		topicResp := protocol.ListOffsetsResponseListOffsetsTopicResponse{Name: topic.Name}
+		topicMeta, topicExists := topicsMetadata[topic.Name]
+
+		for _, partReq := range topic.Partitions {
+			listed := listedOffset{ErrorCode: UNKNOWN_TOPIC_OR_PARTITION, Timestamp: -1, Offset: -1, LeaderEpoch: -1}
+			if topicExists {
+				if partition, ok := topicMeta.Partition(partReq.PartitionIndex); ok {
+					listed = listPartitionOffset(topic.Name, partReq, req.IsolationLevel, version)
+					listed.LeaderEpoch = partition.LeaderEpoch
+				}
+			}
+
+			partResp := protocol.ListOffsetsResponseListOffsetsPartitionResponse{
+				PartitionIndex:  partReq.PartitionIndex,
+				ErrorCode:       listed.ErrorCode,
+				OldStyleOffsets: []int64{},
+				Timestamp:       listed.Timestamp,
+				Offset:          listed.Offset,
+				LeaderEpoch:     listed.LeaderEpoch,
+			}
+			// OldStyleOffsets (v0) holds at most the one offset found
+			if listed.Offset >= 0 && partReq.MaxNumOffsets > 0 {
+				partResp.OldStyleOffsets = []int64{listed.Offset}
+			}
+			topicResp.Partitions = append(topicResp.Partitions, partResp)
+		}
+		response.Topics = append(response.Topics, topicResp)
+	}
+
+	return response.Encode(version)
+}
+
+// listedOffset is the result of a ListOffsets lookup for one partition
+type listedOffset struct {
+	ErrorCode   int16
+	Timestamp   int64
+	Offset      int64
+	LeaderEpoch int32
+}
+
+// listPartitionOffset resolves the requested timestamp against the partition log.
+// For read_committed the latest offset is the last stable offset.
+func listPartitionOffset(topic string, partReq protocol.ListOffsetsRequestListOffsetsPartition, isolationLevel int8, version int16) listedOffset {
+	result := listedOffset{ErrorCode: ErrNone, Timestamp: -1, Offset: -1, LeaderEpoch: -1}
+
+	log, err := storage.GetLog(topic, partReq.PartitionIndex)
+	if err != nil {
+		fmt.Printf("Warning: Could not open partition log: %v\n", err)
+		result.ErrorCode = KAFKA_STORAGE_ERROR
+		return result
+	}
+
+	var found storage.TimestampAndOffset
+	var exists bool
+	switch {
+	case partReq.Timestamp == LatestTimestamp && isolationLevel == ReadCommitted:
+		result.Offset = log.LastStableOffset()
+		return result
+	case partReq.Timestamp == LatestTimestamp:
+		result.Offset = log.LogEndOffset()
+		return result
+	case partReq.Timestamp == EarliestTimestamp || (partReq.Timestamp == EarliestLocalTimestamp && version >= 8):
+		result.Offset = log.LogStartOffset()
+		return result
+	case partReq.Timestamp == MaxTimestamp && version >= 7:
+		found, exists, err = log.FindMaxTimestamp()
+	case partReq.Timestamp == MaxTimestamp || partReq.Timestamp == EarliestLocalTimestamp:
+		// A sentinel defined by a later version than the request's
+		result.ErrorCode = UNSUPPORTED_VERSION
+		return result
+	case partReq.Timestamp >= 0:
+		found, exists, err = log.FindOffsetByTimestamp(partReq.Timestamp)
+	default:
+		// Any other negative timestamp is invalid in every version
+		result.ErrorCode = INVALID_REQUEST
+		return result
+	}
+
+	if err != nil {
+		fmt.Printf("Warning: Could not search partition log: %v\n", err)
+		result.ErrorCode = storageErrorCode(err)
+		return result
+	}
+	if exists {
+		result.Timestamp = found.Timestamp
+		result.Offset = found.Offset
+	}
+	return result
+}
+
+func BuildCreateTopicsResponse(version int16, req protocol.CreateTopicsRequest) []byte {
+	var response protocol.CreateTopicsResponse
+	response.Default()
+
+	// A name listed twice is rejected in every occurrence
+	nameCounts := make(map[string]int)
+	for _, topic := range req.Topics {
+		nameCounts[topic.Name]++
+	}
+
+	for _, topic := range req.Topics {
+		var created createdTopic
+		if nameCounts[topic.Name] > 1 {
+			created = createTopicError(INVALID_REQUEST, fmt.Sprintf("Duplicate topic name %s", topic.Name))
+		} else {
+			created = createTopic(topic, req.ValidateOnly)
+		}
+
+		var topicResp protocol.CreateTopicsResponseCreatableTopicResult
+		topicResp.Default()
+		topicResp.Name = topic.Name
+		topicResp.TopicId = created.TopicID
+		topicResp.ErrorCode = created.ErrorCode
+		topicResp.ErrorMessage = nullableString(created.ErrorMessage)
+		topicResp.NumPartitions = created.NumPartitions
+		topicResp.ReplicationFactor = created.ReplicationFactor
+		// Configs (v5+) stay null for a topic that failed
+		if created.ErrorCode == ErrNone {
+			topicResp.Configs = createdTopicConfigs(created.Configs)
+		}
+		response.Topics = append(response.Topics, topicResp)
+	}
+
+	return response.Encode(version)
+}
+
+// createdTopic is the outcome of creating (or only validating) one topic
+type createdTopic struct {
+	ErrorCode         int16
+	ErrorMessage      string
+	TopicID           [16]byte
+	NumPartitions     int32
+	ReplicationFactor int16
+	Configs           map[string]string // topic-level overrides
+}
+
+func createTopicError(errorCode int16, message string) createdTopic {
+	return createdTopic{ErrorCode: errorCode, ErrorMessage: message, NumPartitions: -1, ReplicationFactor: -1}
+}
+
+// createTopic validates a CreateTopics entry and, unless validateOnly, writes the
+// topic to the metadata log and creates its partition directories
+func createTopic(topic protocol.CreateTopicsRequestCreatableTopic, validateOnly bool) createdTopic {
+	if err := validateTopicName(topic.Name); err != nil {
+		return createTopicError(INVALID_TOPIC_EXCEPTION, err.Error())
+	}
+	if topic.Name == group.ConsumerOffsetsTopic || topic.Name == "__cluster_metadata" {
+		return createTopicError(INVALID_REQUEST, fmt.Sprintf("Creation of internal topic %s is prohibited", topic.Name))
+	}
+	if _, exists := metadata.Image().TopicByName(topic.Name); exists {
+		return createTopicError(TOPIC_ALREADY_EXISTS, fmt.Sprintf("Topic '%s' already exists.", topic.Name))
+	}
+
+	numPartitions := topic.NumPartitions
+	replicationFactor := topic.ReplicationFactor
+	if len(topic.Assignments) > 0 {
+		if numPartitions != -1 || replicationFactor != -1 {
+			return createTopicError(INVALID_REQUEST, "Both numPartitions or replicationFactor and replicasAssignments were set. Both cannot be used at the same time.")
+		}
+		if errorCode, message := validateAssignments(topic.Assignments); errorCode != ErrNone {
+			return createTopicError(errorCode, message)
+		}
+		numPartitions = int32(len(topic.Assignments))
+		replicationFactor = int16(len(topic.Assignments[0].BrokerIds))
+	}
+	if numPartitions == -1 {
+		numPartitions = DefaultNumPartitions
+	}
+	if replicationFactor == -1 {
+		replicationFactor = DefaultReplicationFactor
+	}
+	if numPartitions <= 0 {
+		return createTopicError(INVALID_PARTITIONS, "Number of partitions must be larger than 0.")
+	}
+	if replicationFactor <= 0 {
+		return createTopicError(INVALID_REPLICATION_FACTOR, "Replication factor must be larger than 0.")
+	}
+	if replicationFactor > 1 {
+		return createTopicError(INVALID_REPLICATION_FACTOR,
+			fmt.Sprintf("Unable to replicate the partition %d time(s): The target replication factor of %d cannot be reached because only 1 broker(s) are registered.", replicationFactor, replicationFactor))
+	}
+
+	configs := make(map[string]string, len(topic.Configs))
+	for _, config := range topic.Configs {
+		if errorCode, message := validateTopicConfig(config); errorCode != ErrNone {
+			return createTopicError(errorCode, message)
+		}
+		configs[config.Name] = *config.Value
+	}
+
+	result := createdTopic{ErrorCode: ErrNone, NumPartitions: numPartitions, ReplicationFactor: replicationFactor, Configs: configs}
+	if validateOnly {
+		return result
+	}
+
+	rand.Read(result.TopicID[:])
+	partitions := make([]metadata.PartitionMetadata, 0, numPartitions)
+	for i := int32(0); i < numPartitions; i++ {
+		partitions = append(partitions, metadata.PartitionMetadata{
+			PartitionIndex: i,
+			LeaderID:       BrokerNodeID,
+			LeaderEpoch:    0,
+			ReplicaNodes:   []int32{BrokerNodeID},
+			IsrNodes:       []int32{BrokerNodeID},
+		})
+	}
+
+	if err := metadata.CreateTopic(topic.Name, result.TopicID, partitions, configs); err != nil {
+		if errors.Is(err, metadata.ErrTopicAlreadyExists) {
+			return createTopicError(TOPIC_ALREADY_EXISTS, fmt.Sprintf("Topic '%s' already exists.", topic.Name))
+		}
+		fmt.Printf("Failed to create topic %s: %v\n", topic.Name, err)
+		return createTopicError(UNKNOWN_SERVER_ERROR, err.Error())
+	}
+
+	for _, partition := range partitions {
+		if _, err := storage.GetLog(topic.Name, partition.PartitionIndex); err != nil {
+			fmt.Printf("Warning: Could not create log for %s-%d: %v\n", topic.Name, partition.PartitionIndex, err)
+		}
+	}
+	return result
+}
+
+// topicConfigNames lists the topic-level configs kafgo understands
+var topicConfigNames = []string{"cleanup.policy", "compression.type", "delete.retention.ms", "retention.bytes", "retention.ms"}
+
+// topicConfigDefault returns the broker-wide value a topic config falls back to
+func topicConfigDefault(name string) string {
+	switch name {
+	case "cleanup.policy":
+		return storage.LogCleanupPolicy
+	case "compression.type":
+		return storage.CompressionType
+	case "delete.retention.ms":
+		return strconv.FormatInt(storage.DeleteRetentionMs, 10)
+	case "retention.bytes":
+		return strconv.FormatInt(storage.RetentionBytes, 10)
+	case "retention.ms":
+		return strconv.FormatInt(storage.RetentionMs, 10)
+	}
+	return ""
+}
+
+// createdTopicConfigs lists the CreateTopics v5+ Configs of a topic: every
+// known topic config with its override or broker default
+func createdTopicConfigs(configs map[string]string) []protocol.CreateTopicsResponseCreatableTopicConfigs {
+	entries := make([]protocol.CreateTopicsResponseCreatableTopicConfigs, 0, len(topicConfigNames))
+	for _, name := range topicConfigNames {
+		value, overridden := configs[name]
+		source := int8(5) // DEFAULT_CONFIG
+		if overridden {
+			source = 1 // TOPIC_CONFIG
+		} else {
+			value = topicConfigDefault(name)
+		}
+		entries = append(entries, protocol.CreateTopicsResponseCreatableTopicConfigs{
+			Name:         name,
+			Value:        &value,
+			ConfigSource: source,
+		})
+	}
+	return entries
+}
+
+// validateTopicConfig rejects unknown topic configs and invalid values
+func validateTopicConfig(config protocol.CreateTopicsRequestCreatableTopicConfig) (int16, string) {
+	if config.Value == nil {
+		return INVALID_CONFIG, fmt.Sprintf("Null value not supported for topic configs: %s", config.Name)
+	}
+	switch config.Name {
+	case "compression.type":
+		if !compress.ValidCompressionType(*config.Value) {
+			return INVALID_CONFIG, fmt.Sprintf("Invalid value %s for configuration compression.type: String must be one of: uncompressed, zstd, lz4, snappy, gzip, producer", *config.Value)
+		}
+		return ErrNone, ""
+	case "cleanup.policy":
+		for _, policy := range strings.Split(*config.Value, ",") {
+			if policy = strings.TrimSpace(policy); policy != "compact" && policy != "delete" {
+				return INVALID_CONFIG, fmt.Sprintf("Invalid value %s for configuration cleanup.policy: String must be one of: compact, delete", *config.Value)
+			}
+		}
+		return ErrNone, ""
+	case "delete.retention.ms", "retention.bytes", "retention.ms":
+		value, err := strconv.ParseInt(*config.Value, 10, 64)
+		if err != nil {
+			return INVALID_CONFIG, fmt.Sprintf("Invalid value %s for configuration %s: Not a number of type LONG", *config.Value, config.Name)
+		}
+		if config.Name == "retention.ms" && value < -1 {
+			return INVALID_CONFIG, fmt.Sprintf("Invalid value %s for configuration retention.ms: Value must be at least -1", *config.Value)
+		}
+		if config.Name == "delete.retention.ms" && value < 0 {
+			return INVALID_CONFIG, fmt.Sprintf("Invalid value %s for configuration delete.retention.ms: Value must be at least 0", *config.Value)
+		}
+		return ErrNone, ""
+	}
+	return INVALID_CONFIG, fmt.Sprintf("Unknown topic config name: %s", config.Name)
+}
+
+// validateTopicName applies Kafka's topic naming rules
+func validateTopicName(name string) error {
+	if name == "" {
+		return fmt.Errorf("Topic name is illegal, it can't be empty")
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("Topic name cannot be \".\" or \"..\"")
+	}
+	if len(name) > 249 {
+		return fmt.Errorf("Topic name is illegal, it can't be longer than 249 characters, topic name: %s", name)
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return fmt.Errorf("Topic name %s is illegal, it contains a character other than ASCII alphanumerics, '.', '_' and '-'", name)
+		}
+	}
+	return nil
+}
+
+// validateAssignments checks manual replica assignments: partitions 0..n-1, each
+// with the same number of distinct replicas, all on this broker
+func validateAssignments(assignments []protocol.CreateTopicsRequestCreatableReplicaAssignment) (int16, string) {
+	seen := make(map[int32]bool)
+	for _, assignment := range assignments {
+		if assignment.PartitionIndex < 0 || assignment.PartitionIndex >= int32(len(assignments)) || seen[assignment.PartitionIndex] {
+			return INVALID_REPLICA_ASSIGNMENT, "Partitions should be a consecutive 0-based integer sequence"
+		}
+		seen[assignment.PartitionIndex] = true
+
+		if len(assignment.BrokerIds) == 0 || len(assignment.BrokerIds) != len(assignments[0].BrokerIds) {
+			return INVALID_REPLICA_ASSIGNMENT, "All partitions should have the same number of replicas"
+		}
+		replicas := make(map[int32]bool)
+		for _, brokerID := range assignment.BrokerIds {
+			if replicas[brokerID] {
+				return INVALID_REPLICA_ASSIGNMENT, fmt.Sprintf("Duplicate brokers not allowed in replica assignment for partition %d", assignment.PartitionIndex)
+			}
+			replicas[brokerID] = true
+			if brokerID != BrokerNodeID {
+				return INVALID_REPLICA_ASSIGNMENT, fmt.Sprintf("Unknown broker %d in replica assignment for partition %d", brokerID, assignment.PartitionIndex)
+			}
+		}
+	}
+	return ErrNone, ""
+}
+
+func BuildDeleteTopicsResponse(version int16, req protocol.DeleteTopicsRequest) []byte {
+	var response protocol.DeleteTopicsResponse
+	response.Default()
+
+	// Topics are named in TopicNames up to v5 and by name or ID in Topics from v6
+	topics := req.Topics
+	if version <= 5 {
+		topics = make([]protocol.DeleteTopicsRequestDeleteTopicState, 0, len(req.TopicNames))
+		for _, name := range req.TopicNames {
+			topics = append(topics, protocol.DeleteTopicsRequestDeleteTopicState{Name: &name})
+		}
+	}
+
+	for _, topic := range topics {
+		deleted := deleteTopic(topic)
+		response.Responses = append(response.Responses, protocol.DeleteTopicsResponseDeletableTopicResult{
+			Name:         nullableString(deleted.Name),
+			TopicId:      deleted.TopicID,
+			ErrorCode:    deleted.ErrorCode,
+			ErrorMessage: nullableString(deleted.ErrorMessage),
+		})
+	}
+
+	return response.Encode(version)
+}
+
+// deletedTopic is the outcome of deleting one topic
+type deletedTopic struct {
+	Name         string
+	TopicID      [16]byte
+	ErrorCode    int16
+	ErrorMessage string
+}
+
+// deleteTopic removes a topic, named either by name or by ID, from the metadata log
+// and marks its partition directories for deletion
+func deleteTopic(topic protocol.DeleteTopicsRequestDeleteTopicState) deletedTopic {
+	result := deletedTopic{Name: stringValue(topic.Name), TopicID: topic.TopicId, ErrorCode: ErrNone}
+
+	var zeroID [16]byte
+	byID := topic.TopicId != zeroID
+	if byID == (result.Name != "") {
+		result.ErrorCode = INVALID_REQUEST
+		result.ErrorMessage = "Exactly one of topic name and topic ID must be set"
+		return result
+	}
+
+	var topicMeta *metadata.TopicMetadata
+	var exists bool
+	if byID {
+		topicMeta, exists = metadata.Image().TopicByID(topic.TopicId)
+	} else {
+		topicMeta, exists = metadata.Image().TopicByName(result.Name)
+	}
+	if !exists {
+		if byID {
+			result.ErrorCode = UNKNOWN_TOPIC_ID
+		} else {
+			result.ErrorCode = UNKNOWN_TOPIC_OR_PARTITION
+		}
+		return result
+	}
+	result.Name = topicMeta.Name
+	result.TopicID = topicMeta.TopicID
+
+	removed, err := metadata.DeleteTopic(topicMeta.TopicID)
+	if err != nil {
+		if errors.Is(err, metadata.ErrUnknownTopic) {
+			result.ErrorCode = UNKNOWN_TOPIC_ID
+			return result
+		}
+		fmt.Printf("Failed to delete topic %s: %v\n", topicMeta.Name, err)
+		result.ErrorCode = UNKNOWN_SERVER_ERROR
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	for _, partition := range removed.Partitions {
+		if err := storage.DeleteLog(removed.Name, partition.PartitionIndex); err != nil {
+			fmt.Printf("Warning: Could not delete log for %s-%d: %v\n", removed.Name, partition.PartitionIndex, err)
+		}
+		// Wake parked fetches so they notice the partition is gone
+		fetchPurgatory.CheckAndComplete(partitionKey(removed.Name, partition.PartitionIndex))
+	}
+	return result
+}
+
+func BuildInitProducerIdResponse(version int16, errorCode int16, producerID int64, producerEpoch int16) []byte {
+	var response protocol.InitProducerIdResponse
+	response.Default()
+	response.ErrorCode = errorCode
+	response.ProducerId = producerID
+	response.ProducerEpoch = producerEpoch
+	return response.Encode(version)
+}
+
+func BuildAddPartitionsToTxnResponse(version int16, req protocol.AddPartitionsToTxnRequest, errorCodes map[txn.TopicPartition]int16) []byte {
+	var response protocol.AddPartitionsToTxnResponse
+	response.Default()
+	for _, topic := range req.Topics {
+		topicResp := protocol.AddPartitionsToTxnResponseAddPartitionsToTxnTopicResult{Name: topic.Name}
+		for _, partition := range topic.Partitions {
+			topicResp.Results = append(topicResp.Results, protocol.AddPartitionsToTxnResponseAddPartitionsToTxnPartitionResult{
+				PartitionIndex: partition,
+				ErrorCode:      errorCodes[txn.TopicPartition{Topic: topic.Name, Partition: partition}],
+			})
+		}
+		response.Results = append(response.Results, topicResp)
+	}
+	return response.Encode(version)
+}
+
+func BuildAddOffsetsToTxnResponse(version int16, errorCode int16) []byte {
+	var response protocol.AddOffsetsToTxnResponse
+	response.Default()
+	response.ErrorCode = errorCode
+	return response.Encode(version)
+}
+
+func BuildEndTxnResponse(version int16, errorCode int16) []byte {
+	var response protocol.EndTxnResponse
+	response.Default()
+	response.ErrorCode = errorCode
+	return response.Encode(version)
+}
+
+func BuildTxnOffsetCommitResponse(version int16, req protocol.TxnOffsetCommitRequest, errorCodes map[group.TopicPartition]int16) []byte {
+	var response protocol.TxnOffsetCommitResponse
+	response.Default()
+	for _, topic := range req.Topics {
+		topicResp := protocol.TxnOffsetCommitResponseTopic{Name: topic.Name}
+		for _, partition := range topic.Partitions {
+			topicResp.Partitions = append(topicResp.Partitions, protocol.TxnOffsetCommitResponsePartition{
+				PartitionIndex: partition.PartitionIndex,
+				ErrorCode:      errorCodes[group.TopicPartition{Topic: topic.Name, Partition: partition.PartitionIndex}],
+			})
+		}
+		response.Topics = append(response.Topics, topicResp)
+	}
+	return response.Encode(version)
+}
+
+// describeLogDirs returns the log directories with the partitions req asks
+// for. Topics without a requested partition in a directory are left out.
+func describeLogDirs(req protocol.DescribeLogDirsRequest) []storage.LogDirInfo {
+	infos := storage.DescribeLogDirs()
+	if req.Topics == nil {
+		return infos
+	}
+
+	requested := make(map[string]map[int32]bool, len(req.Topics))
+	for _, topic := range req.Topics {
+		if requested[topic.Topic] == nil {
+			requested[topic.Topic] = make(map[int32]bool)
+		}
+		for _, partition := range topic.Partitions {
+			requested[topic.Topic][partition] = true
+		}
+	}
+	for i := range infos {
+		kept := infos[i].Partitions[:0]
+		for _, partition := range infos[i].Partitions {
+			if requested[partition.Topic][partition.Partition] {
+				kept = append(kept, partition)
+			}
+		}
+		infos[i].Partitions = kept
+	}
+	return infos
+}
+
+// BuildDescribeLogDirsResponse encodes one result per log directory; an
+// offline directory reports KAFKA_STORAGE_ERROR and no partitions
+func BuildDescribeLogDirsResponse(version int16, errorCode int16, infos []storage.LogDirInfo) []byte {
+	var response protocol.DescribeLogDirsResponse
+	response.Default()
+	response.ErrorCode = errorCode
+
+	for _, info := range infos {
+		var result protocol.DescribeLogDirsResponseDescribeLogDirsResult
+		result.Default()
+		if info.Err != nil {
+			result.ErrorCode = KAFKA_STORAGE_ERROR
+		}
+		result.LogDir = info.Dir
+		result.TotalBytes = info.TotalBytes
+		result.UsableBytes = info.UsableBytes
+
+		// Group the sorted partitions by topic. IsFutureKey stays false: there
+		// are no reassignments between directories.
+		for i, partition := range info.Partitions {
+			if i == 0 || partition.Topic != info.Partitions[i-1].Topic {
+				result.Topics = append(result.Topics, protocol.DescribeLogDirsResponseDescribeLogDirsTopic{Name: partition.Topic})
+			}
+			topic := &result.Topics[len(result.Topics)-1]
+			topic.Partitions = append(topic.Partitions, protocol.DescribeLogDirsResponseDescribeLogDirsPartition{
+				PartitionIndex: partition.Partition,
+				PartitionSize:  partition.Size,
+				OffsetLag:      partition.OffsetLag,
+			})
+		}
+		response.Results = append(response.Results, result)
+	}
 
+	return response.Encode(version)
 }
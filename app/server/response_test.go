@@ -0,0 +1,773 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"kafgo/app/metadata"
+	"kafgo/app/protocol"
+	"kafgo/app/storage"
+)
+
+// TestMain points the partition logs and the metadata log at a scratch
+// directory shared by the package's tests
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "server-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	storage.LogDirs = []string{filepath.Join(dir, "logs")}
+	metadata.MetadataLogDir = filepath.Join(dir, "metadata")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestReadPartitionRecordsIsolation(t *testing.T) {
+	log, err := storage.GetLog("payments", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Producer 1 aborts offset 0, producer 2 leaves offset 3 open
+	batch := func(producerID int64) []byte {
+		batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, 1700000000000)
+		if producerID >= 0 {
+			batch.Attributes |= metadata.AttributeTransactional
+			batch.ProducerID = producerID
+			batch.ProducerEpoch = 0
+			batch.BaseSequence = 0
+		}
+		return batch.Encode()
+	}
+	for _, step := range []func() error{
+		func() error { _, err := log.Append(batch(1)); return err },
+		func() error { _, err := log.Append(batch(-1)); return err },
+		func() error { _, err := log.AppendControlMarker(1, 0, 0, false); return err },
+		func() error { _, err := log.Append(batch(2)); return err },
+		func() error { _, err := log.Append(batch(-1)); return err },
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	producer1 := protocol.FetchResponseAbortedTransaction{ProducerId: 1, FirstOffset: 0}
+
+	tests := []struct {
+		name           string
+		isolationLevel int8
+		fetchOffset    int64
+		wantLastOffset int64 // of the last batch returned, or -1 for none
+		wantAborted    []protocol.FetchResponseAbortedTransaction
+	}{
+		{"read_uncommitted reads past the open transaction", ReadUncommitted, 0, 4, nil},
+		{"read_committed stops at the last stable offset", ReadCommitted, 0, 2, []protocol.FetchResponseAbortedTransaction{producer1}},
+		{"read_committed from inside the aborted transaction", ReadCommitted, 1, 2, []protocol.FetchResponseAbortedTransaction{producer1}},
+		{"read_committed at the last stable offset", ReadCommitted, 3, -1, []protocol.FetchResponseAbortedTransaction{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partReq := protocol.FetchRequestFetchPartition{Partition: 0, FetchOffset: tt.fetchOffset, PartitionMaxBytes: 1024 * 1024}
+			fetched := readPartitionRecords("payments", partReq, tt.isolationLevel, 1024*1024, true)
+			if fetched.ErrorCode != ErrNone {
+				t.Fatalf("error code %d", fetched.ErrorCode)
+			}
+			if fetched.HighWatermark != 5 || fetched.LastStableOffset != 3 {
+				t.Errorf("high watermark %d and last stable offset %d, want 5 and 3", fetched.HighWatermark, fetched.LastStableOffset)
+			}
+
+			lastOffset := int64(-1)
+			reader := bytes.NewReader(fetched.Records)
+			for reader.Len() > 0 {
+				batch, err := metadata.ReadRecordBatch(reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				lastOffset = batch.LastOffset()
+			}
+			if lastOffset != tt.wantLastOffset {
+				t.Errorf("records end at offset %d, want %d", lastOffset, tt.wantLastOffset)
+			}
+
+			// Null for read_uncommitted, a (possibly empty) list for read_committed
+			if (fetched.AbortedTransactions == nil) != (tt.wantAborted == nil) || len(fetched.AbortedTransactions) != len(tt.wantAborted) {
+				t.Fatalf("aborted transactions %+v, want %+v", fetched.AbortedTransactions, tt.wantAborted)
+			}
+			for i := range tt.wantAborted {
+				if fetched.AbortedTransactions[i] != tt.wantAborted[i] {
+					t.Errorf("aborted transaction %d = %+v, want %+v", i, fetched.AbortedTransactions[i], tt.wantAborted[i])
+				}
+			}
+		})
+	}
+}
+
+// createTestTopic creates a topic whose partitions are led by this broker and
+// returns its ID
+func createTestTopic(t *testing.T, name string, partitions int32) [16]byte {
+	t.Helper()
+	topicID := [16]byte{byte(len(name)), name[0], name[len(name)-1], 1}
+	copy(topicID[4:], name)
+	assignments := make([]metadata.PartitionMetadata, 0, partitions)
+	for i := range partitions {
+		assignments = append(assignments, metadata.PartitionMetadata{
+			PartitionIndex: i,
+			LeaderID:       BrokerNodeID,
+			ReplicaNodes:   []int32{BrokerNodeID},
+			IsrNodes:       []int32{BrokerNodeID},
+		})
+	}
+	if err := metadata.CreateTopic(name, topicID, assignments, nil); err != nil {
+		t.Fatal(err)
+	}
+	return topicID
+}
+
+func TestBuildMetadataResponse(t *testing.T) {
+	ordersID := createTestTopic(t, "metadata-orders", 3)
+	createTestTopic(t, "metadata-payments", 1)
+	name := func(s string) *string { return &s }
+	// metadataTopic is the error code and partition count of a topic answered
+	type metadataTopic struct {
+		errorCode  int16
+		partitions int
+	}
+
+	tests := []struct {
+		name    string
+		version int16
+		topics  []protocol.MetadataRequestTopic
+		want    map[string]metadataTopic
+	}{
+		{
+			name:    "topic by name",
+			version: 1,
+			topics:  []protocol.MetadataRequestTopic{{Name: name("metadata-orders")}},
+			want:    map[string]metadataTopic{"metadata-orders": {0, 3}},
+		},
+		{
+			name:    "unknown topic",
+			version: 9,
+			topics:  []protocol.MetadataRequestTopic{{Name: name("metadata-missing")}, {Name: name("metadata-payments")}},
+			want: map[string]metadataTopic{
+				"metadata-missing":  {UNKNOWN_TOPIC_OR_PARTITION, 0},
+				"metadata-payments": {0, 1},
+			},
+		},
+		{
+			name:    "topic by ID",
+			version: 12,
+			topics:  []protocol.MetadataRequestTopic{{TopicId: ordersID}},
+			want:    map[string]metadataTopic{"metadata-orders": {0, 3}},
+		},
+		{
+			name:    "unknown topic ID",
+			version: 12,
+			topics:  []protocol.MetadataRequestTopic{{TopicId: [16]byte{0xff}}},
+			want:    map[string]metadataTopic{"": {UNKNOWN_TOPIC_ID, 0}},
+		},
+		{
+			name:    "all topics with a null list",
+			version: 4,
+			topics:  nil,
+			want: map[string]metadataTopic{
+				"metadata-orders":   {0, 3},
+				"metadata-payments": {0, 1},
+			},
+		},
+		{
+			name:    "all topics with an empty v0 list",
+			version: 0,
+			topics:  []protocol.MetadataRequestTopic{},
+			want: map[string]metadataTopic{
+				"metadata-orders":   {0, 3},
+				"metadata-payments": {0, 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response protocol.MetadataResponse
+			if err := response.Decode(BuildMetadataResponse(tt.version, protocol.MetadataRequest{Topics: tt.topics}), tt.version); err != nil {
+				t.Fatal(err)
+			}
+
+			broker := protocol.MetadataResponseBroker{NodeId: BrokerNodeID, Host: BrokerHost, Port: BrokerPort}
+			if len(response.Brokers) != 1 || response.Brokers[0].NodeId != broker.NodeId ||
+				response.Brokers[0].Host != broker.Host || response.Brokers[0].Port != broker.Port {
+				t.Errorf("brokers %+v, want only %+v", response.Brokers, broker)
+			}
+			if tt.version >= 1 && response.ControllerId != BrokerNodeID {
+				t.Errorf("controller %d, want %d", response.ControllerId, BrokerNodeID)
+			}
+
+			// Topics created by other tests may also be listed
+			topics := make(map[string]protocol.MetadataResponseTopic)
+			for _, topic := range response.Topics {
+				topics[stringValue(topic.Name)] = topic
+			}
+			for topicName, want := range tt.want {
+				topic, found := topics[topicName]
+				if !found {
+					t.Errorf("topic %q missing from %d topics", topicName, len(response.Topics))
+					continue
+				}
+				if topic.ErrorCode != want.errorCode || len(topic.Partitions) != want.partitions {
+					t.Errorf("topic %q: error %d with %d partitions, want error %d with %d",
+						topicName, topic.ErrorCode, len(topic.Partitions), want.errorCode, want.partitions)
+				}
+				for i, partition := range topic.Partitions {
+					if partition.PartitionIndex != int32(i) || partition.LeaderId != BrokerNodeID {
+						t.Errorf("topic %q: partition %d is %d led by %d", topicName, i, partition.PartitionIndex, partition.LeaderId)
+					}
+				}
+				if tt.version >= 10 && want.errorCode == 0 && topic.TopicId == ([16]byte{}) {
+					t.Errorf("topic %q has no topic ID", topicName)
+				}
+			}
+		})
+	}
+}
+
+// batchOffsets returns the last offset of every batch in records
+func batchOffsets(t *testing.T, records []byte) []int64 {
+	t.Helper()
+	offsets := make([]int64, 0)
+	reader := bytes.NewReader(records)
+	for reader.Len() > 0 {
+		batch, err := metadata.ReadRecordBatch(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, batch.LastOffset())
+	}
+	return offsets
+}
+
+func TestBuildFetchResponse(t *testing.T) {
+	const topic = "fetch-orders"
+	topicID := createTestTopic(t, topic, 2)
+	// Partition 0 holds offsets 0-2 and partition 1 offset 0, one batch each
+	highWatermarks := []int64{3, 1}
+	batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, 1700000000000).Encode()
+	batchSize := int32(len(batch))
+	for partition, highWatermark := range highWatermarks {
+		log, err := storage.GetLog(topic, int32(partition))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range highWatermark {
+			if _, err := log.Append(batch); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// fetchedPartition is what a partition of the response holds
+	type fetchedPartition struct {
+		errorCode int16
+		// batches are the offsets of the batches returned
+		batches []int64
+	}
+	partition := func(index int32, fetchOffset int64, maxBytes int32) protocol.FetchRequestFetchPartition {
+		return protocol.FetchRequestFetchPartition{Partition: index, FetchOffset: fetchOffset, PartitionMaxBytes: maxBytes}
+	}
+	tests := []struct {
+		name       string
+		version    int16
+		topicID    [16]byte
+		maxBytes   int32
+		partitions []protocol.FetchRequestFetchPartition
+		want       []fetchedPartition
+	}{
+		{
+			name:       "whole partitions",
+			version:    12,
+			maxBytes:   1024 * 1024,
+			partitions: []protocol.FetchRequestFetchPartition{partition(0, 0, 1024*1024), partition(1, 0, 1024*1024)},
+			want:       []fetchedPartition{{ErrNone, []int64{0, 1, 2}}, {ErrNone, []int64{0}}},
+		},
+		{
+			name:       "from the fetch offset",
+			version:    12,
+			maxBytes:   1024 * 1024,
+			partitions: []protocol.FetchRequestFetchPartition{partition(0, 1, 1024*1024)},
+			want:       []fetchedPartition{{ErrNone, []int64{1, 2}}},
+		},
+		{
+			name:       "partition_max_bytes truncates each partition",
+			version:    12,
+			maxBytes:   1024 * 1024,
+			partitions: []protocol.FetchRequestFetchPartition{partition(0, 0, 2*batchSize), partition(1, 0, 2*batchSize)},
+			want:       []fetchedPartition{{ErrNone, []int64{0, 1}}, {ErrNone, []int64{0}}},
+		},
+		{
+			name:       "max_bytes truncates the response",
+			version:    12,
+			maxBytes:   2 * batchSize,
+			partitions: []protocol.FetchRequestFetchPartition{partition(0, 0, 1024*1024), partition(1, 0, 1024*1024)},
+			want:       []fetchedPartition{{ErrNone, []int64{0, 1}}, {ErrNone, []int64{}}},
+		},
+		{
+			name:       "first batch returned past the limits",
+			version:    12,
+			maxBytes:   1,
+			partitions: []protocol.FetchRequestFetchPartition{partition(0, 0, 1), partition(1, 0, 1)},
+			want:       []fetchedPartition{{ErrNone, []int64{0}}, {ErrNone, []int64{}}},
+		},
+		{
+			name:       "at the high watermark",
+			version:    12,
+			maxBytes:   1024 * 1024,
+			partitions: []protocol.FetchRequestFetchPartition{partition(0, 3, 1024*1024)},
+			want:       []fetchedPartition{{ErrNone, []int64{}}},
+		},
+		{
+			name:       "past the high watermark",
+			version:    12,
+			maxBytes:   1024 * 1024,
+			partitions: []protocol.FetchRequestFetchPartition{partition(0, 4, 1024*1024), partition(1, 0, 1024*1024)},
+			want:       []fetchedPartition{{OFFSET_OUT_OF_RANGE, []int64{}}, {ErrNone, []int64{0}}},
+		},
+		{
+			name:       "unknown partition",
+			version:    12,
+			maxBytes:   1024 * 1024,
+			partitions: []protocol.FetchRequestFetchPartition{partition(7, 0, 1024*1024)},
+			want:       []fetchedPartition{{UNKNOWN_TOPIC_OR_PARTITION, []int64{}}},
+		},
+		{
+			name:       "topic ID",
+			version:    13,
+			topicID:    topicID,
+			maxBytes:   1024 * 1024,
+			partitions: []protocol.FetchRequestFetchPartition{partition(1, 0, 1024*1024)},
+			want:       []fetchedPartition{{ErrNone, []int64{0}}},
+		},
+		{
+			name:       "unknown topic ID",
+			version:    13,
+			topicID:    [16]byte{0xff},
+			maxBytes:   1024 * 1024,
+			partitions: []protocol.FetchRequestFetchPartition{partition(0, 0, 1024*1024)},
+			want:       []fetchedPartition{{UNKNOWN_TOPIC_ID, []int64{}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := protocol.FetchRequest{
+				MaxBytes: tt.maxBytes,
+				Topics:   []protocol.FetchRequestFetchTopic{{Topic: topic, TopicId: tt.topicID, Partitions: tt.partitions}},
+			}
+			buf, _ := BuildFetchResponse(ResponseHeader{ApiKey: 1, ApiVersion: tt.version}, request)
+			var response protocol.FetchResponse
+			if err := response.Decode(buf, tt.version); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Responses) != 1 || len(response.Responses[0].Partitions) != len(tt.want) {
+				t.Fatalf("response %+v, want one topic with %d partitions", response.Responses, len(tt.want))
+			}
+
+			for i, partResp := range response.Responses[0].Partitions {
+				want := tt.want[i]
+				if partResp.ErrorCode != want.errorCode {
+					t.Errorf("partition %d: error %d, want %d", partResp.PartitionIndex, partResp.ErrorCode, want.errorCode)
+				}
+				if offsets := batchOffsets(t, partResp.Records); !slices.Equal(offsets, want.batches) {
+					t.Errorf("partition %d: batches at %v, want %v", partResp.PartitionIndex, offsets, want.batches)
+				}
+				if want.errorCode == ErrNone && partResp.HighWatermark != highWatermarks[partResp.PartitionIndex] {
+					t.Errorf("partition %d: high watermark %d, want %d", partResp.PartitionIndex, partResp.HighWatermark, highWatermarks[partResp.PartitionIndex])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildListOffsetsResponse(t *testing.T) {
+	const topic = "list-orders"
+	createTestTopic(t, topic, 1)
+	log, err := storage.GetLog(topic, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The largest timestamp is at offset 1
+	for _, timestamp := range []int64{1000, 3000, 2000} {
+		batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, timestamp)
+		if _, err := log.Append(batch.Encode()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		version       int16
+		partition     int32
+		timestamp     int64
+		wantError     int16
+		wantOffset    int64
+		wantTimestamp int64
+	}{
+		{"latest", 1, 0, LatestTimestamp, ErrNone, 3, -1},
+		{"earliest", 1, 0, EarliestTimestamp, ErrNone, 0, -1},
+		{"max timestamp", 7, 0, MaxTimestamp, ErrNone, 1, 3000},
+		{"max timestamp before v7", 6, 0, MaxTimestamp, UNSUPPORTED_VERSION, -1, -1},
+		{"earliest local", 8, 0, EarliestLocalTimestamp, ErrNone, 0, -1},
+		{"earliest local before v8", 7, 0, EarliestLocalTimestamp, UNSUPPORTED_VERSION, -1, -1},
+		{"first record at a timestamp", 1, 0, 1000, ErrNone, 0, 1000},
+		{"first record after a timestamp", 1, 0, 1500, ErrNone, 1, 3000},
+		{"every record older", 1, 0, 5000, ErrNone, -1, -1},
+		{"invalid negative timestamp", 1, 0, -5, INVALID_REQUEST, -1, -1},
+		{"unknown partition", 1, 3, LatestTimestamp, UNKNOWN_TOPIC_OR_PARTITION, -1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := protocol.ListOffsetsRequest{Topics: []protocol.ListOffsetsRequestListOffsetsTopic{{
+				Name:       topic,
+				Partitions: []protocol.ListOffsetsRequestListOffsetsPartition{{PartitionIndex: tt.partition, Timestamp: tt.timestamp}},
+			}}}
+			var response protocol.ListOffsetsResponse
+			if err := response.Decode(BuildListOffsetsResponse(tt.version, request), tt.version); err != nil {
+				t.Fatal(err)
+			}
+			partResp := response.Topics[0].Partitions[0]
+			if partResp.ErrorCode != tt.wantError || partResp.Offset != tt.wantOffset || partResp.Timestamp != tt.wantTimestamp {
+				t.Errorf("error %d, offset %d, timestamp %d; want error %d, offset %d, timestamp %d",
+					partResp.ErrorCode, partResp.Offset, partResp.Timestamp, tt.wantError, tt.wantOffset, tt.wantTimestamp)
+			}
+		})
+	}
+
+	// v0 answers with OldStyleOffsets, holding at most the one offset found
+	request := protocol.ListOffsetsRequest{Topics: []protocol.ListOffsetsRequestListOffsetsTopic{{
+		Name:       topic,
+		Partitions: []protocol.ListOffsetsRequestListOffsetsPartition{{Timestamp: LatestTimestamp, MaxNumOffsets: 5}},
+	}}}
+	var response protocol.ListOffsetsResponse
+	if err := response.Decode(BuildListOffsetsResponse(0, request), 0); err != nil {
+		t.Fatal(err)
+	}
+	if offsets := response.Topics[0].Partitions[0].OldStyleOffsets; !slices.Equal(offsets, []int64{3}) {
+		t.Errorf("v0 offsets %v, want [3]", offsets)
+	}
+}
+
+func TestBuildCreateTopicsResponse(t *testing.T) {
+	createTestTopic(t, "create-existing", 1)
+	value := func(s string) *string { return &s }
+	topic := func(name string, partitions int32, replicationFactor int16, configs ...protocol.CreateTopicsRequestCreatableTopicConfig) protocol.CreateTopicsRequestCreatableTopic {
+		return protocol.CreateTopicsRequestCreatableTopic{Name: name, NumPartitions: partitions, ReplicationFactor: replicationFactor, Configs: configs}
+	}
+	assigned := func(name string, partitions int32, replicationFactor int16, brokerIDs ...[]int32) protocol.CreateTopicsRequestCreatableTopic {
+		created := topic(name, partitions, replicationFactor)
+		for i, replicas := range brokerIDs {
+			created.Assignments = append(created.Assignments, protocol.CreateTopicsRequestCreatableReplicaAssignment{PartitionIndex: int32(i), BrokerIds: replicas})
+		}
+		return created
+	}
+	self := []int32{BrokerNodeID}
+
+	tests := []struct {
+		name         string
+		topic        protocol.CreateTopicsRequestCreatableTopic
+		validateOnly bool
+		wantError    int16
+		// wantPartitions is the partition count answered, -1 for an error
+		wantPartitions int32
+	}{
+		{"explicit partitions", topic("create-orders", 3, 1), false, ErrNone, 3},
+		{"default partitions and replication", topic("create-defaults", -1, -1), false, ErrNone, DefaultNumPartitions},
+		{"empty name", topic("", 1, 1), false, INVALID_TOPIC_EXCEPTION, -1},
+		{"illegal character", topic("create-orders!", 1, 1), false, INVALID_TOPIC_EXCEPTION, -1},
+		{"name too long", topic(strings.Repeat("a", 250), 1, 1), false, INVALID_TOPIC_EXCEPTION, -1},
+		{"internal topic", topic("__consumer_offsets", 1, 1), false, INVALID_REQUEST, -1},
+		{"existing topic", topic("create-existing", 1, 1), false, TOPIC_ALREADY_EXISTS, -1},
+		{"no partitions", topic("create-empty", 0, 1), false, INVALID_PARTITIONS, -1},
+		{"no replicas", topic("create-unreplicated", 1, 0), false, INVALID_REPLICATION_FACTOR, -1},
+		{"more replicas than brokers", topic("create-replicated", 1, 2), false, INVALID_REPLICATION_FACTOR, -1},
+		{"manual assignment", assigned("create-assigned", -1, -1, self, self), false, ErrNone, 2},
+		{"assignment with a partition count", assigned("create-assigned-count", 2, -1, self, self), false, INVALID_REQUEST, -1},
+		{"assignment to an unknown broker", assigned("create-assigned-unknown", -1, -1, []int32{BrokerNodeID + 1}), false, INVALID_REPLICA_ASSIGNMENT, -1},
+		{"valid configs", topic("create-configured", 1, 1,
+			protocol.CreateTopicsRequestCreatableTopicConfig{Name: "retention.ms", Value: value("-1")},
+			protocol.CreateTopicsRequestCreatableTopicConfig{Name: "cleanup.policy", Value: value("compact,delete")},
+			protocol.CreateTopicsRequestCreatableTopicConfig{Name: "compression.type", Value: value("zstd")},
+		), false, ErrNone, 1},
+		{"unknown config", topic("create-unknown-config", 1, 1,
+			protocol.CreateTopicsRequestCreatableTopicConfig{Name: "segment.bytes", Value: value("1024")},
+		), false, INVALID_CONFIG, -1},
+		{"invalid retention.ms", topic("create-bad-retention", 1, 1,
+			protocol.CreateTopicsRequestCreatableTopicConfig{Name: "retention.ms", Value: value("-2")},
+		), false, INVALID_CONFIG, -1},
+		{"invalid compression.type", topic("create-bad-compression", 1, 1,
+			protocol.CreateTopicsRequestCreatableTopicConfig{Name: "compression.type", Value: value("brotli")},
+		), false, INVALID_CONFIG, -1},
+		{"null config value", topic("create-null-config", 1, 1,
+			protocol.CreateTopicsRequestCreatableTopicConfig{Name: "retention.ms"},
+		), false, INVALID_CONFIG, -1},
+		{"validate_only", topic("create-validated", 2, 1), true, ErrNone, 2},
+		{"validate_only with an error", topic("create-validated-empty", 0, 1), true, INVALID_PARTITIONS, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := protocol.CreateTopicsRequest{Topics: []protocol.CreateTopicsRequestCreatableTopic{tt.topic}, ValidateOnly: tt.validateOnly}
+			var response protocol.CreateTopicsResponse
+			if err := response.Decode(BuildCreateTopicsResponse(7, request), 7); err != nil {
+				t.Fatal(err)
+			}
+			result := response.Topics[0]
+			if result.ErrorCode != tt.wantError || result.NumPartitions != tt.wantPartitions {
+				t.Errorf("error %d (%s) with %d partitions, want error %d with %d",
+					result.ErrorCode, stringValue(result.ErrorMessage), result.NumPartitions, tt.wantError, tt.wantPartitions)
+			}
+			// Configs are listed for topics that were (or would be) created
+			if (result.Configs == nil) != (tt.wantError != ErrNone) {
+				t.Errorf("configs %+v with error %d", result.Configs, result.ErrorCode)
+			}
+			for _, config := range tt.topic.Configs {
+				if tt.wantError != ErrNone {
+					break
+				}
+				for _, listed := range result.Configs {
+					if listed.Name == config.Name && (stringValue(listed.Value) != *config.Value || listed.ConfigSource != 1) {
+						t.Errorf("config %s listed as %q from source %d, want %q from the topic", config.Name, stringValue(listed.Value), listed.ConfigSource, *config.Value)
+					}
+				}
+			}
+
+			if tt.wantError == TOPIC_ALREADY_EXISTS {
+				return
+			}
+			created, exists := metadata.Image().TopicByName(tt.topic.Name)
+			if wantCreated := tt.wantError == ErrNone && !tt.validateOnly; exists != wantCreated {
+				t.Fatalf("topic in the image: %v, want %v", exists, !exists)
+			}
+			if exists && (len(created.Partitions) != int(tt.wantPartitions) || created.TopicID != result.TopicId) {
+				t.Errorf("image has %d partitions under ID %x, want %d under %x", len(created.Partitions), created.TopicID, tt.wantPartitions, result.TopicId)
+			}
+		})
+	}
+
+	// A name listed twice is rejected in both places
+	request := protocol.CreateTopicsRequest{Topics: []protocol.CreateTopicsRequestCreatableTopic{topic("create-twice", 1, 1), topic("create-twice", 2, 1)}}
+	var response protocol.CreateTopicsResponse
+	if err := response.Decode(BuildCreateTopicsResponse(7, request), 7); err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range response.Topics {
+		if result.ErrorCode != INVALID_REQUEST {
+			t.Errorf("duplicate %d: error %d, want INVALID_REQUEST", i, result.ErrorCode)
+		}
+	}
+	if _, exists := metadata.Image().TopicByName("create-twice"); exists {
+		t.Error("topic listed twice was created")
+	}
+}
+
+func TestBuildDeleteTopicsResponse(t *testing.T) {
+	name := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		version int16
+		// topic is created with a record in its log before the request, unless empty
+		topic     string
+		request   func(topicID [16]byte) protocol.DeleteTopicsRequest
+		wantError int16
+	}{
+		{
+			name:    "by name before v6",
+			version: 5,
+			topic:   "delete-by-name-v5",
+			request: func([16]byte) protocol.DeleteTopicsRequest {
+				return protocol.DeleteTopicsRequest{TopicNames: []string{"delete-by-name-v5"}}
+			},
+		},
+		{
+			name:    "by name",
+			version: 6,
+			topic:   "delete-by-name",
+			request: func([16]byte) protocol.DeleteTopicsRequest {
+				return protocol.DeleteTopicsRequest{Topics: []protocol.DeleteTopicsRequestDeleteTopicState{{Name: name("delete-by-name")}}}
+			},
+		},
+		{
+			name:    "by ID",
+			version: 6,
+			topic:   "delete-by-id",
+			request: func(topicID [16]byte) protocol.DeleteTopicsRequest {
+				return protocol.DeleteTopicsRequest{Topics: []protocol.DeleteTopicsRequestDeleteTopicState{{TopicId: topicID}}}
+			},
+		},
+		{
+			name:    "unknown name",
+			version: 1,
+			request: func([16]byte) protocol.DeleteTopicsRequest {
+				return protocol.DeleteTopicsRequest{TopicNames: []string{"delete-missing"}}
+			},
+			wantError: UNKNOWN_TOPIC_OR_PARTITION,
+		},
+		{
+			name:    "unknown ID",
+			version: 6,
+			request: func([16]byte) protocol.DeleteTopicsRequest {
+				return protocol.DeleteTopicsRequest{Topics: []protocol.DeleteTopicsRequestDeleteTopicState{{TopicId: [16]byte{0xff}}}}
+			},
+			wantError: UNKNOWN_TOPIC_ID,
+		},
+		{
+			name:    "both name and ID",
+			version: 6,
+			topic:   "delete-both",
+			request: func(topicID [16]byte) protocol.DeleteTopicsRequest {
+				return protocol.DeleteTopicsRequest{Topics: []protocol.DeleteTopicsRequestDeleteTopicState{{Name: name("delete-both"), TopicId: topicID}}}
+			},
+			wantError: INVALID_REQUEST,
+		},
+		{
+			name:    "neither name nor ID",
+			version: 6,
+			request: func([16]byte) protocol.DeleteTopicsRequest {
+				return protocol.DeleteTopicsRequest{Topics: []protocol.DeleteTopicsRequestDeleteTopicState{{}}}
+			},
+			wantError: INVALID_REQUEST,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var topicID [16]byte
+			if tt.topic != "" {
+				topicID = createTestTopic(t, tt.topic, 1)
+				log, err := storage.GetLog(tt.topic, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, 1700000000000)
+				if _, err := log.Append(batch.Encode()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var response protocol.DeleteTopicsResponse
+			if err := response.Decode(BuildDeleteTopicsResponse(tt.version, tt.request(topicID)), tt.version); err != nil {
+				t.Fatal(err)
+			}
+			result := response.Responses[0]
+			if result.ErrorCode != tt.wantError {
+				t.Fatalf("error %d (%s), want %d", result.ErrorCode, stringValue(result.ErrorMessage), tt.wantError)
+			}
+			if tt.topic == "" {
+				return
+			}
+
+			_, exists := metadata.Image().TopicByName(tt.topic)
+			partitionDir := filepath.Join(storage.LogDirs[0], tt.topic+"-0")
+			_, statErr := os.Stat(partitionDir)
+			if tt.wantError != ErrNone {
+				if !exists || statErr != nil {
+					t.Errorf("rejected delete removed the topic (in image: %v, directory: %v)", exists, statErr)
+				}
+				return
+			}
+			if stringValue(result.Name) != tt.topic || (tt.version >= 6 && result.TopicId != topicID) {
+				t.Errorf("deleted %q with ID %x, want %q with %x", stringValue(result.Name), result.TopicId, tt.topic, topicID)
+			}
+			if exists {
+				t.Error("topic still in the image")
+			}
+			if !os.IsNotExist(statErr) {
+				t.Errorf("partition directory not renamed: %v", statErr)
+			}
+			renamed, _ := filepath.Glob(partitionDir + ".*-delete")
+			if len(renamed) != 1 {
+				t.Errorf("directories marked for deletion: %v", renamed)
+			}
+		})
+	}
+}
+
+func TestBuildDescribeLogDirsResponse(t *testing.T) {
+	const topic = "describe-orders"
+	createTestTopic(t, topic, 2)
+	// Partition 0 holds one batch and partition 1 two
+	batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, 1700000000000).Encode()
+	batchSize := int64(len(batch))
+	for partition, count := range []int{1, 2} {
+		log, err := storage.GetLog(topic, int32(partition))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range count {
+			if _, err := log.Append(batch); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		version int16
+		topics  []protocol.DescribeLogDirsRequestDescribableLogDirTopic
+		// want are the sizes of the partitions of the topic listed, by index
+		want map[int32]int64
+	}{
+		{name: "every partition", version: 4, want: map[int32]int64{0: batchSize, 1: 2 * batchSize}},
+		{
+			name:    "requested partitions only",
+			version: 4,
+			topics:  []protocol.DescribeLogDirsRequestDescribableLogDirTopic{{Topic: topic, Partitions: []int32{1, 5}}},
+			want:    map[int32]int64{1: 2 * batchSize},
+		},
+		{
+			name:    "other topics only",
+			version: 4,
+			topics:  []protocol.DescribeLogDirsRequestDescribableLogDirTopic{{Topic: "describe-unknown", Partitions: []int32{0}}},
+			want:    map[int32]int64{},
+		},
+		{name: "v0", version: 0, want: map[int32]int64{0: batchSize, 1: 2 * batchSize}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos := describeLogDirs(protocol.DescribeLogDirsRequest{Topics: tt.topics})
+			var decoded protocol.DescribeLogDirsResponse
+			if err := decoded.Decode(BuildDescribeLogDirsResponse(tt.version, ErrNone, infos), tt.version); err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded.Results) != 1 {
+				t.Fatalf("%d log directories, want 1", len(decoded.Results))
+			}
+			result := decoded.Results[0]
+			if result.ErrorCode != ErrNone || result.LogDir != storage.LogDirs[0] {
+				t.Errorf("log directory %s with error code %d, want %s", result.LogDir, result.ErrorCode, storage.LogDirs[0])
+			}
+			// The file system sizes are only sent from v4
+			if tt.version >= 4 && (result.TotalBytes <= 0 || result.UsableBytes < 0) {
+				t.Errorf("%d total and %d usable bytes", result.TotalBytes, result.UsableBytes)
+			}
+
+			got := make(map[int32]int64)
+			for _, topicResult := range result.Topics {
+				if topicResult.Name != topic {
+					if tt.topics != nil {
+						t.Errorf("unrequested topic %s listed", topicResult.Name)
+					}
+					continue
+				}
+				for _, partition := range topicResult.Partitions {
+					got[partition.PartitionIndex] = partition.PartitionSize
+					if partition.OffsetLag != 0 {
+						t.Errorf("partition %d offset lag %d, want 0", partition.PartitionIndex, partition.OffsetLag)
+					}
+				}
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("partition sizes %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ type RequestHeader struct {
 	ApiKey        int16
 	ApiVersion    int16
 	CorrelationID uint32
-	ClientID      reqClientID
+	ClientID      string // absent (empty) in request header v0
 }
 type ResponseHeader struct {
 	ApiKey        int16
@@ -13,109 +13,64 @@ type ResponseHeader struct {
 }
 
 type ApiKeyInfo struct {
-	Key        int16
-	MinVersion int16
-	MaxVersion int16
-}
-type reqClientID struct {
-	length  uint16
-	content string
-}
-
-type DescribeTopicPartitionsRequest struct {
-	ClientID   string
-	TopicNames []string
+	Key             int16
+	MinVersion      int16
+	MaxVersion      int16
+	FlexibleVersion int16 // first version with compact encodings and tagged fields, -1 if none
 }
 
 var SupportedApiKeys = []ApiKeyInfo{
-	{Key: 0, MinVersion: 0, MaxVersion: 11}, // Produce - FIXED: was duplicate Fetch
-	{Key: 1, MinVersion: 0, MaxVersion: 16}, // Fetch
-	{Key: 18, MinVersion: 0, MaxVersion: 4}, // ApiVersions
-	{Key: 75, MinVersion: 0, MaxVersion: 0}, // DescribeTopicPartitions
-}
-
-type FetchRequest struct {
-	MaxWaitMs           int32
-	MinBytes            int32
-	MaxBytes            int32
-	IsolationLevel      int8
-	SessionID           int32
-	SessionEpoch        int32
-	Topics              []FetchTopic
-	ForgottenTopicsData []ForgottenTopic
-	RackID              string
-}
-
-type FetchTopic struct {
-	TopicID    [16]byte
-	Partitions []FetchPartition
-}
-
-type FetchPartition struct {
-	Partition          int32
-	CurrentLeaderEpoch int32
-	FetchOffset        int64
-	LastFetchedEpoch   int32
-	LogStartOffset     int64
-	PartitionMaxBytes  int32
-}
-
-type ForgottenTopic struct {
-	TopicID    [16]byte
-	Partitions []int32
-}
-
-type ProduceRequest struct {
-	TransactionalID string
-	Acks            int16
-	TimeoutMs       int32
-	TopicData       []ProduceTopic
-	TaggedFields    []byte
-}
-
-// ProduceTopic represents topic data in produce request
-type ProduceTopic struct {
-	Name          string
-	PartitionData []ProducePartition
-	TaggedFields  []byte
+	{Key: 0, MinVersion: 0, MaxVersion: 11, FlexibleVersion: 9},  // Produce
+	{Key: 1, MinVersion: 0, MaxVersion: 16, FlexibleVersion: 12}, // Fetch
+	{Key: 2, MinVersion: 0, MaxVersion: 8, FlexibleVersion: 6},   // ListOffsets
+	{Key: 3, MinVersion: 0, MaxVersion: 12, FlexibleVersion: 9},  // Metadata
+	{Key: 8, MinVersion: 0, MaxVersion: 9, FlexibleVersion: 8},   // OffsetCommit
+	{Key: 9, MinVersion: 0, MaxVersion: 9, FlexibleVersion: 6},   // OffsetFetch
+	{Key: 10, MinVersion: 0, MaxVersion: 4, FlexibleVersion: 3},  // FindCoordinator
+	{Key: 11, MinVersion: 0, MaxVersion: 9, FlexibleVersion: 6},  // JoinGroup
+	{Key: 12, MinVersion: 0, MaxVersion: 4, FlexibleVersion: 4},  // Heartbeat
+	{Key: 13, MinVersion: 0, MaxVersion: 5, FlexibleVersion: 4},  // LeaveGroup
+	{Key: 14, MinVersion: 0, MaxVersion: 5, FlexibleVersion: 4},  // SyncGroup
+	{Key: 18, MinVersion: 0, MaxVersion: 4, FlexibleVersion: 3},  // ApiVersions
+	{Key: 19, MinVersion: 0, MaxVersion: 7, FlexibleVersion: 5},  // CreateTopics
+	{Key: 20, MinVersion: 0, MaxVersion: 6, FlexibleVersion: 4},  // DeleteTopics
+	{Key: 22, MinVersion: 0, MaxVersion: 5, FlexibleVersion: 2},  // InitProducerId
+	{Key: 24, MinVersion: 0, MaxVersion: 3, FlexibleVersion: 3},  // AddPartitionsToTxn
+	{Key: 25, MinVersion: 0, MaxVersion: 3, FlexibleVersion: 3},  // AddOffsetsToTxn
+	{Key: 26, MinVersion: 0, MaxVersion: 3, FlexibleVersion: 3},  // EndTxn
+	{Key: 28, MinVersion: 0, MaxVersion: 3, FlexibleVersion: 3},  // TxnOffsetCommit
+	{Key: 35, MinVersion: 0, MaxVersion: 4, FlexibleVersion: 2},  // DescribeLogDirs
+	{Key: 75, MinVersion: 0, MaxVersion: 0, FlexibleVersion: 0},  // DescribeTopicPartitions
 }
 
-// ProducePartition represents partition data in produce request
-// This contains the actual records to produce
-type ProducePartition struct {
-	Index        int32
-	Records      []byte // COMPACT_RECORDS (raw bytes)
-	TaggedFields []byte
-}
+// Broker identity, named after the broker configs they mirror. BrokerHost
+// and BrokerPort are advertised to clients in Metadata and FindCoordinator
+// responses.
+var (
+	BrokerNodeID int32 = 1           // node.id
+	BrokerHost         = "localhost" // advertised.listeners
+	BrokerPort   int32 = 9092        // advertised.listeners
 
-// ProduceResponse types
-type ProduceResponse struct {
-	TopicResponses []ProduceTopicResponse
-	ThrottleTimeMs int32
-	TaggedFields   []byte
-}
+	// Listeners are the addresses the broker accepts connections on
+	Listeners = []string{"0.0.0.0:9092"} // listeners
+)
 
-// ProduceTopicResponse represents topic response in produce response
-type ProduceTopicResponse struct {
-	Name               string
-	PartitionResponses []ProducePartitionResponse
-	TaggedFields       []byte
-}
+// Topic defaults, named after the broker configs they mirror
+var (
+	DefaultNumPartitions     int32 = 1 // num.partitions
+	DefaultReplicationFactor int16 = 1 // default.replication.factor
+)
 
-// ProducePartitionResponse represents partition response in produce response
-type ProducePartitionResponse struct {
-	PartitionID    int32
-	ErrorCode      int16
-	BaseOffset     int64
-	LogAppendTime  int64
-	LogStartOffset int64
-	RecordErrors   []RecordError
-	ErrorMessage   string
-	TaggedFields   []byte
-}
+// Fetch and ListOffsets isolation levels
+const (
+	ReadUncommitted int8 = 0
+	ReadCommitted   int8 = 1 // only records below the last stable offset, skipping aborted transactions
+)
 
-// RecordError represents individual record errors
-type RecordError struct {
-	BatchIndex int32
-	BatchError string
-}
+// Special ListOffsets timestamps
+const (
+	LatestTimestamp        int64 = -1 // log end offset
+	EarliestTimestamp      int64 = -2 // log start offset
+	MaxTimestamp           int64 = -3 // record with the largest timestamp (v7+)
+	EarliestLocalTimestamp int64 = -4 // first offset kept on local disk (v8+)
+)
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"testing"
+
+	"kafgo/app/protocol"
+)
+
+// request is implemented by every generated request message
+type request interface {
+	ApiKey() int16
+	MinVersion() int16
+	MaxVersion() int16
+	IsFlexible(version int16) bool
+}
+
+// requests lists a value of each generated request the broker serves
+var requests = []request{
+	&protocol.ProduceRequest{},
+	&protocol.FetchRequest{},
+	&protocol.ListOffsetsRequest{},
+	&protocol.MetadataRequest{},
+	&protocol.OffsetCommitRequest{},
+	&protocol.OffsetFetchRequest{},
+	&protocol.FindCoordinatorRequest{},
+	&protocol.JoinGroupRequest{},
+	&protocol.HeartbeatRequest{},
+	&protocol.LeaveGroupRequest{},
+	&protocol.SyncGroupRequest{},
+	&protocol.ApiVersionsRequest{},
+	&protocol.CreateTopicsRequest{},
+	&protocol.DeleteTopicsRequest{},
+	&protocol.InitProducerIdRequest{},
+	&protocol.AddPartitionsToTxnRequest{},
+	&protocol.AddOffsetsToTxnRequest{},
+	&protocol.EndTxnRequest{},
+	&protocol.TxnOffsetCommitRequest{},
+	&protocol.DescribeLogDirsRequest{},
+	&protocol.DescribeTopicPartitionsRequest{},
+}
+
+// TestSupportedApiKeysMatchRequests checks that ApiVersions advertises exactly
+// the versions the generated request decoders accept
+func TestSupportedApiKeysMatchRequests(t *testing.T) {
+	if len(requests) != len(SupportedApiKeys) {
+		t.Fatalf("%d generated requests for %d supported API keys", len(requests), len(SupportedApiKeys))
+	}
+	for _, req := range requests {
+		api, ok := supportedApi(req.ApiKey())
+		if !ok {
+			t.Errorf("%T: API key %d is not in SupportedApiKeys", req, req.ApiKey())
+			continue
+		}
+		if api.MinVersion != req.MinVersion() || api.MaxVersion != req.MaxVersion() {
+			t.Errorf("%T: SupportedApiKeys has versions %d-%d, the schema %d-%d",
+				req, api.MinVersion, api.MaxVersion, req.MinVersion(), req.MaxVersion())
+		}
+		for version := api.MinVersion; version <= api.MaxVersion; version++ {
+			if flexible := isFlexibleVersion(api.Key, version); flexible != req.IsFlexible(version) {
+				t.Errorf("%T v%d: SupportedApiKeys flexible %v, the schema %v", req, version, flexible, req.IsFlexible(version))
+			}
+		}
+	}
+}
+
+func supportedApi(apiKey int16) (ApiKeyInfo, bool) {
+	for _, api := range SupportedApiKeys {
+		if api.Key == apiKey {
+			return api, true
+		}
+	}
+	return ApiKeyInfo{}, false
+}
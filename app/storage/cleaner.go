@@ -0,0 +1,349 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"kafgo/app/metadata"
+)
+
+// Log cleaner settings, named after the broker configs they mirror
+var (
+	// LogCleanupPolicy is the default cleanup.policy of topics: "delete" applies
+	// retention, "compact" keeps only the latest record of every key, and
+	// "compact,delete" does both
+	LogCleanupPolicy = "delete" // log.cleanup.policy
+	// DeleteRetentionMs is how long tombstones are kept, so consumers catch up
+	// on deletions before the key disappears (topic config delete.retention.ms)
+	DeleteRetentionMs   int64 = 86400000 // log.cleaner.delete.retention.ms
+	LogCleanerBackoffMs int64 = 15000    // log.cleaner.backoff.ms
+)
+
+// internalTopics hold coordinator state keyed by group or transactional ID.
+// Like Kafka's __consumer_offsets and __transaction_state they are always
+// compacted and never deleted by retention.
+var internalTopics = map[string]bool{"__consumer_offsets": true, "__transaction_state": true}
+
+// cleanupPolicy reports whether a topic is compacted and whether retention deletes from it
+func cleanupPolicy(topic string) (compact bool, delete bool) {
+	if internalTopics[topic] {
+		return true, false
+	}
+	policy, found := metadata.TopicConfig(topic, "cleanup.policy")
+	if !found {
+		policy = LogCleanupPolicy
+	}
+	for _, name := range strings.Split(policy, ",") {
+		switch strings.TrimSpace(name) {
+		case "compact":
+			compact = true
+		case "delete":
+			delete = true
+		}
+	}
+	return compact, delete
+}
+
+// StartLogCleaner starts the background task that compacts the logs of
+// compacted topics every LogCleanerBackoffMs
+func StartLogCleaner() {
+	go func() {
+		ticker := time.NewTicker(time.Duration(LogCleanerBackoffMs) * time.Millisecond)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			for _, log := range allLogs() {
+				if compact, _ := cleanupPolicy(log.Topic); !compact {
+					continue
+				}
+				if err := log.Clean(now); err != nil && !errors.Is(err, ErrLogDeleted) {
+					fmt.Printf("Failed to clean %s-%d: %v\n", log.Topic, log.Partition, err)
+				}
+			}
+		}
+	}()
+}
+
+// Clean compacts the log, keeping only the latest record of every key. Only
+// segments wholly below the active segment and the last stable offset are
+// cleaned. Records keep their offsets, records of aborted transactions are
+// removed, and tombstones (null values) are removed too once their batch is
+// older than delete.retention.ms. Transaction markers and records without a
+// key are kept.
+//
+// Each changed segment is rewritten to <base>.log.cleaned while readers carry
+// on, renamed to <base>.log.swap once it is synced, and then swapped in for
+// the original; OpenLog finishes or discards a swap interrupted by a crash.
+// A swap that fails takes the log's directory offline, and the error says how
+// many segments were swapped in before it.
+func (l *Log) Clean(now time.Time) error {
+	deleteRetentionMs := retentionConfig(l.Topic, "delete.retention.ms", DeleteRetentionMs)
+
+	swaps, err := l.writeCleanedSegments(now.UnixMilli() - deleteRetentionMs)
+	if err != nil {
+		return l.checkIOError(err)
+	}
+	if len(swaps) == 0 {
+		return nil
+	}
+
+	cleaned := len(swaps)
+	swapped, err := l.swapCleanedSegments(swaps)
+	if swapped > 0 || err == nil {
+		fmt.Printf("Cleaned %d segments of %s-%d\n", swapped, l.Topic, l.Partition)
+	}
+	if err != nil && !errors.Is(err, ErrLogDeleted) {
+		// checkIOError closes the log, so it is called without l.mu
+		return l.checkIOError(fmt.Errorf("%d of %d cleaned segments swapped in: %w", swapped, cleaned, err))
+	}
+	return err
+}
+
+// swapCleanedSegments swaps the cleaned copies in, in log order, and returns
+// how many were. A segment whose swap fails is reopened from whatever log file
+// it was left with, or dropped from the log if that fails too, so the log
+// never keeps a closed segment; the swaps not done yet are left for OpenLog.
+func (l *Log) swapCleanedSegments(swaps map[*LogSegment]string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.deleted {
+		// The cleaned copies went with the renamed directory
+		return 0, ErrLogDeleted
+	}
+
+	swapped := 0
+	for i, segment := range l.segments {
+		swapPath, cleaned := swaps[segment]
+		if !cleaned {
+			continue
+		}
+		delete(swaps, segment)
+		swappedIn, err := swapSegment(segment, swapPath)
+		if err != nil {
+			reopened, openErr := openSegment(l.Dir, segment.BaseOffset)
+			if openErr != nil {
+				l.segments = append(l.segments[:i], l.segments[i+1:]...)
+			} else {
+				l.segments[i] = reopened
+			}
+			return swapped, fmt.Errorf("segment %d: %w", segment.BaseOffset, err)
+		}
+		l.segments[i] = swappedIn
+		swapped++
+	}
+	// The rest were deleted by retention while they were being cleaned
+	for _, swapPath := range swaps {
+		os.Remove(swapPath)
+	}
+	return swapped, nil
+}
+
+// writeCleanedSegments writes a cleaned copy of every cleanable segment that
+// has records to remove and returns the path of each copy. Tombstones in
+// batches older than deleteHorizonMs are removed.
+func (l *Log) writeCleanedSegments(deleteHorizonMs int64) (map[*LogSegment]string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.deleted {
+		return nil, ErrLogDeleted
+	}
+
+	firstUncleanableOffset := min(l.activeSegment().BaseOffset, l.lastStableOffset())
+	segments := make([]*LogSegment, 0)
+	aborted := make([]AbortedTxn, 0)
+	for _, segment := range l.segments {
+		if segment.nextOffset > firstUncleanableOffset {
+			break
+		}
+		if segment.size > 0 {
+			segments = append(segments, segment)
+		}
+		aborted = append(aborted, segment.txnIndex.entries...)
+	}
+
+	// The offset of the latest record of every key
+	latestOffsets := make(map[string]int64)
+	for _, segment := range segments {
+		err := segment.forEachBatch(func(batch *metadata.RecordBatch) error {
+			if batch.IsControl() || isAborted(batch, aborted) {
+				return nil
+			}
+			records, err := metadata.DecodeRecords(batch)
+			if err != nil {
+				return fmt.Errorf("batch at offset %d: %w", batch.BaseOffset, err)
+			}
+			for _, record := range records {
+				if record.Key != nil {
+					latestOffsets[string(record.Key)] = batch.BaseOffset + int64(record.OffsetDelta)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	swaps := make(map[*LogSegment]string)
+	for _, segment := range segments {
+		swapPath, err := segment.writeCleaned(latestOffsets, aborted, deleteHorizonMs)
+		if err != nil {
+			for _, path := range swaps {
+				os.Remove(path)
+			}
+			return nil, err
+		}
+		if swapPath != "" {
+			swaps[segment] = swapPath
+		}
+	}
+	return swaps, nil
+}
+
+// isAborted reports whether batch belongs to one of the aborted transactions
+func isAborted(batch *metadata.RecordBatch, aborted []AbortedTxn) bool {
+	if !batch.IsTransactional() {
+		return false
+	}
+	for _, txn := range aborted {
+		if txn.ProducerID == batch.ProducerID && txn.FirstOffset <= batch.BaseOffset && batch.BaseOffset <= txn.LastOffset {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCleaned writes the segment without superseded records, aborted
+// transactions and expired tombstones to <base>.log.swap, returning its path,
+// or "" if nothing would be removed. Batches keep their base and last offsets,
+// so the offsets of the remaining records do not change.
+func (s *LogSegment) writeCleaned(latestOffsets map[string]int64, aborted []AbortedTxn, deleteHorizonMs int64) (string, error) {
+	data := make([]byte, 0, s.size)
+	removed := 0
+	err := s.forEachBatch(func(batch *metadata.RecordBatch) error {
+		if batch.IsControl() {
+			data = append(data, batch.Encode()...)
+			return nil
+		}
+		records, err := metadata.DecodeRecords(batch)
+		if err != nil {
+			return fmt.Errorf("batch at offset %d: %w", batch.BaseOffset, err)
+		}
+		if isAborted(batch, aborted) {
+			removed += len(records)
+			return nil
+		}
+
+		retained := make([]metadata.Record, 0, len(records))
+		for _, record := range records {
+			offset := batch.BaseOffset + int64(record.OffsetDelta)
+			superseded := record.Key != nil && latestOffsets[string(record.Key)] != offset
+			expiredTombstone := record.Key != nil && record.Value == nil && batch.MaxTimestamp < deleteHorizonMs
+			if superseded || expiredTombstone {
+				continue
+			}
+			retained = append(retained, record)
+		}
+		removed += len(records) - len(retained)
+		if len(retained) == len(records) {
+			data = append(data, batch.Encode()...)
+			return nil
+		}
+		if len(retained) == 0 {
+			return nil
+		}
+
+		codec := batch.Compression()
+		batch.Records = nil
+		for _, record := range retained {
+			batch.Records = append(batch.Records, metadata.EncodeRecord(record)...)
+		}
+		batch.RecordCount = int32(len(retained))
+		batch.Attributes &^= metadata.AttributeCompressionMask
+		if err := batch.SetCompression(codec); err != nil {
+			return err
+		}
+		data = append(data, batch.Encode()...)
+		return nil
+	})
+	if err != nil || removed == 0 {
+		return "", err
+	}
+
+	logPath := s.log.Name()
+	cleanedPath := logPath + ".cleaned"
+	file, err := os.Create(cleanedPath)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(cleanedPath, logPath+".swap")
+	}
+	if err != nil {
+		os.Remove(cleanedPath)
+		return "", err
+	}
+	return logPath + ".swap", nil
+}
+
+// swapSegment replaces the files of segment with the cleaned log at swapPath
+// and opens the result, rebuilding its offset and time indexes
+func swapSegment(segment *LogSegment, swapPath string) (*LogSegment, error) {
+	dir := filepath.Dir(segment.log.Name())
+	segment.Close()
+	if err := completeSwap(dir, segment.BaseOffset); err != nil {
+		return nil, err
+	}
+	return openSegment(dir, segment.BaseOffset)
+}
+
+// completeSwap moves <base>.log.swap over <base>.log, dropping the indexes of
+// the old log so they are rebuilt. The rename is atomic, so a crash leaves
+// either the old or the cleaned segment.
+func completeSwap(dir string, baseOffset int64) error {
+	for _, suffix := range []string{".index", ".timeindex"} {
+		if err := os.Remove(segmentFileName(dir, baseOffset, suffix)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	logPath := segmentFileName(dir, baseOffset, ".log")
+	return os.Rename(logPath+".swap", logPath)
+}
+
+// recoverCleanedSegment finishes a clean interrupted by a crash: a synced
+// .log.swap file replaces its segment, a partly written .log.cleaned file is removed
+func recoverCleanedSegment(dir string, name string) error {
+	switch {
+	case strings.HasSuffix(name, ".log.cleaned"):
+		return os.Remove(filepath.Join(dir, name))
+	case strings.HasSuffix(name, ".log.swap"):
+		baseOffset, err := strconv.ParseInt(strings.TrimSuffix(name, ".log.swap"), 10, 64)
+		if err != nil {
+			return nil
+		}
+		fmt.Printf("Completing interrupted clean of %s\n", segmentFileName(dir, baseOffset, ".log"))
+		return completeSwap(dir, baseOffset)
+	}
+	return nil
+}
+
+// segmentIndex returns the position of segment in the log, or -1
+func (l *Log) segmentIndex(segment *LogSegment) int {
+	for i, s := range l.segments {
+		if s == segment {
+			return i
+		}
+	}
+	return -1
+}
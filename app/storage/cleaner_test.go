@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kafgo/app/compress"
+	"kafgo/app/metadata"
+)
+
+func TestClean(t *testing.T) {
+	type cleanTest struct {
+		name string
+		// build appends the records; every segment but the active one is cleaned
+		build func(t *testing.T, log *Log)
+		// now is when the log is cleaned, in Unix ms
+		now  int64
+		want []logRecord
+	}
+	none := compress.None
+	tests := []cleanTest{
+		{
+			name: "superseded keys are removed and offsets kept",
+			build: func(t *testing.T, log *Log) {
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, keyed("a", "1"), keyed("b", "1")))
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, metadata.Record{Value: []byte("no key")}, keyed("a", "2")))
+				rollLog(t, log)
+			},
+			now: testTimestamp,
+			want: []logRecord{
+				{offset: 1, key: "b", value: "1"},
+				{offset: 2, value: "no key"},
+				{offset: 3, key: "a", value: "2"},
+			},
+		},
+		{
+			name: "tombstone is kept for delete.retention.ms",
+			build: func(t *testing.T, log *Log) {
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, keyed("a", "1")))
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, tombstone("a")))
+				rollLog(t, log)
+			},
+			now:  testTimestamp + DeleteRetentionMs,
+			want: []logRecord{{offset: 1, key: "a", tombstone: true}},
+		},
+		{
+			name: "tombstone is removed after delete.retention.ms",
+			build: func(t *testing.T, log *Log) {
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, keyed("a", "1")))
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, tombstone("a")))
+				rollLog(t, log)
+			},
+			now:  testTimestamp + DeleteRetentionMs + 1,
+			want: nil,
+		},
+		{
+			name: "aborted transactional records are removed",
+			build: func(t *testing.T, log *Log) {
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, keyed("a", "1")))
+				aborted := keyedBatch(t, testTimestamp, none, keyed("a", "aborted"), keyed("b", "aborted"))
+				aborted.Attributes |= metadata.AttributeTransactional
+				aborted.ProducerID, aborted.ProducerEpoch, aborted.BaseSequence = 7, 0, 0
+				appendBatch(t, log, aborted)
+				if _, err := log.AppendControlMarker(7, 0, 0, false); err != nil {
+					t.Fatal(err)
+				}
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, keyed("c", "1")))
+				rollLog(t, log)
+			},
+			now: testTimestamp,
+			want: []logRecord{
+				{offset: 0, key: "a", value: "1"},
+				{offset: 3, marker: true},
+				{offset: 4, key: "c", value: "1"},
+			},
+		},
+		{
+			name: "records of the active segment are not cleaned",
+			build: func(t *testing.T, log *Log) {
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, keyed("a", "1")))
+				rollLog(t, log)
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, keyed("a", "2")))
+				appendBatch(t, log, keyedBatch(t, testTimestamp, none, keyed("a", "3")))
+			},
+			now: testTimestamp,
+			want: []logRecord{
+				{offset: 0, key: "a", value: "1"},
+				{offset: 1, key: "a", value: "2"},
+				{offset: 2, key: "a", value: "3"},
+			},
+		},
+	}
+	// A batch losing records is recompressed with its own codec
+	for _, codec := range []compress.Codec{compress.Gzip, compress.Snappy, compress.LZ4, compress.Zstd} {
+		tests = append(tests, cleanTest{
+			name: codec.String() + " batch is recompressed",
+			build: func(t *testing.T, log *Log) {
+				appendBatch(t, log, keyedBatch(t, testTimestamp, codec, keyed("a", "1"), keyed("b", "1")))
+				appendBatch(t, log, keyedBatch(t, testTimestamp, codec, keyed("a", "2")))
+				rollLog(t, log)
+			},
+			now: testTimestamp,
+			want: []logRecord{
+				{offset: 1, key: "b", value: "1", codec: codec},
+				{offset: 2, key: "a", value: "2", codec: codec},
+			},
+		})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := openTestLog(t)
+			tt.build(t, log)
+			end := log.LogEndOffset()
+
+			if err := log.Clean(time.UnixMilli(tt.now)); err != nil {
+				t.Fatal(err)
+			}
+			checkLogRecords(t, readLogRecords(t, log), tt.want)
+			if got := log.LogEndOffset(); got != end {
+				t.Errorf("log end offset %d after cleaning, want %d", got, end)
+			}
+		})
+	}
+}
+
+func TestRecoverCleanedSegment(t *testing.T) {
+	tests := []struct {
+		name string
+		// crash turns the cleaned copy of segment 0 into what a crash at some
+		// point of the clean leaves behind
+		crash func(t *testing.T, swapPath string)
+		want  []logRecord
+	}{
+		{
+			name:  "synced swap file replaces the segment",
+			crash: func(t *testing.T, swapPath string) {},
+			want:  []logRecord{{offset: 1, key: "a", value: "2"}},
+		},
+		{
+			name: "partly written cleaned file is discarded",
+			crash: func(t *testing.T, swapPath string) {
+				if err := os.Rename(swapPath, strings.TrimSuffix(swapPath, ".swap")+".cleaned"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []logRecord{{offset: 0, key: "a", value: "1"}, {offset: 1, key: "a", value: "2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			log, err := OpenLog(dir, "test", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			appendBatch(t, log, keyedBatch(t, testTimestamp, compress.None, keyed("a", "1")))
+			appendBatch(t, log, keyedBatch(t, testTimestamp, compress.None, keyed("a", "2")))
+			rollLog(t, log)
+
+			// Crash after writing the cleaned copy, before swapping it in
+			swaps, err := log.writeCleanedSegments(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(swaps) != 1 {
+				t.Fatalf("%d segments cleaned, want 1", len(swaps))
+			}
+			for _, swapPath := range swaps {
+				tt.crash(t, swapPath)
+			}
+			log.Close()
+
+			reopened, err := OpenLog(dir, "test", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			checkLogRecords(t, readLogRecords(t, reopened), tt.want)
+			if got := reopened.LogEndOffset(); got != 2 {
+				t.Errorf("log end offset %d after recovery, want 2", got)
+			}
+			leftover, _ := filepath.Glob(filepath.Join(dir, "*.log.*"))
+			if len(leftover) != 0 {
+				t.Errorf("files left after recovery: %v", leftover)
+			}
+		})
+	}
+}
+
+func TestSwapCleanedSegmentsFailure(t *testing.T) {
+	log := openTestLog(t)
+	appendBatch(t, log, keyedBatch(t, testTimestamp, compress.None, keyed("a", "1")))
+	appendBatch(t, log, keyedBatch(t, testTimestamp, compress.None, keyed("a", "2")))
+	rollLog(t, log)
+	appendBatch(t, log, keyedBatch(t, testTimestamp, compress.None, keyed("b", "1")))
+	appendBatch(t, log, keyedBatch(t, testTimestamp, compress.None, keyed("b", "2")))
+	rollLog(t, log)
+
+	swaps, err := log.writeCleanedSegments(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(swaps) != 2 {
+		t.Fatalf("%d segments cleaned, want 2", len(swaps))
+	}
+	// The cleaned copy of the second segment disappears before it is swapped in
+	for segment, swapPath := range swaps {
+		if segment.BaseOffset == 2 {
+			os.Remove(swapPath)
+		}
+	}
+
+	swapped, err := log.swapCleanedSegments(swaps)
+	if swapped != 1 {
+		t.Errorf("%d segments swapped in, want 1", swapped)
+	}
+	if !isIOError(err) {
+		t.Fatalf("swapCleanedSegments error %v, want an I/O error", err)
+	}
+	// The first segment is cleaned and the second reopened as it was
+	checkLogRecords(t, readLogRecords(t, log), []logRecord{
+		{offset: 1, key: "a", value: "2"},
+		{offset: 2, key: "b", value: "1"},
+		{offset: 3, key: "b", value: "2"},
+	})
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileDeleteDelayMs is how long a deleted log directory is kept before it is removed (file.delete.delay.ms)
+var FileDeleteDelayMs int64 = 60000
+
+// deleteDirSuffix marks a partition directory whose topic was deleted
+const deleteDirSuffix = "-delete"
+
+var (
+	// logsToDelete maps renamed directories to the time they were marked for deletion
+	logsToDelete   = make(map[string]time.Time)
+	logsToDeleteMu sync.Mutex
+)
+
+// DeleteLog closes the log of a topic partition and renames its directory to
+// <topic>-<partition>.<id>-delete, the way Kafka does. The directory itself is
+// removed by the log deleter once FileDeleteDelayMs has passed. Fetches,
+// Produce waiters and background tasks still holding the *Log get
+// ErrLogDeleted from then on; the files are closed once operations already
+// using them are done.
+func DeleteLog(topic string, partition int32) error {
+	key := fmt.Sprintf("%s-%d", topic, partition)
+
+	// Hold the registry lock so GetLog cannot reopen the directory mid-rename
+	logsMu.Lock()
+	defer logsMu.Unlock()
+
+	if log, open := logs[key]; open {
+		log.markDeleted()
+		delete(logs, key)
+	}
+
+	logDir, placed := placements[key]
+	if !placed {
+		return nil
+	}
+	dir := filepath.Join(logDir, key)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		delete(placements, key)
+		return nil
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	deletedDir := filepath.Join(logDir, fmt.Sprintf("%s.%x%s", key, id, deleteDirSuffix))
+	if err := os.Rename(dir, deletedDir); err != nil {
+		return err
+	}
+	delete(placements, key)
+
+	scheduleDeletion(deletedDir, time.Now())
+	fmt.Printf("Renamed %s to %s for deletion\n", dir, deletedDir)
+	return nil
+}
+
+func scheduleDeletion(dir string, markedAt time.Time) {
+	logsToDeleteMu.Lock()
+	defer logsToDeleteMu.Unlock()
+	logsToDelete[dir] = markedAt
+}
+
+// StartLogDeleter picks up directories left marked for deletion by a previous run
+// and starts the background task that removes marked directories
+func StartLogDeleter() {
+	for _, logDir := range onlineLogDirs() {
+		entries, err := os.ReadDir(logDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && strings.HasSuffix(entry.Name(), deleteDirSuffix) {
+				scheduleDeletion(filepath.Join(logDir, entry.Name()), time.Now())
+			}
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			deleteExpiredLogs(now)
+		}
+	}()
+}
+
+// deleteExpiredLogs removes every marked directory whose delay has passed
+func deleteExpiredLogs(now time.Time) {
+	logsToDeleteMu.Lock()
+	defer logsToDeleteMu.Unlock()
+
+	for dir, markedAt := range logsToDelete {
+		if now.Sub(markedAt).Milliseconds() < FileDeleteDelayMs {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Printf("Failed to delete %s: %v\n", dir, err)
+			continue
+		}
+		delete(logsToDelete, dir)
+		fmt.Printf("Deleted log directory %s\n", dir)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package storage
+
+import "errors"
+
+// diskUsage is not supported on this platform
+func diskUsage(dir string) (int64, int64, error) {
+	return -1, -1, errors.New("disk usage is not supported on this platform")
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// Flush settings, named after the broker configs they mirror. By default
+// nothing is forced to disk and the OS decides when to write pages back, as in
+// Kafka; durability comes from acks=-1 Produce requests, which flush.
+var (
+	// FlushIntervalMessages flushes a log once this many messages are unflushed
+	FlushIntervalMessages int64 = math.MaxInt64 // log.flush.interval.messages
+	// FlushIntervalMs flushes a log once its last flush is this old
+	FlushIntervalMs int64 = math.MaxInt64 // log.flush.interval.ms
+	// FlushSchedulerIntervalMs is how often logs are checked against FlushIntervalMs
+	FlushSchedulerIntervalMs int64 = 1000 // log.flush.scheduler.interval.ms
+)
+
+// StartLogFlusher starts the background task that flushes logs whose last
+// flush is older than FlushIntervalMs
+func StartLogFlusher() {
+	go func() {
+		ticker := time.NewTicker(time.Duration(FlushSchedulerIntervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			for _, log := range openLogs() {
+				if now.Sub(log.LastFlushTime()).Milliseconds() < FlushIntervalMs {
+					continue
+				}
+				if err := log.Flush(); err != nil && !errors.Is(err, ErrLogDeleted) {
+					fmt.Printf("Failed to flush %s-%d: %v\n", log.Topic, log.Partition, err)
+				}
+			}
+		}
+	}()
+}
+
+// openLogs returns every log currently open
+func openLogs() []*Log {
+	logsMu.Lock()
+	defer logsMu.Unlock()
+
+	open := make([]*Log, 0, len(logs))
+	for _, log := range logs {
+		open = append(open, log)
+	}
+	return open
+}
+
+// FlushedOffset returns the recovery point: every record below it has been
+// synced to disk
+func (l *Log) FlushedOffset() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.recoveryPoint
+}
+
+// LastFlushTime returns when the log was last flushed (or opened)
+func (l *Log) LastFlushTime() time.Time {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lastFlushTime
+}
+
+// Flush syncs every segment holding records past the recovery point, with
+// their indexes, and moves the recovery point to the log end offset. Appends
+// carry on while the files are synced.
+func (l *Log) Flush() error {
+	return l.checkIOError(l.flush())
+}
+
+func (l *Log) flush() error {
+	l.flushMu.Lock()
+	defer l.flushMu.Unlock()
+
+	l.mu.RLock()
+	if l.deleted {
+		l.mu.RUnlock()
+		return ErrLogDeleted
+	}
+	flushTo := l.logEndOffset()
+	segments := make([]*LogSegment, 0)
+	for _, segment := range l.segments {
+		if segment.nextOffset > l.recoveryPoint || segment == l.activeSegment() {
+			segments = append(segments, segment)
+		}
+	}
+	l.mu.RUnlock()
+
+	for _, segment := range segments {
+		// Retention or the cleaner may have closed the segment in the meantime
+		if err := segment.Flush(); err != nil && !errors.Is(err, os.ErrClosed) {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recoveryPoint = max(l.recoveryPoint, flushTo)
+	l.lastFlushTime = time.Now()
+	return nil
+}
+
+// maybeFlush flushes the log once FlushIntervalMessages messages are unflushed
+func (l *Log) maybeFlush() error {
+	l.mu.RLock()
+	unflushed := l.logEndOffset() - l.recoveryPoint
+	l.mu.RUnlock()
+
+	if unflushed < FlushIntervalMessages {
+		return nil
+	}
+	return l.flush()
+}
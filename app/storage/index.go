@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"encoding/binary"
+	"os"
+	"sort"
+)
+
+const (
+	offsetIndexEntrySize = 8  // relative offset (INT32) + position (INT32)
+	timeIndexEntrySize   = 12 // timestamp (INT64) + relative offset (INT32)
+)
+
+// OffsetIndexEntry maps the last offset of a batch to the batch's position in the segment
+type OffsetIndexEntry struct {
+	Offset   int64
+	Position int32
+}
+
+// TimeIndexEntry maps the largest timestamp seen so far to the offset of the batch holding it
+type TimeIndexEntry struct {
+	Timestamp int64
+	Offset    int64
+}
+
+// OffsetIndex is the .index file of a segment. Entries are kept in memory
+// and appended to the file as they are added.
+type OffsetIndex struct {
+	file       *os.File
+	baseOffset int64
+	entries    []OffsetIndexEntry
+}
+
+// TimeIndex is the .timeindex file of a segment
+type TimeIndex struct {
+	file       *os.File
+	baseOffset int64
+	entries    []TimeIndexEntry
+}
+
+func openOffsetIndex(path string, baseOffset int64) (*OffsetIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &OffsetIndex{file: file, baseOffset: baseOffset}
+	for i := 0; i+offsetIndexEntrySize <= len(data); i += offsetIndexEntrySize {
+		index.entries = append(index.entries, OffsetIndexEntry{
+			Offset:   baseOffset + int64(int32(binary.BigEndian.Uint32(data[i:i+4]))),
+			Position: int32(binary.BigEndian.Uint32(data[i+4 : i+8])),
+		})
+	}
+	return index, nil
+}
+
+func (idx *OffsetIndex) Append(offset int64, position int32) error {
+	entry := make([]byte, 0, offsetIndexEntrySize)
+	entry = binary.BigEndian.AppendUint32(entry, uint32(offset-idx.baseOffset))
+	entry = binary.BigEndian.AppendUint32(entry, uint32(position))
+	if _, err := idx.file.Write(entry); err != nil {
+		return err
+	}
+	idx.entries = append(idx.entries, OffsetIndexEntry{Offset: offset, Position: position})
+	return nil
+}
+
+// Lookup returns the position of the closest indexed batch that does not pass offset,
+// or 0 (the start of the segment) when there is none
+func (idx *OffsetIndex) Lookup(offset int64) int32 {
+	i := sort.Search(len(idx.entries), func(i int) bool {
+		return idx.entries[i].Offset > offset
+	})
+	if i == 0 {
+		return 0
+	}
+	return idx.entries[i-1].Position
+}
+
+// LastEntry returns the newest entry, or a zero entry at the segment base when empty
+func (idx *OffsetIndex) LastEntry() OffsetIndexEntry {
+	if len(idx.entries) == 0 {
+		return OffsetIndexEntry{Offset: idx.baseOffset, Position: 0}
+	}
+	return idx.entries[len(idx.entries)-1]
+}
+
+func (idx *OffsetIndex) SizeInBytes() int {
+	return len(idx.entries) * offsetIndexEntrySize
+}
+
+// Reset truncates the index so it can be rebuilt from the segment
+func (idx *OffsetIndex) Reset() error {
+	idx.entries = nil
+	return idx.file.Truncate(0)
+}
+
+func (idx *OffsetIndex) Close() error {
+	return idx.file.Close()
+}
+
+func openTimeIndex(path string, baseOffset int64) (*TimeIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &TimeIndex{file: file, baseOffset: baseOffset}
+	for i := 0; i+timeIndexEntrySize <= len(data); i += timeIndexEntrySize {
+		index.entries = append(index.entries, TimeIndexEntry{
+			Timestamp: int64(binary.BigEndian.Uint64(data[i : i+8])),
+			Offset:    baseOffset + int64(int32(binary.BigEndian.Uint32(data[i+8:i+12]))),
+		})
+	}
+	return index, nil
+}
+
+// MaybeAppend adds an entry only if timestamp is larger than the last indexed one
+func (idx *TimeIndex) MaybeAppend(timestamp int64, offset int64) error {
+	if len(idx.entries) > 0 && timestamp <= idx.entries[len(idx.entries)-1].Timestamp {
+		return nil
+	}
+	entry := make([]byte, 0, timeIndexEntrySize)
+	entry = binary.BigEndian.AppendUint64(entry, uint64(timestamp))
+	entry = binary.BigEndian.AppendUint32(entry, uint32(offset-idx.baseOffset))
+	if _, err := idx.file.Write(entry); err != nil {
+		return err
+	}
+	idx.entries = append(idx.entries, TimeIndexEntry{Timestamp: timestamp, Offset: offset})
+	return nil
+}
+
+// Lookup returns the offset to start scanning from for the first batch with a
+// timestamp >= timestamp: the entry before the first one reaching it, or the segment base
+func (idx *TimeIndex) Lookup(timestamp int64) int64 {
+	i := sort.Search(len(idx.entries), func(i int) bool {
+		return idx.entries[i].Timestamp >= timestamp
+	})
+	if i == 0 {
+		return idx.baseOffset
+	}
+	return idx.entries[i-1].Offset
+}
+
+// MaxTimestamp returns the largest indexed timestamp, or -1 when empty
+func (idx *TimeIndex) MaxTimestamp() int64 {
+	if len(idx.entries) == 0 {
+		return -1
+	}
+	return idx.entries[len(idx.entries)-1].Timestamp
+}
+
+// LastEntry returns the entry with the largest timestamp, or a zero entry when empty
+func (idx *TimeIndex) LastEntry() TimeIndexEntry {
+	if len(idx.entries) == 0 {
+		return TimeIndexEntry{Timestamp: -1, Offset: idx.baseOffset}
+	}
+	return idx.entries[len(idx.entries)-1]
+}
+
+func (idx *TimeIndex) Reset() error {
+	idx.entries = nil
+	return idx.file.Truncate(0)
+}
+
+func (idx *TimeIndex) Close() error {
+	return idx.file.Close()
+}
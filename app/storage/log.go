@@ -0,0 +1,653 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kafgo/app/compress"
+	"kafgo/app/metadata"
+)
+
+// Log settings, named after the broker configs they mirror
+var (
+	SegmentBytes       int64 = 1073741824 // log.segment.bytes
+	SegmentMs          int64 = 604800000  // log.roll.ms
+	SegmentIndexBytes        = 10485760   // log.index.size.max.bytes
+	IndexIntervalBytes       = 4096       // log.index.interval.bytes
+
+	// LogMessageTimestampType is "CreateTime" (keep producer timestamps) or
+	// "LogAppendTime" (the broker stamps each batch when it is appended)
+	LogMessageTimestampType = "CreateTime"
+
+	// CompressionType is the default compression.type of topics: "producer"
+	// keeps each batch as produced, a codec name makes Append recompress to it
+	CompressionType = compress.ProducerCompressionType
+)
+
+// AppendInfo describes where a produced record set landed in the partition log
+type AppendInfo struct {
+	BaseOffset     int64
+	LastOffset     int64
+	LogAppendTime  int64 // -1 unless LogAppendTime is in use
+	LogStartOffset int64
+}
+
+// addOffsets widens the offsets of an append to cover a batch at first-last
+func (info *AppendInfo) addOffsets(first bool, baseOffset int64, lastOffset int64) {
+	if first {
+		info.BaseOffset, info.LastOffset = baseOffset, lastOffset
+		return
+	}
+	info.BaseOffset = min(info.BaseOffset, baseOffset)
+	info.LastOffset = max(info.LastOffset, lastOffset)
+}
+
+// TimestampAndOffset is the answer to a ListOffsets lookup
+type TimestampAndOffset struct {
+	Timestamp int64
+	Offset    int64
+}
+
+// ErrLogDeleted is returned by operations on a log whose topic was deleted
+var ErrLogDeleted = errors.New("log deleted")
+
+// Log is the segmented log of a single topic partition
+type Log struct {
+	Topic     string
+	Partition int32
+	Dir       string
+
+	mu        sync.RWMutex
+	segments  []*LogSegment // sorted by base offset, the last one is active
+	producers *producerStateManager
+	// deleted is set by DeleteLog, after which every operation fails with
+	// ErrLogDeleted instead of touching the closed files
+	deleted bool
+
+	// flushMu serializes flushes, which sync files without holding mu
+	flushMu sync.Mutex
+	// recoveryPoint is the offset below which every record is synced to disk
+	recoveryPoint int64
+	lastFlushTime time.Time
+}
+
+var (
+	logs   = make(map[string]*Log)
+	logsMu sync.Mutex
+)
+
+// GetLog returns the log for a topic partition, loading it from disk on first
+// use. A new partition is placed in the least loaded online log directory.
+func GetLog(topic string, partition int32) (*Log, error) {
+	key := fmt.Sprintf("%s-%d", topic, partition)
+
+	logsMu.Lock()
+	defer logsMu.Unlock()
+
+	if log, exists := logs[key]; exists {
+		return log, nil
+	}
+
+	logDir, err := placeLog(key)
+	if err != nil {
+		return nil, err
+	}
+	log, err := OpenLog(filepath.Join(logDir, key), topic, partition)
+	if err != nil {
+		if isIOError(err) {
+			markLogDirOffline(logDir, err)
+		}
+		return nil, err
+	}
+	if _, placed := placements[key]; !placed {
+		fmt.Printf("Placed %s in log directory %s\n", key, logDir)
+	}
+	placements[key] = logDir
+	logs[key] = log
+	return log, nil
+}
+
+// OpenLog loads every segment in dir, creating the directory and a first segment if needed
+func OpenLog(dir string, topic string, partition int32) (*Log, error) {
+	return openLog(dir, topic, partition, false)
+}
+
+// openLog is OpenLog, recovering every segment first when recoverSegments is set
+func openLog(dir string, topic string, partition int32, recoverSegments bool) (*Log, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	baseOffsets := make([]int64, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if err := recoverCleanedSegment(dir, name); err != nil {
+			return nil, err
+		}
+		if entry.IsDir() || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		baseOffset, err := strconv.ParseInt(strings.TrimSuffix(name, ".log"), 10, 64)
+		if err != nil {
+			continue
+		}
+		baseOffsets = append(baseOffsets, baseOffset)
+	}
+	sort.Slice(baseOffsets, func(i, j int) bool { return baseOffsets[i] < baseOffsets[j] })
+	if len(baseOffsets) == 0 {
+		baseOffsets = append(baseOffsets, 0)
+	}
+
+	log := &Log{Topic: topic, Partition: partition, Dir: dir}
+	for _, baseOffset := range baseOffsets {
+		segment, err := openSegment(dir, baseOffset)
+		if err != nil {
+			log.Close()
+			return nil, err
+		}
+		log.segments = append(log.segments, segment)
+	}
+	if recoverSegments {
+		if err := log.recover(); err != nil {
+			log.Close()
+			return nil, err
+		}
+	}
+
+	if err := log.loadProducerState(); err != nil {
+		log.Close()
+		return nil, err
+	}
+	// Whatever survived on disk (and recovery) counts as flushed
+	log.recoveryPoint = log.logEndOffset()
+	log.lastFlushTime = time.Now()
+
+	fmt.Printf("Loaded log %s: %d segments, log end offset %d\n", dir, len(log.segments), log.logEndOffset())
+	return log, nil
+}
+
+// loadProducerState restores producer state from the latest usable snapshot,
+// then replays the batches written after it. Snapshots past the log end are
+// stale and removed.
+func (l *Log) loadProducerState() error {
+	l.producers = newProducerStateManager()
+
+	offsets, err := listProducerSnapshots(l.Dir)
+	if err != nil {
+		return err
+	}
+	snapshotOffset := l.logStartOffset()
+	for i := len(offsets) - 1; i >= 0; i-- {
+		path := segmentFileName(l.Dir, offsets[i], ".snapshot")
+		if offsets[i] > l.logEndOffset() {
+			os.Remove(path)
+			continue
+		}
+		producers, err := readProducerSnapshot(path)
+		if err != nil {
+			fmt.Printf("Removing unreadable producer snapshot %s: %v\n", path, err)
+			os.Remove(path)
+			continue
+		}
+		l.producers.producers = producers
+		snapshotOffset = offsets[i]
+		break
+	}
+
+	for _, segment := range l.segments {
+		if segment.nextOffset <= snapshotOffset {
+			continue
+		}
+		batches, err := segment.batchHeadersFrom(snapshotOffset)
+		if err != nil {
+			return err
+		}
+		l.producers.replay(batches)
+	}
+	return nil
+}
+
+// takeProducerSnapshot writes the producer state as of the log end offset,
+// keeping only the previous snapshot besides it
+func (l *Log) takeProducerSnapshot() error {
+	offset := l.logEndOffset()
+	if err := writeProducerSnapshot(l.Dir, offset, l.producers.producers); err != nil {
+		return err
+	}
+	offsets, err := listProducerSnapshots(l.Dir)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(offsets)-2; i++ {
+		os.Remove(segmentFileName(l.Dir, offsets[i], ".snapshot"))
+	}
+	return nil
+}
+
+func (l *Log) activeSegment() *LogSegment {
+	return l.segments[len(l.segments)-1]
+}
+
+// LogEndOffset returns the offset that the next appended record will get
+func (l *Log) LogEndOffset() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.logEndOffset()
+}
+
+func (l *Log) logEndOffset() int64 {
+	return l.activeSegment().nextOffset
+}
+
+// LogStartOffset returns the first offset still present in the log. It only
+// moves forward, when retention deletes the oldest segments.
+func (l *Log) LogStartOffset() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.logStartOffset()
+}
+
+func (l *Log) logStartOffset() int64 {
+	return l.segments[0].BaseOffset
+}
+
+// LastStableOffset returns the first offset of the oldest open transaction, or
+// the log end offset when no transaction is open. read_committed consumers
+// only see records below it.
+func (l *Log) LastStableOffset() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lastStableOffset()
+}
+
+func (l *Log) lastStableOffset() int64 {
+	if first := l.producers.firstUnstableOffset(); first >= 0 {
+		// The start of a transaction may already have been deleted by retention
+		return max(first, l.logStartOffset())
+	}
+	return l.logEndOffset()
+}
+
+// Append validates records, assigns offsets to every batch starting at the log
+// end offset, and writes the rewritten batches to the active segment. Invalid
+// record sets return ErrCorruptRecords or an *InvalidRecordsError. Batches from
+// idempotent producers must continue their producer's sequence
+// (ErrOutOfOrderSequence, ErrInvalidProducerEpoch); a retried batch that is
+// already in the log is not written again, and the returned offsets span its
+// original ones while the other batches of the set are appended as usual.
+// A producer with an open transaction may only write transactional batches
+// (ErrInvalidTxnState).
+func (l *Log) Append(records []byte) (AppendInfo, error) {
+	info, err := l.append(records, appendFromClient)
+	if err == nil {
+		err = l.maybeFlush()
+	}
+	return info, l.checkIOError(err)
+}
+
+// AppendAsCoordinator appends records written by a coordinator to its internal
+// topic. Their batches carry no sequence numbers, so only producer epochs are checked.
+func (l *Log) AppendAsCoordinator(records []byte) (AppendInfo, error) {
+	info, err := l.append(records, appendFromCoordinator)
+	if err == nil {
+		err = l.maybeFlush()
+	}
+	return info, l.checkIOError(err)
+}
+
+func (l *Log) append(records []byte, origin appendOrigin) (AppendInfo, error) {
+	info := AppendInfo{BaseOffset: -1, LastOffset: -1, LogAppendTime: -1, LogStartOffset: -1}
+
+	batches, err := readBatches(records)
+	if err != nil {
+		return info, err
+	}
+	if len(batches) == 0 {
+		return info, fmt.Errorf("%w: no record batches in produce data", ErrCorruptRecords)
+	}
+	if err := l.recompress(batches); err != nil {
+		return info, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.deleted {
+		return info, ErrLogDeleted
+	}
+
+	if LogMessageTimestampType == "LogAppendTime" {
+		info.LogAppendTime = time.Now().UnixMilli()
+	}
+
+	nextOffset := l.logEndOffset()
+	baseOffset := nextOffset
+	producerAppend := l.producers.prepareAppend(origin)
+	written := make([]*metadata.RecordBatch, 0, len(batches))
+	data := make([]byte, 0, len(records))
+	for i, batch := range batches {
+		// A retried batch is skipped, while the other batches are still appended;
+		// the returned offsets span its original offsets too
+		if duplicate, found := producerAppend.findDuplicate(batch); found {
+			fmt.Printf("Duplicate batch of producer %d (sequence %d) in %s-%d, already at offset %d\n",
+				batch.ProducerID, batch.BaseSequence, l.Topic, l.Partition, duplicate.FirstOffset)
+			info.addOffsets(i == 0, duplicate.FirstOffset, duplicate.LastOffset)
+			continue
+		}
+		if err := producerAppend.validate(batch); err != nil {
+			return AppendInfo{BaseOffset: -1, LastOffset: -1, LogAppendTime: -1, LogStartOffset: -1}, err
+		}
+
+		batch.BaseOffset = nextOffset
+		if info.LogAppendTime != -1 {
+			batch.Attributes |= metadata.AttributeTimestampType
+			batch.MaxTimestamp = info.LogAppendTime
+		}
+		info.addOffsets(i == 0, batch.BaseOffset, batch.LastOffset())
+		data = append(data, batch.Encode()...)
+		nextOffset = batch.LastOffset() + 1
+		producerAppend.update(batch)
+		written = append(written, batch)
+	}
+	info.LogStartOffset = l.logStartOffset()
+	if len(written) == 0 {
+		info.LogAppendTime = -1
+		return info, nil
+	}
+
+	if l.activeSegment().shouldRoll(len(data)) {
+		if err := l.roll(baseOffset); err != nil {
+			return info, err
+		}
+	}
+
+	if err := l.activeSegment().Append(written, data); err != nil {
+		return info, err
+	}
+	producerAppend.commit()
+	info.LogStartOffset = l.logStartOffset()
+
+	fmt.Printf("Appended offsets %d-%d to %s-%d\n", info.BaseOffset, info.LastOffset, l.Topic, l.Partition)
+	return info, nil
+}
+
+// AppendControlMarker ends the open transaction of a producer with a COMMIT or
+// ABORT marker and returns the marker's offset. An aborted transaction is
+// added to the active segment's transaction index so read_committed fetches
+// can skip it. Markers from an older producer epoch return ErrInvalidProducerEpoch.
+func (l *Log) AppendControlMarker(producerID int64, producerEpoch int16, coordinatorEpoch int32, commit bool) (int64, error) {
+	offset, err := l.appendControlMarker(producerID, producerEpoch, coordinatorEpoch, commit)
+	if err == nil {
+		err = l.maybeFlush()
+	}
+	return offset, l.checkIOError(err)
+}
+
+func (l *Log) appendControlMarker(producerID int64, producerEpoch int16, coordinatorEpoch int32, commit bool) (int64, error) {
+	controlType, marker := metadata.ControlTypeAbort, "ABORT"
+	if commit {
+		controlType, marker = metadata.ControlTypeCommit, "COMMIT"
+	}
+	batch := metadata.NewControlBatch(producerID, producerEpoch, coordinatorEpoch, controlType, time.Now().UnixMilli())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.deleted {
+		return -1, ErrLogDeleted
+	}
+
+	producerAppend := l.producers.prepareAppend(appendFromCoordinator)
+	if err := producerAppend.validate(batch); err != nil {
+		return -1, err
+	}
+	firstOffset := int64(-1)
+	if entry := producerAppend.entry(producerID); entry != nil {
+		firstOffset = entry.CurrentTxnFirstOffset
+	}
+
+	batch.BaseOffset = l.logEndOffset()
+	data := batch.Encode()
+	if l.activeSegment().shouldRoll(len(data)) {
+		if err := l.roll(batch.BaseOffset); err != nil {
+			return -1, err
+		}
+	}
+	if err := l.activeSegment().Append([]*metadata.RecordBatch{batch}, data); err != nil {
+		return -1, err
+	}
+	producerAppend.update(batch)
+	producerAppend.commit()
+	l.producers.producers[producerID].CoordinatorEpoch = coordinatorEpoch
+
+	if !commit && firstOffset >= 0 {
+		aborted := AbortedTxn{ProducerID: producerID, FirstOffset: firstOffset, LastOffset: batch.BaseOffset, LastStableOffset: l.lastStableOffset()}
+		if err := l.activeSegment().txnIndex.Append(aborted); err != nil {
+			return -1, err
+		}
+	}
+
+	fmt.Printf("Appended %s marker of producer %d at offset %d to %s-%d\n", marker, producerID, batch.BaseOffset, l.Topic, l.Partition)
+	return batch.BaseOffset, nil
+}
+
+// recompress re-encodes batches whose codec differs from the topic's
+// compression.type, falling back to the broker default
+func (l *Log) recompress(batches []*metadata.RecordBatch) error {
+	compressionType, found := metadata.TopicConfig(l.Topic, "compression.type")
+	if !found {
+		compressionType = CompressionType
+	}
+	codec, ok := compress.ParseCompressionType(compressionType)
+	if !ok {
+		// "producer"
+		return nil
+	}
+	for _, batch := range batches {
+		if batch.Compression() == codec {
+			continue
+		}
+		from := batch.Compression()
+		if err := batch.SetCompression(codec); err != nil {
+			return fmt.Errorf("recompressing %v batch as %v: %w", from, codec, err)
+		}
+	}
+	return nil
+}
+
+// roll starts a new active segment at baseOffset, snapshotting the producer state first
+func (l *Log) roll(baseOffset int64) error {
+	if err := l.takeProducerSnapshot(); err != nil {
+		return err
+	}
+	segment, err := openSegment(l.Dir, baseOffset)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, segment)
+	fmt.Printf("Rolled new segment %s\n", segmentFileName(l.Dir, baseOffset, ".log"))
+	return nil
+}
+
+// Read returns whole batches starting at the batch containing offset, reading
+// from a single segment and stopping before maxBytes. If minOneBatch is set the
+// first batch is returned even when it alone exceeds maxBytes.
+func (l *Log) Read(offset int64, maxBytes int, minOneBatch bool) ([]byte, error) {
+	return l.ReadUpTo(offset, math.MaxInt64, maxBytes, minOneBatch)
+}
+
+// ReadUpTo is Read without any batch starting at or past maxOffset, such as
+// the last stable offset for read_committed fetches
+func (l *Log) ReadUpTo(offset int64, maxOffset int64, maxBytes int, minOneBatch bool) ([]byte, error) {
+	data, err := l.readUpTo(offset, maxOffset, maxBytes, minOneBatch)
+	return data, l.checkIOError(err)
+}
+
+func (l *Log) readUpTo(offset int64, maxOffset int64, maxBytes int, minOneBatch bool) ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.deleted {
+		return nil, ErrLogDeleted
+	}
+
+	if offset >= min(l.logEndOffset(), maxOffset) {
+		return []byte{}, nil
+	}
+
+	// Skip segments that end before offset (e.g. when offset falls in a gap)
+	i := sort.Search(len(l.segments), func(i int) bool {
+		return l.segments[i].BaseOffset > offset
+	})
+	for i = max(i-1, 0); i < len(l.segments); i++ {
+		data, err := l.segments[i].Read(offset, maxOffset, maxBytes, minOneBatch)
+		if err != nil || len(data) > 0 {
+			return data, err
+		}
+	}
+	return []byte{}, nil
+}
+
+// CollectAbortedTxns returns the aborted transactions overlapping
+// [fetchOffset, upperBoundOffset). Abort markers follow their transaction, so
+// only the segments from the one containing fetchOffset are searched.
+func (l *Log) CollectAbortedTxns(fetchOffset int64, upperBoundOffset int64) []AbortedTxn {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.deleted {
+		return nil
+	}
+
+	i := sort.Search(len(l.segments), func(i int) bool {
+		return l.segments[i].BaseOffset > fetchOffset
+	})
+	aborted := make([]AbortedTxn, 0)
+	for i = max(i-1, 0); i < len(l.segments); i++ {
+		aborted = append(aborted, l.segments[i].txnIndex.collect(fetchOffset, upperBoundOffset)...)
+	}
+	return aborted
+}
+
+// FindOffsetByTimestamp returns the first record with a timestamp >= timestamp,
+// or false if every record in the log is older
+func (l *Log) FindOffsetByTimestamp(timestamp int64) (TimestampAndOffset, bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.deleted {
+		return TimestampAndOffset{}, false, ErrLogDeleted
+	}
+
+	startOffset := l.logStartOffset()
+	for _, segment := range l.segments {
+		result, found, err := segment.findOffsetByTimestamp(timestamp, startOffset)
+		if err != nil || found {
+			return result, found, err
+		}
+	}
+	return TimestampAndOffset{}, false, nil
+}
+
+// FindMaxTimestamp returns the first record carrying the largest timestamp in the log
+func (l *Log) FindMaxTimestamp() (TimestampAndOffset, bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.deleted {
+		return TimestampAndOffset{}, false, ErrLogDeleted
+	}
+
+	var best *LogSegment
+	for _, segment := range l.segments {
+		if segment.size > 0 && (best == nil || segment.maxTimestampSoFar > best.maxTimestampSoFar) {
+			best = segment
+		}
+	}
+	if best == nil {
+		return TimestampAndOffset{}, false, nil
+	}
+	return best.findMaxTimestamp()
+}
+
+// Shutdown snapshots the producer state of every open log and closes them, so the
+// next start does not have to replay the logs to rebuild it, then writes the
+// clean shutdown marker so the next start skips recovery
+func Shutdown() {
+	logsMu.Lock()
+	defer logsMu.Unlock()
+
+	// A directory whose logs could not all be flushed gets no clean shutdown marker
+	failedDirs := make(map[string]bool)
+	for key, log := range logs {
+		if err := log.flush(); err != nil {
+			fmt.Printf("Failed to flush %s: %v\n", key, err)
+			failedDirs[placements[key]] = true
+		}
+		log.mu.Lock()
+		if err := log.takeProducerSnapshot(); err != nil {
+			fmt.Printf("Failed to snapshot producer state of %s: %v\n", key, err)
+		}
+		log.Close()
+		log.mu.Unlock()
+		delete(logs, key)
+	}
+	for _, dir := range LogDirs {
+		if offlineDirs[dir] != nil || failedDirs[dir] {
+			continue
+		}
+		if err := writeCleanShutdownFile(dir); err != nil {
+			fmt.Printf("Failed to write %s to %s: %v\n", CleanShutdownFile, dir, err)
+		}
+	}
+}
+
+// markDeleted makes every later operation on the log fail with ErrLogDeleted
+// and closes its files once the operations using them are done: those holding
+// mu, and a flush syncing segments outside it.
+func (l *Log) markDeleted() {
+	l.flushMu.Lock()
+	defer l.flushMu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.deleted = true
+	l.Close()
+}
+
+func (l *Log) Close() error {
+	for _, segment := range l.segments {
+		segment.Close()
+	}
+	return nil
+}
+
+// firstRecordAtOrAfter returns the first record in batch at or after startOffset with a
+// timestamp >= timestamp. LogAppendTime batches share one timestamp, so they
+// resolve to the batch itself.
+func firstRecordAtOrAfter(batch *metadata.RecordBatch, timestamp int64, startOffset int64) TimestampAndOffset {
+	batchResult := TimestampAndOffset{Timestamp: batch.MaxTimestamp, Offset: max(batch.BaseOffset, startOffset)}
+	if batch.Attributes&metadata.AttributeTimestampType != 0 {
+		return batchResult
+	}
+
+	records, err := metadata.DecodeRecords(batch)
+	if err != nil {
+		return batchResult
+	}
+	for _, record := range records {
+		offset := batch.BaseOffset + int64(record.OffsetDelta)
+		recordTimestamp := batch.BaseTimestamp + record.TimestampDelta
+		if offset >= startOffset && recordTimestamp >= timestamp {
+			return TimestampAndOffset{Timestamp: recordTimestamp, Offset: offset}
+		}
+	}
+	return batchResult
+}
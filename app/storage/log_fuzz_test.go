@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"kafgo/app/compress"
+	"kafgo/app/metadata"
+)
+
+// FuzzReadBatches feeds arbitrary produce record sets to readBatches, which
+// must either split them into batches covering every byte or reject them
+func FuzzReadBatches(f *testing.F) {
+	batch := metadata.NewRecordBatch([]metadata.Record{
+		{Key: []byte("key"), Value: []byte("value")},
+		{Value: []byte("second")},
+	}, 1700000000000)
+	encoded := batch.Encode()
+
+	f.Add([]byte{})
+	f.Add(encoded)
+	f.Add(append(append([]byte{}, encoded...), encoded...))
+	f.Add(encoded[:len(encoded)-1])
+
+	// A valid CRC over a record with the wrong offset delta
+	misnumbered := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, 1700000000000)
+	misnumbered.Records = metadata.EncodeRecord(metadata.Record{OffsetDelta: 3, Value: []byte("value")})
+	f.Add(misnumbered.Encode())
+
+	for _, codec := range []compress.Codec{compress.Gzip, compress.Snappy, compress.LZ4, compress.Zstd} {
+		compressed := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}, {Value: []byte("value")}}, 1700000000000)
+		if err := compressed.SetCompression(codec); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(compressed.Encode())
+	}
+
+	f.Fuzz(func(t *testing.T, records []byte) {
+		batches, err := readBatches(records)
+		if err != nil {
+			var invalidRecords *InvalidRecordsError
+			if !errors.Is(err, ErrCorruptRecords) && !errors.As(err, &invalidRecords) {
+				t.Fatalf("error %v is neither corrupt nor invalid records", err)
+			}
+			return
+		}
+		size := 0
+		for _, batch := range batches {
+			size += batch.Size()
+		}
+		if size != len(records) {
+			t.Fatalf("batches cover %d of %d bytes", size, len(records))
+		}
+	})
+}
+
+// FuzzRecoverSegment recovers a segment file with arbitrary contents, which
+// must leave only whole, valid batches with increasing offsets
+func FuzzRecoverSegment(f *testing.F) {
+	first := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, 1700000000000)
+	second := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}, {Value: []byte("value")}}, 1700000000000)
+	second.BaseOffset = 1
+	encoded := append(first.Encode(), second.Encode()...)
+
+	f.Add([]byte{})
+	f.Add(encoded)
+	f.Add(encoded[:len(encoded)-5])
+	corrupt := append([]byte{}, encoded...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	f.Add(corrupt)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dir := t.TempDir()
+		if err := os.WriteFile(segmentFileName(dir, 0, ".log"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		segment, err := openSegment(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer segment.Close()
+		truncated, err := segment.recover()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if segment.size+truncated != int64(len(data)) {
+			t.Fatalf("kept %d and truncated %d of %d bytes", segment.size, truncated, len(data))
+		}
+
+		nextOffset := int64(0)
+		for position := int64(0); position < segment.size; {
+			batch, err := segment.readBatch(position)
+			if err != nil {
+				t.Fatalf("batch at %d: %v", position, err)
+			}
+			if !batch.IsValid() || batch.BaseOffset < nextOffset {
+				t.Fatalf("invalid batch kept at %d", position)
+			}
+			nextOffset = batch.LastOffset() + 1
+			position += int64(batch.Size())
+		}
+		if nextOffset != segment.nextOffset {
+			t.Fatalf("next offset %d, want %d", segment.nextOffset, nextOffset)
+		}
+	})
+}
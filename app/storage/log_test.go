@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+
+	"kafgo/app/compress"
+	"kafgo/app/metadata"
+)
+
+// testTimestamp is the timestamp of every test batch
+const testTimestamp int64 = 1700000000000
+
+// txnStep is one append in a transaction test: a single-record batch, plain
+// (producerID < 0) or in the producer's transaction, or a marker ending it
+type txnStep struct {
+	producerID int64
+	marker     string // "commit" or "abort"
+	roll       bool   // start a new segment instead
+}
+
+func plain() txnStep                     { return txnStep{producerID: -1} }
+func inTxn(producerID int64) txnStep     { return txnStep{producerID: producerID} }
+func commitTxn(producerID int64) txnStep { return txnStep{producerID: producerID, marker: "commit"} }
+func abortTxn(producerID int64) txnStep  { return txnStep{producerID: producerID, marker: "abort"} }
+
+func openTestLog(t *testing.T) *Log {
+	t.Helper()
+	log, err := OpenLog(t.TempDir(), "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Close() })
+	return log
+}
+
+func keyed(key string, value string) metadata.Record {
+	return metadata.Record{Key: []byte(key), Value: []byte(value)}
+}
+
+func tombstone(key string) metadata.Record {
+	return metadata.Record{Key: []byte(key)}
+}
+
+// keyedBatch returns a batch of records compressed with codec
+func keyedBatch(t *testing.T, timestamp int64, codec compress.Codec, records ...metadata.Record) *metadata.RecordBatch {
+	t.Helper()
+	batch := metadata.NewRecordBatch(records, timestamp)
+	if err := batch.SetCompression(codec); err != nil {
+		t.Fatal(err)
+	}
+	return batch
+}
+
+func appendBatch(t *testing.T, log *Log, batch *metadata.RecordBatch) {
+	t.Helper()
+	if _, err := log.Append(batch.Encode()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rollLog starts a new active segment, so the records before it can be cleaned
+func rollLog(t *testing.T, log *Log) {
+	t.Helper()
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if err := log.roll(log.logEndOffset()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// logRecord is a record read back from a log, or a transaction marker
+type logRecord struct {
+	offset    int64
+	key       string
+	value     string
+	tombstone bool
+	codec     compress.Codec
+	marker    bool
+}
+
+// readLogRecords returns every record in the log in offset order
+func readLogRecords(t *testing.T, log *Log) []logRecord {
+	t.Helper()
+	var got []logRecord
+	offset := log.LogStartOffset()
+	for {
+		data, err := log.Read(offset, 1024*1024, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) == 0 {
+			return got
+		}
+		reader := bytes.NewReader(data)
+		for reader.Len() > 0 {
+			batch, err := metadata.ReadRecordBatch(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			offset = batch.LastOffset() + 1
+			if batch.IsControl() {
+				got = append(got, logRecord{offset: batch.BaseOffset, marker: true})
+				continue
+			}
+			records, err := metadata.DecodeRecords(batch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, record := range records {
+				got = append(got, logRecord{
+					offset:    batch.BaseOffset + int64(record.OffsetDelta),
+					key:       string(record.Key),
+					value:     string(record.Value),
+					tombstone: record.Value == nil,
+					codec:     batch.Compression(),
+				})
+			}
+		}
+	}
+}
+
+func checkLogRecords(t *testing.T, got []logRecord, want []logRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("log holds %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// transactionalBatch returns a batch of one record in the open transaction of producerID
+func transactionalBatch(producerID int64, sequence int32) []byte {
+	batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, testTimestamp)
+	batch.Attributes |= metadata.AttributeTransactional
+	batch.ProducerID = producerID
+	batch.ProducerEpoch = 0
+	batch.BaseSequence = sequence
+	return batch.Encode()
+}
+
+func appendSteps(t *testing.T, log *Log, steps []txnStep) {
+	t.Helper()
+	sequences := make(map[int64]int32)
+	for i, step := range steps {
+		var err error
+		switch {
+		case step.roll:
+			rollLog(t, log)
+		case step.marker != "":
+			_, err = log.AppendControlMarker(step.producerID, 0, 0, step.marker == "commit")
+		case step.producerID < 0:
+			_, err = log.Append(metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, testTimestamp).Encode())
+		default:
+			_, err = log.Append(transactionalBatch(step.producerID, sequences[step.producerID]))
+			sequences[step.producerID]++
+		}
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+}
+
+func TestLastStableOffset(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []txnStep
+		wantLSO int64
+	}{
+		{"no transactions", []txnStep{plain(), plain()}, 2},
+		{"open transaction pins the LSO at its first offset", []txnStep{plain(), inTxn(1), inTxn(1), plain()}, 1},
+		{"commit releases the LSO", []txnStep{plain(), inTxn(1), commitTxn(1), plain()}, 4},
+		{"abort releases the LSO", []txnStep{inTxn(1), abortTxn(1)}, 2},
+		{"oldest open transaction pins the LSO", []txnStep{inTxn(1), inTxn(2), commitTxn(2)}, 0},
+		{"LSO moves to the next open transaction", []txnStep{inTxn(1), inTxn(2), commitTxn(1)}, 1},
+		{"open transaction in an earlier segment", []txnStep{plain(), inTxn(1), {roll: true}, plain()}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := openTestLog(t)
+			appendSteps(t, log, tt.steps)
+			if got := log.LastStableOffset(); got != tt.wantLSO {
+				t.Errorf("LastStableOffset() = %d, want %d (log end offset %d)", got, tt.wantLSO, log.LogEndOffset())
+			}
+		})
+	}
+}
+
+func TestCollectAbortedTxns(t *testing.T) {
+	// Producer 1 aborts offsets 0-2, producer 2 commits 1-5 and producer 3
+	// aborts 4-6, with its marker in the segment rolled at offset 5
+	steps := []txnStep{
+		inTxn(1), inTxn(2), abortTxn(1), plain(),
+		inTxn(3), {roll: true}, commitTxn(2), abortTxn(3), plain(),
+	}
+	producer1 := AbortedTxn{ProducerID: 1, FirstOffset: 0, LastOffset: 2, LastStableOffset: 1}
+	producer3 := AbortedTxn{ProducerID: 3, FirstOffset: 4, LastOffset: 6, LastStableOffset: 7}
+
+	tests := []struct {
+		name             string
+		fetchOffset      int64
+		upperBoundOffset int64
+		want             []AbortedTxn
+	}{
+		{"whole log", 0, 8, []AbortedTxn{producer1, producer3}},
+		{"from the abort marker", 2, 3, []AbortedTxn{producer1}},
+		{"after the first abort", 3, 8, []AbortedTxn{producer3}},
+		{"bound before the second transaction", 0, 4, []AbortedTxn{producer1}},
+		{"fetch inside a transaction started in an earlier segment", 5, 8, []AbortedTxn{producer3}},
+		{"past every abort", 7, 8, nil},
+	}
+
+	log := openTestLog(t)
+	appendSteps(t, log, steps)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := log.CollectAbortedTxns(tt.fetchOffset, tt.upperBoundOffset)
+			if len(got) != len(tt.want) {
+				t.Fatalf("CollectAbortedTxns(%d, %d) = %+v, want %+v", tt.fetchOffset, tt.upperBoundOffset, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("CollectAbortedTxns(%d, %d)[%d] = %+v, want %+v", tt.fetchOffset, tt.upperBoundOffset, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LogDirs are the directories partition logs are spread over, usually one
+// per disk (log.dirs)
+var LogDirs = []string{"/tmp/kraft-combined-logs"}
+
+// ErrLogDirOffline is returned for a partition whose log directory was taken
+// offline after an I/O error
+var ErrLogDirOffline = errors.New("log directory is offline")
+
+// The registry lock logsMu also guards the placement of every partition and
+// the offline directories
+var (
+	// placements maps "<topic>-<partition>" to the log directory holding it
+	placements = make(map[string]string)
+	// offlineDirs maps each offline log directory to the error that took it offline
+	offlineDirs = make(map[string]error)
+)
+
+// placeLog returns the log directory of a partition: the one it was placed
+// in, or for a new partition the online directory with the fewest
+// partitions. It does not record the placement.
+func placeLog(key string) (string, error) {
+	if dir, placed := placements[key]; placed {
+		if offlineDirs[dir] != nil {
+			return "", fmt.Errorf("%w: %s holds %s", ErrLogDirOffline, dir, key)
+		}
+		return dir, nil
+	}
+
+	load := make(map[string]int, len(LogDirs))
+	for _, dir := range placements {
+		load[dir]++
+	}
+	chosen := ""
+	for _, dir := range LogDirs {
+		if offlineDirs[dir] != nil {
+			continue
+		}
+		if chosen == "" || load[dir] < load[chosen] {
+			chosen = dir
+		}
+	}
+	if chosen == "" {
+		return "", fmt.Errorf("%w: every log directory is offline", ErrLogDirOffline)
+	}
+	return chosen, nil
+}
+
+// MarkLogDirOffline takes a log directory offline: its logs are closed and
+// their partitions fail with ErrLogDirOffline until the broker restarts,
+// while partitions in other directories carry on
+func MarkLogDirOffline(dir string, cause error) {
+	logsMu.Lock()
+	defer logsMu.Unlock()
+	markLogDirOffline(dir, cause)
+}
+
+func markLogDirOffline(dir string, cause error) {
+	if offlineDirs[dir] != nil {
+		return
+	}
+	offlineDirs[dir] = cause
+	fmt.Printf("Log directory %s is offline: %v\n", dir, cause)
+
+	for key, log := range logs {
+		if placements[key] != dir {
+			continue
+		}
+		log.mu.Lock()
+		log.Close()
+		log.mu.Unlock()
+		delete(logs, key)
+	}
+	if len(offlineDirs) == len(LogDirs) {
+		fmt.Println("Every log directory is offline")
+	}
+}
+
+// onlineLogDirs returns the log directories that are not offline
+func onlineLogDirs() []string {
+	logsMu.Lock()
+	defer logsMu.Unlock()
+
+	online := make([]string, 0, len(LogDirs))
+	for _, dir := range LogDirs {
+		if offlineDirs[dir] == nil {
+			online = append(online, dir)
+		}
+	}
+	return online
+}
+
+// isIOError reports whether err comes from the file system rather than from
+// validation. Reads of a segment closed by retention or compaction are not
+// failures of the disk.
+func isIOError(err error) bool {
+	if err == nil || errors.Is(err, os.ErrClosed) {
+		return false
+	}
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	return errors.As(err, &pathErr) || errors.As(err, &linkErr) || errors.As(err, &syscallErr)
+}
+
+// checkIOError takes the log's directory offline when err is an I/O error
+// and returns err unchanged. The caller must not hold logsMu.
+func (l *Log) checkIOError(err error) error {
+	if isIOError(err) {
+		MarkLogDirOffline(filepath.Dir(l.Dir), err)
+	}
+	return err
+}
+
+// LogDirInfo describes a log directory for DescribeLogDirs
+type LogDirInfo struct {
+	Dir string
+	// Err is why the directory is offline, nil when it is online
+	Err        error
+	Partitions []PartitionDirInfo
+	// TotalBytes and UsableBytes describe the directory's file system, -1 if unknown
+	TotalBytes  int64
+	UsableBytes int64
+}
+
+// PartitionDirInfo is the log of one partition in a log directory
+type PartitionDirInfo struct {
+	Topic     string
+	Partition int32
+	Size      int64
+	// OffsetLag is how far a follower replica is behind the leader. Every log
+	// here is the leader's only replica, so it is always 0.
+	OffsetLag int64
+}
+
+// DescribeLogDirs returns every log directory with the partitions it holds,
+// sorted by topic and partition
+func DescribeLogDirs() []LogDirInfo {
+	logsMu.Lock()
+	byDir := make(map[string][]*Log, len(LogDirs))
+	for key, log := range logs {
+		byDir[placements[key]] = append(byDir[placements[key]], log)
+	}
+	infos := make([]LogDirInfo, 0, len(LogDirs))
+	for _, dir := range LogDirs {
+		infos = append(infos, LogDirInfo{Dir: dir, Err: offlineDirs[dir]})
+	}
+	logsMu.Unlock()
+
+	for i := range infos {
+		info := &infos[i]
+		info.TotalBytes, info.UsableBytes = -1, -1
+		if info.Err != nil {
+			continue
+		}
+		if total, usable, err := diskUsage(info.Dir); err == nil {
+			info.TotalBytes, info.UsableBytes = total, usable
+		}
+		for _, log := range byDir[info.Dir] {
+			log.mu.RLock()
+			info.Partitions = append(info.Partitions, PartitionDirInfo{
+				Topic:     log.Topic,
+				Partition: log.Partition,
+				Size:      log.size(),
+			})
+			log.mu.RUnlock()
+		}
+		sort.Slice(info.Partitions, func(a, b int) bool {
+			pa, pb := info.Partitions[a], info.Partitions[b]
+			if pa.Topic != pb.Topic {
+				return pa.Topic < pb.Topic
+			}
+			return pa.Partition < pb.Partition
+		})
+	}
+	return infos
+}
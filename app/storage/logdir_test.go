@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"kafgo/app/compress"
+)
+
+// useLogDirs points LogDirs at count empty directories with a fresh registry
+// of logs, placements and offline directories, restored when the test ends
+func useLogDirs(t *testing.T, count int) []string {
+	t.Helper()
+	dirs := make([]string, count)
+	for i := range dirs {
+		dirs[i] = filepath.Join(t.TempDir(), fmt.Sprintf("logs-%d", i))
+	}
+
+	logsMu.Lock()
+	defer logsMu.Unlock()
+	oldDirs, oldLogs, oldPlacements, oldOffline := LogDirs, logs, placements, offlineDirs
+	LogDirs, logs, placements, offlineDirs = dirs, make(map[string]*Log), make(map[string]string), make(map[string]error)
+	t.Cleanup(func() {
+		logsMu.Lock()
+		defer logsMu.Unlock()
+		for _, log := range logs {
+			log.Close()
+		}
+		LogDirs, logs, placements, offlineDirs = oldDirs, oldLogs, oldPlacements, oldOffline
+	})
+	return dirs
+}
+
+func TestGetLogPlacement(t *testing.T) {
+	tests := []struct {
+		name string
+		// placed maps partitions of topic "placed" to the directory they are in
+		placed map[int32]int
+		// offline are the directories taken offline first
+		offline []int
+		// want is the directory each new partition of topic "new" lands in, in
+		// order, or -1 for ErrLogDirOffline
+		want []int
+	}{
+		{name: "spread over the directories", want: []int{0, 1, 2, 0, 1, 2}},
+		{name: "existing placements counted", placed: map[int32]int{0: 0, 1: 0, 2: 1}, want: []int{2, 1, 2, 0}},
+		{name: "offline directory skipped", offline: []int{0}, want: []int{1, 2, 1}},
+		{name: "every directory offline", offline: []int{0, 1, 2}, want: []int{-1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirs := useLogDirs(t, 3)
+			for partition, dir := range tt.placed {
+				placements[fmt.Sprintf("placed-%d", partition)] = dirs[dir]
+			}
+			for _, dir := range tt.offline {
+				MarkLogDirOffline(dirs[dir], errors.New("disk failed"))
+			}
+
+			for partition, want := range tt.want {
+				log, err := GetLog("new", int32(partition))
+				if want < 0 {
+					if !errors.Is(err, ErrLogDirOffline) {
+						t.Fatalf("partition %d: error %v, want ErrLogDirOffline", partition, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("partition %d: %v", partition, err)
+				}
+				if dir := filepath.Dir(log.Dir); dir != dirs[want] {
+					t.Errorf("partition %d placed in %s, want %s", partition, dir, dirs[want])
+				}
+			}
+		})
+	}
+
+	t.Run("placed in an offline directory", func(t *testing.T) {
+		dirs := useLogDirs(t, 2)
+		placements["placed-0"] = dirs[1]
+		MarkLogDirOffline(dirs[1], errors.New("disk failed"))
+		if _, err := GetLog("placed", 0); !errors.Is(err, ErrLogDirOffline) {
+			t.Errorf("error %v, want ErrLogDirOffline", err)
+		}
+	})
+}
+
+func TestDescribeLogDirs(t *testing.T) {
+	dirs := useLogDirs(t, 3)
+	batchSize := int64(len(keyedBatch(t, testTimestamp, compress.None, keyed("a", "1")).Encode()))
+
+	// Partitions are placed round robin: b-1 and a-0 in the first directory,
+	// b-0 in the second and a-1 in the third, which is then taken offline
+	batches := []struct {
+		topic     string
+		partition int32
+		count     int
+	}{{"b", 1, 2}, {"b", 0, 1}, {"a", 1, 1}, {"a", 0, 3}}
+	for _, b := range batches {
+		log, err := GetLog(b.topic, b.partition)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range b.count {
+			appendBatch(t, log, keyedBatch(t, testTimestamp, compress.None, keyed("a", "1")))
+		}
+	}
+	MarkLogDirOffline(dirs[2], errors.New("disk failed"))
+
+	want := []LogDirInfo{
+		{Dir: dirs[0], Partitions: []PartitionDirInfo{{"a", 0, 3 * batchSize, 0}, {"b", 1, 2 * batchSize, 0}}},
+		{Dir: dirs[1], Partitions: []PartitionDirInfo{{"b", 0, batchSize, 0}}},
+		{Dir: dirs[2]},
+	}
+	infos := DescribeLogDirs()
+	if len(infos) != len(want) {
+		t.Fatalf("%d log directories, want %d", len(infos), len(want))
+	}
+	for i, info := range infos {
+		if info.Dir != want[i].Dir {
+			t.Errorf("directory %d is %s, want %s", i, info.Dir, want[i].Dir)
+		}
+		if !slices.Equal(info.Partitions, want[i].Partitions) {
+			t.Errorf("%s partitions %+v, want %+v", info.Dir, info.Partitions, want[i].Partitions)
+		}
+		if offline := i == 2; (info.Err != nil) != offline {
+			t.Errorf("%s error %v, want offline %v", info.Dir, info.Err, offline)
+		}
+		if i == 2 && (info.TotalBytes != -1 || info.UsableBytes != -1) {
+			t.Errorf("offline %s reports %d total and %d usable bytes, want -1", info.Dir, info.TotalBytes, info.UsableBytes)
+		}
+	}
+}
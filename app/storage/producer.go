@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"kafgo/app/metadata"
+)
+
+// ErrOutOfOrderSequence is returned by Append when a batch does not continue
+// its producer's sequence (OUT_OF_ORDER_SEQUENCE_NUMBER)
+var ErrOutOfOrderSequence = errors.New("out of order sequence number")
+
+// ErrInvalidProducerEpoch is returned by Append when a batch comes from an
+// older epoch of a producer than the log has seen (INVALID_PRODUCER_EPOCH)
+var ErrInvalidProducerEpoch = errors.New("invalid producer epoch")
+
+// ErrInvalidTxnState is returned by Append when a producer with an open
+// transaction writes a non-transactional batch (INVALID_TXN_STATE)
+var ErrInvalidTxnState = errors.New("invalid transaction state")
+
+// producerBatchesToRetain is how many recent batches per producer are kept for
+// duplicate detection, matching max.in.flight.requests.per.connection=5
+const producerBatchesToRetain = 5
+
+// BatchMetadata is where one batch of a producer landed in the log
+type BatchMetadata struct {
+	FirstSeq    int32
+	LastSeq     int32
+	FirstOffset int64
+	LastOffset  int64
+	Timestamp   int64
+}
+
+// ProducerStateEntry is the state of one producer ID in a partition
+type ProducerStateEntry struct {
+	ProducerID       int64
+	ProducerEpoch    int16
+	CoordinatorEpoch int32
+	// CurrentTxnFirstOffset is the first offset of the open transaction, or -1
+	CurrentTxnFirstOffset int64
+	// Batches holds the most recent batches, oldest first
+	Batches []BatchMetadata
+}
+
+// LastSeq returns the last sequence number written by the producer, or -1
+func (e *ProducerStateEntry) LastSeq() int32 {
+	if len(e.Batches) == 0 {
+		return -1
+	}
+	return e.Batches[len(e.Batches)-1].LastSeq
+}
+
+// findDuplicateBatch returns the retained batch with the same epoch and sequence range
+func (e *ProducerStateEntry) findDuplicateBatch(batch *metadata.RecordBatch) (BatchMetadata, bool) {
+	if batch.ProducerEpoch != e.ProducerEpoch {
+		return BatchMetadata{}, false
+	}
+	lastSeq := lastSequence(batch)
+	for _, retained := range e.Batches {
+		if retained.FirstSeq == batch.BaseSequence && retained.LastSeq == lastSeq {
+			return retained, true
+		}
+	}
+	return BatchMetadata{}, false
+}
+
+// addBatch records an appended batch, starting over when the epoch changes.
+// The first transactional batch opens a transaction; batches written without
+// sequence numbers (by a coordinator) are not retained for deduplication.
+func (e *ProducerStateEntry) addBatch(batch *metadata.RecordBatch) {
+	e.setEpoch(batch.ProducerEpoch)
+	if batch.IsTransactional() && e.CurrentTxnFirstOffset < 0 {
+		e.CurrentTxnFirstOffset = batch.BaseOffset
+	}
+	if batch.BaseSequence < 0 {
+		return
+	}
+	e.Batches = append(e.Batches, BatchMetadata{
+		FirstSeq:    batch.BaseSequence,
+		LastSeq:     lastSequence(batch),
+		FirstOffset: batch.BaseOffset,
+		LastOffset:  batch.LastOffset(),
+		Timestamp:   batch.MaxTimestamp,
+	})
+	if len(e.Batches) > producerBatchesToRetain {
+		e.Batches = e.Batches[len(e.Batches)-producerBatchesToRetain:]
+	}
+}
+
+// completeTxn records a transaction marker, which closes the open transaction
+func (e *ProducerStateEntry) completeTxn(batch *metadata.RecordBatch) {
+	e.setEpoch(batch.ProducerEpoch)
+	e.CurrentTxnFirstOffset = -1
+}
+
+// setEpoch moves the producer to epoch, forgetting the batches of the previous one
+func (e *ProducerStateEntry) setEpoch(epoch int16) {
+	if epoch != e.ProducerEpoch {
+		e.ProducerEpoch = epoch
+		e.Batches = nil
+	}
+}
+
+func (e *ProducerStateEntry) clone() *ProducerStateEntry {
+	clone := *e
+	clone.Batches = append([]BatchMetadata(nil), e.Batches...)
+	return &clone
+}
+
+// lastSequence returns the sequence number of the last record in batch,
+// wrapping around after math.MaxInt32 like the producer does
+func lastSequence(batch *metadata.RecordBatch) int32 {
+	if batch.BaseSequence > math.MaxInt32-batch.LastOffsetDelta {
+		return batch.LastOffsetDelta - (math.MaxInt32 - batch.BaseSequence) - 1
+	}
+	return batch.BaseSequence + batch.LastOffsetDelta
+}
+
+// inSequence reports whether nextSeq directly follows lastSeq
+func inSequence(lastSeq int32, nextSeq int32) bool {
+	return int64(nextSeq) == int64(lastSeq)+1 || (nextSeq == 0 && lastSeq == math.MaxInt32)
+}
+
+// producerStateManager tracks the producers that have written to a partition
+type producerStateManager struct {
+	producers map[int64]*ProducerStateEntry
+}
+
+func newProducerStateManager() *producerStateManager {
+	return &producerStateManager{producers: make(map[int64]*ProducerStateEntry)}
+}
+
+// firstUnstableOffset returns the first offset of the oldest open transaction, or -1
+func (m *producerStateManager) firstUnstableOffset() int64 {
+	first := int64(-1)
+	for _, entry := range m.producers {
+		if entry.CurrentTxnFirstOffset >= 0 && (first < 0 || entry.CurrentTxnFirstOffset < first) {
+			first = entry.CurrentTxnFirstOffset
+		}
+	}
+	return first
+}
+
+// truncateHead forgets producers whose last batch is below logStartOffset, once
+// retention has deleted it. Producers with an open transaction are kept.
+func (m *producerStateManager) truncateHead(logStartOffset int64) {
+	for producerID, entry := range m.producers {
+		if entry.CurrentTxnFirstOffset >= 0 {
+			continue
+		}
+		if len(entry.Batches) == 0 || entry.Batches[len(entry.Batches)-1].LastOffset < logStartOffset {
+			delete(m.producers, producerID)
+		}
+	}
+}
+
+// appendOrigin is who wrote a record set. Coordinators write their internal
+// topics without sequence numbers, so only clients are sequence-checked.
+type appendOrigin int
+
+const (
+	appendFromClient appendOrigin = iota
+	appendFromCoordinator
+)
+
+// producerAppend collects the producer state changes of one Append, which are
+// only applied to the manager once the batches are written
+type producerAppend struct {
+	manager *producerStateManager
+	origin  appendOrigin
+	updated map[int64]*ProducerStateEntry
+}
+
+func (m *producerStateManager) prepareAppend(origin appendOrigin) *producerAppend {
+	return &producerAppend{manager: m, origin: origin, updated: make(map[int64]*ProducerStateEntry)}
+}
+
+// entry returns the state of producerID as of the batches seen so far, or nil
+func (a *producerAppend) entry(producerID int64) *ProducerStateEntry {
+	if entry, exists := a.updated[producerID]; exists {
+		return entry
+	}
+	return a.manager.producers[producerID]
+}
+
+// findDuplicate returns where batch was written before if it is a retry of a
+// batch that is already in the log
+func (a *producerAppend) findDuplicate(batch *metadata.RecordBatch) (BatchMetadata, bool) {
+	if batch.ProducerID < 0 || a.origin != appendFromClient {
+		return BatchMetadata{}, false
+	}
+	entry := a.entry(batch.ProducerID)
+	if entry == nil {
+		return BatchMetadata{}, false
+	}
+	return entry.findDuplicateBatch(batch)
+}
+
+// validate checks the epoch, sequence and transaction state of a produced batch
+// against its producer's state. Markers and coordinator writes only need a current epoch.
+func (a *producerAppend) validate(batch *metadata.RecordBatch) error {
+	if batch.ProducerID < 0 {
+		return nil
+	}
+	entry := a.entry(batch.ProducerID)
+	if entry == nil {
+		// Unknown producers (new, or whose state is gone) may start at any sequence
+		return nil
+	}
+
+	if batch.ProducerEpoch < entry.ProducerEpoch {
+		return fmt.Errorf("%w: producer %d epoch %d is older than the current epoch %d",
+			ErrInvalidProducerEpoch, batch.ProducerID, batch.ProducerEpoch, entry.ProducerEpoch)
+	}
+	if batch.IsControl() || a.origin != appendFromClient {
+		return nil
+	}
+	if !batch.IsTransactional() && entry.CurrentTxnFirstOffset >= 0 {
+		return fmt.Errorf("%w: producer %d wrote a non-transactional batch inside the transaction started at offset %d",
+			ErrInvalidTxnState, batch.ProducerID, entry.CurrentTxnFirstOffset)
+	}
+	if batch.ProducerEpoch != entry.ProducerEpoch {
+		if batch.BaseSequence != 0 {
+			return fmt.Errorf("%w: producer %d starts epoch %d at sequence %d instead of 0",
+				ErrOutOfOrderSequence, batch.ProducerID, batch.ProducerEpoch, batch.BaseSequence)
+		}
+		return nil
+	}
+	if !inSequence(entry.LastSeq(), batch.BaseSequence) {
+		return fmt.Errorf("%w: producer %d sent sequence %d after %d (epoch %d)",
+			ErrOutOfOrderSequence, batch.ProducerID, batch.BaseSequence, entry.LastSeq(), batch.ProducerEpoch)
+	}
+	return nil
+}
+
+// update records a batch that is about to be written
+func (a *producerAppend) update(batch *metadata.RecordBatch) {
+	if batch.ProducerID < 0 {
+		return
+	}
+	entry, exists := a.updated[batch.ProducerID]
+	if !exists {
+		if current := a.manager.producers[batch.ProducerID]; current != nil {
+			entry = current.clone()
+		} else {
+			entry = &ProducerStateEntry{ProducerID: batch.ProducerID, ProducerEpoch: batch.ProducerEpoch, CoordinatorEpoch: -1, CurrentTxnFirstOffset: -1}
+		}
+		a.updated[batch.ProducerID] = entry
+	}
+	if batch.IsControl() {
+		entry.completeTxn(batch)
+	} else {
+		entry.addBatch(batch)
+	}
+}
+
+// commit applies the collected changes to the manager
+func (a *producerAppend) commit() {
+	for producerID, entry := range a.updated {
+		a.manager.producers[producerID] = entry
+	}
+}
+
+// replay applies batches read back from the log, without validation
+func (m *producerStateManager) replay(batches []*metadata.RecordBatch) {
+	producerAppend := m.prepareAppend(appendFromClient)
+	for _, batch := range batches {
+		producerAppend.update(batch)
+	}
+	producerAppend.commit()
+}
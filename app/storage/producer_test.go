@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"kafgo/app/metadata"
+)
+
+// sequenceBatch returns a batch of producer 5 holding one record whose value is its sequence
+func sequenceBatch(sequence int32) []byte {
+	return producerBatch(0, sequence)
+}
+
+// producerBatch is sequenceBatch at the given epoch
+func producerBatch(epoch int16, sequence int32) []byte {
+	batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte(strconv.Itoa(int(sequence)))}}, testTimestamp)
+	batch.ProducerID = 5
+	batch.ProducerEpoch = epoch
+	batch.BaseSequence = sequence
+	return batch.Encode()
+}
+
+func TestAppendDuplicateBatches(t *testing.T) {
+	tests := []struct {
+		name string
+		// before are the sequences appended one batch per Append
+		before []int32
+		// set are the sequences of the batches of the record set under test
+		set      []int32
+		wantErr  error
+		wantBase int64
+		wantLast int64
+		wantEnd  int64
+	}{
+		{name: "retry of the last batch", before: []int32{0, 1}, set: []int32{1}, wantBase: 1, wantLast: 1, wantEnd: 2},
+		{name: "retry followed by a new batch", before: []int32{0, 1}, set: []int32{1, 2}, wantBase: 1, wantLast: 2, wantEnd: 3},
+		{name: "new batch followed by a retry", before: []int32{0}, set: []int32{1, 0}, wantBase: 0, wantLast: 1, wantEnd: 2},
+		{name: "batch repeated in the set", before: []int32{0}, set: []int32{1, 1}, wantBase: 1, wantLast: 1, wantEnd: 2},
+		{name: "retry followed by a gap", before: []int32{0}, set: []int32{0, 5}, wantErr: ErrOutOfOrderSequence, wantEnd: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := openTestLog(t)
+			for _, sequence := range tt.before {
+				if _, err := log.Append(sequenceBatch(sequence)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var set []byte
+			for _, sequence := range tt.set {
+				set = append(set, sequenceBatch(sequence)...)
+			}
+			info, err := log.Append(set)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Append error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (info.BaseOffset != tt.wantBase || info.LastOffset != tt.wantLast) {
+				t.Errorf("Append offsets %d-%d, want %d-%d", info.BaseOffset, info.LastOffset, tt.wantBase, tt.wantLast)
+			}
+			if end := log.LogEndOffset(); end != tt.wantEnd {
+				t.Errorf("log end offset %d, want %d", end, tt.wantEnd)
+			}
+
+			// Every sequence is in the log exactly once
+			want := make([]logRecord, 0, tt.wantEnd)
+			for offset := range tt.wantEnd {
+				want = append(want, logRecord{offset: offset, value: strconv.FormatInt(offset, 10)})
+			}
+			checkLogRecords(t, readLogRecords(t, log), want)
+		})
+	}
+}
+
+func TestAppendProducerSequences(t *testing.T) {
+	type produce struct {
+		epoch    int16
+		sequence int32
+		wantErr  error
+	}
+	tests := []struct {
+		name     string
+		produces []produce
+	}{
+		{"in order", []produce{{0, 0, nil}, {0, 1, nil}, {0, 2, nil}}},
+		{"gap", []produce{{0, 0, nil}, {0, 2, ErrOutOfOrderSequence}, {0, 1, nil}}},
+		{"retry of an earlier batch", []produce{{0, 0, nil}, {0, 1, nil}, {0, 2, nil}, {0, 0, nil}, {0, 3, nil}}},
+		{"unknown producer starts anywhere", []produce{{0, 7, nil}, {0, 8, nil}}},
+		{"sequence wraps around", []produce{{0, math.MaxInt32, nil}, {0, 0, nil}}},
+		{"new epoch starts at 0", []produce{{0, 0, nil}, {0, 1, nil}, {1, 0, nil}, {1, 1, nil}}},
+		{"new epoch not at 0", []produce{{0, 0, nil}, {1, 3, ErrOutOfOrderSequence}}},
+		{"old epoch fenced", []produce{{0, 0, nil}, {1, 0, nil}, {0, 1, ErrInvalidProducerEpoch}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := openTestLog(t)
+			for i, p := range tt.produces {
+				end := log.LogEndOffset()
+				_, err := log.Append(producerBatch(p.epoch, p.sequence))
+				if !errors.Is(err, p.wantErr) {
+					t.Fatalf("produce %d (epoch %d, sequence %d): error %v, want %v", i, p.epoch, p.sequence, err, p.wantErr)
+				}
+				if err != nil && log.LogEndOffset() != end {
+					t.Errorf("produce %d: rejected batch appended", i)
+				}
+			}
+		})
+	}
+}
+
+func TestProducerStateReload(t *testing.T) {
+	tests := []struct {
+		name string
+		// snapshot takes a producer snapshot before the log is closed; otherwise
+		// the state is rebuilt from the log
+		snapshot bool
+	}{
+		{"from a snapshot", true},
+		{"from the log", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			log, err := OpenLog(dir, "test", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, sequence := range []int32{0, 1} {
+				if _, err := log.Append(sequenceBatch(sequence)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.snapshot {
+				rollLog(t, log)
+			}
+			log.Close()
+
+			reopened, err := OpenLog(dir, "test", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+
+			// The retry is answered with the offset it was first written at
+			info, err := reopened.Append(sequenceBatch(1))
+			if err != nil || info.BaseOffset != 1 || reopened.LogEndOffset() != 2 {
+				t.Errorf("retry after reload: offset %d, log end offset %d, error %v; want 1, 2 and none", info.BaseOffset, reopened.LogEndOffset(), err)
+			}
+			if _, err := reopened.Append(sequenceBatch(3)); !errors.Is(err, ErrOutOfOrderSequence) {
+				t.Errorf("gap after reload: error %v, want %v", err, ErrOutOfOrderSequence)
+			}
+			if _, err := reopened.Append(producerBatch(-1, 2)); !errors.Is(err, ErrInvalidProducerEpoch) {
+				t.Errorf("old epoch after reload: error %v, want %v", err, ErrInvalidProducerEpoch)
+			}
+			if _, err := reopened.Append(sequenceBatch(2)); err != nil {
+				t.Errorf("next sequence after reload: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"kafgo/app/metadata"
+)
+
+// CleanShutdownFile is written to each log directory once its logs are
+// closed on shutdown. If it is missing at startup the broker did not stop
+// cleanly, and every log in the directory is recovered before it is used.
+const CleanShutdownFile = ".kafka_cleanshutdown"
+
+// LoadLogs opens the log of every partition directory in LogDirs and records
+// where each partition is placed. After an unclean shutdown each log is
+// recovered: batches are validated and a torn tail is truncated. The clean
+// shutdown markers are removed, so a crash from here on is detected at the
+// next start. A log directory that fails with an I/O error is taken offline;
+// only when every directory is offline does LoadLogs fail.
+func LoadLogs() error {
+	logsMu.Lock()
+	defer logsMu.Unlock()
+
+	for _, dir := range LogDirs {
+		if err := loadLogDir(dir); err != nil {
+			if !isIOError(err) {
+				return err
+			}
+			markLogDirOffline(dir, err)
+		}
+	}
+	if len(offlineDirs) == len(LogDirs) {
+		return fmt.Errorf("%w: every log directory is offline", ErrLogDirOffline)
+	}
+	return nil
+}
+
+// loadLogDir opens the logs of one log directory, creating it if needed
+func loadLogDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	markerPath := filepath.Join(dir, CleanShutdownFile)
+	_, err := os.Stat(markerPath)
+	cleanShutdown := err == nil
+	if cleanShutdown {
+		if err := os.Remove(markerPath); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	recovered := 0
+	for _, entry := range entries {
+		topic, partition, ok := parsePartitionDir(entry)
+		if !ok {
+			continue
+		}
+		key := entry.Name()
+		if placed, found := placements[key]; found && placed != dir {
+			return fmt.Errorf("%s is in both %s and %s", key, placed, dir)
+		}
+		if _, open := logs[key]; open {
+			continue
+		}
+		log, err := openLog(filepath.Join(dir, key), topic, partition, !cleanShutdown)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", key, err)
+		}
+		placements[key] = dir
+		logs[key] = log
+		recovered++
+	}
+	if !cleanShutdown && recovered > 0 {
+		fmt.Printf("Recovered %d logs in %s after an unclean shutdown\n", recovered, dir)
+	}
+	return nil
+}
+
+// parsePartitionDir returns the topic partition a <topic>-<partition>
+// directory belongs to. Directories of deleted topics and the metadata log,
+// which the metadata package reads itself, are skipped.
+func parsePartitionDir(entry os.DirEntry) (string, int32, bool) {
+	name := entry.Name()
+	if !entry.IsDir() || strings.HasSuffix(name, deleteDirSuffix) || name == "__cluster_metadata-0" {
+		return "", 0, false
+	}
+	i := strings.LastIndex(name, "-")
+	if i <= 0 {
+		return "", 0, false
+	}
+	partition, err := strconv.ParseInt(name[i+1:], 10, 32)
+	if err != nil || partition < 0 {
+		return "", 0, false
+	}
+	return name[:i], int32(partition), true
+}
+
+// writeCleanShutdownFile marks a log directory as cleanly shut down
+func writeCleanShutdownFile(dir string) error {
+	return os.WriteFile(filepath.Join(dir, CleanShutdownFile), nil, 0644)
+}
+
+// recover validates every segment from the first one and rebuilds their
+// indexes. At the first segment with a torn or corrupt tail the segment is
+// truncated there and the segments after it are deleted, since their offsets
+// no longer follow on.
+func (l *Log) recover() error {
+	for i, segment := range l.segments {
+		truncated, err := segment.recover()
+		if err != nil {
+			return err
+		}
+		if truncated == 0 {
+			continue
+		}
+		fmt.Printf("Truncated %d invalid bytes at offset %d from %s\n", truncated, segment.nextOffset, segment.log.Name())
+		for _, later := range l.segments[i+1:] {
+			if err := later.Delete(); err != nil {
+				return err
+			}
+		}
+		l.segments = l.segments[:i+1]
+		break
+	}
+	return nil
+}
+
+// recover rebuilds the segment's indexes while validating every batch: it must
+// be complete, have magic 2 and a matching CRC-32C, and continue the offsets
+// of the batch before it. The segment is truncated at the first batch that
+// does not, and the number of bytes removed is returned.
+func (s *LogSegment) recover() (int64, error) {
+	if err := s.index.Reset(); err != nil {
+		return 0, err
+	}
+	if err := s.timeIndex.Reset(); err != nil {
+		return 0, err
+	}
+	s.nextOffset = s.BaseOffset
+	s.maxTimestampSoFar = -1
+	s.bytesSinceLastIndexEntry = 0
+
+	position := int64(0)
+	for position < s.size {
+		// The header check keeps a garbage length from being allocated
+		if _, err := s.readBatchHeader(position); err != nil {
+			break
+		}
+		batch, err := metadata.ReadRecordBatch(io.NewSectionReader(s.log, position, s.size-position))
+		if err != nil || batch.Magic != 2 || !batch.IsValid() || batch.BaseOffset < s.nextOffset || batch.LastOffsetDelta < 0 {
+			break
+		}
+		if err := s.indexBatch(batch, int32(position)); err != nil {
+			return 0, err
+		}
+		s.nextOffset = batch.LastOffset() + 1
+		position += int64(batch.Size())
+	}
+
+	truncated := s.size - position
+	if truncated > 0 {
+		if err := s.log.Truncate(position); err != nil {
+			return 0, err
+		}
+		s.size = position
+	}
+	// Drop aborted transactions whose marker was lost
+	return truncated, s.txnIndex.truncateTo(s.nextOffset)
+}
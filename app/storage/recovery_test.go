@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kafgo/app/metadata"
+)
+
+// loadTestLogs runs LoadLogs over dir alone, with an empty registry, and returns the log of test-0
+func loadTestLogs(t *testing.T, dir string) *Log {
+	t.Helper()
+	savedDirs := LogDirs
+	resetRegistry := func() {
+		logsMu.Lock()
+		defer logsMu.Unlock()
+		for _, log := range logs {
+			log.Close()
+		}
+		logs = make(map[string]*Log)
+		placements = make(map[string]string)
+		offlineDirs = make(map[string]error)
+	}
+	resetRegistry()
+	LogDirs = []string{dir}
+	t.Cleanup(func() {
+		resetRegistry()
+		LogDirs = savedDirs
+	})
+
+	if err := LoadLogs(); err != nil {
+		t.Fatal(err)
+	}
+	log := logs["test-0"]
+	if log == nil {
+		t.Fatal("test-0 was not loaded")
+	}
+	return log
+}
+
+// idempotentBatch returns a one-record batch of producer 5 with the given sequence
+func idempotentBatch(sequence int32) []byte {
+	batch := metadata.NewRecordBatch([]metadata.Record{keyed("key", "value")}, testTimestamp)
+	batch.ProducerID = 5
+	batch.ProducerEpoch = 0
+	batch.BaseSequence = sequence
+	return batch.Encode()
+}
+
+func TestLoadLogsRecovery(t *testing.T) {
+	savedInterval := IndexIntervalBytes
+	IndexIntervalBytes = 0 // index every batch
+	defer func() { IndexIntervalBytes = savedInterval }()
+
+	tests := []struct {
+		name string
+		// damage changes the first segment, holding offsets 0-2 at positions
+		damage        func(t *testing.T, path string, positions []int64)
+		cleanShutdown bool
+		wantEnd       int64
+		wantSegments  int
+	}{
+		{
+			name:         "intact log",
+			damage:       func(t *testing.T, path string, positions []int64) {},
+			wantEnd:      5,
+			wantSegments: 3,
+		},
+		{
+			name: "CRC-corrupt batch mid-segment",
+			damage: func(t *testing.T, path string, positions []int64) {
+				flipByte(t, path, positions[2]-1)
+			},
+			wantEnd:      1,
+			wantSegments: 1,
+		},
+		{
+			name: "torn batch mid-segment",
+			damage: func(t *testing.T, path string, positions []int64) {
+				if err := os.Truncate(path, positions[2]+10); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantEnd:      2,
+			wantSegments: 1,
+		},
+		{
+			name: "batch length past the end of the segment",
+			damage: func(t *testing.T, path string, positions []int64) {
+				writeAt(t, path, positions[1]+8, binary.BigEndian.AppendUint32(nil, 0x7fffffff))
+			},
+			wantEnd:      1,
+			wantSegments: 1,
+		},
+		{
+			name: "clean shutdown marker skips recovery",
+			damage: func(t *testing.T, path string, positions []int64) {
+				flipByte(t, path, positions[2]-1)
+			},
+			cleanShutdown: true,
+			wantEnd:       5,
+			wantSegments:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			log, err := OpenLog(filepath.Join(dir, "test-0"), "test", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Offsets 0-2 in the first segment, then one batch per segment
+			positions := []int64{0}
+			for sequence := int32(0); sequence < 5; sequence++ {
+				if sequence == 3 || sequence == 4 {
+					rollLog(t, log)
+				}
+				if _, err := log.Append(idempotentBatch(sequence)); err != nil {
+					t.Fatal(err)
+				}
+				if sequence < 3 {
+					positions = append(positions, log.segments[0].size)
+				}
+			}
+			// Crash: the logs are closed without a producer snapshot at the end
+			log.Close()
+
+			tt.damage(t, segmentFileName(log.Dir, 0, ".log"), positions)
+			if tt.cleanShutdown {
+				if err := writeCleanShutdownFile(dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			loaded := loadTestLogs(t, dir)
+			if got := loaded.LogEndOffset(); got != tt.wantEnd {
+				t.Errorf("log end offset %d, want %d", got, tt.wantEnd)
+			}
+			segmentFiles, _ := filepath.Glob(filepath.Join(loaded.Dir, "*.log"))
+			if len(segmentFiles) != tt.wantSegments || len(loaded.segments) != tt.wantSegments {
+				t.Errorf("%d segment files and %d segments, want %d", len(segmentFiles), len(loaded.segments), tt.wantSegments)
+			}
+			if _, err := os.Stat(filepath.Join(dir, CleanShutdownFile)); !os.IsNotExist(err) {
+				t.Errorf("clean shutdown marker left after loading")
+			}
+
+			// Every remaining batch of the first segment is indexed, and nothing past them
+			first := loaded.segments[0]
+			if want := min(tt.wantEnd, 3); int64(len(first.index.entries)) != want {
+				t.Errorf("first segment has %d index entries, want %d", len(first.index.entries), want)
+			}
+			for _, entry := range first.index.entries {
+				if entry.Offset >= tt.wantEnd || int64(entry.Position) >= first.size {
+					t.Errorf("index entry %+v points past the log (end %d, size %d)", entry, tt.wantEnd, first.size)
+				}
+			}
+
+			// The producer continues after its last surviving batch
+			if got := loaded.producers.producers[5].LastSeq(); got != int32(tt.wantEnd-1) {
+				t.Errorf("producer last sequence %d, want %d", got, tt.wantEnd-1)
+			}
+			info, err := loaded.Append(idempotentBatch(int32(tt.wantEnd)))
+			if err != nil {
+				t.Fatalf("appending the next sequence: %v", err)
+			}
+			if info.BaseOffset != tt.wantEnd {
+				t.Errorf("next batch at offset %d, want %d", info.BaseOffset, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func flipByte(t *testing.T, path string, position int64) {
+	t.Helper()
+	b := make([]byte, 1)
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.ReadAt(b, position); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteAt([]byte{b[0] ^ 0xff}, position); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeAt(t *testing.T, path string, position int64, data []byte) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteAt(data, position); err != nil {
+		t.Fatal(err)
+	}
+}
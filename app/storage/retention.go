@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"kafgo/app/metadata"
+)
+
+// Retention settings, named after the broker configs they mirror. Topics
+// override the first two with retention.ms and retention.bytes; -1 disables
+// that limit.
+var (
+	RetentionMs              int64 = 604800000 // log.retention.ms
+	RetentionBytes           int64 = -1        // log.retention.bytes
+	RetentionCheckIntervalMs int64 = 300000    // log.retention.check.interval.ms
+)
+
+// StartLogRetention starts the background task that deletes expired segments
+// of every partition each RetentionCheckIntervalMs
+func StartLogRetention() {
+	go func() {
+		ticker := time.NewTicker(time.Duration(RetentionCheckIntervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			for _, log := range allLogs() {
+				if _, err := log.DeleteOldSegments(now); log.checkIOError(err) != nil && !errors.Is(err, ErrLogDeleted) {
+					fmt.Printf("Failed to apply retention to %s-%d: %v\n", log.Topic, log.Partition, err)
+				}
+			}
+		}
+	}()
+}
+
+// allLogs opens the log of every partition in the cluster metadata and returns
+// them together with the other open logs, such as those of internal topics
+func allLogs() []*Log {
+	for topicName, topic := range metadata.GetTopicMetadata() {
+		for _, partition := range topic.Partitions {
+			// Partitions of an offline log directory stay offline
+			if _, err := GetLog(topicName, partition.PartitionIndex); err != nil && !errors.Is(err, ErrLogDirOffline) {
+				fmt.Printf("Failed to open %s-%d: %v\n", topicName, partition.PartitionIndex, err)
+			}
+		}
+	}
+
+	return openLogs()
+}
+
+// retentionConfig returns a topic's override of a retention config, falling
+// back to the broker default
+func retentionConfig(topic string, name string, defaultValue int64) int64 {
+	value, found := metadata.TopicConfig(topic, name)
+	if !found {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// DeleteOldSegments deletes the oldest segments whose records are all older
+// than retention.ms, then the oldest segments while the log exceeds
+// retention.bytes by at least their size, and returns how many were deleted.
+// Only whole segments are deleted, so the log start offset moves to the base
+// offset of the first remaining one. When every segment goes, an empty segment
+// is rolled at the log end offset first, so offsets are never reused. Topics
+// whose cleanup.policy does not include "delete" are left alone.
+func (l *Log) DeleteOldSegments(now time.Time) (int, error) {
+	if _, deletes := cleanupPolicy(l.Topic); !deletes {
+		return 0, nil
+	}
+	retentionMs := retentionConfig(l.Topic, "retention.ms", RetentionMs)
+	retentionBytes := retentionConfig(l.Topic, "retention.bytes", RetentionBytes)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.deleted {
+		return 0, ErrLogDeleted
+	}
+
+	deletable := 0
+	if retentionMs >= 0 {
+		for deletable < len(l.segments) && l.isExpired(l.segments[deletable], now, retentionMs) {
+			deletable++
+		}
+	}
+	if retentionBytes >= 0 {
+		excess := l.size() - retentionBytes
+		for _, segment := range l.segments[:deletable] {
+			excess -= segment.size
+		}
+		for deletable < len(l.segments) && l.segments[deletable].size > 0 && excess-l.segments[deletable].size >= 0 {
+			excess -= l.segments[deletable].size
+			deletable++
+		}
+	}
+	if deletable == 0 {
+		return 0, nil
+	}
+	return deletable, l.deleteSegments(deletable)
+}
+
+// isExpired reports whether every record in a non-empty segment is older than retentionMs
+func (l *Log) isExpired(segment *LogSegment, now time.Time, retentionMs int64) bool {
+	if segment.size == 0 {
+		return false
+	}
+	largestTimestamp := segment.maxTimestampSoFar
+	if largestTimestamp < 0 {
+		// No timestamps (e.g. only control batches): fall back to the file's age
+		largestTimestamp = segment.created.UnixMilli()
+	}
+	return now.UnixMilli()-largestTimestamp > retentionMs
+}
+
+// size returns the total size of the log's segments in bytes
+func (l *Log) size() int64 {
+	total := int64(0)
+	for _, segment := range l.segments {
+		total += segment.size
+	}
+	return total
+}
+
+// deleteSegments removes the count oldest segments and the producer state that
+// only they referenced
+func (l *Log) deleteSegments(count int) error {
+	if count == len(l.segments) {
+		if err := l.roll(l.logEndOffset()); err != nil {
+			return err
+		}
+	}
+
+	deleted := l.segments[:count]
+	l.segments = append([]*LogSegment(nil), l.segments[count:]...)
+	startOffset := l.logStartOffset()
+	for _, segment := range deleted {
+		if err := segment.Delete(); err != nil {
+			return err
+		}
+	}
+
+	l.producers.truncateHead(startOffset)
+	offsets, err := listProducerSnapshots(l.Dir)
+	if err != nil {
+		return err
+	}
+	for _, offset := range offsets {
+		if offset < startOffset {
+			os.Remove(segmentFileName(l.Dir, offset, ".snapshot"))
+		}
+	}
+
+	fmt.Printf("Deleted %d segments of %s-%d by retention, log start offset is now %d\n", count, l.Topic, l.Partition, startOffset)
+	return nil
+}
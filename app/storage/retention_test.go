@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"kafgo/app/compress"
+)
+
+func TestDeleteOldSegments(t *testing.T) {
+	// Each test log has three one-batch segments with records at 1000, 2000
+	// and 3000 ms; the last one is active
+	timestamps := []int64{1000, 2000, 3000}
+	batchSize := int64(len(keyedBatch(t, 0, compress.None, keyed("a", "1")).Encode()))
+
+	tests := []struct {
+		name           string
+		cleanupPolicy  string
+		retentionMs    int64
+		retentionBytes int64
+		now            int64
+		wantDeleted    int
+		wantStart      int64
+	}{
+		{"nothing expired", "delete", 1000, -1, 1500, 0, 0},
+		{"older segments expired", "delete", 1000, -1, 3500, 2, 2},
+		{"every segment expired", "delete", 1000, -1, 10000, 3, 3},
+		{"no time limit", "delete", -1, -1, 1 << 40, 0, 0},
+		{"over retention.bytes by a segment", "delete", -1, 2 * batchSize, 0, 1, 1},
+		{"over retention.bytes by less than a segment", "delete", -1, 3*batchSize - 1, 0, 0, 0},
+		{"over retention.bytes by more than a segment", "delete", -1, 2*batchSize - 1, 0, 1, 1},
+		{"no bytes allowed", "delete", -1, 0, 0, 3, 3},
+		{"time then size", "delete", 1000, batchSize, 2500, 2, 2},
+		{"compact only", "compact", 1000, 0, 10000, 0, 0},
+		{"compact and delete", "compact,delete", 1000, -1, 3500, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(policy string, retentionMs, retentionBytes int64) {
+				LogCleanupPolicy, RetentionMs, RetentionBytes = policy, retentionMs, retentionBytes
+			}(LogCleanupPolicy, RetentionMs, RetentionBytes)
+			LogCleanupPolicy, RetentionMs, RetentionBytes = tt.cleanupPolicy, tt.retentionMs, tt.retentionBytes
+
+			log := openTestLog(t)
+			for i, timestamp := range timestamps {
+				if i > 0 {
+					rollLog(t, log)
+				}
+				appendBatch(t, log, keyedBatch(t, timestamp, compress.None, keyed("a", "1")))
+			}
+
+			deleted, err := log.DeleteOldSegments(time.UnixMilli(tt.now))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("%d segments deleted, want %d", deleted, tt.wantDeleted)
+			}
+			if start := log.LogStartOffset(); start != tt.wantStart {
+				t.Errorf("log start offset %d, want %d", start, tt.wantStart)
+			}
+			// Offsets are never reused, even when every segment is deleted
+			if end := log.LogEndOffset(); end != 3 {
+				t.Errorf("log end offset %d, want 3", end)
+			}
+
+			want := make([]logRecord, 0)
+			for offset := tt.wantStart; offset < 3; offset++ {
+				want = append(want, logRecord{offset: offset, key: "a", value: "1"})
+			}
+			checkLogRecords(t, readLogRecords(t, log), want)
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"kafgo/app/metadata"
+)
+
+// LogSegment is one <base offset>.log file of a partition plus its indexes
+type LogSegment struct {
+	BaseOffset int64
+
+	log       *os.File
+	index     *OffsetIndex
+	timeIndex *TimeIndex
+	txnIndex  *TransactionIndex
+	size      int64
+	created   time.Time
+
+	// nextOffset is the offset after the last batch in this segment
+	nextOffset               int64
+	maxTimestampSoFar        int64
+	offsetOfMaxTimestamp     int64
+	bytesSinceLastIndexEntry int
+}
+
+// segmentFileName formats a base offset the way Kafka names segment files
+func segmentFileName(dir string, baseOffset int64, suffix string) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", baseOffset, suffix))
+}
+
+// openSegment opens (or creates) the segment starting at baseOffset in dir,
+// rebuilding its indexes if they are missing or do not match the log file
+func openSegment(dir string, baseOffset int64) (*LogSegment, error) {
+	logFile, err := os.OpenFile(segmentFileName(dir, baseOffset, ".log"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := logFile.Stat()
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+
+	index, err := openOffsetIndex(segmentFileName(dir, baseOffset, ".index"), baseOffset)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	timeIndex, err := openTimeIndex(segmentFileName(dir, baseOffset, ".timeindex"), baseOffset)
+	if err != nil {
+		logFile.Close()
+		index.Close()
+		return nil, err
+	}
+	txnIndex, err := openTransactionIndex(segmentFileName(dir, baseOffset, ".txnindex"))
+	if err != nil {
+		logFile.Close()
+		index.Close()
+		timeIndex.Close()
+		return nil, err
+	}
+
+	segment := &LogSegment{
+		BaseOffset:           baseOffset,
+		log:                  logFile,
+		index:                index,
+		timeIndex:            timeIndex,
+		txnIndex:             txnIndex,
+		size:                 stat.Size(),
+		created:              stat.ModTime(),
+		nextOffset:           baseOffset,
+		maxTimestampSoFar:    timeIndex.LastEntry().Timestamp,
+		offsetOfMaxTimestamp: timeIndex.LastEntry().Offset,
+	}
+	if segment.size == 0 {
+		segment.created = time.Now()
+	}
+
+	// An index pointing past the end of the log (or a non-empty log without
+	// an index) means the index files are stale, so rebuild them
+	last := index.LastEntry()
+	if (last.Position != 0 && int64(last.Position) >= segment.size) || (len(index.entries) == 0 && segment.size > int64(IndexIntervalBytes)) {
+		if err := segment.rebuildIndexes(); err != nil {
+			segment.Close()
+			return nil, err
+		}
+		return segment, nil
+	}
+
+	// Scan forward from the last indexed batch to find the segment's end offset
+	position := int64(last.Position)
+	for position < segment.size {
+		batch, err := segment.readBatchHeader(position)
+		if err != nil {
+			break
+		}
+		if batch.MaxTimestamp > segment.maxTimestampSoFar {
+			segment.maxTimestampSoFar = batch.MaxTimestamp
+			segment.offsetOfMaxTimestamp = batch.LastOffset()
+		}
+		segment.nextOffset = batch.LastOffset() + 1
+		position += int64(batch.Size())
+	}
+	return segment, nil
+}
+
+// rebuildIndexes recreates .index and .timeindex by scanning every batch in the segment
+func (s *LogSegment) rebuildIndexes() error {
+	fmt.Printf("Rebuilding indexes for segment %s\n", s.log.Name())
+	if err := s.index.Reset(); err != nil {
+		return err
+	}
+	if err := s.timeIndex.Reset(); err != nil {
+		return err
+	}
+	s.nextOffset = s.BaseOffset
+	s.maxTimestampSoFar = -1
+	s.bytesSinceLastIndexEntry = 0
+
+	position := int64(0)
+	for position < s.size {
+		batch, err := s.readBatchHeader(position)
+		if err != nil {
+			break
+		}
+		if err := s.indexBatch(batch, int32(position)); err != nil {
+			return err
+		}
+		s.nextOffset = batch.LastOffset() + 1
+		position += int64(batch.Size())
+	}
+	return nil
+}
+
+// readBatchHeader decodes the header of the batch starting at position
+func (s *LogSegment) readBatchHeader(position int64) (*metadata.RecordBatch, error) {
+	header := make([]byte, metadata.RecordBatchHeaderSize)
+	if _, err := s.log.ReadAt(header, position); err != nil {
+		return nil, err
+	}
+	batch, err := metadata.ParseRecordBatch(header)
+	if err != nil {
+		return nil, err
+	}
+	if position+int64(batch.Size()) > s.size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return batch, nil
+}
+
+// indexBatch adds index entries for a batch written at position when the index interval has passed
+func (s *LogSegment) indexBatch(batch *metadata.RecordBatch, position int32) error {
+	if batch.MaxTimestamp > s.maxTimestampSoFar {
+		s.maxTimestampSoFar = batch.MaxTimestamp
+		s.offsetOfMaxTimestamp = batch.LastOffset()
+	}
+
+	s.bytesSinceLastIndexEntry += batch.Size()
+	if s.bytesSinceLastIndexEntry > IndexIntervalBytes {
+		if err := s.index.Append(batch.LastOffset(), position); err != nil {
+			return err
+		}
+		if err := s.timeIndex.MaybeAppend(s.maxTimestampSoFar, s.offsetOfMaxTimestamp); err != nil {
+			return err
+		}
+		s.bytesSinceLastIndexEntry = 0
+	}
+	return nil
+}
+
+// Append writes already offset-assigned batches to the end of the segment
+func (s *LogSegment) Append(batches []*metadata.RecordBatch, data []byte) error {
+	position := s.size
+	if _, err := s.log.Write(data); err != nil {
+		return err
+	}
+	for _, batch := range batches {
+		if err := s.indexBatch(batch, int32(position)); err != nil {
+			return err
+		}
+		position += int64(batch.Size())
+	}
+	s.size = position
+	s.nextOffset = batches[len(batches)-1].LastOffset() + 1
+	return nil
+}
+
+// translateOffset returns the position of the first batch whose last offset is >= offset,
+// or the segment size if there is none
+func (s *LogSegment) translateOffset(offset int64) int64 {
+	position := int64(s.index.Lookup(offset))
+	for position < s.size {
+		batch, err := s.readBatchHeader(position)
+		if err != nil {
+			return s.size
+		}
+		if batch.LastOffset() >= offset {
+			return position
+		}
+		position += int64(batch.Size())
+	}
+	return s.size
+}
+
+// Read returns whole batches starting at the batch containing offset, up to
+// maxBytes and stopping at the first batch at or past maxOffset. If
+// minOneBatch is set the first batch is returned even if it is larger.
+func (s *LogSegment) Read(offset int64, maxOffset int64, maxBytes int, minOneBatch bool) ([]byte, error) {
+	start := s.translateOffset(offset)
+	end := start
+	for end < s.size {
+		batch, err := s.readBatchHeader(end)
+		if err != nil {
+			break
+		}
+		if batch.BaseOffset >= maxOffset {
+			break
+		}
+		if end+int64(batch.Size())-start > int64(maxBytes) && !(minOneBatch && end == start) {
+			break
+		}
+		end += int64(batch.Size())
+	}
+
+	data := make([]byte, end-start)
+	if _, err := s.log.ReadAt(data, start); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// batchHeadersFrom returns the headers of every batch from the one containing offset to the end
+func (s *LogSegment) batchHeadersFrom(offset int64) ([]*metadata.RecordBatch, error) {
+	batches := make([]*metadata.RecordBatch, 0)
+	for position := s.translateOffset(offset); position < s.size; {
+		batch, err := s.readBatchHeader(position)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+		position += int64(batch.Size())
+	}
+	return batches, nil
+}
+
+// readBatch decodes the whole batch, records included, starting at position
+func (s *LogSegment) readBatch(position int64) (*metadata.RecordBatch, error) {
+	header, err := s.readBatchHeader(position)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, header.Size())
+	if _, err := s.log.ReadAt(data, position); err != nil {
+		return nil, err
+	}
+	return metadata.ParseRecordBatch(data)
+}
+
+// forEachBatch decodes every batch in the segment, records included, and calls fn with it
+func (s *LogSegment) forEachBatch(fn func(batch *metadata.RecordBatch) error) error {
+	for position := int64(0); position < s.size; {
+		batch, err := s.readBatch(position)
+		if err != nil {
+			return err
+		}
+		// fn may rewrite the batch, so step over it first
+		position += int64(batch.Size())
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findOffsetByTimestamp returns the first record at or after startOffset with a
+// timestamp >= timestamp, using the time index to skip ahead
+func (s *LogSegment) findOffsetByTimestamp(timestamp int64, startOffset int64) (TimestampAndOffset, bool, error) {
+	if s.maxTimestampSoFar < timestamp {
+		return TimestampAndOffset{}, false, nil
+	}
+
+	position := s.translateOffset(max(s.timeIndex.Lookup(timestamp), startOffset))
+	for position < s.size {
+		header, err := s.readBatchHeader(position)
+		if err != nil {
+			return TimestampAndOffset{}, false, err
+		}
+		if header.MaxTimestamp >= timestamp && header.LastOffset() >= startOffset {
+			batch, err := s.readBatch(position)
+			if err != nil {
+				return TimestampAndOffset{}, false, err
+			}
+			return firstRecordAtOrAfter(batch, timestamp, startOffset), true, nil
+		}
+		position += int64(header.Size())
+	}
+	return TimestampAndOffset{}, false, nil
+}
+
+// findMaxTimestamp returns the first record carrying the largest timestamp in the segment
+func (s *LogSegment) findMaxTimestamp() (TimestampAndOffset, bool, error) {
+	if s.size == 0 || s.maxTimestampSoFar < 0 {
+		return TimestampAndOffset{}, false, nil
+	}
+	batch, err := s.readBatch(s.translateOffset(s.offsetOfMaxTimestamp))
+	if err != nil {
+		return TimestampAndOffset{}, false, err
+	}
+	return firstRecordAtOrAfter(batch, s.maxTimestampSoFar, batch.BaseOffset), true, nil
+}
+
+func (s *LogSegment) Size() int64 {
+	return s.size
+}
+
+// shouldRoll reports whether appending size more bytes should start a new segment
+func (s *LogSegment) shouldRoll(size int) bool {
+	if s.size == 0 {
+		return false
+	}
+	return s.size+int64(size) > SegmentBytes ||
+		time.Since(s.created).Milliseconds() > SegmentMs ||
+		s.index.SizeInBytes()+offsetIndexEntrySize > SegmentIndexBytes
+}
+
+// Flush syncs the segment's log and index files to disk
+func (s *LogSegment) Flush() error {
+	for _, file := range []*os.File{s.log, s.index.file, s.timeIndex.file, s.txnIndex.file} {
+		if err := file.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LogSegment) Close() error {
+	s.index.Close()
+	s.timeIndex.Close()
+	s.txnIndex.Close()
+	return s.log.Close()
+}
+
+// Delete closes the segment and removes its log and index files
+func (s *LogSegment) Delete() error {
+	s.Close()
+	dir, baseOffset := filepath.Dir(s.log.Name()), s.BaseOffset
+	for _, suffix := range []string{".log", ".index", ".timeindex", ".txnindex"} {
+		if err := os.Remove(segmentFileName(dir, baseOffset, suffix)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
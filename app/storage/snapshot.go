@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Producer state snapshots are <offset>.snapshot files holding the producer
+// state as of that offset, in Kafka's format:
+//
+//	Version (INT16) = 1
+//	Crc (UINT32), CRC-32C of everything after it
+//	ProducerEntries (ARRAY)
+//	  ProducerId (INT64), Epoch (INT16), LastSequence (INT32), LastOffset (INT64),
+//	  OffsetDelta (INT32), Timestamp (INT64), CoordinatorEpoch (INT32),
+//	  CurrentTxnFirstOffset (INT64)
+//
+// Only the last batch of each producer is kept. Producers with an open
+// transaction but no sequenced batch (coordinator writes) are stored with
+// LastSequence and LastOffset -1.
+const (
+	producerSnapshotVersion   int16 = 1
+	producerSnapshotEntrySize       = 8 + 2 + 4 + 8 + 4 + 8 + 4 + 8
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// writeProducerSnapshot writes the producers to <offset>.snapshot in dir and syncs it
+func writeProducerSnapshot(dir string, offset int64, producers map[int64]*ProducerStateEntry) error {
+	producerIDs := make([]int64, 0, len(producers))
+	for producerID, entry := range producers {
+		if len(entry.Batches) > 0 || entry.CurrentTxnFirstOffset >= 0 {
+			producerIDs = append(producerIDs, producerID)
+		}
+	}
+	sort.Slice(producerIDs, func(i, j int) bool { return producerIDs[i] < producerIDs[j] })
+
+	entries := make([]byte, 0, 4+len(producerIDs)*producerSnapshotEntrySize)
+	entries = binary.BigEndian.AppendUint32(entries, uint32(len(producerIDs)))
+	for _, producerID := range producerIDs {
+		entry := producers[producerID]
+		last := BatchMetadata{FirstSeq: -1, LastSeq: -1, FirstOffset: -1, LastOffset: -1, Timestamp: -1}
+		if len(entry.Batches) > 0 {
+			last = entry.Batches[len(entry.Batches)-1]
+		}
+		entries = binary.BigEndian.AppendUint64(entries, uint64(entry.ProducerID))
+		entries = binary.BigEndian.AppendUint16(entries, uint16(entry.ProducerEpoch))
+		entries = binary.BigEndian.AppendUint32(entries, uint32(last.LastSeq))
+		entries = binary.BigEndian.AppendUint64(entries, uint64(last.LastOffset))
+		entries = binary.BigEndian.AppendUint32(entries, uint32(last.LastOffset-last.FirstOffset))
+		entries = binary.BigEndian.AppendUint64(entries, uint64(last.Timestamp))
+		entries = binary.BigEndian.AppendUint32(entries, uint32(entry.CoordinatorEpoch))
+		entries = binary.BigEndian.AppendUint64(entries, uint64(entry.CurrentTxnFirstOffset))
+	}
+
+	buf := binary.BigEndian.AppendUint16(make([]byte, 0, 6+len(entries)), uint16(producerSnapshotVersion))
+	buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum(entries, crc32cTable))
+	buf = append(buf, entries...)
+
+	file, err := os.Create(segmentFileName(dir, offset, ".snapshot"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(buf); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// readProducerSnapshot loads the producers stored in a snapshot file
+func readProducerSnapshot(path string) (map[int64]*ProducerStateEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 10 {
+		return nil, errors.New("snapshot is truncated")
+	}
+	if version := int16(binary.BigEndian.Uint16(data[0:2])); version != producerSnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	entries := data[6:]
+	if crc := crc32.Checksum(entries, crc32cTable); crc != binary.BigEndian.Uint32(data[2:6]) {
+		return nil, fmt.Errorf("snapshot CRC mismatch: computed %08x", crc)
+	}
+
+	count := int64(int32(binary.BigEndian.Uint32(entries[0:4])))
+	entries = entries[4:]
+	if count < 0 || count*producerSnapshotEntrySize != int64(len(entries)) {
+		return nil, fmt.Errorf("snapshot claims %d producers in %d bytes", count, len(entries))
+	}
+
+	producers := make(map[int64]*ProducerStateEntry, count)
+	for offset := 0; offset < len(entries); offset += producerSnapshotEntrySize {
+		entry := entries[offset : offset+producerSnapshotEntrySize]
+		lastSeq := int32(binary.BigEndian.Uint32(entry[10:14]))
+		lastOffset := int64(binary.BigEndian.Uint64(entry[14:22]))
+		offsetDelta := int32(binary.BigEndian.Uint32(entry[22:26]))
+
+		// The first sequence is recovered from the last one, undoing any wrap-around
+		firstSeq := lastSeq - offsetDelta
+		if lastSeq < offsetDelta {
+			firstSeq = lastSeq + math.MaxInt32 - offsetDelta + 1
+		}
+
+		producer := &ProducerStateEntry{
+			ProducerID:            int64(binary.BigEndian.Uint64(entry[0:8])),
+			ProducerEpoch:         int16(binary.BigEndian.Uint16(entry[8:10])),
+			CoordinatorEpoch:      int32(binary.BigEndian.Uint32(entry[34:38])),
+			CurrentTxnFirstOffset: int64(binary.BigEndian.Uint64(entry[38:46])),
+		}
+		if lastOffset >= 0 {
+			producer.Batches = []BatchMetadata{{
+				FirstSeq:    firstSeq,
+				LastSeq:     lastSeq,
+				FirstOffset: lastOffset - int64(offsetDelta),
+				LastOffset:  lastOffset,
+				Timestamp:   int64(binary.BigEndian.Uint64(entry[26:34])),
+			}}
+		}
+		producers[producer.ProducerID] = producer
+	}
+	return producers, nil
+}
+
+// listProducerSnapshots returns the offsets of the snapshot files in dir, in ascending order
+func listProducerSnapshots(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make([]int64, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".snapshot") {
+			continue
+		}
+		offset, err := strconv.ParseInt(strings.TrimSuffix(name, ".snapshot"), 10, 64)
+		if err != nil {
+			continue
+		}
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets, nil
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"encoding/binary"
+	"os"
+)
+
+const (
+	txnIndexVersion   int16 = 0
+	txnIndexEntrySize       = 2 + 8 + 8 + 8 + 8 // version + producer ID + first, last and last stable offsets
+)
+
+// AbortedTxn is the offset range of an aborted transaction in a partition
+type AbortedTxn struct {
+	ProducerID  int64
+	FirstOffset int64
+	// LastOffset is the offset of the abort marker
+	LastOffset int64
+	// LastStableOffset is the partition's last stable offset once the abort was written
+	LastStableOffset int64
+}
+
+// TransactionIndex is the .txnindex file of a segment, listing the aborted
+// transactions whose abort marker is in the segment
+type TransactionIndex struct {
+	file    *os.File
+	entries []AbortedTxn
+}
+
+func openTransactionIndex(path string) (*TransactionIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop a partly written entry left by a crash, so appends stay aligned
+	if whole := len(data) - len(data)%txnIndexEntrySize; whole < len(data) {
+		if err := file.Truncate(int64(whole)); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	index := &TransactionIndex{file: file}
+	for i := 0; i+txnIndexEntrySize <= len(data); i += txnIndexEntrySize {
+		index.entries = append(index.entries, AbortedTxn{
+			ProducerID:       int64(binary.BigEndian.Uint64(data[i+2 : i+10])),
+			FirstOffset:      int64(binary.BigEndian.Uint64(data[i+10 : i+18])),
+			LastOffset:       int64(binary.BigEndian.Uint64(data[i+18 : i+26])),
+			LastStableOffset: int64(binary.BigEndian.Uint64(data[i+26 : i+34])),
+		})
+	}
+	return index, nil
+}
+
+func (idx *TransactionIndex) Append(txn AbortedTxn) error {
+	entry := make([]byte, 0, txnIndexEntrySize)
+	entry = binary.BigEndian.AppendUint16(entry, uint16(txnIndexVersion))
+	entry = binary.BigEndian.AppendUint64(entry, uint64(txn.ProducerID))
+	entry = binary.BigEndian.AppendUint64(entry, uint64(txn.FirstOffset))
+	entry = binary.BigEndian.AppendUint64(entry, uint64(txn.LastOffset))
+	entry = binary.BigEndian.AppendUint64(entry, uint64(txn.LastStableOffset))
+	if _, err := idx.file.Write(entry); err != nil {
+		return err
+	}
+	idx.entries = append(idx.entries, txn)
+	return nil
+}
+
+// truncateTo removes the entries whose abort marker is at or past offset
+func (idx *TransactionIndex) truncateTo(offset int64) error {
+	kept := idx.entries[:0]
+	for _, txn := range idx.entries {
+		if txn.LastOffset < offset {
+			kept = append(kept, txn)
+		}
+	}
+	if len(kept) == len(idx.entries) {
+		return nil
+	}
+	retained := append([]AbortedTxn(nil), kept...)
+	idx.entries = nil
+	if err := idx.file.Truncate(0); err != nil {
+		return err
+	}
+	for _, txn := range retained {
+		if err := idx.Append(txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collect returns the aborted transactions overlapping [fetchOffset, upperBoundOffset)
+func (idx *TransactionIndex) collect(fetchOffset int64, upperBoundOffset int64) []AbortedTxn {
+	var overlapping []AbortedTxn
+	for _, txn := range idx.entries {
+		if txn.LastOffset >= fetchOffset && txn.FirstOffset < upperBoundOffset {
+			overlapping = append(overlapping, txn)
+		}
+	}
+	return overlapping
+}
+
+func (idx *TransactionIndex) Close() error {
+	return idx.file.Close()
+}
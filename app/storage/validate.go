@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"kafgo/app/metadata"
+)
+
+// ErrCorruptRecords is returned by Append when a produced record set is not a
+// sequence of well-formed v2 record batches (CORRUPT_MESSAGE)
+var ErrCorruptRecords = errors.New("corrupt record set")
+
+// RecordError identifies a record that caused its record set to be dropped
+type RecordError struct {
+	BatchIndex int32 // position of the record within its batch
+	Message    string
+}
+
+// InvalidRecordsError is returned by Append when batches are intact but some
+// of their records are not (INVALID_RECORD). Nothing from the record set is appended.
+type InvalidRecordsError struct {
+	RecordErrors []RecordError
+}
+
+func (e *InvalidRecordsError) Error() string {
+	first := e.RecordErrors[0]
+	return fmt.Sprintf("%d invalid records, first at index %d: %s", len(e.RecordErrors), first.BatchIndex, first.Message)
+}
+
+// readBatches splits a produce request's record set into its batches and
+// validates each one the way Kafka's LogValidator does: lengths, magic and
+// CRC-32C per batch, then every record, decompressing compressed batches. The
+// record set comes straight from a client, so every length is checked against
+// the bytes actually present before anything is sliced.
+func readBatches(records []byte) ([]*metadata.RecordBatch, error) {
+	batches := make([]*metadata.RecordBatch, 0)
+	var recordErrors []RecordError
+
+	for position := 0; position < len(records); {
+		remaining := records[position:]
+		if len(remaining) < metadata.RecordBatchHeaderSize {
+			return nil, fmt.Errorf("%w: truncated batch header at position %d", ErrCorruptRecords, position)
+		}
+
+		// BatchLength (INT32) counts everything after BaseOffset and itself
+		size := 12 + int64(int32(binary.BigEndian.Uint32(remaining[8:12])))
+		if size < metadata.RecordBatchHeaderSize || size > int64(len(remaining)) {
+			return nil, fmt.Errorf("%w: batch at position %d claims %d bytes, have %d", ErrCorruptRecords, position, size, len(remaining))
+		}
+
+		batch, err := metadata.ParseRecordBatch(remaining[:size])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorruptRecords, err)
+		}
+		if err := validateBatch(batch); err != nil {
+			return nil, fmt.Errorf("%w: batch at position %d: %v", ErrCorruptRecords, position, err)
+		}
+
+		data, err := batch.RecordData()
+		if err != nil {
+			return nil, fmt.Errorf("%w: batch at position %d: %v", ErrCorruptRecords, position, err)
+		}
+		if batch.IsControl() {
+			// Transaction markers are only written by the transaction coordinator
+			recordErrors = append(recordErrors, RecordError{BatchIndex: 0, Message: "clients may not write control batches"})
+		}
+		recordErrors = append(recordErrors, validateRecords(batch, data)...)
+
+		batches = append(batches, batch)
+		position += int(size)
+	}
+
+	if len(recordErrors) > 0 {
+		return nil, &InvalidRecordsError{RecordErrors: recordErrors}
+	}
+	return batches, nil
+}
+
+// validateBatch checks the batch header: magic, record count and CRC
+func validateBatch(batch *metadata.RecordBatch) error {
+	if batch.Magic < 2 {
+		return fmt.Errorf("magic %d is not supported, only v2 record batches are", batch.Magic)
+	}
+	if batch.Magic > 2 {
+		return fmt.Errorf("unknown magic %d", batch.Magic)
+	}
+	if batch.RecordCount < 1 {
+		return fmt.Errorf("record count %d, batches must contain at least one record", batch.RecordCount)
+	}
+	if batch.LastOffsetDelta != batch.RecordCount-1 {
+		return fmt.Errorf("last offset delta %d does not match record count %d", batch.LastOffsetDelta, batch.RecordCount)
+	}
+	if crc := batch.ComputeCRC(); crc != batch.CRC {
+		return fmt.Errorf("CRC mismatch: stored %08x, computed %08x", batch.CRC, crc)
+	}
+	return nil
+}
+
+// validateRecords decodes every record in data, the batch's decompressed
+// records section, and returns an error for each one that is malformed or out
+// of sequence, indexed by the record's position within the batch.
+func validateRecords(batch *metadata.RecordBatch, data []byte) []RecordError {
+	var recordErrors []RecordError
+	offset := 0
+	for i := int32(0); i < batch.RecordCount; i++ {
+		// Record length (varint)
+		recordLen, n := binary.Varint(data[offset:])
+		if n <= 0 || recordLen < 0 || int64(offset+n)+recordLen > int64(len(data)) {
+			// The remaining records cannot be located
+			return append(recordErrors, RecordError{BatchIndex: i, Message: "invalid record length"})
+		}
+		offset += n
+
+		record, err := metadata.DecodeRecord(data[offset : offset+int(recordLen)])
+		offset += int(recordLen)
+		if err != nil {
+			recordErrors = append(recordErrors, RecordError{BatchIndex: i, Message: err.Error()})
+			continue
+		}
+		if record.OffsetDelta != i {
+			recordErrors = append(recordErrors, RecordError{
+				BatchIndex: i,
+				Message:    fmt.Sprintf("offset delta %d, expected %d", record.OffsetDelta, i),
+			})
+		}
+	}
+
+	if offset != len(data) {
+		recordErrors = append(recordErrors, RecordError{
+			BatchIndex: batch.RecordCount - 1,
+			Message:    fmt.Sprintf("%d bytes after the last record", len(data)-offset),
+		})
+	}
+	return recordErrors
+}
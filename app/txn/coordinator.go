@@ -0,0 +1,434 @@
+package txn
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"kafgo/app/metadata"
+	"kafgo/app/storage"
+)
+
+// TransactionStateTopic is the internal topic transaction metadata is written
+// to. Every transactional ID is stored in partition 0.
+const TransactionStateTopic = "__transaction_state"
+
+// Kafka protocol error codes returned by the transaction coordinator
+const (
+	ErrNone                     int16 = 0
+	COORDINATOR_NOT_AVAILABLE   int16 = 15
+	INVALID_REQUEST             int16 = 42
+	INVALID_PRODUCER_EPOCH      int16 = 47
+	INVALID_TXN_STATE           int16 = 48
+	INVALID_PRODUCER_ID_MAPPING int16 = 49
+	INVALID_TRANSACTION_TIMEOUT int16 = 50
+	CONCURRENT_TRANSACTIONS     int16 = 51
+)
+
+// Coordinator settings, named after the broker configs they mirror
+var (
+	TransactionMaxTimeoutMs int32 = 900000 // transaction.max.timeout.ms
+	// TransactionAbortInterval is how often open transactions are checked for timeouts
+	TransactionAbortInterval = 10 * time.Second // transaction.abort.timed.out.transaction.cleanup.interval.ms
+)
+
+// coordinatorEpoch is written into every marker; this broker is the only
+// coordinator there has ever been
+const coordinatorEpoch int32 = 0
+
+// MarkerListener is told about every transaction marker the coordinator writes
+type MarkerListener func(tp TopicPartition, producerID int64, commit bool)
+
+// Coordinator runs the transaction protocol for every transactional ID on this broker
+type Coordinator struct {
+	mu           sync.Mutex
+	producerIDs  *ProducerIDManager
+	transactions map[string]*TransactionMetadata
+	onMarker     MarkerListener
+}
+
+func NewCoordinator(producerIDs *ProducerIDManager, onMarker MarkerListener) *Coordinator {
+	return &Coordinator{
+		producerIDs:  producerIDs,
+		transactions: make(map[string]*TransactionMetadata),
+		onMarker:     onMarker,
+	}
+}
+
+// InitResult is the producer ID and epoch handed to a transactional producer
+type InitResult struct {
+	ErrorCode     int16
+	ProducerID    int64
+	ProducerEpoch int16
+}
+
+// InitProducerId gives a transactional ID its producer ID and bumps its epoch,
+// fencing any older producer using the same transactional ID. An open
+// transaction of the old producer is aborted first. From InitProducerId v3 an
+// existing producer passes its current producerID and epoch (otherwise -1).
+func (c *Coordinator) InitProducerId(transactionalID string, timeoutMs int32, producerID int64, producerEpoch int16) InitResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := InitResult{ErrorCode: ErrNone, ProducerID: -1, ProducerEpoch: -1}
+	if transactionalID == "" {
+		result.ErrorCode = INVALID_REQUEST
+		return result
+	}
+	if timeoutMs <= 0 || timeoutMs > TransactionMaxTimeoutMs {
+		result.ErrorCode = INVALID_TRANSACTION_TIMEOUT
+		return result
+	}
+
+	meta, exists := c.transactions[transactionalID]
+	if !exists {
+		newID, err := c.producerIDs.Generate()
+		if err != nil {
+			fmt.Printf("InitProducerId for %s failed: %v\n", transactionalID, err)
+			result.ErrorCode = COORDINATOR_NOT_AVAILABLE
+			return result
+		}
+		meta = &TransactionMetadata{
+			TransactionalID: transactionalID,
+			ProducerID:      newID,
+			ProducerEpoch:   -1,
+			State:           Empty,
+			Partitions:      make(map[TopicPartition]struct{}),
+			StartTimestamp:  -1,
+		}
+	} else if producerID >= 0 && (producerID != meta.ProducerID || producerEpoch != meta.ProducerEpoch) {
+		result.ErrorCode = INVALID_PRODUCER_EPOCH
+		return result
+	}
+
+	switch meta.State {
+	case PrepareCommit, PrepareAbort:
+		result.ErrorCode = CONCURRENT_TRANSACTIONS
+		return result
+	case Ongoing:
+		if errorCode := c.completeTransaction(meta, false, true); errorCode != ErrNone {
+			result.ErrorCode = errorCode
+			return result
+		}
+		meta = c.transactions[transactionalID]
+	}
+
+	next := meta.clone()
+	if next.ProducerEpoch >= math.MaxInt16-1 {
+		// Out of epochs: continue under a new producer ID
+		newID, err := c.producerIDs.Generate()
+		if err != nil {
+			fmt.Printf("InitProducerId for %s failed: %v\n", transactionalID, err)
+			result.ErrorCode = COORDINATOR_NOT_AVAILABLE
+			return result
+		}
+		next.ProducerID = newID
+		next.ProducerEpoch = 0
+	} else {
+		next.ProducerEpoch++
+	}
+	next.TimeoutMs = timeoutMs
+	next.State = Empty
+	next.Partitions = make(map[TopicPartition]struct{})
+	next.StartTimestamp = -1
+	if errorCode := c.transition(next); errorCode != ErrNone {
+		result.ErrorCode = errorCode
+		return result
+	}
+
+	fmt.Printf("Transactional ID %s initialized as producer %d epoch %d\n", transactionalID, next.ProducerID, next.ProducerEpoch)
+	result.ProducerID = next.ProducerID
+	result.ProducerEpoch = next.ProducerEpoch
+	return result
+}
+
+// AddPartitions adds partitions to the producer's transaction, opening one if needed
+func (c *Coordinator) AddPartitions(transactionalID string, producerID int64, producerEpoch int16, partitions []TopicPartition) int16 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meta, errorCode := c.validateProducer(transactionalID, producerID, producerEpoch)
+	if errorCode != ErrNone {
+		return errorCode
+	}
+	if meta.State == PrepareCommit || meta.State == PrepareAbort {
+		return CONCURRENT_TRANSACTIONS
+	}
+
+	next := meta.clone()
+	if next.State != Ongoing {
+		next.State = Ongoing
+		next.Partitions = make(map[TopicPartition]struct{})
+		next.StartTimestamp = time.Now().UnixMilli()
+	}
+	added := false
+	for _, tp := range partitions {
+		if _, exists := next.Partitions[tp]; !exists {
+			next.Partitions[tp] = struct{}{}
+			added = true
+		}
+	}
+	if !added && meta.State == Ongoing {
+		// A retry: every partition is already part of the transaction
+		return ErrNone
+	}
+	return c.transition(next)
+}
+
+// EndTxn commits or aborts the producer's open transaction, writing a marker
+// to every partition in it. Retrying a completed EndTxn succeeds.
+func (c *Coordinator) EndTxn(transactionalID string, producerID int64, producerEpoch int16, commit bool) int16 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meta, errorCode := c.validateProducer(transactionalID, producerID, producerEpoch)
+	if errorCode != ErrNone {
+		return errorCode
+	}
+
+	switch meta.State {
+	case Ongoing:
+		return c.completeTransaction(meta, commit, false)
+	case CompleteCommit:
+		if commit {
+			return ErrNone
+		}
+	case CompleteAbort:
+		if !commit {
+			return ErrNone
+		}
+	case PrepareCommit, PrepareAbort:
+		return CONCURRENT_TRANSACTIONS
+	}
+	return INVALID_TXN_STATE
+}
+
+// validateProducer looks up a transactional ID and checks that producerID and
+// producerEpoch are its current ones
+func (c *Coordinator) validateProducer(transactionalID string, producerID int64, producerEpoch int16) (*TransactionMetadata, int16) {
+	meta, exists := c.transactions[transactionalID]
+	if !exists || meta.ProducerID != producerID {
+		return nil, INVALID_PRODUCER_ID_MAPPING
+	}
+	if meta.ProducerEpoch != producerEpoch {
+		return nil, INVALID_PRODUCER_EPOCH
+	}
+	return meta, ErrNone
+}
+
+// VerifyPartition checks that tp is part of the producer's open transaction,
+// as the group coordinator does before writing transactional offsets
+func (c *Coordinator) VerifyPartition(transactionalID string, producerID int64, producerEpoch int16, tp TopicPartition) int16 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meta, errorCode := c.validateProducer(transactionalID, producerID, producerEpoch)
+	if errorCode != ErrNone {
+		return errorCode
+	}
+	if meta.State != Ongoing {
+		return INVALID_TXN_STATE
+	}
+	if _, exists := meta.Partitions[tp]; !exists {
+		return INVALID_TXN_STATE
+	}
+	return ErrNone
+}
+
+// completeTransaction moves an ongoing transaction through PrepareCommit or
+// PrepareAbort, writes the markers and records the completed state. fence bumps
+// the epoch first, so the markers also fence the producer that started the
+// transaction (timeouts and re-initialization). It is called with c.mu held,
+// which is released while the markers are written.
+func (c *Coordinator) completeTransaction(meta *TransactionMetadata, commit bool, fence bool) int16 {
+	prepare := meta.clone()
+	prepare.State = PrepareAbort
+	if commit {
+		prepare.State = PrepareCommit
+	}
+	if fence && prepare.ProducerEpoch < math.MaxInt16-1 {
+		prepare.ProducerEpoch++
+	}
+	if errorCode := c.transition(prepare); errorCode != ErrNone {
+		return errorCode
+	}
+	return c.writeMarkersAndComplete(prepare)
+}
+
+// writeMarkersAndComplete writes the markers of a prepared transaction and
+// moves it to CompleteCommit or CompleteAbort. It is called with c.mu held and
+// releases it during the marker appends, so other transactional IDs are not
+// held up by the partitions' disks; requests for this one meanwhile see the
+// Prepare state and get CONCURRENT_TRANSACTIONS.
+func (c *Coordinator) writeMarkersAndComplete(prepare *TransactionMetadata) int16 {
+	commit := prepare.State == PrepareCommit
+	c.mu.Unlock()
+	for _, tp := range prepare.sortedPartitions() {
+		if err := c.writeMarker(prepare, tp, commit); err != nil {
+			// Kafka retries until the partition accepts the marker or is deleted;
+			// a partition that cannot take it here is left with an open transaction
+			fmt.Printf("Failed to write marker for %s to %s-%d: %v\n", prepare.TransactionalID, tp.Topic, tp.Partition, err)
+		}
+	}
+	c.mu.Lock()
+
+	complete := prepare.clone()
+	complete.State = CompleteAbort
+	if commit {
+		complete.State = CompleteCommit
+	}
+	complete.Partitions = make(map[TopicPartition]struct{})
+	complete.StartTimestamp = -1
+	if errorCode := c.transition(complete); errorCode != ErrNone {
+		return errorCode
+	}
+	fmt.Printf("Transaction of %s (producer %d epoch %d): %s\n",
+		complete.TransactionalID, complete.ProducerID, complete.ProducerEpoch, complete.State)
+	return ErrNone
+}
+
+func (c *Coordinator) writeMarker(meta *TransactionMetadata, tp TopicPartition, commit bool) error {
+	log, err := storage.GetLog(tp.Topic, tp.Partition)
+	if err != nil {
+		return err
+	}
+	if _, err := log.AppendControlMarker(meta.ProducerID, meta.ProducerEpoch, coordinatorEpoch, commit); err != nil {
+		return err
+	}
+	if c.onMarker != nil {
+		c.onMarker(tp, meta.ProducerID, commit)
+	}
+	return nil
+}
+
+// transition appends the new metadata to __transaction_state and then makes it current
+func (c *Coordinator) transition(next *TransactionMetadata) int16 {
+	next.LastUpdateTimestamp = time.Now().UnixMilli()
+	record := metadata.Record{
+		Key:   encodeTransactionKey(next.TransactionalID),
+		Value: encodeTransactionValue(next),
+	}
+	if err := appendTransactionRecords([]metadata.Record{record}, next.LastUpdateTimestamp); err != nil {
+		fmt.Printf("Failed to write transaction state of %s: %v\n", next.TransactionalID, err)
+		return COORDINATOR_NOT_AVAILABLE
+	}
+	c.transactions[next.TransactionalID] = next
+	return ErrNone
+}
+
+func appendTransactionRecords(records []metadata.Record, timestamp int64) error {
+	log, err := storage.GetLog(TransactionStateTopic, 0)
+	if err != nil {
+		return err
+	}
+	_, err = log.Append(metadata.NewRecordBatch(records, timestamp).Encode())
+	return err
+}
+
+// Start runs the background loop that aborts transactions open longer than their timeout
+func (c *Coordinator) Start() {
+	go func() {
+		ticker := time.NewTicker(TransactionAbortInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			c.abortTimedOut(now)
+		}
+	}()
+}
+
+// abortTimedOut aborts every ongoing transaction past its timeout, fencing its producer
+func (c *Coordinator) abortTimedOut(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// completeTransaction releases c.mu, so the map is not ranged over meanwhile
+	var timedOut []string
+	for transactionalID, meta := range c.transactions {
+		if meta.State == Ongoing && now.UnixMilli()-meta.StartTimestamp > int64(meta.TimeoutMs) {
+			timedOut = append(timedOut, transactionalID)
+		}
+	}
+	for _, transactionalID := range timedOut {
+		meta := c.transactions[transactionalID]
+		if meta == nil || meta.State != Ongoing {
+			// Ended or fenced while an earlier transaction was being aborted
+			continue
+		}
+		fmt.Printf("Transaction of %s timed out after %dms, aborting\n", meta.TransactionalID, meta.TimeoutMs)
+		c.completeTransaction(meta, false, true)
+	}
+}
+
+// Load rebuilds the transaction metadata by replaying __transaction_state,
+// then finishes transactions that were left between prepare and complete
+func (c *Coordinator) Load() error {
+	log, err := storage.GetLog(TransactionStateTopic, 0)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	offset := log.LogStartOffset()
+	for {
+		data, err := log.Read(offset, 1024*1024, true)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			break
+		}
+
+		reader := bytes.NewReader(data)
+		for reader.Len() > 0 {
+			batch, err := metadata.ReadRecordBatch(reader)
+			if err != nil {
+				return fmt.Errorf("invalid batch in %s at offset %d: %w", TransactionStateTopic, offset, err)
+			}
+			records, err := metadata.DecodeRecords(batch)
+			if err != nil {
+				return fmt.Errorf("invalid batch in %s at offset %d: %w", TransactionStateTopic, batch.BaseOffset, err)
+			}
+			for _, record := range records {
+				c.replayTransactionRecord(record)
+			}
+			offset = batch.LastOffset() + 1
+		}
+	}
+
+	var prepared []*TransactionMetadata
+	for _, meta := range c.transactions {
+		if meta.State == PrepareCommit || meta.State == PrepareAbort {
+			prepared = append(prepared, meta)
+		}
+	}
+	var errs []error
+	for _, meta := range prepared {
+		fmt.Printf("Completing transaction of %s left in %s\n", meta.TransactionalID, meta.State)
+		if errorCode := c.writeMarkersAndComplete(meta); errorCode != ErrNone {
+			errs = append(errs, fmt.Errorf("completing transaction of %s failed with error %d", meta.TransactionalID, errorCode))
+		}
+	}
+
+	fmt.Printf("Loaded %d transactional IDs from %s\n", len(c.transactions), TransactionStateTopic)
+	return errors.Join(errs...)
+}
+
+// replayTransactionRecord applies one __transaction_state record; a null value removes the transactional ID
+func (c *Coordinator) replayTransactionRecord(record metadata.Record) {
+	transactionalID, ok := decodeTransactionKey(record.Key)
+	if !ok {
+		return
+	}
+	if record.Value == nil {
+		delete(c.transactions, transactionalID)
+		return
+	}
+	if meta, ok := decodeTransactionValue(transactionalID, record.Value); ok {
+		c.transactions[transactionalID] = meta
+	}
+}
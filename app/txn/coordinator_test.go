@@ -0,0 +1,189 @@
+package txn
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kafgo/app/metadata"
+	"kafgo/app/storage"
+)
+
+// TestMain points the partition logs and the metadata log (where producer ID
+// blocks are reserved) at a scratch directory shared by the package's tests
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "txn-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	storage.LogDirs = []string{filepath.Join(dir, "logs")}
+	metadata.MetadataLogDir = filepath.Join(dir, "metadata")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// produceInTxn appends one record to tp in the producer's open transaction and
+// returns its offset
+func produceInTxn(t *testing.T, tp TopicPartition, producerID int64, producerEpoch int16) int64 {
+	t.Helper()
+	log, err := storage.GetLog(tp.Topic, tp.Partition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := metadata.NewRecordBatch([]metadata.Record{{Value: []byte("value")}}, 1700000000000)
+	batch.Attributes |= metadata.AttributeTransactional
+	batch.ProducerID = producerID
+	batch.ProducerEpoch = producerEpoch
+	batch.BaseSequence = 0
+	info, err := log.Append(batch.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.BaseOffset
+}
+
+// readBatch returns the batch holding offset
+func readBatch(t *testing.T, log *storage.Log, offset int64) *metadata.RecordBatch {
+	t.Helper()
+	data, err := log.Read(offset, 1024*1024, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := metadata.ReadRecordBatch(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return batch
+}
+
+func TestEndTxnAcrossTwoPartitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		commit      bool
+		wantState   TransactionState
+		wantControl int16
+	}{
+		{"commit", true, CompleteCommit, metadata.ControlTypeCommit},
+		{"abort", false, CompleteAbort, metadata.ControlTypeAbort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type marker struct {
+				tp     TopicPartition
+				commit bool
+			}
+			var markers []marker
+			c := NewCoordinator(NewProducerIDManager(1), func(tp TopicPartition, producerID int64, commit bool) {
+				markers = append(markers, marker{tp, commit})
+			})
+
+			transactionalID := "txn-" + tt.name
+			partitions := []TopicPartition{{"orders-" + tt.name, 0}, {"orders-" + tt.name, 1}}
+			init := c.InitProducerId(transactionalID, 60000, -1, -1)
+			if init.ErrorCode != ErrNone {
+				t.Fatalf("InitProducerId error %d", init.ErrorCode)
+			}
+			if errorCode := c.AddPartitions(transactionalID, init.ProducerID, init.ProducerEpoch, partitions); errorCode != ErrNone {
+				t.Fatalf("AddPartitions error %d", errorCode)
+			}
+			if state := c.transactions[transactionalID].State; state != Ongoing {
+				t.Fatalf("state after AddPartitions = %v, want Ongoing", state)
+			}
+			firstOffsets := make(map[TopicPartition]int64)
+			for _, tp := range partitions {
+				firstOffsets[tp] = produceInTxn(t, tp, init.ProducerID, init.ProducerEpoch)
+			}
+
+			if errorCode := c.EndTxn(transactionalID, init.ProducerID, init.ProducerEpoch, tt.commit); errorCode != ErrNone {
+				t.Fatalf("EndTxn error %d", errorCode)
+			}
+
+			meta := c.transactions[transactionalID]
+			if meta.State != tt.wantState || len(meta.Partitions) != 0 {
+				t.Errorf("state after EndTxn = %v with %d partitions, want %v with none", meta.State, len(meta.Partitions), tt.wantState)
+			}
+			if len(markers) != len(partitions) {
+				t.Fatalf("%d markers reported, want %d", len(markers), len(partitions))
+			}
+			for i, tp := range partitions {
+				if markers[i] != (marker{tp, tt.commit}) {
+					t.Errorf("marker %d = %+v, want %+v", i, markers[i], marker{tp, tt.commit})
+				}
+
+				log, err := storage.GetLog(tp.Topic, tp.Partition)
+				if err != nil {
+					t.Fatal(err)
+				}
+				end := log.LogEndOffset()
+				batch := readBatch(t, log, end-1)
+				controlType, err := metadata.ControlRecordType(batch)
+				if !batch.IsControl() || err != nil || controlType != tt.wantControl || batch.ProducerID != init.ProducerID {
+					t.Errorf("%v: last batch is not the %s marker of producer %d", tp, tt.name, init.ProducerID)
+				}
+				if lso := log.LastStableOffset(); lso != end {
+					t.Errorf("%v: last stable offset %d, want the log end offset %d", tp, lso, end)
+				}
+				aborted := log.CollectAbortedTxns(0, end)
+				if tt.commit && len(aborted) != 0 {
+					t.Errorf("%v: committed transaction listed as aborted: %+v", tp, aborted)
+				}
+				if !tt.commit && (len(aborted) != 1 || aborted[0].ProducerID != init.ProducerID || aborted[0].FirstOffset != firstOffsets[tp]) {
+					t.Errorf("%v: aborted transactions %+v, want producer %d from offset %d", tp, aborted, init.ProducerID, firstOffsets[tp])
+				}
+			}
+
+			// Retrying the same EndTxn succeeds, the opposite one does not
+			if errorCode := c.EndTxn(transactionalID, init.ProducerID, init.ProducerEpoch, tt.commit); errorCode != ErrNone {
+				t.Errorf("retried EndTxn error %d, want none", errorCode)
+			}
+			if errorCode := c.EndTxn(transactionalID, init.ProducerID, init.ProducerEpoch, !tt.commit); errorCode != INVALID_TXN_STATE {
+				t.Errorf("opposite EndTxn error %d, want INVALID_TXN_STATE", errorCode)
+			}
+			if len(markers) != len(partitions) {
+				t.Errorf("retries wrote %d more markers", len(markers)-len(partitions))
+			}
+		})
+	}
+}
+
+func TestVerifyPartition(t *testing.T) {
+	offsets := TopicPartition{"__consumer_offsets", 0}
+	c := NewCoordinator(NewProducerIDManager(1), nil)
+	const transactionalID = "txn-verify"
+	init := c.InitProducerId(transactionalID, 60000, -1, -1)
+	if init.ErrorCode != ErrNone {
+		t.Fatalf("InitProducerId error %d", init.ErrorCode)
+	}
+	if errorCode := c.VerifyPartition(transactionalID, init.ProducerID, init.ProducerEpoch, offsets); errorCode != INVALID_TXN_STATE {
+		t.Errorf("VerifyPartition without a transaction error %d, want INVALID_TXN_STATE", errorCode)
+	}
+	if errorCode := c.AddPartitions(transactionalID, init.ProducerID, init.ProducerEpoch, []TopicPartition{offsets}); errorCode != ErrNone {
+		t.Fatalf("AddPartitions error %d", errorCode)
+	}
+
+	tests := []struct {
+		name            string
+		transactionalID string
+		producerID      int64
+		producerEpoch   int16
+		tp              TopicPartition
+		want            int16
+	}{
+		{"added partition", transactionalID, init.ProducerID, init.ProducerEpoch, offsets, ErrNone},
+		{"partition not added", transactionalID, init.ProducerID, init.ProducerEpoch, TopicPartition{"orders-verify", 0}, INVALID_TXN_STATE},
+		{"old epoch", transactionalID, init.ProducerID, init.ProducerEpoch - 1, offsets, INVALID_PRODUCER_EPOCH},
+		{"other producer", transactionalID, init.ProducerID + 1, init.ProducerEpoch, offsets, INVALID_PRODUCER_ID_MAPPING},
+		{"unknown transactional ID", "txn-unknown", init.ProducerID, init.ProducerEpoch, offsets, INVALID_PRODUCER_ID_MAPPING},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errorCode := c.VerifyPartition(tt.transactionalID, tt.producerID, tt.producerEpoch, tt.tp); errorCode != tt.want {
+				t.Errorf("VerifyPartition error %d, want %d", errorCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Package txn hands out producer IDs and coordinates transactions.
+package txn
+
+import (
+	"fmt"
+	"sync"
+
+	"kafgo/app/metadata"
+)
+
+// ProducerIDBlockSize is how many producer IDs are reserved in the metadata log at a time
+var ProducerIDBlockSize int64 = 1000
+
+// ProducerIDManager generates unique producer IDs from blocks reserved with
+// ProducerIdsRecords, so IDs are never reused across restarts
+type ProducerIDManager struct {
+	mu       sync.Mutex
+	brokerID int32
+	next     int64 // next ID of the current block
+	end      int64 // first ID past the current block
+}
+
+func NewProducerIDManager(brokerID int32) *ProducerIDManager {
+	return &ProducerIDManager{brokerID: brokerID}
+}
+
+// SetBrokerID changes the broker ID recorded with the blocks reserved from now on
+func (m *ProducerIDManager) SetBrokerID(brokerID int32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.brokerID = brokerID
+}
+
+// Generate returns a new producer ID, reserving a new block when the current one is used up
+func (m *ProducerIDManager) Generate() (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.next >= m.end {
+		start, err := metadata.AllocateProducerIDs(m.brokerID, ProducerIDBlockSize)
+		if err != nil {
+			return -1, fmt.Errorf("failed to allocate producer IDs: %w", err)
+		}
+		m.next = start
+		m.end = start + ProducerIDBlockSize
+	}
+
+	id := m.next
+	m.next++
+	return id, nil
+}
@@ -0,0 +1,210 @@
+package txn
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// TransactionState follows Kafka's transaction coordinator state machine
+type TransactionState int8
+
+const (
+	// Empty: the producer is initialized but has no transaction open
+	Empty TransactionState = iota
+	// Ongoing: partitions have been added to an open transaction
+	Ongoing
+	// PrepareCommit: EndTxn(commit) was accepted, markers are being written
+	PrepareCommit
+	// PrepareAbort: EndTxn(abort) or a timeout was accepted, markers are being written
+	PrepareAbort
+	// CompleteCommit: every partition has its COMMIT marker
+	CompleteCommit
+	// CompleteAbort: every partition has its ABORT marker
+	CompleteAbort
+	// Dead: the transactional ID has expired
+	Dead
+)
+
+func (s TransactionState) String() string {
+	switch s {
+	case Empty:
+		return "Empty"
+	case Ongoing:
+		return "Ongoing"
+	case PrepareCommit:
+		return "PrepareCommit"
+	case PrepareAbort:
+		return "PrepareAbort"
+	case CompleteCommit:
+		return "CompleteCommit"
+	case CompleteAbort:
+		return "CompleteAbort"
+	case Dead:
+		return "Dead"
+	}
+	return "Unknown"
+}
+
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// TransactionMetadata is the coordinator's view of one transactional ID, as
+// stored in __transaction_state
+type TransactionMetadata struct {
+	TransactionalID string
+	ProducerID      int64
+	ProducerEpoch   int16
+	TimeoutMs       int32
+	State           TransactionState
+	// Partitions holds the partitions of the open (or completing) transaction
+	Partitions map[TopicPartition]struct{}
+	// StartTimestamp is when the open transaction added its first partition, or -1
+	StartTimestamp      int64
+	LastUpdateTimestamp int64
+}
+
+func (m *TransactionMetadata) clone() *TransactionMetadata {
+	clone := *m
+	clone.Partitions = make(map[TopicPartition]struct{}, len(m.Partitions))
+	for tp := range m.Partitions {
+		clone.Partitions[tp] = struct{}{}
+	}
+	return &clone
+}
+
+// sortedPartitions returns the transaction's partitions ordered by topic and partition
+func (m *TransactionMetadata) sortedPartitions() []TopicPartition {
+	partitions := make([]TopicPartition, 0, len(m.Partitions))
+	for tp := range m.Partitions {
+		partitions = append(partitions, tp)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+	return partitions
+}
+
+// __transaction_state record versions, as written by Kafka
+const (
+	transactionLogKeyVersion   int16 = 0
+	transactionLogValueVersion int16 = 0
+)
+
+// encodeTransactionKey writes a TransactionLogKey: version, transactional ID
+func encodeTransactionKey(transactionalID string) []byte {
+	buf := make([]byte, 0, 2+2+len(transactionalID))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(transactionLogKeyVersion))
+	return appendString(buf, transactionalID)
+}
+
+func decodeTransactionKey(data []byte) (string, bool) {
+	if len(data) < 2 || int16(binary.BigEndian.Uint16(data[0:2])) != transactionLogKeyVersion {
+		return "", false
+	}
+	transactionalID, _, ok := readString(data, 2)
+	return transactionalID, ok
+}
+
+// encodeTransactionValue writes a TransactionLogValue v0: producer ID and
+// epoch, timeout, state, the partitions grouped by topic, and the last update
+// and start timestamps
+func encodeTransactionValue(meta *TransactionMetadata) []byte {
+	buf := make([]byte, 0, 64)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(transactionLogValueVersion))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(meta.ProducerID))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(meta.ProducerEpoch))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(meta.TimeoutMs))
+	buf = append(buf, byte(meta.State))
+
+	// TransactionPartitions (ARRAY of Topic, PartitionIds)
+	partitions := meta.sortedPartitions()
+	topics := make([]string, 0)
+	for _, tp := range partitions {
+		if len(topics) == 0 || topics[len(topics)-1] != tp.Topic {
+			topics = append(topics, tp.Topic)
+		}
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(topics)))
+	for _, topic := range topics {
+		buf = appendString(buf, topic)
+		ids := make([]int32, 0)
+		for _, tp := range partitions {
+			if tp.Topic == topic {
+				ids = append(ids, tp.Partition)
+			}
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(ids)))
+		for _, id := range ids {
+			buf = binary.BigEndian.AppendUint32(buf, uint32(id))
+		}
+	}
+
+	buf = binary.BigEndian.AppendUint64(buf, uint64(meta.LastUpdateTimestamp))
+	return binary.BigEndian.AppendUint64(buf, uint64(meta.StartTimestamp))
+}
+
+func decodeTransactionValue(transactionalID string, data []byte) (*TransactionMetadata, bool) {
+	meta := &TransactionMetadata{TransactionalID: transactionalID, Partitions: make(map[TopicPartition]struct{})}
+	if len(data) < 2+8+2+4+1+4 || int16(binary.BigEndian.Uint16(data[0:2])) != transactionLogValueVersion {
+		return nil, false
+	}
+	meta.ProducerID = int64(binary.BigEndian.Uint64(data[2:10]))
+	meta.ProducerEpoch = int16(binary.BigEndian.Uint16(data[10:12]))
+	meta.TimeoutMs = int32(binary.BigEndian.Uint32(data[12:16]))
+	meta.State = TransactionState(data[16])
+	pos := 17
+
+	// TransactionPartitions (nullable ARRAY)
+	numTopics := int(int32(binary.BigEndian.Uint32(data[pos : pos+4])))
+	pos += 4
+	for i := 0; i < numTopics; i++ {
+		var topic string
+		var ok bool
+		topic, pos, ok = readString(data, pos)
+		if !ok || pos+4 > len(data) {
+			return nil, false
+		}
+		numPartitions := int(int32(binary.BigEndian.Uint32(data[pos : pos+4])))
+		pos += 4
+		if numPartitions < 0 || pos+4*numPartitions > len(data) {
+			return nil, false
+		}
+		for j := 0; j < numPartitions; j++ {
+			partition := int32(binary.BigEndian.Uint32(data[pos : pos+4]))
+			meta.Partitions[TopicPartition{Topic: topic, Partition: partition}] = struct{}{}
+			pos += 4
+		}
+	}
+
+	if pos+16 > len(data) {
+		return nil, false
+	}
+	meta.LastUpdateTimestamp = int64(binary.BigEndian.Uint64(data[pos : pos+8]))
+	meta.StartTimestamp = int64(binary.BigEndian.Uint64(data[pos+8 : pos+16]))
+	return meta, true
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+func readString(data []byte, offset int) (string, int, bool) {
+	if offset+2 > len(data) {
+		return "", offset, false
+	}
+	length := int(int16(binary.BigEndian.Uint16(data[offset : offset+2])))
+	offset += 2
+	if length < 0 {
+		return "", offset, true
+	}
+	if offset+length > len(data) {
+		return "", offset, false
+	}
+	return string(data[offset : offset+length]), offset + length, true
+}
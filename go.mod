@@ -1,3 +1,8 @@
 module kafgo
 
 go 1.25.2
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/pierrec/lz4/v4 v4.1.31
+)
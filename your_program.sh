@@ -1,4 +1,5 @@
-go build  app/*.go
-
-go run app/main.go
+#!/bin/sh
+set -e
 
+go build -o /tmp/kafgo ./app
+exec /tmp/kafgo "$@"